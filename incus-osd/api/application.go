@@ -18,6 +18,8 @@ type ApplicationState struct {
 }
 
 // Application represents the state and configuration of a generic application.
+//
+// swagger:model
 type Application struct {
 	State ApplicationState `json:"state" yaml:"state"`
 
@@ -31,3 +33,24 @@ type ApplicationAction struct {
 	Action string            `json:"action"           yaml:"action"`
 	Config map[string]string `json:"config,omitempty" yaml:"config,omitempty"`
 }
+
+// ApplicationPost defines a struct with information about the application to install.
+//
+// swagger:model
+type ApplicationPost struct {
+	Name string `json:"name" yaml:"name"`
+}
+
+// ApplicationBackup defines a struct used to configure an application backup.
+//
+// swagger:model
+type ApplicationBackup struct {
+	Complete bool `json:"complete" yaml:"complete"` // If true, also include the application's data.
+}
+
+// ApplicationSwitchVersion defines a struct with information about the application version to switch to.
+//
+// swagger:model
+type ApplicationSwitchVersion struct {
+	Version string `json:"version" yaml:"version"`
+}
@@ -19,6 +19,8 @@ type ApplicationIncusState struct {
 }
 
 // ApplicationIncus represents the state and configuration of the Incus application.
+//
+// swagger:model
 type ApplicationIncus struct {
 	State ApplicationIncusState `json:"state" yaml:"state"`
 
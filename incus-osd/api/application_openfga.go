@@ -8,7 +8,7 @@ import (
 type ApplicationOpenFGAConfig struct {
 	ApplicationConfig
 
-	APITokens []string `json:"api_tokens" yaml:"api_tokens"`
+	APITokens []string `incusos:"secret" json:"api_tokens" yaml:"api_tokens"`
 
 	// Sync holds the openfga-sync configuration, the daemon only runs when set.
 	Sync *syncconfig.Config `incusos:"-" json:"sync,omitempty" yaml:"sync,omitempty"`
@@ -20,6 +20,8 @@ type ApplicationOpenFGAState struct {
 }
 
 // ApplicationOpenFGA represents the state and configuration of the openfga application.
+//
+// swagger:model
 type ApplicationOpenFGA struct {
 	State ApplicationOpenFGAState `json:"state" yaml:"state"`
 
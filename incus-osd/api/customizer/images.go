@@ -29,6 +29,27 @@ type ImagesPostSeeds struct {
 	Update           *apiseed.Update           `json:"update"            yaml:"update"`
 }
 
+// ImagesBatchPost represents the data needed for POST /1.0/images/batch.
+type ImagesBatchPost struct {
+	Architecture apiimages.UpdateFileArchitecture `json:"architecture" yaml:"architecture"`
+	Type         string                           `json:"type"         yaml:"type"`
+	Channel      string                           `json:"channel"      yaml:"channel"`
+	Version      string                           `json:"version"      yaml:"version"`
+
+	// SeedsTemplate is a Go text/template rendering to the YAML (or JSON) form of ImagesPostSeeds for each host.
+	// The "quote" and "toYaml" functions safely insert a host variable as a quoted string or as YAML.
+	SeedsTemplate string `json:"seeds_template" yaml:"seeds_template"`
+
+	// Hosts lists the variables of each host, either as a list or as CSV with a header line. Each host
+	// must have a unique "name" variable, used to name its image. At most 50 hosts can be provided.
+	Hosts    []map[string]string `json:"hosts"     yaml:"hosts"`
+	HostsCSV string              `json:"hosts_csv" yaml:"hosts_csv"`
+
+	// Output is either "manifest" (default) for per-image download URLs, or "zip" for a single archive.
+	Output  string `json:"output"  yaml:"output"`
+	Offline bool   `json:"offline" yaml:"offline"`
+}
+
 // UpdatesPost represents the data needed for POST /1.0/updates.
 type UpdatesPost struct {
 	UpdateFilter `yaml:",inline"`
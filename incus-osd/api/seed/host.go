@@ -0,0 +1,19 @@
+package seed
+
+import (
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// Host represents a complete host description, usable both as a seed and with "incus admin os apply".
+// When used as a seed, each section is used in place of its dedicated seed file if that file is missing.
+type Host struct {
+	Applications []Application            `json:"applications,omitempty" yaml:"applications,omitempty"`
+	Kernel       *api.SystemKernelConfig  `json:"kernel,omitempty"       yaml:"kernel,omitempty"`
+	Logging      *api.SystemLoggingConfig `json:"logging,omitempty"      yaml:"logging,omitempty"`
+	Network      *api.SystemNetworkConfig `json:"network,omitempty"      yaml:"network,omitempty"`
+	Services     map[string]any           `json:"services,omitempty"     yaml:"services,omitempty"` // Service configurations, keyed by service name.
+	Storage      *api.SystemStorageConfig `json:"storage,omitempty"      yaml:"storage,omitempty"`
+	Update       *api.SystemUpdateConfig  `json:"update,omitempty"       yaml:"update,omitempty"`
+
+	Version string `json:"version" yaml:"version"`
+}
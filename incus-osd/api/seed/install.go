@@ -1,5 +1,9 @@
 package seed
 
+import (
+	"time"
+)
+
 // Install represents the install seed.
 type Install struct {
 	Version string `json:"version" yaml:"version"`
@@ -9,6 +13,51 @@ type Install struct {
 	ForceReboot              bool             `json:"force_reboot"                         yaml:"force_reboot"`                         // If true, reboot the system automatically upon completion rather than waiting for the install media to be removed.
 	Security                 *InstallSecurity `json:"security,omitempty"                   yaml:"security,omitempty"`                   // Optional install options to allow IncusOS to run in a degraded security state.
 	Target                   *InstallTarget   `json:"target"                               yaml:"target"`                               // Optional selector for the target install disk; if not set, expect a single drive to be present.
+	MirrorTarget             *InstallTarget   `json:"mirror_target,omitempty"              yaml:"mirror_target,omitempty"`              // Optional selector for a second install disk, which will hold a mirror of the boot partitions and "local" storage pool.
+	Layout                   *InstallLayout   `json:"layout,omitempty"                     yaml:"layout,omitempty"`                     // Optional overrides of the partition sizes on the install disk.
+	Report                   *InstallReport   `json:"report,omitempty"                     yaml:"report,omitempty"`                     // Optional URL to report the install progress and result to.
+	CheckOnly                bool             `json:"check_only,omitempty"                 yaml:"check_only,omitempty"`                 // If true, don't install but run all the hardware compatibility checks and save the resulting report to the seed partition.
+}
+
+// InstallReport defines where to report the install progress and result.
+type InstallReport struct {
+	URL         string `json:"url"                   yaml:"url"`                   // URL to POST each install phase transition to, as JSON.
+	Certificate string `json:"certificate,omitempty" yaml:"certificate,omitempty"` // Optional PEM-encoded client certificate used to authenticate to the server.
+	Key         string `json:"key,omitempty"         yaml:"key,omitempty"`         // PEM-encoded private key of the client certificate.
+}
+
+// InstallReportEvent is sent to the install report URL on each install phase transition.
+type InstallReportEvent struct {
+	Phase        string                 `json:"phase"                   yaml:"phase"`                   // One of "requirements", "target", "copy", "tpm", "done", "failed" or "check".
+	Timestamp    time.Time              `json:"timestamp"               yaml:"timestamp"`               // Time of the phase transition.
+	Message      string                 `json:"message,omitempty"       yaml:"message,omitempty"`       // Human-readable description of the phase.
+	Progress     float64                `json:"progress,omitempty"      yaml:"progress,omitempty"`      // Overall progress of the copy phase, between 0 and 1.
+	Error        string                 `json:"error,omitempty"         yaml:"error,omitempty"`         // Error details when the install failed.
+	Target       string                 `json:"target,omitempty"        yaml:"target,omitempty"`        // ID of the selected install disk.
+	MirrorTarget string                 `json:"mirror_target,omitempty" yaml:"mirror_target,omitempty"` // ID of the selected mirror install disk, if any.
+	Hardware     *InstallReportHardware `json:"hardware,omitempty"      yaml:"hardware,omitempty"`      // Summary of the system's hardware.
+	Check        *InstallCheckReport    `json:"check,omitempty"         yaml:"check,omitempty"`         // Hardware compatibility report, when only running the checks.
+}
+
+// InstallReportHardware is a summary of the system's hardware, used to identify the system being installed.
+type InstallReportHardware struct {
+	Vendor       string   `json:"vendor,omitempty"        yaml:"vendor,omitempty"`        // System vendor.
+	Product      string   `json:"product,omitempty"       yaml:"product,omitempty"`       // System product name.
+	Serial       string   `json:"serial,omitempty"        yaml:"serial,omitempty"`        // System serial number.
+	UUID         string   `json:"uuid,omitempty"          yaml:"uuid,omitempty"`          // System UUID.
+	CPU          string   `json:"cpu,omitempty"           yaml:"cpu,omitempty"`           // CPU model name.
+	CPUThreads   uint64   `json:"cpu_threads,omitempty"   yaml:"cpu_threads,omitempty"`   // Total number of CPU threads.
+	Memory       uint64   `json:"memory,omitempty"        yaml:"memory,omitempty"`        // Total memory in bytes.
+	MACAddresses []string `json:"mac_addresses,omitempty" yaml:"mac_addresses,omitempty"` // MAC addresses of the network ports.
+	Disks        []string `json:"disks,omitempty"         yaml:"disks,omitempty"`         // IDs of the potential install disks.
+}
+
+// InstallLayout defines optional overrides of the partition sizes on the install disk. Sizes are given as strings such as 50GiB.
+type InstallLayout struct {
+	RootSize      string `json:"root_size,omitempty"       yaml:"root_size,omitempty"`       // Size of the encrypted root partition; defaults to, and must be at least, 25GiB.
+	SwapSize      string `json:"swap_size,omitempty"       yaml:"swap_size,omitempty"`       // Size of the encrypted swap partition; defaults to 4GiB and must be at least 1GiB.
+	LocalPoolSize string `json:"local_pool_size,omitempty" yaml:"local_pool_size,omitempty"` // Size of the "local" storage pool partition, at least 10GiB; if not set, the pool spans the rest of the disk.
+	ReservedSize  string `json:"reserved_size,omitempty"   yaml:"reserved_size,omitempty"`   // Space to leave unpartitioned at the end of the disk, for use by a separate pool or for overprovisioning.
 }
 
 // InstallSecurity defines a set of mutually exclusive options that allow IncusOS to run in a degraded security state.
@@ -0,0 +1,68 @@
+package seed
+
+import (
+	"time"
+)
+
+// InstallCheckReport is the hardware compatibility report saved to the seed partition when the install seed only requests checks.
+type InstallCheckReport struct {
+	Timestamp  time.Time              `json:"timestamp"          yaml:"timestamp"`          // Time the checks were run.
+	Version    string                 `json:"version"            yaml:"version"`            // Version of the install media running the checks.
+	Compatible bool                   `json:"compatible"         yaml:"compatible"`         // True if the system meets all the requirements to install onto.
+	Problems   []string               `json:"problems,omitempty" yaml:"problems,omitempty"` // Unmet requirements which prevent installing.
+	Warnings   []string               `json:"warnings,omitempty" yaml:"warnings,omitempty"` // Requirements which are only met by running in a degraded state.
+	System     *InstallReportHardware `json:"system"             yaml:"system"`             // Summary of the system's hardware.
+	TPM        InstallCheckTPM        `json:"tpm"                yaml:"tpm"`                // TPM details.
+	SecureBoot InstallCheckSecureBoot `json:"secure_boot"        yaml:"secure_boot"`        // Secure Boot state.
+	CPU        InstallCheckCPU        `json:"cpu"                yaml:"cpu"`                // CPU details.
+	Memory     uint64                 `json:"memory"             yaml:"memory"`             // Total memory in bytes.
+	Disks      []InstallCheckDisk     `json:"disks"              yaml:"disks"`              // All disks found, with the reasons for rejecting them as install targets.
+	NICs       []InstallCheckNIC      `json:"nics"               yaml:"nics"`               // All network cards found.
+}
+
+// InstallCheckTPM holds the TPM details of a hardware compatibility report.
+type InstallCheckTPM struct {
+	Present  bool     `json:"present"             yaml:"present"`             // True if a TPM device was found.
+	Working  bool     `json:"working"             yaml:"working"`             // True if the TPM passed its self test.
+	Software bool     `json:"software"            yaml:"software"`            // True if the TPM is backed by swtpm.
+	Version  string   `json:"version,omitempty"   yaml:"version,omitempty"`   // TPM major version, such as "2".
+	PCRBanks []string `json:"pcr_banks,omitempty" yaml:"pcr_banks,omitempty"` // Active PCR banks, such as "sha256".
+}
+
+// InstallCheckSecureBoot holds the Secure Boot state of a hardware compatibility report.
+type InstallCheckSecureBoot struct {
+	Enabled   bool `json:"enabled"    yaml:"enabled"`    // True if Secure Boot is enabled.
+	SetupMode bool `json:"setup_mode" yaml:"setup_mode"` // True if the firmware is in setup mode, allowing keys to be enrolled.
+	AuditMode bool `json:"audit_mode" yaml:"audit_mode"` // True if the firmware is in audit mode.
+}
+
+// InstallCheckCPU holds the CPU details of a hardware compatibility report.
+type InstallCheckCPU struct {
+	Architecture   string   `json:"architecture"       yaml:"architecture"`       // CPU architecture, such as "x86_64".
+	Model          string   `json:"model,omitempty"    yaml:"model,omitempty"`    // CPU model name.
+	Threads        uint64   `json:"threads"            yaml:"threads"`            // Total number of CPU threads.
+	Baseline       string   `json:"baseline,omitempty" yaml:"baseline,omitempty"` // x86_64 micro-architecture level, such as "x86_64_v3".
+	Virtualization bool     `json:"virtualization"     yaml:"virtualization"`     // True if hardware virtualization is available.
+	Features       []string `json:"features,omitempty" yaml:"features,omitempty"` // CPU feature flags.
+}
+
+// InstallCheckDisk holds the details of a disk in a hardware compatibility report.
+type InstallCheckDisk struct {
+	ID         string   `json:"id,omitempty"      yaml:"id,omitempty"`      // Disk ID as listed in /dev/disk/by-id/.
+	Device     string   `json:"device"            yaml:"device"`            // Kernel device path.
+	Subsystems string   `json:"subsystems"        yaml:"subsystems"`        // Bus subsystems of the disk, such as "block:nvme:pci".
+	Size       int      `json:"size"              yaml:"size"`              // Size in bytes.
+	Suitable   bool     `json:"suitable"          yaml:"suitable"`          // True if the disk can be used as the install target.
+	Reasons    []string `json:"reasons,omitempty" yaml:"reasons,omitempty"` // Reasons the disk can't be used as the install target.
+}
+
+// InstallCheckNIC holds the details of a network card in a hardware compatibility report.
+type InstallCheckNIC struct {
+	Vendor        string   `json:"vendor,omitempty"         yaml:"vendor,omitempty"`         // Card vendor.
+	Product       string   `json:"product,omitempty"        yaml:"product,omitempty"`        // Card product name.
+	PCIAddress    string   `json:"pci_address,omitempty"    yaml:"pci_address,omitempty"`    // PCI address of the card.
+	Driver        string   `json:"driver,omitempty"         yaml:"driver,omitempty"`         // Kernel driver in use, if any.
+	DriverVersion string   `json:"driver_version,omitempty" yaml:"driver_version,omitempty"` // Version of the kernel driver.
+	Ports         []string `json:"ports,omitempty"          yaml:"ports,omitempty"`          // Interface names of the card's ports.
+	MACAddresses  []string `json:"mac_addresses,omitempty"  yaml:"mac_addresses,omitempty"`  // MAC addresses of the card's ports.
+}
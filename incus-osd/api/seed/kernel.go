@@ -6,7 +6,7 @@ import (
 
 // Kernel represents the kernel seed.
 type Kernel struct {
-	Console []api.SystemKernelConfigConsole `json:"console,omitempty" yaml:"console,omitempty"`
+	api.SystemKernelConfig `yaml:",inline"`
 
 	Version string `json:"version" yaml:"version"`
 }
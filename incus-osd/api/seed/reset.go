@@ -0,0 +1,13 @@
+package seed
+
+import (
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// Reset represents the reset seed, written by a factory reset to restore the preserved configuration on first boot.
+type Reset struct {
+	Pools                     []api.SystemStoragePoolKey `json:"pools,omitempty"                       yaml:"pools,omitempty"`                       // Storage pools to import, along with their base64-encoded encryption keys sealed to the TPM.
+	TrustedClientCertificates []string                   `json:"trusted_client_certificates,omitempty" yaml:"trusted_client_certificates,omitempty"` // PEM-encoded client certificates trusted by the fallback listener.
+
+	Version string `json:"version" yaml:"version"`
+}
@@ -10,7 +10,7 @@ type ServiceCephCluster struct {
 
 // ServiceCephKeyring represents a single Ceph keyring entry.
 type ServiceCephKeyring struct {
-	Key string `json:"key" yaml:"key"`
+	Key string `incusos:"secret" json:"key" yaml:"key"`
 }
 
 // ServiceCephConfig represents additional configuration for the Ceph service.
@@ -86,6 +86,8 @@ type ServiceCephState struct {
 }
 
 // ServiceCeph represents the state and configuration of the Ceph service.
+//
+// swagger:model
 type ServiceCeph struct {
 	State ServiceCephState `incusos:"-" json:"state" yaml:"state"`
 
@@ -14,6 +14,8 @@ type ServiceISCSIConfig struct {
 }
 
 // ServiceISCSI represents the state and configuration of the ISCSI service.
+//
+// swagger:model
 type ServiceISCSI struct {
 	State ServiceISCSIState `incusos:"-" json:"state" yaml:"state"`
 
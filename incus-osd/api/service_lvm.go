@@ -7,6 +7,8 @@ type ServiceLVMConfig struct {
 }
 
 // ServiceLVM represents the state and configuration of the LVM service.
+//
+// swagger:model
 type ServiceLVM struct {
 	State ServiceLVMState `incusos:"-" json:"state" yaml:"state"`
 
@@ -39,6 +39,8 @@ type ServiceMultipathConfig struct {
 }
 
 // ServiceMultipath represents the state and configuration of the Multipath service.
+//
+// swagger:model
 type ServiceMultipath struct {
 	State ServiceMultipathState `incusos:"-" json:"state" yaml:"state"`
 
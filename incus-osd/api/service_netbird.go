@@ -1,6 +1,8 @@
 package api
 
 // ServiceNetbird represents the state and configuration of the Netbird service.
+//
+// swagger:model
 type ServiceNetbird struct {
 	Config ServiceNetbirdConfig `json:"config" yaml:"config"`
 	State  struct{}             `json:"state"  yaml:"state"`
@@ -9,7 +11,7 @@ type ServiceNetbird struct {
 // ServiceNetbirdConfig represents additional configuration for the Netbird service.
 type ServiceNetbirdConfig struct {
 	Enabled             bool     `json:"enabled"               yaml:"enabled"`
-	SetupKey            string   `json:"setup_key"             yaml:"setup_key"`
+	SetupKey            string   `incusos:"secret"             json:"setup_key"             yaml:"setup_key"`
 	ManagementURL       string   `json:"management_url"        yaml:"management_url"`
 	AdminURL            string   `json:"admin_url"             yaml:"admin_url"`
 	Anonymize           bool     `json:"anonymize"             yaml:"anonymize"`
@@ -32,6 +32,8 @@ type ServiceNVMEConfig struct {
 }
 
 // ServiceNVME represents the state and configuration of the NVME service.
+//
+// swagger:model
 type ServiceNVME struct {
 	State ServiceNVMEState `incusos:"-" json:"state" yaml:"state"`
 
@@ -6,7 +6,7 @@ type ServiceOVNConfig struct {
 	ICChassis            bool   `json:"ic_chassis,omitempty"             yaml:"ic_chassis,omitempty"`
 	Database             string `json:"database"                         yaml:"database"`
 	TLSClientCertificate string `json:"tls_client_certificate,omitempty" yaml:"tls_client_certificate,omitempty"`
-	TLSClientKey         string `json:"tls_client_key,omitempty"         yaml:"tls_client_key,omitempty"`
+	TLSClientKey         string `incusos:"secret"                        json:"tls_client_key,omitempty"         yaml:"tls_client_key,omitempty"`
 	TLSCACertificate     string `json:"tls_ca_certificate,omitempty"     yaml:"tls_ca_certificate,omitempty"`
 	TunnelAddress        string `json:"tunnel_address"                   yaml:"tunnel_address"`
 	TunnelProtocol       string `json:"tunnel_protocol"                  yaml:"tunnel_protocol"`
@@ -16,6 +16,8 @@ type ServiceOVNConfig struct {
 type ServiceOVNState struct{}
 
 // ServiceOVN represents the state and configuration of the OVN service.
+//
+// swagger:model
 type ServiceOVN struct {
 	State ServiceOVNState `incusos:"-" json:"state" yaml:"state"`
 
@@ -5,6 +5,8 @@ import (
 )
 
 // ServiceTailscale represents the state and configuration of the Tailscale service.
+//
+// swagger:model
 type ServiceTailscale struct {
 	Config ServiceTailscaleConfig `json:"config" yaml:"config"`
 	State  ServiceTailscaleState  `incusos:"-"   json:"state"  yaml:"state"`
@@ -14,7 +16,7 @@ type ServiceTailscale struct {
 type ServiceTailscaleConfig struct {
 	Enabled                bool     `json:"enabled"                    yaml:"enabled"`
 	LoginServer            string   `json:"login_server"               yaml:"login_server"`
-	AuthKey                string   `json:"auth_key"                   yaml:"auth_key"`
+	AuthKey                string   `incusos:"secret"                  json:"auth_key"                   yaml:"auth_key"`
 	AcceptRoutes           bool     `json:"accept_routes"              yaml:"accept_routes"`
 	AcceptDNS              bool     `json:"accept_dns"                 yaml:"accept_dns"`
 	AdvertisedRoutes       []string `json:"advertised_routes"          yaml:"advertised_routes"`
@@ -16,6 +16,8 @@ type ServiceUSBIPConfig struct {
 type ServiceUSBIPState struct{}
 
 // ServiceUSBIP represents the state and configuration of the USBIP service.
+//
+// swagger:model
 type ServiceUSBIP struct {
 	State ServiceUSBIPState `incusos:"-" json:"state" yaml:"state"`
 
@@ -0,0 +1,18 @@
+package api
+
+import (
+	"encoding/json"
+)
+
+// SystemApply defines a set of configuration sections to be applied as a single transaction.
+// Sections which aren't provided are left unchanged.
+//
+// swagger:model
+type SystemApply struct {
+	Kernel   *SystemKernelConfig        `json:"kernel,omitempty"   yaml:"kernel,omitempty"`
+	Network  *SystemNetworkConfig       `json:"network,omitempty"  yaml:"network,omitempty"`
+	Services map[string]json.RawMessage `json:"services,omitempty" yaml:"services,omitempty"` // Service configurations, keyed by service name.
+	Logging  *SystemLoggingConfig       `json:"logging,omitempty"  yaml:"logging,omitempty"`
+	Update   *SystemUpdateConfig        `json:"update,omitempty"   yaml:"update,omitempty"`
+	ETags    map[string]string          `json:"etags,omitempty"    yaml:"etags,omitempty"` // Expected ETags, keyed by section path such as "system/network" or "services/lvm".
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// SystemCertificateSource defines where the server certificate currently in use comes from.
+type SystemCertificateSource string
+
+const (
+	// CertificateSourceApplication is the server certificate of the primary application.
+	CertificateSourceApplication SystemCertificateSource = "application"
+
+	// CertificateSourceCustom is a user-provided certificate and key.
+	CertificateSourceCustom SystemCertificateSource = "custom"
+
+	// CertificateSourceACME is a certificate issued by an ACME server.
+	CertificateSourceACME SystemCertificateSource = "acme"
+)
+
+// SystemCertificateACMEChallenge defines the ACME challenge type used to prove control of a domain.
+type SystemCertificateACMEChallenge string
+
+const (
+	// ACMEChallengeHTTP01 answers challenges over plain HTTP.
+	ACMEChallengeHTTP01 SystemCertificateACMEChallenge = "http-01"
+
+	// ACMEChallengeDNS01 answers challenges through a DNS TXT record.
+	ACMEChallengeDNS01 SystemCertificateACMEChallenge = "dns-01"
+)
+
+// ACMEDefaultDirectory is the ACME directory used when none is configured.
+const ACMEDefaultDirectory = "https://acme-v02.api.letsencrypt.org/directory"
+
+// SystemCertificateACMEDNSWebhook defines an HTTP endpoint used to manage DNS TXT records for dns-01 challenges.
+//
+// The endpoint receives a POST request on "/present" and "/cleanup" with a JSON body containing the
+// "fqdn" and "value" of the TXT record, as done by the "httpreq" DNS provider of lego.
+type SystemCertificateACMEDNSWebhook struct {
+	URL      string `json:"url"                yaml:"url"`
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password string `incusos:"secret"          json:"password,omitempty" yaml:"password,omitempty"`
+}
+
+// SystemCertificateACME holds the configuration for requesting a certificate from an ACME server.
+type SystemCertificateACME struct {
+	Directory         string                           `json:"directory,omitempty"           yaml:"directory,omitempty"` // Defaults to Let's Encrypt.
+	Email             string                           `json:"email,omitempty"               yaml:"email,omitempty"`
+	Domains           []string                         `json:"domains"                       yaml:"domains"`
+	Challenge         SystemCertificateACMEChallenge   `json:"challenge"                     yaml:"challenge"`
+	HTTPListenAddress string                           `json:"http_listen_address,omitempty" yaml:"http_listen_address,omitempty"` // Only used for http-01, defaults to ":80".
+	DNSWebhook        *SystemCertificateACMEDNSWebhook `json:"dns_webhook,omitempty"         yaml:"dns_webhook,omitempty"`         // Only used for dns-01.
+}
+
+// SystemCertificateConfig holds the server certificate configuration.
+//
+// Either a PEM-encoded certificate and key, or an ACME configuration may be provided. If neither
+// is set, the server certificate of the primary application is used.
+type SystemCertificateConfig struct {
+	Certificate string                 `json:"certificate,omitempty" yaml:"certificate,omitempty"`
+	Key         string                 `incusos:"-"                  json:"key,omitempty"         yaml:"key,omitempty"` // Never returned, and may be omitted to keep the current key.
+	ACME        *SystemCertificateACME `json:"acme,omitempty"        yaml:"acme,omitempty"`
+}
+
+// SystemCertificateState holds information about the server certificate currently in use.
+type SystemCertificateState struct {
+	Source       SystemCertificateSource `incusos:"-"                    json:"source"                  yaml:"source"`
+	Fingerprint  string                  `incusos:"-"                    json:"fingerprint"             yaml:"fingerprint"`
+	Subject      string                  `incusos:"-"                    json:"subject"                 yaml:"subject"`
+	Issuer       string                  `incusos:"-"                    json:"issuer"                  yaml:"issuer"`
+	DNSNames     []string                `incusos:"-"                    json:"dns_names"               yaml:"dns_names"`
+	NotAfter     time.Time               `incusos:"-"                    json:"not_after"               yaml:"not_after"`
+	RenewalError string                  `json:"renewal_error,omitempty" yaml:"renewal_error,omitempty"`
+}
+
+// SystemCertificate defines a struct to configure the server certificate presented by the HTTPS listener.
+//
+// swagger:model
+type SystemCertificate struct {
+	Config SystemCertificateConfig `json:"config" yaml:"config"`
+
+	State SystemCertificateState `json:"state" yaml:"state"`
+}
+
+// Validate performs basic sanity checks against the server certificate configuration.
+func (c *SystemCertificateConfig) Validate() error {
+	if c.ACME == nil {
+		if c.Certificate == "" && c.Key != "" {
+			return errors.New("a key can't be provided without a certificate")
+		}
+
+		return nil
+	}
+
+	if c.Certificate != "" || c.Key != "" {
+		return errors.New("a certificate can't be provided along with an ACME configuration")
+	}
+
+	return c.ACME.Validate()
+}
+
+// Validate performs basic sanity checks against the ACME configuration.
+func (c *SystemCertificateACME) Validate() error {
+	if c.Directory != "" {
+		u, err := url.Parse(c.Directory)
+		if err != nil {
+			return fmt.Errorf("invalid ACME directory '%s': %w", c.Directory, err)
+		}
+
+		if u.Scheme != "https" {
+			return fmt.Errorf("invalid ACME directory '%s': must be https", c.Directory)
+		}
+	}
+
+	if len(c.Domains) == 0 {
+		return errors.New("at least one domain must be provided")
+	}
+
+	for _, domain := range c.Domains {
+		if domain == "" {
+			return errors.New("domains can't be empty")
+		}
+	}
+
+	switch c.Challenge {
+	case ACMEChallengeHTTP01:
+		if c.DNSWebhook != nil {
+			return errors.New("a DNS webhook can only be used with the dns-01 challenge")
+		}
+
+	case ACMEChallengeDNS01:
+		if c.HTTPListenAddress != "" {
+			return errors.New("an HTTP listen address can only be used with the http-01 challenge")
+		}
+
+		if c.DNSWebhook == nil {
+			return errors.New("a DNS webhook must be provided for the dns-01 challenge")
+		}
+
+		u, err := url.Parse(c.DNSWebhook.URL)
+		if err != nil {
+			return fmt.Errorf("invalid DNS webhook URL '%s': %w", c.DNSWebhook.URL, err)
+		}
+
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid DNS webhook URL '%s': must be http or https", c.DNSWebhook.URL)
+		}
+
+	default:
+		return fmt.Errorf("invalid ACME challenge '%s'", c.Challenge)
+	}
+
+	return nil
+}
@@ -0,0 +1,9 @@
+package api
+
+// SystemExportSeed defines the options used when exporting the running configuration as seed data.
+//
+// swagger:model
+type SystemExportSeed struct {
+	Generalize     bool `json:"generalize"      yaml:"generalize"`      // If true, replace hardware-specific values, such as MAC addresses and the install disk ID, with generic matchers.
+	IncludeSecrets bool `json:"include_secrets" yaml:"include_secrets"` // If true, include secrets as plain text rather than redacted.
+}
@@ -0,0 +1,17 @@
+package api
+
+import (
+	"time"
+)
+
+// SystemHistoryRevision represents a single recorded change to a configuration section.
+//
+// swagger:model
+type SystemHistoryRevision struct {
+	Revision   int       `json:"revision"  yaml:"revision"`
+	Timestamp  time.Time `json:"timestamp" yaml:"timestamp"`
+	Actor      string    `json:"actor"     yaml:"actor"`
+	Section    string    `json:"section"   yaml:"section"` // The API path of the section, such as "system/network" or "services/ovn".
+	Diff       string    `json:"diff"      yaml:"diff"`
+	Revertible bool      `incusos:"-"      json:"revertible" yaml:"revertible"` // If false, the prior configuration is no longer available.
+}
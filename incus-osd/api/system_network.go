@@ -118,7 +118,7 @@ type SystemNetworkWireguard struct {
 	Name              string                       `json:"name"                          yaml:"name"`
 	Peers             []SystemNetworkWireguardPeer `json:"peers,omitempty"               yaml:"peers,omitempty"`
 	Port              int                          `json:"port,omitempty"                yaml:"port,omitempty"`
-	PrivateKey        string                       `json:"private_key,omitempty"         yaml:"private_key,omitempty"`
+	PrivateKey        string                       `incusos:"secret"                     json:"private_key,omitempty"         yaml:"private_key,omitempty"`
 	RequiredForOnline string                       `json:"required_for_online,omitempty" yaml:"required_for_online,omitempty"`
 	Roles             []string                     `json:"roles,omitempty"               yaml:"roles,omitempty"`
 	Routes            []SystemNetworkRoute         `json:"routes,omitempty"              yaml:"routes,omitempty"`
@@ -129,7 +129,7 @@ type SystemNetworkWireguardPeer struct {
 	AllowedIPs          []string `json:"allowed_ips"                    yaml:"allowed_ips"`
 	Endpoint            string   `json:"endpoint,omitempty"             yaml:"endpoint,omitempty"`
 	PersistentKeepalive int      `json:"persistent_keepalive,omitempty" yaml:"persistent_keepalive,omitempty"`
-	PresharedKey        string   `json:"preshared_key,omitempty"        yaml:"preshared_key,omitempty"`
+	PresharedKey        string   `incusos:"secret"                      json:"preshared_key,omitempty"        yaml:"preshared_key,omitempty"`
 	PublicKey           string   `json:"public_key"                     yaml:"public_key"`
 }
 
@@ -164,7 +164,7 @@ type SystemNetworkProxy struct {
 type SystemNetworkProxyServer struct {
 	Auth     string `json:"auth"               yaml:"auth"`
 	Host     string `json:"host"               yaml:"host"`
-	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+	Password string `incusos:"secret"          json:"password,omitempty" yaml:"password,omitempty"`
 	Realm    string `json:"realm,omitempty"    yaml:"realm,omitempty"`
 	Username string `json:"username,omitempty" yaml:"username,omitempty"`
 	UseTLS   bool   `json:"use_tls"            yaml:"use_tls"`
@@ -4,9 +4,30 @@ import (
 	"encoding/json"
 )
 
+// Sections of the current configuration which can be preserved across a factory reset.
+const (
+	// SystemResetPreserveNetwork preserves the network configuration.
+	SystemResetPreserveNetwork = "network"
+
+	// SystemResetPreserveStorage preserves the storage pools other than "local", escrowing their encryption keys so they're imported again.
+	SystemResetPreserveStorage = "storage"
+
+	// SystemResetPreserveCertificates preserves the trusted client certificates of the fallback listener.
+	SystemResetPreserveCertificates = "certificates"
+
+	// SystemResetPreserveProvider preserves the provider configuration, used to register with the provider again.
+	SystemResetPreserveProvider = "provider"
+)
+
+// SystemResetPreserveSections lists the sections of the current configuration which can be preserved across a factory reset.
+var SystemResetPreserveSections = []string{SystemResetPreserveNetwork, SystemResetPreserveStorage, SystemResetPreserveCertificates, SystemResetPreserveProvider}
+
 // SystemReset defines a struct that takes an optional map of seed data to set as part of the factory reset.
+//
+// swagger:model
 type SystemReset struct {
 	AllowTPMResetFailure bool                       `json:"allow_tpm_reset_failure" yaml:"allow_tpm_reset_failure"`
+	Preserve             []string                   `json:"preserve,omitempty"      yaml:"preserve,omitempty"` // Sections of the current configuration to keep: "network", "storage", "certificates" and/or "provider".
 	Seeds                map[string]json.RawMessage `json:"seeds"                   yaml:"seeds"`
 	WipeExistingSeeds    bool                       `json:"wipe_existing_seeds"     yaml:"wipe_existing_seeds"`
 }
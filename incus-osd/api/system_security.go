@@ -1,5 +1,12 @@
 package api
 
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
 // TPMStatus defines a custom type for reporting the system's TPM status.
 type TPMStatus string
 
@@ -14,8 +21,11 @@ const (
 type SystemSecurityState struct {
 	EncryptedVolumes                []SystemSecurityEncryptedVolume       `incusos:"-"                               json:"encrypted_volumes"                  yaml:"encrypted_volumes"`
 	EncryptionRecoveryKeysRetrieved bool                                  `json:"encryption_recovery_keys_retrieved" yaml:"encryption_recovery_keys_retrieved"`
+	EncryptionKeysRotated           map[string]time.Time                  `json:"encryption_keys_rotated,omitempty"  yaml:"encryption_keys_rotated,omitempty"` // In system's timezone.
+	NetworkUnlockStatus             map[string]string                     `incusos:"-"                               json:"network_unlock_status,omitempty"    yaml:"network_unlock_status,omitempty"`
 	DriveRecoveryKeys               map[string]string                     `incusos:"-"                               json:"drive_recovery_keys"                yaml:"drive_recovery_keys"`
 	PoolRecoveryKeys                map[string]string                     `incusos:"-"                               json:"pool_recovery_keys"                 yaml:"pool_recovery_keys"`
+	SecretsError                    string                                `incusos:"-"                               json:"secrets_error,omitempty"            yaml:"secrets_error,omitempty"` // Set when stored secrets can't be decrypted.
 	SecureBootCertificates          []SystemSecuritySecureBootCertificate `incusos:"-"                               json:"secure_boot_certificates"           yaml:"secure_boot_certificates"`
 	SecureBootEnabled               bool                                  `incusos:"-"                               json:"secure_boot_enabled"                yaml:"secure_boot_enabled"`
 	SystemStateIsTrusted            bool                                  `incusos:"-"                               json:"system_state_is_trusted"            yaml:"system_state_is_trusted"`
@@ -26,8 +36,62 @@ type SystemSecurityState struct {
 
 // SystemSecurityConfig holds additional security configuration settings.
 type SystemSecurityConfig struct {
-	CustomCACerts          []string `json:"custom_ca_certs,omitempty" yaml:"custom_ca_certs,omitempty"`
-	EncryptionRecoveryKeys []string `json:"encryption_recovery_keys"  yaml:"encryption_recovery_keys"`
+	CustomCACerts          []string                     `json:"custom_ca_certs,omitempty" yaml:"custom_ca_certs,omitempty"`
+	EncryptionRecoveryKeys []string                     `json:"encryption_recovery_keys"  yaml:"encryption_recovery_keys"`
+	NetworkUnlock          *SystemSecurityNetworkUnlock `json:"network_unlock,omitempty"  yaml:"network_unlock,omitempty"`
+}
+
+// SystemSecurityNetworkUnlockMode defines how network-bound unlocking is used for the main system drive.
+type SystemSecurityNetworkUnlockMode string
+
+const (
+	// NetworkUnlockModeFallback allows the main system drive to be unlocked through a Tang server when the TPM can't unlock it.
+	NetworkUnlockModeFallback SystemSecurityNetworkUnlockMode = "fallback"
+
+	// NetworkUnlockModeRequired requires both the TPM and a Tang server to unlock the main system drive.
+	NetworkUnlockModeRequired SystemSecurityNetworkUnlockMode = "required"
+)
+
+// SystemSecurityNetworkUnlock holds the configuration for network-bound (Tang) unlocking of the main system drive.
+type SystemSecurityNetworkUnlock struct {
+	Mode SystemSecurityNetworkUnlockMode `json:"mode" yaml:"mode"`
+	// Reaching any one of the servers is sufficient to unlock the drive.
+	Servers []SystemSecurityNetworkUnlockServer `json:"servers" yaml:"servers"`
+}
+
+// SystemSecurityNetworkUnlockServer defines a Tang server used for network-bound unlocking.
+type SystemSecurityNetworkUnlockServer struct {
+	URL string `json:"url" yaml:"url"`
+	// Base64url-encoded thumbprint of the server's signing key, as reported by "tang-show-keys".
+	Thumbprint string `json:"thumbprint" yaml:"thumbprint"`
+}
+
+// Validate performs basic sanity checks against the network unlock configuration.
+func (c *SystemSecurityNetworkUnlock) Validate() error {
+	if c.Mode != NetworkUnlockModeFallback && c.Mode != NetworkUnlockModeRequired {
+		return fmt.Errorf("invalid network unlock mode '%s'", c.Mode)
+	}
+
+	if len(c.Servers) == 0 {
+		return errors.New("at least one network unlock server must be provided")
+	}
+
+	for _, server := range c.Servers {
+		u, err := url.Parse(server.URL)
+		if err != nil {
+			return fmt.Errorf("invalid network unlock server URL '%s': %w", server.URL, err)
+		}
+
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid network unlock server URL '%s': must be http or https", server.URL)
+		}
+
+		if server.Thumbprint == "" {
+			return fmt.Errorf("missing signing key thumbprint for network unlock server '%s'", server.URL)
+		}
+	}
+
+	return nil
 }
 
 // SystemSecurity defines a struct to hold information about the system's security state.
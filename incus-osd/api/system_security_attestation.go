@@ -0,0 +1,747 @@
+package api
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+)
+
+// SystemSecurityAttestationMaxNonceSize is the maximum size of an attestation nonce, in bytes.
+const SystemSecurityAttestationMaxNonceSize = 32
+
+// SystemSecurityAttestationPCRCount is the number of PCRs, starting from PCR 0, covered by an attestation.
+const SystemSecurityAttestationPCRCount = 16
+
+// SystemSecurityAttestationMaxSecretSize is the maximum size of a credential challenge secret, in bytes.
+const SystemSecurityAttestationMaxSecretSize = 32
+
+// SystemSecurityAttestationPost holds the verifier-provided nonce used to qualify a TPM quote.
+type SystemSecurityAttestationPost struct {
+	Nonce []byte `json:"nonce" yaml:"nonce"`
+}
+
+// SystemSecurityAttestation holds a TPM quote along with the information needed to verify it.
+type SystemSecurityAttestation struct {
+	// Raw TPMS_ATTEST structure generated by the TPM.
+	Quote []byte `json:"quote" yaml:"quote"`
+	// ASN.1 DER encoded ECDSA signature of the quote by the attestation key.
+	Signature []byte `json:"signature" yaml:"signature"`
+	// SHA256 values of PCRs 0 to 15, as covered by the quote.
+	PCRs [][]byte `json:"pcrs" yaml:"pcrs"`
+	// Raw TCG event log.
+	EventLog []byte `json:"event_log" yaml:"event_log"`
+	// Raw TPM2B_PUBLIC structure of the attestation key.
+	AttestationKey []byte `json:"attestation_key" yaml:"attestation_key"`
+	// Raw TPM2B_PUBLIC structure of the RSA endorsement key the attestation key was created under.
+	EndorsementKey []byte `json:"endorsement_key" yaml:"endorsement_key"`
+	// ASN.1 DER encoded endorsement key certificate, as provisioned by the TPM manufacturer.
+	EndorsementCertificate []byte `json:"endorsement_certificate" yaml:"endorsement_certificate"`
+}
+
+// SystemSecurityAttestationChallenge holds a credential, generated by MakeCredential, which only the TPM holding the endorsement and attestation keys can activate.
+type SystemSecurityAttestationChallenge struct {
+	// Content of the TPM2B_ID_OBJECT structure protecting the secret.
+	Credential []byte `json:"credential" yaml:"credential"`
+	// Content of the TPM2B_ENCRYPTED_SECRET structure, encrypted to the endorsement key.
+	EncryptedSecret []byte `json:"encrypted_secret" yaml:"encrypted_secret"`
+}
+
+// SystemSecurityAttestationChallengeResponse holds the secret recovered by the TPM from a challenge.
+type SystemSecurityAttestationChallengeResponse struct {
+	Secret []byte `json:"secret" yaml:"secret"`
+}
+
+// SystemSecurityAttestationEvent holds a single event from the TCG event log.
+type SystemSecurityAttestationEvent struct {
+	PCR    int    `json:"pcr"    yaml:"pcr"`
+	Type   uint32 `json:"type"   yaml:"type"`
+	Digest []byte `json:"digest" yaml:"digest"`
+	Data   []byte `json:"data"   yaml:"data"`
+}
+
+// SystemSecurityAttestationResult holds the verified content of an attestation.
+//
+// swagger:model
+type SystemSecurityAttestationResult struct {
+	PCRs [][]byte `json:"pcrs" yaml:"pcrs"`
+	// PCRs whose quoted value is reproduced by replaying the event log. Events only affecting
+	// other PCRs, such as those extended after boot, can't be trusted.
+	ReplayedPCRs []int                            `json:"replayed_pcrs" yaml:"replayed_pcrs"`
+	Events       []SystemSecurityAttestationEvent `json:"events"        yaml:"events"`
+}
+
+// TPM and TCG constants used when verifying an attestation.
+const (
+	tpmGeneratedValue   = 0xff544347
+	tpmSTAttestQuote    = 0x8018
+	tpmAlgRSA           = 0x0001
+	tpmAlgAES           = 0x0006
+	tpmAlgSHA256        = 0x000b
+	tpmAlgNull          = 0x0010
+	tpmAlgECC           = 0x0023
+	tpmAlgCFB           = 0x0043
+	tpmECCNISTP256      = 0x0003
+	tcgEventNoAction    = 0x00000003
+	tcgSpecIDSignature  = "Spec ID Event03\x00"
+	tcgStartupLocality  = "StartupLocality\x00"
+	tcgLegacyDigestSize = 20
+)
+
+// Object attributes required from the attestation key: a restricted signing key generated by the
+// TPM, which can't be duplicated to another TPM.
+const (
+	tpmaObjectFixedTPM            = 1 << 1
+	tpmaObjectFixedParent         = 1 << 4
+	tpmaObjectSensitiveDataOrigin = 1 << 5
+	tpmaObjectRestricted          = 1 << 16
+	tpmaObjectDecrypt             = 1 << 17
+	tpmaObjectSign                = 1 << 18
+
+	attestationKeyAttributes = tpmaObjectFixedTPM | tpmaObjectFixedParent | tpmaObjectSensitiveDataOrigin | tpmaObjectRestricted | tpmaObjectSign
+	endorsementKeyAttributes = tpmaObjectFixedTPM | tpmaObjectFixedParent | tpmaObjectSensitiveDataOrigin | tpmaObjectRestricted | tpmaObjectDecrypt
+)
+
+// Verify checks the attestation against the nonce provided by the verifier and the pool of trusted
+// TPM manufacturer certificates. On success, the quoted PCR values are returned along with the event log.
+//
+// Verify doesn't prove that the attestation key lives in the same TPM as the endorsement key. The
+// verifier must also send a challenge generated by MakeCredential to the system and check that the
+// returned secret matches.
+func (a *SystemSecurityAttestation) Verify(nonce []byte, roots *x509.CertPool) (*SystemSecurityAttestationResult, error) {
+	// Establish trust in the endorsement key.
+	err := a.verifyEndorsementKey(roots)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the attestation key is a restricted signing key.
+	publicArea, err := readTPM2B(bytes.NewReader(a.AttestationKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the attestation key: %w", err)
+	}
+
+	attestationKey, err := parseAttestationKey(publicArea)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the quote was signed by the attestation key.
+	quoteDigest := sha256.Sum256(a.Quote)
+	if !ecdsa.VerifyASN1(attestationKey, quoteDigest[:], a.Signature) {
+		return nil, errors.New("invalid quote signature")
+	}
+
+	// Check the content of the quote.
+	extraData, pcrDigest, err := parseQuote(a.Quote)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nonce) == 0 || !bytes.Equal(extraData, nonce) {
+		return nil, errors.New("quote doesn't match the provided nonce")
+	}
+
+	if len(a.PCRs) != SystemSecurityAttestationPCRCount {
+		return nil, fmt.Errorf("expected %d PCR values, got %d", SystemSecurityAttestationPCRCount, len(a.PCRs))
+	}
+
+	h := sha256.New()
+	for _, pcr := range a.PCRs {
+		_, _ = h.Write(pcr)
+	}
+
+	if !bytes.Equal(h.Sum(nil), pcrDigest) {
+		return nil, errors.New("PCR values don't match the quote")
+	}
+
+	// Replay the event log.
+	events, replayedPCRs, err := replayEventLog(a.EventLog)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SystemSecurityAttestationResult{
+		PCRs:         a.PCRs,
+		ReplayedPCRs: []int{},
+		Events:       events,
+	}
+
+	for i, pcr := range a.PCRs {
+		if bytes.Equal(pcr, replayedPCRs[i]) {
+			result.ReplayedPCRs = append(result.ReplayedPCRs, i)
+		}
+	}
+
+	return result, nil
+}
+
+// verifyEndorsementKey checks that the endorsement key is certified by a trusted TPM manufacturer.
+func (a *SystemSecurityAttestation) verifyEndorsementKey(roots *x509.CertPool) error {
+	if len(a.EndorsementCertificate) == 0 {
+		return errors.New("the TPM doesn't have an endorsement key certificate")
+	}
+
+	cert, err := x509.ParseCertificate(a.EndorsementCertificate)
+	if err != nil {
+		return fmt.Errorf("failed to parse the endorsement key certificate: %w", err)
+	}
+
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return fmt.Errorf("untrusted endorsement key certificate: %w", err)
+	}
+
+	endorsementKey, _, err := a.parseEndorsementKey()
+	if err != nil {
+		return err
+	}
+
+	if !endorsementKey.Equal(cert.PublicKey) {
+		return errors.New("endorsement key doesn't match its certificate")
+	}
+
+	return nil
+}
+
+// parseEndorsementKey parses the TPM2B_PUBLIC structure of the RSA endorsement key, checking its
+// attributes. The key is returned along with the size of its symmetric key.
+func (a *SystemSecurityAttestation) parseEndorsementKey() (*rsa.PublicKey, int, error) {
+	publicArea, err := readTPM2B(bytes.NewReader(a.EndorsementKey))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read the endorsement key: %w", err)
+	}
+
+	r := bytes.NewReader(publicArea)
+
+	var header struct {
+		Type       uint16
+		NameAlg    uint16
+		Attributes uint32
+	}
+
+	err = binary.Read(r, binary.BigEndian, &header)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if header.Type != tpmAlgRSA || header.NameAlg != tpmAlgSHA256 {
+		return nil, 0, errors.New("unsupported endorsement key type")
+	}
+
+	if header.Attributes&endorsementKeyAttributes != endorsementKeyAttributes || header.Attributes&tpmaObjectSign != 0 {
+		return nil, 0, errors.New("endorsement key isn't a restricted decryption key bound to the TPM")
+	}
+
+	// Skip the authorization policy.
+	_, err = readTPM2B(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var params struct {
+		SymmetricAlg  uint16
+		SymmetricBits uint16
+		SymmetricMode uint16
+		Scheme        uint16
+		KeyBits       uint16
+		Exponent      uint32
+	}
+
+	err = binary.Read(r, binary.BigEndian, &params)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if params.SymmetricAlg != tpmAlgAES || params.SymmetricMode != tpmAlgCFB || (params.SymmetricBits != 128 && params.SymmetricBits != 256) {
+		return nil, 0, errors.New("unsupported endorsement key symmetric algorithm")
+	}
+
+	if params.Scheme != tpmAlgNull {
+		return nil, 0, errors.New("unsupported endorsement key scheme")
+	}
+
+	modulus, err := readTPM2B(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(modulus)*8 != int(params.KeyBits) {
+		return nil, 0, errors.New("invalid endorsement key modulus")
+	}
+
+	exponent := int(params.Exponent)
+	if exponent == 0 {
+		exponent = 65537
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(modulus), E: exponent}, int(params.SymmetricBits), nil
+}
+
+// MakeCredential generates a challenge protecting the provided secret, following TPM2_MakeCredential.
+// Only the TPM holding the endorsement key can recover the secret, and only when it also holds the
+// attestation key. The endorsement key should be verified beforehand.
+func (a *SystemSecurityAttestation) MakeCredential(secret []byte) (*SystemSecurityAttestationChallenge, error) {
+	if len(secret) == 0 || len(secret) > SystemSecurityAttestationMaxSecretSize {
+		return nil, fmt.Errorf("the secret must be between 1 and %d bytes long", SystemSecurityAttestationMaxSecretSize)
+	}
+
+	endorsementKey, symmetricBits, err := a.parseEndorsementKey()
+	if err != nil {
+		return nil, err
+	}
+
+	publicArea, err := readTPM2B(bytes.NewReader(a.AttestationKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the attestation key: %w", err)
+	}
+
+	publicDigest := sha256.Sum256(publicArea)
+	name := binary.BigEndian.AppendUint16(nil, tpmAlgSHA256)
+	name = append(name, publicDigest[:]...)
+
+	// Encrypt a random seed to the endorsement key.
+	seed := make([]byte, sha256.Size)
+
+	_, err = rand.Read(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedSecret, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, endorsementKey, seed, []byte("IDENTITY\x00"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Encrypt the secret with a key derived from the seed and the attestation key's name.
+	block, err := aes.NewCipher(kdfa(seed, "STORAGE", name, symmetricBits))
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := binary.BigEndian.AppendUint16(nil, uint16(len(secret)))
+	plaintext = append(plaintext, secret...)
+	encryptedIdentity := make([]byte, len(plaintext))
+
+	// The TPM specification mandates CFB mode, integrity being provided by the outer HMAC.
+	cipher.NewCFBEncrypter(block, make([]byte, aes.BlockSize)).XORKeyStream(encryptedIdentity, plaintext) //nolint:staticcheck
+
+	mac := hmac.New(sha256.New, kdfa(seed, "INTEGRITY", nil, sha256.Size*8))
+	_, _ = mac.Write(encryptedIdentity)
+	_, _ = mac.Write(name)
+
+	credential := binary.BigEndian.AppendUint16(nil, sha256.Size)
+	credential = append(credential, mac.Sum(nil)...)
+	credential = append(credential, encryptedIdentity...)
+
+	return &SystemSecurityAttestationChallenge{
+		Credential:      credential,
+		EncryptedSecret: encryptedSecret,
+	}, nil
+}
+
+// kdfa implements the SHA256 based TPM key derivation function, without a second context value.
+func kdfa(key []byte, label string, context []byte, bits int) []byte {
+	derived := []byte{}
+
+	for counter := uint32(1); len(derived)*8 < bits; counter++ {
+		mac := hmac.New(sha256.New, key)
+		_ = binary.Write(mac, binary.BigEndian, counter)
+		_, _ = mac.Write([]byte(label + "\x00"))
+		_, _ = mac.Write(context)
+		_ = binary.Write(mac, binary.BigEndian, uint32(bits))
+
+		derived = mac.Sum(derived)
+	}
+
+	return derived[:bits/8]
+}
+
+// parseAttestationKey parses the TPMT_PUBLIC structure of the attestation key, checking its attributes.
+func parseAttestationKey(publicArea []byte) (*ecdsa.PublicKey, error) {
+	r := bytes.NewReader(publicArea)
+
+	var header struct {
+		Type       uint16
+		NameAlg    uint16
+		Attributes uint32
+	}
+
+	err := binary.Read(r, binary.BigEndian, &header)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.Type != tpmAlgECC || header.NameAlg != tpmAlgSHA256 {
+		return nil, errors.New("unsupported attestation key type")
+	}
+
+	if header.Attributes&attestationKeyAttributes != attestationKeyAttributes || header.Attributes&tpmaObjectDecrypt != 0 {
+		return nil, errors.New("attestation key isn't a restricted signing key bound to the TPM")
+	}
+
+	// Skip the authorization policy.
+	_, err = readTPM2B(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Skip the symmetric algorithm and signing scheme, each followed by their details when set.
+	for _, detailsSize := range []int64{4, 2} {
+		var alg uint16
+
+		err := binary.Read(r, binary.BigEndian, &alg)
+		if err != nil {
+			return nil, err
+		}
+
+		if alg != tpmAlgNull {
+			_, err = r.Seek(detailsSize, io.SeekCurrent)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var params struct {
+		CurveID uint16
+		KDF     uint16
+	}
+
+	err = binary.Read(r, binary.BigEndian, &params)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.CurveID != tpmECCNISTP256 {
+		return nil, errors.New("unsupported attestation key curve")
+	}
+
+	if params.KDF != tpmAlgNull {
+		_, err = r.Seek(2, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	x, err := readTPM2B(r)
+	if err != nil {
+		return nil, err
+	}
+
+	y, err := readTPM2B(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Let the standard library validate the point.
+	point := append([]byte{0x04}, make([]byte, 64)...)
+	if len(x) > 32 || len(y) > 32 {
+		return nil, errors.New("invalid attestation key point")
+	}
+
+	copy(point[1+32-len(x):33], x)
+	copy(point[33+32-len(y):], y)
+
+	key, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), point)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attestation key point: %w", err)
+	}
+
+	return key, nil
+}
+
+// parseQuote parses a TPMS_ATTEST quote structure, returning its extra data and PCR digest.
+func parseQuote(quote []byte) ([]byte, []byte, error) {
+	r := bytes.NewReader(quote)
+
+	var header struct {
+		Magic uint32
+		Type  uint16
+	}
+
+	err := binary.Read(r, binary.BigEndian, &header)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if header.Magic != tpmGeneratedValue || header.Type != tpmSTAttestQuote {
+		return nil, nil, errors.New("attestation isn't a TPM generated quote")
+	}
+
+	// Skip the qualified signer.
+	_, err = readTPM2B(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	extraData, err := readTPM2B(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Skip the clock information (17 bytes) and firmware version (8 bytes).
+	_, err = r.Seek(17+8, io.SeekCurrent)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Check the PCR selection.
+	var selectionCount uint32
+
+	err = binary.Read(r, binary.BigEndian, &selectionCount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if selectionCount != 1 {
+		return nil, nil, errors.New("quote doesn't cover a single PCR bank")
+	}
+
+	var selection struct {
+		Hash uint16
+		Size uint8
+	}
+
+	err = binary.Read(r, binary.BigEndian, &selection)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bitmap := make([]byte, selection.Size)
+
+	_, err = io.ReadFull(r, bitmap)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	selected := []int{}
+
+	for i, b := range bitmap {
+		for bit := range 8 {
+			if b&(1<<bit) != 0 {
+				selected = append(selected, i*8+bit)
+			}
+		}
+	}
+
+	expected := make([]int, 0, SystemSecurityAttestationPCRCount)
+	for i := range SystemSecurityAttestationPCRCount {
+		expected = append(expected, i)
+	}
+
+	if selection.Hash != tpmAlgSHA256 || !slices.Equal(selected, expected) {
+		return nil, nil, fmt.Errorf("quote doesn't cover SHA256 PCRs 0-%d", SystemSecurityAttestationPCRCount-1)
+	}
+
+	pcrDigest, err := readTPM2B(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return extraData, pcrDigest, nil
+}
+
+// readTPM2B reads a size-prefixed TPM2B buffer.
+func readTPM2B(r io.Reader) ([]byte, error) {
+	var size uint16
+
+	err := binary.Read(r, binary.BigEndian, &size)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, size)
+
+	_, err = io.ReadFull(r, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// replayEventLog parses a crypto-agile TCG event log and returns its events along with the
+// resulting SHA256 PCR values.
+func replayEventLog(eventLog []byte) ([]SystemSecurityAttestationEvent, [][]byte, error) {
+	r := bytes.NewReader(eventLog)
+
+	// The first event uses the legacy format and describes the digests used by the other events.
+	var header struct {
+		PCR    uint32
+		Type   uint32
+		Digest [tcgLegacyDigestSize]byte
+		Size   uint32
+	}
+
+	err := binary.Read(r, binary.LittleEndian, &header)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	specID := make([]byte, header.Size)
+
+	_, err = io.ReadFull(r, specID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	digestSizes, err := parseSpecIDEvent(specID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Replay the events.
+	pcrs := make([][]byte, SystemSecurityAttestationPCRCount)
+	for i := range pcrs {
+		pcrs[i] = make([]byte, sha256.Size)
+	}
+
+	events := []SystemSecurityAttestationEvent{}
+
+	for r.Len() > 0 {
+		var eventHeader struct {
+			PCR   uint32
+			Type  uint32
+			Count uint32
+		}
+
+		err := binary.Read(r, binary.LittleEndian, &eventHeader)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var digest []byte
+
+		for range eventHeader.Count {
+			var alg uint16
+
+			err := binary.Read(r, binary.LittleEndian, &alg)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			size, ok := digestSizes[alg]
+			if !ok {
+				return nil, nil, fmt.Errorf("event log uses unknown digest algorithm 0x%04x", alg)
+			}
+
+			buf := make([]byte, size)
+
+			_, err = io.ReadFull(r, buf)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if alg == tpmAlgSHA256 {
+				digest = buf
+			}
+		}
+
+		var dataSize uint32
+
+		err = binary.Read(r, binary.LittleEndian, &dataSize)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if int64(dataSize) > int64(r.Len()) {
+			return nil, nil, errors.New("truncated event log")
+		}
+
+		data := make([]byte, dataSize)
+
+		_, err = io.ReadFull(r, data)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		events = append(events, SystemSecurityAttestationEvent{
+			PCR:    int(eventHeader.PCR),
+			Type:   eventHeader.Type,
+			Digest: digest,
+			Data:   data,
+		})
+
+		if eventHeader.PCR >= SystemSecurityAttestationPCRCount {
+			continue
+		}
+
+		if eventHeader.Type == tcgEventNoAction {
+			// The startup locality is reflected in the initial value of PCR 0.
+			if eventHeader.PCR == 0 && len(data) == len(tcgStartupLocality)+1 && string(data[:len(tcgStartupLocality)]) == tcgStartupLocality {
+				pcrs[0][sha256.Size-1] = data[len(tcgStartupLocality)]
+			}
+
+			continue
+		}
+
+		if digest == nil {
+			return nil, nil, errors.New("event log entry is missing a SHA256 digest")
+		}
+
+		h := sha256.New()
+		_, _ = h.Write(pcrs[eventHeader.PCR])
+		_, _ = h.Write(digest)
+		pcrs[eventHeader.PCR] = h.Sum(nil)
+	}
+
+	return events, pcrs, nil
+}
+
+// parseSpecIDEvent parses the TCG_EfiSpecIDEvent structure, returning the size of each digest algorithm.
+func parseSpecIDEvent(data []byte) (map[uint16]int, error) {
+	r := bytes.NewReader(data)
+
+	var specID struct {
+		Signature     [16]byte
+		PlatformClass uint32
+		VersionMinor  uint8
+		VersionMajor  uint8
+		Errata        uint8
+		UintnSize     uint8
+		NumAlgs       uint32
+	}
+
+	err := binary.Read(r, binary.LittleEndian, &specID)
+	if err != nil {
+		return nil, err
+	}
+
+	if string(specID.Signature[:]) != tcgSpecIDSignature {
+		return nil, errors.New("unsupported event log format")
+	}
+
+	digestSizes := map[uint16]int{}
+
+	for range specID.NumAlgs {
+		var alg struct {
+			ID   uint16
+			Size uint16
+		}
+
+		err := binary.Read(r, binary.LittleEndian, &alg)
+		if err != nil {
+			return nil, err
+		}
+
+		digestSizes[alg.ID] = int(alg.Size)
+	}
+
+	return digestSizes, nil
+}
@@ -0,0 +1,396 @@
+package api_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+type testEvent struct {
+	pcr       uint32
+	eventType uint32
+	data      []byte
+}
+
+type testTPM struct {
+	roots          *x509.CertPool
+	endorsementKey *rsa.PrivateKey
+}
+
+// newTestTPM returns a software endorsement key, certified by a newly generated manufacturer CA.
+func newTestTPM(t *testing.T) (*testTPM, []byte, []byte) {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test TPM manufacturer"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	endorsementKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ekTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageKeyEncipherment,
+	}
+
+	ekDER, err := x509.CreateCertificate(rand.Reader, ekTemplate, caCert, &endorsementKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+
+	return &testTPM{roots: roots, endorsementKey: endorsementKey}, buildEndorsementKey(&endorsementKey.PublicKey), ekDER
+}
+
+// buildEndorsementKey returns the TPM2B_PUBLIC structure of an RSA endorsement key using the default template.
+func buildEndorsementKey(pub *rsa.PublicKey) []byte {
+	area := binary.BigEndian.AppendUint16(nil, 0x0001) // RSA.
+	area = binary.BigEndian.AppendUint16(area, 0x000b) // SHA256 name.
+	area = binary.BigEndian.AppendUint32(area, 0x000300b2)
+	area = binary.BigEndian.AppendUint16(area, 32) // Authorization policy.
+	area = append(area, make([]byte, 32)...)
+	area = binary.BigEndian.AppendUint16(area, 0x0006) // AES.
+	area = binary.BigEndian.AppendUint16(area, 128)
+	area = binary.BigEndian.AppendUint16(area, 0x0043) // CFB.
+	area = binary.BigEndian.AppendUint16(area, 0x0010) // No scheme.
+	area = binary.BigEndian.AppendUint16(area, 2048)
+	area = binary.BigEndian.AppendUint32(area, 0) // Default exponent.
+	area = binary.BigEndian.AppendUint16(area, 256)
+	area = append(area, pub.N.FillBytes(make([]byte, 256))...)
+
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(area))), area...)
+}
+
+// kdfa implements the SHA256 based TPM key derivation function.
+func kdfa(key []byte, label string, context []byte, bits int) []byte {
+	mac := hmac.New(sha256.New, key)
+	_ = binary.Write(mac, binary.BigEndian, uint32(1))
+	mac.Write([]byte(label + "\x00"))
+	mac.Write(context)
+	_ = binary.Write(mac, binary.BigEndian, uint32(bits))
+
+	return mac.Sum(nil)[:bits/8]
+}
+
+// activateCredential recovers the secret from a challenge, following TPM2_ActivateCredential.
+func (tpm *testTPM) activateCredential(t *testing.T, challenge *api.SystemSecurityAttestationChallenge, attestationKey []byte) []byte {
+	t.Helper()
+
+	seed, err := rsa.DecryptOAEP(sha256.New(), nil, tpm.endorsementKey, challenge.EncryptedSecret, []byte("IDENTITY\x00"))
+	require.NoError(t, err)
+
+	digest := sha256.Sum256(attestationKey[2:])
+	name := append([]byte{0x00, 0x0b}, digest[:]...)
+
+	integrity := challenge.Credential[2:34]
+	encryptedIdentity := challenge.Credential[34:]
+
+	mac := hmac.New(sha256.New, kdfa(seed, "INTEGRITY", nil, 256))
+	mac.Write(encryptedIdentity)
+	mac.Write(name)
+	require.Equal(t, mac.Sum(nil), integrity)
+
+	block, err := aes.NewCipher(kdfa(seed, "STORAGE", name, 128))
+	require.NoError(t, err)
+
+	plaintext := make([]byte, len(encryptedIdentity))
+	cipher.NewCFBDecrypter(block, make([]byte, aes.BlockSize)).XORKeyStream(plaintext, encryptedIdentity) //nolint:staticcheck
+	require.Equal(t, int(binary.BigEndian.Uint16(plaintext)), len(plaintext)-2)
+
+	return plaintext[2:]
+}
+
+// buildEventLog returns a crypto-agile TCG event log with SHA256 digests along with the resulting PCR values.
+func buildEventLog(events []testEvent) ([]byte, [][]byte) {
+	var buf bytes.Buffer
+
+	// Spec ID event.
+	specID := []byte("Spec ID Event03\x00")
+	specID = binary.LittleEndian.AppendUint32(specID, 0)        // Platform class.
+	specID = append(specID, 0, 2, 0, 2)                         // Version and uintn size.
+	specID = binary.LittleEndian.AppendUint32(specID, 1)        // Number of algorithms.
+	specID = binary.LittleEndian.AppendUint16(specID, 0x000b)   // SHA256.
+	specID = binary.LittleEndian.AppendUint16(specID, 32)       // Digest size.
+	specID = append(specID, 0)                                  // Vendor info size.
+	_ = binary.Write(&buf, binary.LittleEndian, []uint32{0, 3}) // PCR and EV_NO_ACTION.
+	buf.Write(make([]byte, 20))
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(specID)))
+	buf.Write(specID)
+
+	pcrs := make([][]byte, api.SystemSecurityAttestationPCRCount)
+	for i := range pcrs {
+		pcrs[i] = make([]byte, 32)
+	}
+
+	for _, e := range events {
+		digest := sha256.Sum256(e.data)
+
+		_ = binary.Write(&buf, binary.LittleEndian, []uint32{e.pcr, e.eventType, 1})
+		_ = binary.Write(&buf, binary.LittleEndian, uint16(0x000b))
+		buf.Write(digest[:])
+		_ = binary.Write(&buf, binary.LittleEndian, uint32(len(e.data)))
+		buf.Write(e.data)
+
+		if e.eventType == 3 {
+			// The TPM starts PCR 0 with the startup locality.
+			if bytes.HasPrefix(e.data, []byte("StartupLocality\x00")) {
+				pcrs[0][31] = e.data[len(e.data)-1]
+			}
+
+			continue
+		}
+
+		h := sha256.New()
+		h.Write(pcrs[e.pcr])
+		h.Write(digest[:])
+		pcrs[e.pcr] = h.Sum(nil)
+	}
+
+	return buf.Bytes(), pcrs
+}
+
+// buildQuote returns a TPMS_ATTEST quote structure over PCRs 0-15.
+func buildQuote(nonce []byte, pcrs [][]byte) []byte {
+	h := sha256.New()
+	for _, pcr := range pcrs {
+		h.Write(pcr)
+	}
+
+	quote := binary.BigEndian.AppendUint32(nil, 0xff544347)
+	quote = binary.BigEndian.AppendUint16(quote, 0x8018)
+	quote = binary.BigEndian.AppendUint16(quote, 4) // Qualified signer.
+	quote = append(quote, 0x00, 0x0b, 0xaa, 0xbb)
+	quote = binary.BigEndian.AppendUint16(quote, uint16(len(nonce)))
+	quote = append(quote, nonce...)
+	quote = append(quote, make([]byte, 17+8)...) // Clock info and firmware version.
+	quote = binary.BigEndian.AppendUint32(quote, 1)
+	quote = binary.BigEndian.AppendUint16(quote, 0x000b)
+	quote = append(quote, 3, 0xff, 0xff, 0x00)
+	quote = binary.BigEndian.AppendUint16(quote, 32)
+	quote = append(quote, h.Sum(nil)...)
+
+	return quote
+}
+
+// buildPublicArea returns the TPM2B_PUBLIC structure of an ECDSA P256 key with the provided attributes.
+func buildPublicArea(pub *ecdsa.PublicKey, attributes uint32) []byte {
+	point, _ := pub.Bytes()
+
+	area := binary.BigEndian.AppendUint16(nil, 0x0023) // ECC.
+	area = binary.BigEndian.AppendUint16(area, 0x000b) // SHA256 name.
+	area = binary.BigEndian.AppendUint32(area, attributes)
+	area = binary.BigEndian.AppendUint16(area, 0)      // Authorization policy.
+	area = binary.BigEndian.AppendUint16(area, 0x0010) // No symmetric algorithm.
+	area = binary.BigEndian.AppendUint16(area, 0x0018) // ECDSA scheme.
+	area = binary.BigEndian.AppendUint16(area, 0x000b) // SHA256.
+	area = binary.BigEndian.AppendUint16(area, 0x0003) // NIST P256.
+	area = binary.BigEndian.AppendUint16(area, 0x0010) // No KDF.
+	area = binary.BigEndian.AppendUint16(area, 32)
+	area = append(area, point[1:33]...)
+	area = binary.BigEndian.AppendUint16(area, 32)
+	area = append(area, point[33:]...)
+
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(area))), area...)
+}
+
+// newAttestation returns a signed attestation along with the TPM which generated it.
+func newAttestation(t *testing.T, nonce []byte, events []testEvent) (*api.SystemSecurityAttestation, *testTPM) {
+	t.Helper()
+
+	// A restricted signing key generated by the TPM.
+	return newAttestationWithAttributes(t, nonce, events, 0x00050072)
+}
+
+// newAttestationWithAttributes returns a signed attestation for an attestation key with the provided attributes.
+func newAttestationWithAttributes(t *testing.T, nonce []byte, events []testEvent, attributes uint32) (*api.SystemSecurityAttestation, *testTPM) {
+	t.Helper()
+
+	tpm, endorsementKey, endorsementCertificate := newTestTPM(t)
+
+	attestationKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	eventLog, pcrs := buildEventLog(events)
+
+	// Simulate a PCR extended outside of the event log.
+	pcrs[15] = bytes.Repeat([]byte{0x15}, 32)
+
+	quote := buildQuote(nonce, pcrs)
+	digest := sha256.Sum256(quote)
+
+	signature, err := ecdsa.SignASN1(rand.Reader, attestationKey, digest[:])
+	require.NoError(t, err)
+
+	return &api.SystemSecurityAttestation{
+		Quote:                  quote,
+		Signature:              signature,
+		PCRs:                   pcrs,
+		EventLog:               eventLog,
+		AttestationKey:         buildPublicArea(&attestationKey.PublicKey, attributes),
+		EndorsementKey:         endorsementKey,
+		EndorsementCertificate: endorsementCertificate,
+	}, tpm
+}
+
+var testEvents = []testEvent{
+	{pcr: 0, eventType: 3, data: []byte("StartupLocality\x00\x03")},
+	{pcr: 0, eventType: 8, data: []byte("firmware")},
+	{pcr: 4, eventType: 0x80000003, data: []byte("uki")},
+	{pcr: 7, eventType: 0x80000001, data: []byte("SecureBoot")},
+	{pcr: 7, eventType: 4, data: []byte{0, 0, 0, 0}},
+}
+
+func TestAttestationVerify(t *testing.T) {
+	t.Parallel()
+
+	nonce := []byte("0123456789abcdef")
+
+	attestation, tpm := newAttestation(t, nonce, testEvents)
+
+	result, err := attestation.Verify(nonce, tpm.roots)
+	require.NoError(t, err)
+	require.Len(t, result.PCRs, 16)
+	require.Len(t, result.Events, len(testEvents))
+	require.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14}, result.ReplayedPCRs)
+
+	// The startup locality is reflected in the initial value of PCR 0.
+	firmwareDigest := sha256.Sum256([]byte("firmware"))
+	h := sha256.New()
+	h.Write(append(make([]byte, 31), 3))
+	h.Write(firmwareDigest[:])
+	require.Equal(t, h.Sum(nil), result.PCRs[0])
+}
+
+func TestAttestationVerifyFailures(t *testing.T) {
+	t.Parallel()
+
+	nonce := []byte("0123456789abcdef")
+
+	// Wrong nonce.
+	attestation, tpm := newAttestation(t, nonce, testEvents)
+	_, err := attestation.Verify([]byte("fedcba9876543210"), tpm.roots)
+	require.ErrorContains(t, err, "nonce")
+
+	// Untrusted TPM manufacturer.
+	_, otherTPM := newAttestation(t, nonce, testEvents)
+	_, err = attestation.Verify(nonce, otherTPM.roots)
+	require.ErrorContains(t, err, "untrusted endorsement key certificate")
+
+	// Missing endorsement key certificate.
+	attestation, tpm = newAttestation(t, nonce, testEvents)
+	attestation.EndorsementCertificate = nil
+	_, err = attestation.Verify(nonce, tpm.roots)
+	require.ErrorContains(t, err, "doesn't have an endorsement key certificate")
+
+	// Endorsement key swapped for another one.
+	attestation, tpm = newAttestation(t, nonce, testEvents)
+	otherAttestation, _ := newAttestation(t, nonce, testEvents)
+	attestation.EndorsementKey = otherAttestation.EndorsementKey
+	_, err = attestation.Verify(nonce, tpm.roots)
+	require.ErrorContains(t, err, "doesn't match its certificate")
+
+	// Unrestricted attestation key.
+	attestation, tpm = newAttestationWithAttributes(t, nonce, testEvents, 0x00060072)
+	_, err = attestation.Verify(nonce, tpm.roots)
+	require.ErrorContains(t, err, "isn't a restricted signing key")
+
+	// Tampered PCR value.
+	attestation, tpm = newAttestation(t, nonce, testEvents)
+	attestation.PCRs[7] = make([]byte, 32)
+	_, err = attestation.Verify(nonce, tpm.roots)
+	require.ErrorContains(t, err, "PCR values don't match the quote")
+
+	// Tampered quote.
+	attestation, tpm = newAttestation(t, nonce, testEvents)
+	attestation.Quote[len(attestation.Quote)-1] ^= 0xff
+	_, err = attestation.Verify(nonce, tpm.roots)
+	require.ErrorContains(t, err, "invalid quote signature")
+}
+
+func TestAttestationVerifyTamperedEventLog(t *testing.T) {
+	t.Parallel()
+
+	nonce := []byte("0123456789abcdef")
+
+	attestation, tpm := newAttestation(t, nonce, testEvents)
+
+	// Swap the event log for one with a different PCR 4 measurement.
+	tampered := append([]testEvent{}, testEvents...)
+	tampered[2] = testEvent{pcr: 4, eventType: 0x80000003, data: []byte("other uki")}
+
+	attestation.EventLog, _ = buildEventLog(tampered)
+
+	result, err := attestation.Verify(nonce, tpm.roots)
+	require.NoError(t, err)
+	require.NotContains(t, result.ReplayedPCRs, 4)
+	require.Contains(t, result.ReplayedPCRs, 7)
+}
+
+func TestAttestationMakeCredential(t *testing.T) {
+	t.Parallel()
+
+	nonce := []byte("0123456789abcdef")
+	secret := []byte("fedcba9876543210")
+
+	attestation, tpm := newAttestation(t, nonce, testEvents)
+
+	challenge, err := attestation.MakeCredential(secret)
+	require.NoError(t, err)
+	require.Equal(t, secret, tpm.activateCredential(t, challenge, attestation.AttestationKey))
+
+	// The credential is bound to the attestation key.
+	otherAttestation, _ := newAttestation(t, nonce, testEvents)
+	otherAttestation.EndorsementKey = attestation.EndorsementKey
+
+	challenge, err = otherAttestation.MakeCredential(secret)
+	require.NoError(t, err)
+
+	seed, err := rsa.DecryptOAEP(sha256.New(), nil, tpm.endorsementKey, challenge.EncryptedSecret, []byte("IDENTITY\x00"))
+	require.NoError(t, err)
+
+	digest := sha256.Sum256(attestation.AttestationKey[2:])
+	mac := hmac.New(sha256.New, kdfa(seed, "INTEGRITY", nil, 256))
+	mac.Write(challenge.Credential[34:])
+	mac.Write(append([]byte{0x00, 0x0b}, digest[:]...))
+	require.NotEqual(t, mac.Sum(nil), challenge.Credential[2:34])
+
+	// Invalid secret sizes.
+	_, err = attestation.MakeCredential(nil)
+	require.Error(t, err)
+
+	_, err = attestation.MakeCredential(make([]byte, 33))
+	require.Error(t, err)
+}
@@ -10,9 +10,20 @@ type SystemStorageConfig struct {
 
 // SystemStorageState represents additional state for the system's local storage.
 type SystemStorageState struct {
-	Drives        []SystemStorageDrive       `json:"drives"         yaml:"drives"`
-	Pools         []SystemStoragePool        `json:"pools"          yaml:"pools"`
-	RootPartition SystemStorageRootPartition `json:"root_partition" yaml:"root_partition"`
+	Drives        []SystemStorageDrive       `json:"drives"                yaml:"drives"`
+	Pools         []SystemStoragePool        `json:"pools"                 yaml:"pools"`
+	RootPartition SystemStorageRootPartition `json:"root_partition"        yaml:"root_partition"`
+	BootMirror    *SystemStorageBootMirror   `json:"boot_mirror,omitempty" yaml:"boot_mirror,omitempty"`
+}
+
+// SystemStorageBootMirror holds the state of the mirrored boot drives, if set up at install time.
+type SystemStorageBootMirror struct {
+	// IDs of the two drives holding a copy of the boot partitions.
+	Drives []string `json:"drives" yaml:"drives"`
+	// One of "healthy", "out-of-sync" (the second drive hasn't yet received the latest update) or "degraded".
+	State string `json:"state" yaml:"state"`
+	// IDs of drives which are currently missing from the system.
+	DrivesMissing []string `json:"drives_missing,omitempty" yaml:"drives_missing,omitempty"`
 }
 
 // SystemStorageRootPartition defines a struct that holds usage information about the root ("/") partition.
@@ -168,3 +179,36 @@ type SystemStoragePoolKey struct {
 	Type          string `json:"type"           yaml:"type"`
 	EncryptionKey string `json:"encryption_key" yaml:"encryption_key"`
 }
+
+// SystemStorageDeletePool defines a struct with information about what pool to delete.
+//
+// swagger:model
+type SystemStorageDeletePool struct {
+	Name string `json:"name" yaml:"name"`
+}
+
+// SystemStorageScrubPool defines a struct with information about what pool to scrub.
+//
+// swagger:model
+type SystemStorageScrubPool struct {
+	Name string `json:"name" yaml:"name"`
+}
+
+// SystemStorageCreateVolume defines a struct with information about the volume to create.
+//
+// swagger:model
+type SystemStorageCreateVolume struct {
+	Pool  string `json:"pool"  yaml:"pool"`
+	Name  string `json:"name"  yaml:"name"`
+	Quota int    `json:"quota" yaml:"quota"` // In bytes, zero meaning no quota.
+	Use   string `json:"use"   yaml:"use"`
+}
+
+// SystemStorageDeleteVolume defines a struct with information about what volume to delete.
+//
+// swagger:model
+type SystemStorageDeleteVolume struct {
+	Pool  string `json:"pool"  yaml:"pool"`
+	Name  string `json:"name"  yaml:"name"`
+	Force bool   `json:"force" yaml:"force"`
+}
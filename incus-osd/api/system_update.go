@@ -29,6 +29,13 @@ type SystemUpdateState struct {
 	NeedsReboot bool      `json:"needs_reboot" yaml:"needs_reboot"`
 }
 
+// SystemUpdateCheck defines a struct used to trigger an update check.
+//
+// swagger:model
+type SystemUpdateCheck struct {
+	OSOnly bool `json:"os_only" yaml:"os_only"` // If true, only check for OS updates, skipping applications.
+}
+
 // SystemUpdateMaintenanceWindow defines a maintenance window for when it is acceptable to check for and apply updates.
 // StartDayOfWeek and EndDayOfWeek are optional, and if non-zero can be used to limit the migration window to certain day(s).
 type SystemUpdateMaintenanceWindow struct {
@@ -0,0 +1,620 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/lxc/incus/v7/shared/ask"
+	cli "github.com/lxc/incus/v7/shared/cmd"
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v4"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+)
+
+// IncusOS declarative apply command.
+type cmdAdminOSApply struct {
+	os *cmdAdminOS
+
+	flagFile   string
+	flagDryRun bool
+	flagForce  bool
+}
+
+// applyChange represents a single value differing between the host and its description.
+type applyChange struct {
+	action    string
+	path      string
+	oldValue  any
+	newValue  any
+	sensitive bool
+}
+
+// applySection represents a part of the host, such as a configuration section or an application,
+// along with its changes.
+type applySection struct {
+	action  string
+	name    string
+	changes []applyChange
+}
+
+// applyPlan holds the changes needed for the host to match its description.
+type applyPlan struct {
+	sections []applySection
+
+	applications []string
+	system       api.SystemApply
+	storage      *api.SystemStorage
+	storageETag  string
+}
+
+func (c *cmdAdminOSApply) command() *cobra.Command {
+	usage := ""
+	if c.os.args.SupportsRemote {
+		usage = "[<remote>:]"
+	}
+
+	cmd := &cobra.Command{}
+	cmd.Use = cli.Usage("apply", usage)
+	cmd.Short = "Apply a host description"
+	cmd.Long = cli.FormatSection("Description", `Apply a host description
+
+The description covers applications, kernel, logging, network, services, storage and update
+configuration. Sections which aren't provided are left unchanged.
+
+The differences from the current configuration are shown before being applied. The same
+file can be used as a "host" seed.
+
+Secrets are redacted by IncusOS and can't be compared, so a changed secret is only applied
+along with other changes to its section. Nothing is applied if any of the changed sections
+was modified since the changes were computed.`)
+	cmd.Example = cli.FormatSection("", `incus admin os apply -f host.yaml
+    Show the changes needed for the host to match host.yaml and apply them.
+
+incus admin os apply -f host.yaml --dry-run
+    Only show the changes needed for the host to match host.yaml.`)
+
+	cmd.Flags().StringVarP(&c.flagFile, "file", "f", "", "Host description file, or - for stdin``")
+	cmd.Flags().BoolVar(&c.flagDryRun, "dry-run", false, "Only show the changes, without applying them")
+	cmd.Flags().BoolVar(&c.flagForce, "force", false, "Skip the confirmation prompt")
+
+	if c.os.args.SupportsTarget {
+		cmd.Flags().StringVar(&c.os.flagTarget, "target", "", "Cluster member name``")
+	}
+
+	cmd.RunE = c.run
+
+	return cmd
+}
+
+func (c *cmdAdminOSApply) run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	maxArgs := 0
+
+	if c.os.args.SupportsRemote {
+		maxArgs = 1
+	}
+
+	exit, err := cli.CheckArgs(cmd, args, 0, maxArgs)
+	if exit {
+		return err
+	}
+
+	if c.flagFile == "" {
+		return errors.New("a host description file must be provided with --file")
+	}
+
+	// Parse remote.
+	remote := ""
+	if len(args) > 0 {
+		remote, _ = parseRemote(args[0])
+	}
+
+	// Load the host description.
+	host, err := c.loadHost()
+	if err != nil {
+		return err
+	}
+
+	// Compare it against the current configuration.
+	plan, err := c.plan(remote, host)
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Print(plan.render()) //nolint:forbidigo
+
+	if len(plan.sections) == 0 || c.flagDryRun {
+		return nil
+	}
+
+	// Ask for confirmation.
+	if !c.flagForce {
+		asker := ask.NewAsker(bufio.NewReader(os.Stdin))
+
+		confirm, err := asker.AskBool("Are you sure you want to apply these changes? (yes/no) [default=no]: ", "no")
+		if err != nil {
+			return err
+		}
+
+		if !confirm {
+			return nil
+		}
+	}
+
+	return c.apply(remote, plan)
+}
+
+// loadHost reads the host description from the provided file.
+func (c *cmdAdminOSApply) loadHost() (*apiseed.Host, error) {
+	var r io.Reader
+
+	if c.flagFile == "-" {
+		r = os.Stdin
+	} else {
+		f, err := os.Open(c.flagFile)
+		if err != nil {
+			return nil, err
+		}
+
+		defer func() { _ = f.Close() }()
+
+		r = f
+	}
+
+	host := &apiseed.Host{}
+
+	loader, err := yaml.NewLoader(r, yaml.WithKnownFields())
+	if err != nil {
+		return nil, err
+	}
+
+	err = loader.Load(host)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse the host description: %w", err)
+	}
+
+	return host, nil
+}
+
+// apiURL returns the URL of an endpoint, using the cluster target if specified.
+func (c *cmdAdminOSApply) apiURL(endpoint string) string {
+	apiURL := "/os/1.0/" + endpoint
+
+	if c.os.flagTarget != "" {
+		apiURL += "?target=" + c.os.flagTarget
+	}
+
+	return apiURL
+}
+
+// getConfig returns the current configuration of an endpoint.
+func (c *cmdAdminOSApply) getConfig(remote string, endpoint string, target any) (string, error) {
+	resp, etag, err := doQuery(c.os.args.DoHTTP, remote, "GET", c.apiURL(endpoint), nil, nil, "")
+	if err != nil {
+		return "", err
+	}
+
+	err = resp.MetadataAsStruct(target)
+	if err != nil {
+		return "", err
+	}
+
+	return etag, nil
+}
+
+// plan compares the host description against the current configuration.
+func (c *cmdAdminOSApply) plan(remote string, host *apiseed.Host) (*applyPlan, error) {
+	plan := &applyPlan{}
+
+	// Applications.
+	if len(host.Applications) > 0 {
+		resp, _, err := doQuery(c.os.args.DoHTTP, remote, "GET", c.apiURL("applications"), nil, nil, "")
+		if err != nil {
+			return nil, err
+		}
+
+		entries, err := resp.MetadataAsStringSlice()
+		if err != nil {
+			return nil, err
+		}
+
+		installed := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			installed = append(installed, strings.TrimPrefix(entry, "/os/1.0/applications/"))
+		}
+
+		for _, app := range host.Applications {
+			if slices.Contains(installed, app.Name) || slices.Contains(plan.applications, app.Name) {
+				continue
+			}
+
+			plan.applications = append(plan.applications, app.Name)
+			plan.sections = append(plan.sections, applySection{action: "+", name: "application " + app.Name})
+		}
+	}
+
+	// System configuration sections, in the order they are applied.
+	if host.Kernel != nil {
+		changed, err := c.planSection(remote, plan, "system kernel", "system/kernel", host.Kernel)
+		if err != nil {
+			return nil, err
+		}
+
+		if changed {
+			plan.system.Kernel = host.Kernel
+		}
+	}
+
+	if host.Network != nil {
+		changed, err := c.planSection(remote, plan, "system network", "system/network", host.Network)
+		if err != nil {
+			return nil, err
+		}
+
+		if changed {
+			plan.system.Network = host.Network
+		}
+	}
+
+	serviceNames := make([]string, 0, len(host.Services))
+	for name := range host.Services {
+		serviceNames = append(serviceNames, name)
+	}
+
+	sort.Strings(serviceNames)
+
+	for _, name := range serviceNames {
+		changed, err := c.planSection(remote, plan, "service "+name, "services/"+name, host.Services[name])
+		if err != nil {
+			return nil, err
+		}
+
+		if !changed {
+			continue
+		}
+
+		content, err := json.Marshal(host.Services[name])
+		if err != nil {
+			return nil, err
+		}
+
+		if plan.system.Services == nil {
+			plan.system.Services = map[string]json.RawMessage{}
+		}
+
+		plan.system.Services[name] = content
+	}
+
+	if host.Logging != nil {
+		changed, err := c.planSection(remote, plan, "system logging", "system/logging", host.Logging)
+		if err != nil {
+			return nil, err
+		}
+
+		if changed {
+			plan.system.Logging = host.Logging
+		}
+	}
+
+	if host.Update != nil {
+		changed, err := c.planSection(remote, plan, "system update", "system/update", host.Update)
+		if err != nil {
+			return nil, err
+		}
+
+		if changed {
+			plan.system.Update = host.Update
+		}
+	}
+
+	// Storage.
+	if host.Storage != nil {
+		err := c.planStorage(remote, host.Storage, plan)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return plan, nil
+}
+
+// planStorage compares the storage scrub schedule and pools against the current configuration.
+// Pools which aren't part of the description are left unchanged.
+func (c *cmdAdminOSApply) planStorage(remote string, desired *api.SystemStorageConfig, plan *applyPlan) error {
+	current := &api.SystemStorage{}
+
+	etag, err := c.getConfig(remote, "system/storage", current)
+	if err != nil {
+		return err
+	}
+
+	storage := &api.SystemStorage{}
+	storage.Config.ScrubSchedule = current.Config.ScrubSchedule
+
+	if desired.ScrubSchedule != "" && desired.ScrubSchedule != current.Config.ScrubSchedule {
+		storage.Config.ScrubSchedule = desired.ScrubSchedule
+
+		plan.sections = append(plan.sections, applySection{
+			action:  "~",
+			name:    "system storage",
+			changes: []applyChange{{action: "~", path: "scrub_schedule", oldValue: current.Config.ScrubSchedule, newValue: desired.ScrubSchedule}},
+		})
+	}
+
+	for _, pool := range desired.Pools {
+		idx := slices.IndexFunc(current.State.Pools, func(p api.SystemStoragePool) bool { return p.Name == pool.Name })
+
+		var currentPool any
+
+		action := "+"
+
+		if idx >= 0 {
+			currentPool = storagePoolConfig(current.State.Pools[idx])
+			action = "~"
+		}
+
+		changes, err := diffConfig(currentPool, storagePoolConfig(pool))
+		if err != nil {
+			return err
+		}
+
+		if len(changes) == 0 {
+			continue
+		}
+
+		storage.Config.Pools = append(storage.Config.Pools, pool)
+		plan.sections = append(plan.sections, applySection{action: action, name: "storage pool " + pool.Name, changes: changes})
+	}
+
+	if storage.Config.ScrubSchedule != current.Config.ScrubSchedule || len(storage.Config.Pools) > 0 {
+		plan.storage = storage
+		plan.storageETag = etag
+	}
+
+	return nil
+}
+
+// planSection compares the desired configuration against the current one of an endpoint, adding
+// any change to the plan and returning whether the section changed.
+func (c *cmdAdminOSApply) planSection(remote string, plan *applyPlan, name string, endpoint string, desired any) (bool, error) {
+	var current struct {
+		Config any `json:"config"`
+	}
+
+	etag, err := c.getConfig(remote, endpoint, &current)
+	if err != nil {
+		return false, err
+	}
+
+	changes, err := diffConfig(current.Config, desired)
+	if err != nil {
+		return false, err
+	}
+
+	if len(changes) == 0 {
+		return false, nil
+	}
+
+	plan.sections = append(plan.sections, applySection{action: "~", name: name, changes: changes})
+
+	// Only apply the section if it's still the one the plan was computed against.
+	if etag != "" {
+		if plan.system.ETags == nil {
+			plan.system.ETags = map[string]string{}
+		}
+
+		plan.system.ETags[endpoint] = etag
+	}
+
+	return true, nil
+}
+
+// apply applies the planned changes. Applications are added first, so their services can be
+// configured, then the system configuration is applied as a single transaction, followed by storage.
+func (c *cmdAdminOSApply) apply(remote string, plan *applyPlan) error {
+	for _, name := range plan.applications {
+		_, _, err := doQuery(c.os.args.DoHTTP, remote, "POST", c.apiURL("applications"), api.ApplicationPost{Name: name}, nil, "")
+		if err != nil {
+			return fmt.Errorf("failed to add application %q: %w", name, err)
+		}
+	}
+
+	if plan.system.Kernel != nil || plan.system.Network != nil || plan.system.Services != nil || plan.system.Logging != nil || plan.system.Update != nil {
+		_, _, err := doQuery(c.os.args.DoHTTP, remote, "POST", c.apiURL("system/:apply"), plan.system, nil, "")
+		if err != nil {
+			return fmt.Errorf("failed to apply the system configuration: %w", err)
+		}
+	}
+
+	if plan.storage != nil {
+		_, _, err := doQuery(c.os.args.DoHTTP, remote, "PUT", c.apiURL("system/storage"), plan.storage, nil, plan.storageETag)
+		if err != nil {
+			return fmt.Errorf("failed to apply the storage configuration: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// render returns the plan in a human readable form.
+func (p *applyPlan) render() string {
+	if len(p.sections) == 0 {
+		return "No changes. The host matches its description.\n"
+	}
+
+	var b strings.Builder
+
+	b.WriteString("The following changes will be made:\n\n")
+
+	added := 0
+	changed := 0
+
+	for _, section := range p.sections {
+		if section.action == "+" {
+			added++
+		} else {
+			changed++
+		}
+
+		_, _ = fmt.Fprintf(&b, "  %s %s\n", section.action, section.name)
+
+		for _, change := range section.changes {
+			switch change.action {
+			case "+":
+				_, _ = fmt.Fprintf(&b, "      + %s: %s\n", change.path, formatValue(change.newValue, change.sensitive))
+			case "-":
+				_, _ = fmt.Fprintf(&b, "      - %s: %s\n", change.path, formatValue(change.oldValue, change.sensitive))
+			default:
+				_, _ = fmt.Fprintf(&b, "      ~ %s: %s => %s\n", change.path, formatValue(change.oldValue, change.sensitive), formatValue(change.newValue, change.sensitive))
+			}
+		}
+
+		b.WriteString("\n")
+	}
+
+	_, _ = fmt.Fprintf(&b, "Plan: %d to add, %d to change.\n", added, changed)
+
+	return b.String()
+}
+
+// storagePoolConfig returns the user-configurable part of a storage pool, with its devices sorted
+// the same way IncusOS reports them.
+func storagePoolConfig(pool api.SystemStoragePool) api.SystemStoragePool {
+	ret := api.SystemStoragePool{
+		Name:               pool.Name,
+		Type:               pool.Type,
+		AllowMixedDevSizes: pool.AllowMixedDevSizes,
+		Devices:            slices.Sorted(slices.Values(pool.Devices)),
+		Cache:              slices.Sorted(slices.Values(pool.Cache)),
+		Log:                slices.Sorted(slices.Values(pool.Log)),
+	}
+
+	if pool.Special != nil {
+		special := *pool.Special
+		special.Devices = slices.Sorted(slices.Values(pool.Special.Devices))
+		ret.Special = &special
+	}
+
+	return ret
+}
+
+// diffConfig returns the changes between two configurations. Both are compared through their JSON
+// representation, with unset and zero values being considered equal.
+func diffConfig(current any, desired any) ([]applyChange, error) {
+	currentValues := map[string]any{}
+	desiredValues := map[string]any{}
+
+	for _, entry := range []struct {
+		value  any
+		values map[string]any
+	}{{current, currentValues}, {desired, desiredValues}} {
+		if entry.value == nil {
+			continue
+		}
+
+		content, err := json.Marshal(entry.value)
+		if err != nil {
+			return nil, err
+		}
+
+		var data any
+
+		err = json.Unmarshal(content, &data)
+		if err != nil {
+			return nil, err
+		}
+
+		flattenConfig("", data, entry.values)
+	}
+
+	paths := make([]string, 0, len(currentValues)+len(desiredValues))
+	for path := range currentValues {
+		paths = append(paths, path)
+	}
+
+	for path := range desiredValues {
+		if _, ok := currentValues[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+
+	sort.Strings(paths)
+
+	changes := []applyChange{}
+
+	for _, path := range paths {
+		oldValue, hasOld := currentValues[path]
+		newValue, hasNew := desiredValues[path]
+
+		// Secrets are redacted by IncusOS, so neither value of a changed secret is shown. As the
+		// redacted value can't be compared against a plain secret, providing one is always a change,
+		// while a placeholder is compared as-is, as it changes along with the secret.
+		oldString, _ := oldValue.(string)
+		sensitive := isPlaceholder(oldString)
+
+		switch {
+		case !hasOld:
+			changes = append(changes, applyChange{action: "+", path: path, newValue: newValue})
+		case !hasNew:
+			changes = append(changes, applyChange{action: "-", path: path, oldValue: oldValue, sensitive: sensitive})
+		case oldValue != newValue:
+			changes = append(changes, applyChange{action: "~", path: path, oldValue: oldValue, newValue: newValue, sensitive: sensitive})
+		default:
+		}
+	}
+
+	return changes, nil
+}
+
+// isPlaceholder returns whether the value is a secret redacted by IncusOS.
+func isPlaceholder(value string) bool {
+	return strings.HasPrefix(value, "[redacted:") && strings.HasSuffix(value, "]")
+}
+
+// flattenConfig records the non-zero leaf values of a decoded JSON value, keyed by their path.
+func flattenConfig(path string, value any, values map[string]any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if path != "" {
+				key = path + "." + key
+			}
+
+			flattenConfig(key, item, values)
+		}
+
+	case []any:
+		for i, item := range v {
+			flattenConfig(fmt.Sprintf("%s[%d]", path, i), item, values)
+		}
+
+	case nil:
+	default:
+		if v != false && v != "" && v != float64(0) {
+			values[path] = v
+		}
+	}
+}
+
+// formatValue returns the representation of a leaf value in the plan.
+func formatValue(value any, sensitive bool) string {
+	if sensitive {
+		return "(sensitive value)"
+	}
+
+	content, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+
+	return string(content)
+}
@@ -25,6 +25,10 @@ func (c *cmdAdminOS) command() *cobra.Command {
 	applicationCmd := cmdAdminOSApplication{os: c}
 	cmd.AddCommand(applicationCmd.command())
 
+	// Apply.
+	applyCmd := cmdAdminOSApply{os: c}
+	cmd.AddCommand(applyCmd.command())
+
 	// Debug.
 	debugCmd := cmdAdminOSDebug{os: c}
 	cmd.AddCommand(debugCmd.command())
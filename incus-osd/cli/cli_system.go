@@ -17,6 +17,17 @@ func (c *cmdAdminOSSystem) command() *cobra.Command {
 	cmd.Short = "Manage IncusOS system details"
 	cmd.Long = cli.FormatSection("Description", "Manage IncusOS system details")
 
+	// Apply.
+	applyCmd := cmdGenericRun{
+		os:          c.os,
+		action:      "apply",
+		description: "Apply several configuration sections at once",
+		endpoint:    "system",
+		hasData:     true,
+		confirm:     "apply the configuration",
+	}
+	cmd.AddCommand(applyCmd.command())
+
 	// Backup.
 	backupCmd := cmdGenericRun{
 		os:            c.os,
@@ -29,6 +40,18 @@ func (c *cmdAdminOSSystem) command() *cobra.Command {
 	}
 	cmd.AddCommand(backupCmd.command())
 
+	// Export seed.
+	exportSeedCmd := cmdGenericRun{
+		os:            c.os,
+		action:        "export-seed",
+		description:   "Export the running configuration as seed data",
+		endpoint:      "system",
+		hasData:       true,
+		defaultData:   "{}",
+		hasFileOutput: true,
+	}
+	cmd.AddCommand(exportSeedCmd.command())
+
 	// Factory reset.
 	factoryResetCmd := cmdGenericRun{
 		os:          c.os,
@@ -99,11 +122,40 @@ func (c *cmdAdminOSSystem) command() *cobra.Command {
 	}
 
 	subCommands := []subCommand{
+		{
+			name:        "certificate",
+			description: "Server certificate configuration",
+			isWritable:  true,
+		},
 		{
 			name:        "fallback-listener",
 			description: "System fallback HTTPS listener configuration",
 			isWritable:  true,
 		},
+		{
+			name:        "history",
+			description: "Configuration history",
+			isWritable:  false,
+			extraCommands: func() []*cobra.Command {
+				// Revert a configuration change.
+				revertCmd := cmdGenericRun{
+					os:          c.os,
+					action:      "revert",
+					description: "Revert a configuration change",
+					endpoint:    "system/history",
+					confirm:     "revert the configuration change",
+					extraArgs: []cmdGenericRunArgs{
+						{
+							shortFlag:   "r",
+							longFlag:    "revision",
+							description: "Revision to revert",
+						},
+					},
+				}
+
+				return []*cobra.Command{revertCmd.command()}
+			},
+		},
 		{
 			name:        "kernel",
 			description: "System kernel configuration",
@@ -164,6 +216,15 @@ func (c *cmdAdminOSSystem) command() *cobra.Command {
 					endpoint:    "system/security",
 				}
 
+				// Encryption key rotation.
+				rotateKeysCmd := cmdGenericRun{
+					os:          c.os,
+					action:      "rotate-keys",
+					description: "Rotate the encryption keys",
+					endpoint:    "system/security",
+					confirm:     "rotate all encryption keys",
+				}
+
 				// TPM rebind.
 				tpmRebindCmd := cmdGenericRun{
 					os:          c.os,
@@ -173,7 +234,7 @@ func (c *cmdAdminOSSystem) command() *cobra.Command {
 					confirm:     "rebind the TPM and reboot the system",
 				}
 
-				return []*cobra.Command{retrievedCmd.command(), tpmRebindCmd.command()}
+				return []*cobra.Command{retrievedCmd.command(), rotateKeysCmd.command(), tpmRebindCmd.command()}
 			},
 		},
 		{
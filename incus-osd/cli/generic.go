@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	"os"
 	"slices"
@@ -110,8 +111,8 @@ func (c *cmdGenericEdit) run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	// Extract the current value
-	resp, _, err := doQuery(c.os.args.DoHTTP, remote, "GET", apiURL, nil, nil, "")
+	// Extract the current value, along with its ETag so concurrent changes are detected.
+	resp, etag, err := doQuery(c.os.args.DoHTTP, remote, "GET", apiURL, nil, nil, "")
 	if err != nil {
 		return err
 	}
@@ -140,7 +141,10 @@ func (c *cmdGenericEdit) run(cmd *cobra.Command, args []string) error {
 
 		err = yaml.Load(content, &newdata)
 		if err == nil {
-			_, _, err = doQuery(c.os.args.DoHTTP, remote, "PUT", apiURL, makeJsonable(newdata), nil, "")
+			_, _, err = doQuery(c.os.args.DoHTTP, remote, "PUT", apiURL, makeJsonable(newdata), nil, etag)
+			if incusapi.StatusErrorCheck(err, http.StatusPreconditionFailed) {
+				return errors.New("the configuration was modified while being edited, please try again")
+			}
 		}
 
 		// Respawn the editor
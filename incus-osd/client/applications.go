@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// GetApplicationNames returns the names of the installed applications.
+func (c *Client) GetApplicationNames(ctx context.Context) ([]string, error) {
+	urls := []string{}
+
+	_, err := c.queryStruct(ctx, "/applications", &urls)
+	if err != nil {
+		return nil, err
+	}
+
+	return urlsToNames(urls), nil
+}
+
+// AddApplication installs a new application.
+func (c *Client) AddApplication(ctx context.Context, name string) error {
+	req := api.ApplicationPost{Name: name}
+
+	_, err := c.query(ctx, http.MethodPost, "/applications", nil, req, "", nil)
+
+	return err
+}
+
+// GetApplication retrieves the state and configuration of an application into app, which should
+// be a pointer to the application's struct, such as *api.ApplicationIncus. The ETag is returned.
+func (c *Client) GetApplication(ctx context.Context, name string, app any) (string, error) {
+	return c.queryStruct(ctx, "/applications/"+url.PathEscape(name), app)
+}
+
+// UpdateApplication updates the configuration of an application.
+func (c *Client) UpdateApplication(ctx context.Context, name string, app any, etag string) error {
+	_, err := c.query(ctx, http.MethodPut, "/applications/"+url.PathEscape(name), nil, app, etag, nil)
+
+	return err
+}
+
+// ApplicationAction triggers an application-specific action.
+func (c *Client) ApplicationAction(ctx context.Context, name string, action any) error {
+	_, err := c.query(ctx, http.MethodPost, "/applications/"+url.PathEscape(name)+"/:action", nil, action, "", nil)
+
+	return err
+}
+
+// DebugApplication runs an application-specific debug request, writing the raw response to w.
+func (c *Client) DebugApplication(ctx context.Context, name string, req any, w io.Writer) error {
+	return c.stream(ctx, http.MethodPost, "/applications/"+url.PathEscape(name)+"/:debug", req, w)
+}
+
+// CheckApplicationUpdate checks for an update of the application.
+func (c *Client) CheckApplicationUpdate(ctx context.Context, name string) error {
+	_, err := c.query(ctx, http.MethodPost, "/applications/"+url.PathEscape(name)+"/:check-update", nil, nil, "", nil)
+
+	return err
+}
+
+// FactoryResetApplication performs a factory reset of the application, wiping its local configuration.
+func (c *Client) FactoryResetApplication(ctx context.Context, name string) error {
+	_, err := c.query(ctx, http.MethodPost, "/applications/"+url.PathEscape(name)+"/:factory-reset", nil, nil, "", nil)
+
+	return err
+}
+
+// RemoveApplication removes the application.
+func (c *Client) RemoveApplication(ctx context.Context, name string) error {
+	_, err := c.query(ctx, http.MethodPost, "/applications/"+url.PathEscape(name)+"/:remove", nil, nil, "", nil)
+
+	return err
+}
+
+// RestartApplication restarts the application.
+func (c *Client) RestartApplication(ctx context.Context, name string) error {
+	_, err := c.query(ctx, http.MethodPost, "/applications/"+url.PathEscape(name)+"/:restart", nil, nil, "", nil)
+
+	return err
+}
+
+// SwitchApplicationVersion switches the application to another available version.
+func (c *Client) SwitchApplicationVersion(ctx context.Context, name string, version string) error {
+	req := api.ApplicationSwitchVersion{Version: version}
+
+	_, err := c.query(ctx, http.MethodPost, "/applications/"+url.PathEscape(name)+"/:switch-version", nil, req, "", nil)
+
+	return err
+}
+
+// urlsToNames returns the last path element of each API URL.
+func urlsToNames(urls []string) []string {
+	names := make([]string, 0, len(urls))
+
+	for _, u := range urls {
+		names = append(names, path.Base(u))
+	}
+
+	return names
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// BackupSystem generates a system backup, writing the gzip'ed tar archive to w.
+func (c *Client) BackupSystem(ctx context.Context, w io.Writer) error {
+	return c.stream(ctx, http.MethodPost, "/system/:backup", nil, w)
+}
+
+// RestoreSystem restores a gzip'ed tar system backup read from r, skipping any listed item
+// ("encryption-recovery-keys", "local-data-encryption-key" or "network-macs").
+func (c *Client) RestoreSystem(ctx context.Context, r io.Reader, skip []string) error {
+	query := url.Values{}
+	if len(skip) > 0 {
+		query.Set("skip", strings.Join(skip, ","))
+	}
+
+	return c.restore(ctx, "/system/:restore", query, r)
+}
+
+// BackupApplication generates an application backup, writing the gzip'ed tar archive to w. A
+// complete backup also includes the application's data.
+func (c *Client) BackupApplication(ctx context.Context, name string, complete bool, w io.Writer) error {
+	req := api.ApplicationBackup{Complete: complete}
+
+	return c.stream(ctx, http.MethodPost, "/applications/"+url.PathEscape(name)+"/:backup", req, w)
+}
+
+// RestoreApplication restores a gzip'ed tar application backup read from r.
+func (c *Client) RestoreApplication(ctx context.Context, name string, r io.Reader) error {
+	return c.restore(ctx, "/applications/"+url.PathEscape(name)+"/:restore", nil, r)
+}
+
+func (c *Client) restore(ctx context.Context, path string, query url.Values, r io.Reader) error {
+	resp, err := c.rawRequest(ctx, http.MethodPost, path, query, r, "application/gzip", "")
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	return parseResponse(resp, nil)
+}
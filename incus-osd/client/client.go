@@ -0,0 +1,280 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	incusapi "github.com/lxc/incus/v7/shared/api"
+	incustls "github.com/lxc/incus/v7/shared/tls"
+)
+
+// DefaultUnixSocket is the path to the IncusOS daemon's local Unix socket.
+const DefaultUnixSocket = "/run/incus-os/unix.socket"
+
+// ProxyPrefix is the path prefix under which the IncusOS API is reachable when proxied through an
+// application, such as Incus. The fallback HTTPS listener also accepts it.
+const ProxyPrefix = "/os"
+
+// ConnectionArgs represents the optional arguments used when connecting to IncusOS.
+type ConnectionArgs struct {
+	// PEM encoded client certificate and key, used for mTLS authentication.
+	TLSClientCert string
+	TLSClientKey  string
+
+	// PEM encoded server certificate, trusted in addition to the system CAs.
+	TLSServerCert string
+
+	// Whether the API is reached through an application proxy, prefixing all requests with ProxyPrefix.
+	Proxied bool
+
+	// Custom HTTP client, used as-is instead of setting up a new one.
+	HTTPClient *http.Client
+}
+
+// Client is a client for the IncusOS REST API.
+type Client struct {
+	http    *http.Client
+	baseURL string
+}
+
+// NewUnixClient returns a client connecting over a local Unix socket. If no path is provided, the
+// IncusOS daemon's own socket is used.
+func NewUnixClient(path string, args *ConnectionArgs) (*Client, error) {
+	if path == "" {
+		path = DefaultUnixSocket
+	}
+
+	if args == nil {
+		args = &ConnectionArgs{}
+	}
+
+	httpClient := args.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+
+					return d.DialContext(ctx, "unix", path)
+				},
+			},
+		}
+	}
+
+	return newClient(httpClient, "http://unix", args.Proxied), nil //nolint:revive
+}
+
+// NewTLSClient returns a client connecting over HTTPS to the provided URL, authenticating with
+// the client certificate from the connection arguments.
+func NewTLSClient(serverURL string, args *ConnectionArgs) (*Client, error) {
+	if args == nil {
+		args = &ConnectionArgs{}
+	}
+
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "https" {
+		return nil, errors.New("the server URL must use https")
+	}
+
+	httpClient := args.HTTPClient
+	if httpClient == nil {
+		tlsConfig := &tls.Config{
+			MinVersion: tls.VersionTLS13,
+		}
+
+		// Trust the provided server certificate.
+		if args.TLSServerCert != "" {
+			certBlock, _ := pem.Decode([]byte(args.TLSServerCert))
+			if certBlock == nil {
+				return nil, errors.New("invalid server certificate")
+			}
+
+			serverCert, err := x509.ParseCertificate(certBlock.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid server certificate: %w", err)
+			}
+
+			incustls.TLSConfigWithTrustedCert(tlsConfig, serverCert)
+		}
+
+		// Set the client certificate.
+		if args.TLSClientCert != "" || args.TLSClientKey != "" {
+			cert, err := tls.X509KeyPair([]byte(args.TLSClientCert), []byte(args.TLSClientKey))
+			if err != nil {
+				return nil, fmt.Errorf("invalid client certificate: %w", err)
+			}
+
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+
+		httpClient = &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		}
+	}
+
+	return newClient(httpClient, strings.TrimSuffix(u.String(), "/"), args.Proxied), nil
+}
+
+func newClient(httpClient *http.Client, baseURL string, proxied bool) *Client {
+	if proxied {
+		baseURL += ProxyPrefix
+	}
+
+	return &Client{
+		http:    httpClient,
+		baseURL: baseURL,
+	}
+}
+
+// HTTPClient returns the underlying HTTP client.
+func (c *Client) HTTPClient() *http.Client {
+	return c.http
+}
+
+// rawRequest sends a request to the API, returning the raw HTTP response on success. The caller
+// is responsible for closing the response body.
+func (c *Client) rawRequest(ctx context.Context, method string, path string, query url.Values, body io.Reader, contentType string, etag string) (*http.Response, error) {
+	u := c.baseURL + "/1.0" + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Errors are always returned as JSON.
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+
+		return nil, parseError(resp)
+	}
+
+	return resp, nil
+}
+
+// query sends a JSON request to the API, decoding the response metadata into target if provided
+// and returning the ETag of the response.
+func (c *Client) query(ctx context.Context, method string, path string, query url.Values, data any, etag string, target any) (string, error) {
+	body, contentType, err := jsonBody(data)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.rawRequest(ctx, method, path, query, body, contentType, etag)
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	return resp.Header.Get("ETag"), parseResponse(resp, target)
+}
+
+// stream sends a JSON request, copying the raw response body to w.
+func (c *Client) stream(ctx context.Context, method string, path string, data any, w io.Writer) error {
+	body, contentType, err := jsonBody(data)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.rawRequest(ctx, method, path, nil, body, contentType, "")
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	_, err = io.Copy(w, resp.Body)
+
+	return err
+}
+
+// queryStruct is a shorthand for GET requests.
+func (c *Client) queryStruct(ctx context.Context, path string, target any) (string, error) {
+	return c.query(ctx, http.MethodGet, path, nil, nil, "", target)
+}
+
+// jsonBody returns the JSON encoded request body for data, if any, along with its content type.
+func jsonBody(data any) (io.Reader, string, error) {
+	if data == nil {
+		return nil, "", nil
+	}
+
+	content, err := json.Marshal(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return bytes.NewReader(content), "application/json", nil
+}
+
+// parseResponse decodes a sync response, unmarshaling its metadata into target if provided.
+func parseResponse(resp *http.Response, target any) error {
+	apiResp := &incusapi.Response{}
+
+	err := json.NewDecoder(resp.Body).Decode(apiResp)
+	if err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if apiResp.Type == incusapi.ErrorResponse {
+		return incusapi.StatusErrorf(apiResp.Code, "%s", apiResp.Error)
+	}
+
+	if target == nil {
+		return nil
+	}
+
+	err = json.Unmarshal(apiResp.Metadata, target)
+	if err != nil {
+		return fmt.Errorf("failed to parse response metadata: %w", err)
+	}
+
+	return nil
+}
+
+// parseError returns the error for a failed request. Use incusapi.StatusErrorCheck to test for
+// specific HTTP status codes.
+func parseError(resp *http.Response) error {
+	apiResp := &incusapi.Response{}
+
+	err := json.NewDecoder(resp.Body).Decode(apiResp)
+	if err != nil || apiResp.Error == "" {
+		return incusapi.StatusErrorf(resp.StatusCode, "%s", http.StatusText(resp.StatusCode))
+	}
+
+	return incusapi.StatusErrorf(resp.StatusCode, "%s", apiResp.Error)
+}
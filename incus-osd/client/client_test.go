@@ -0,0 +1,205 @@
+package client_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	incusapi "github.com/lxc/incus/v7/shared/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/client"
+	"github.com/lxc/incus-os/incus-osd/internal/rest"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// newKeyPair returns a PEM-encoded self-signed certificate and key.
+func newKeyPair(t *testing.T, usage x509.ExtKeyUsage) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "incus-os.example.com"},
+		DNSNames:     []string{"incus-os.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+}
+
+// newServer starts an in-process REST API server on the provided listener.
+func newServer(t *testing.T, l net.Listener, s *state.State) {
+	t.Helper()
+
+	server, err := rest.NewServer(t.Context(), s, l)
+	require.NoError(t, err)
+
+	go func() { _ = server.Serve() }()
+
+	t.Cleanup(func() { _ = l.Close() })
+}
+
+// newState returns a fresh state backed by a temporary file.
+func newState(t *testing.T) *state.State {
+	t.Helper()
+
+	s, err := state.LoadOrCreate(filepath.Join(t.TempDir(), "state.txt"))
+	require.NoError(t, err)
+
+	return s
+}
+
+func TestUnixClient(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+
+	socketPath := filepath.Join(t.TempDir(), "unix.socket")
+
+	l, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+
+	newServer(t, l, newState(t))
+
+	c, err := client.NewUnixClient(socketPath, nil)
+	require.NoError(t, err)
+
+	// Get the current configuration along with its ETag.
+	update, etag, err := c.GetSystemUpdate(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "stable", update.Config.Channel)
+	require.Equal(t, "6h", update.Config.CheckFrequency)
+	require.NotEmpty(t, etag)
+
+	// Update the configuration.
+	update.Config.CheckFrequency = "12h"
+
+	err = c.UpdateSystemUpdate(ctx, *update, etag)
+	require.NoError(t, err)
+
+	update, newEtag, err := c.GetSystemUpdate(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "12h", update.Config.CheckFrequency)
+	require.NotEqual(t, etag, newEtag)
+
+	// A stale ETag is refused.
+	update.Config.CheckFrequency = "never"
+
+	err = c.UpdateSystemUpdate(ctx, *update, etag)
+	require.Error(t, err)
+	require.True(t, incusapi.StatusErrorCheck(err, http.StatusPreconditionFailed))
+
+	// Invalid configurations are refused.
+	update.Config.CheckFrequency = "invalid"
+
+	err = c.UpdateSystemUpdate(ctx, *update, "")
+	require.Error(t, err)
+	require.True(t, incusapi.StatusErrorCheck(err, http.StatusBadRequest))
+
+	// The change was recorded and can be reverted.
+	revisions, err := c.GetSystemHistory(ctx)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	require.Equal(t, "local", revisions[0].Actor)
+	require.True(t, revisions[0].Revertible)
+
+	err = c.RevertSystemHistory(ctx, revisions[0].Revision)
+	require.NoError(t, err)
+
+	update, _, err = c.GetSystemUpdate(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "6h", update.Config.CheckFrequency)
+
+	err = c.RevertSystemHistory(ctx, 100)
+	require.Error(t, err)
+	require.True(t, incusapi.StatusErrorCheck(err, http.StatusNotFound))
+
+	// The OpenAPI specification is served as-is.
+	spec, err := c.GetOpenAPISpec(ctx)
+	require.NoError(t, err)
+	require.Contains(t, string(spec), `"openapi":"3.0.3"`)
+
+	// Unknown services aren't found.
+	_, err = c.GetService(ctx, "unknown", &map[string]any{})
+	require.Error(t, err)
+	require.True(t, incusapi.StatusErrorCheck(err, http.StatusNotFound))
+}
+
+func TestTLSClient(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+
+	serverCert, serverKey := newKeyPair(t, x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := newKeyPair(t, x509.ExtKeyUsageClientAuth)
+	otherCert, otherKey := newKeyPair(t, x509.ExtKeyUsageClientAuth)
+
+	cert, err := tls.X509KeyPair([]byte(serverCert), []byte(serverKey))
+	require.NoError(t, err)
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequestClientCert,
+		MinVersion:   tls.VersionTLS13,
+	})
+	require.NoError(t, err)
+
+	s := newState(t)
+	s.System.FallbackListener.Config.TrustedClientCertificates = []string{clientCert}
+
+	newServer(t, l, s)
+
+	serverURL := "https://" + l.Addr().String()
+
+	// Plain HTTP is refused.
+	_, err = client.NewTLSClient("http://"+l.Addr().String(), nil)
+	require.Error(t, err)
+
+	// Both the direct and proxied paths are served.
+	for _, proxied := range []bool{false, true} {
+		c, err := client.NewTLSClient(serverURL, &client.ConnectionArgs{
+			TLSClientCert: clientCert,
+			TLSClientKey:  clientKey,
+			TLSServerCert: serverCert,
+			Proxied:       proxied,
+		})
+		require.NoError(t, err)
+
+		update, _, err := c.GetSystemUpdate(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "stable", update.Config.Channel)
+	}
+
+	// Untrusted client certificates are refused.
+	c, err := client.NewTLSClient(serverURL, &client.ConnectionArgs{
+		TLSClientCert: otherCert,
+		TLSClientKey:  otherKey,
+		TLSServerCert: serverCert,
+	})
+	require.NoError(t, err)
+
+	_, _, err = c.GetSystemUpdate(ctx)
+	require.Error(t, err)
+	require.True(t, incusapi.StatusErrorCheck(err, http.StatusForbidden))
+}
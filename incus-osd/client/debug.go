@@ -0,0 +1,130 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// DebugLogArgs represents the optional filters used when retrieving journal entries.
+type DebugLogArgs struct {
+	Unit    string
+	Boot    *int
+	Entries int
+	Since   string
+	Until   string
+}
+
+// DebugSecureBootEventLog represents the TPM event log and final PCR values.
+type DebugSecureBootEventLog struct {
+	EventLog []map[string]any `json:"event_log"`
+	PCR4     string           `json:"pcr4"`
+	PCR7     string           `json:"pcr7"`
+}
+
+// GetDebugKernel returns kernel debug information.
+func (c *Client) GetDebugKernel(ctx context.Context) (*api.DebugKernel, error) {
+	ret := &api.DebugKernel{}
+
+	_, err := c.queryStruct(ctx, "/debug/kernel", ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// GetDebugLog returns systemd journal entries. Without any filter, all entries of the current boot are returned.
+func (c *Client) GetDebugLog(ctx context.Context, args *DebugLogArgs) ([]map[string]any, error) {
+	query := url.Values{}
+
+	if args != nil {
+		if args.Unit != "" {
+			query.Set("unit", args.Unit)
+		}
+
+		if args.Boot != nil {
+			query.Set("boot", strconv.Itoa(*args.Boot))
+		}
+
+		if args.Entries > 0 {
+			query.Set("entries", strconv.Itoa(args.Entries))
+		}
+
+		if args.Since != "" {
+			query.Set("since", args.Since)
+		}
+
+		if args.Until != "" {
+			query.Set("until", args.Until)
+		}
+	}
+
+	ret := []map[string]any{}
+
+	_, err := c.query(ctx, http.MethodGet, "/debug/log", query, nil, "", &ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// GetDebugProcesses returns the text list of system processes.
+func (c *Client) GetDebugProcesses(ctx context.Context) (string, error) {
+	var ret string
+
+	_, err := c.queryStruct(ctx, "/debug/processes", &ret)
+	if err != nil {
+		return "", err
+	}
+
+	return ret, nil
+}
+
+// GetDebugSecureBootEventLog returns the TPM event log.
+func (c *Client) GetDebugSecureBootEventLog(ctx context.Context) (*DebugSecureBootEventLog, error) {
+	ret := &DebugSecureBootEventLog{}
+
+	_, err := c.queryStruct(ctx, "/debug/secureboot/event-log", ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// UpdateDebugSecureBoot applies a Secure Boot certificate update.
+func (c *Client) UpdateDebugSecureBoot(ctx context.Context, update []byte) error {
+	resp, err := c.rawRequest(ctx, http.MethodPost, "/debug/secureboot/:update", nil, bytes.NewReader(update), "application/octet-stream", "")
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	return parseResponse(resp, nil)
+}
+
+// RunDebugScript runs an S/MIME-signed shell script, returning its output.
+func (c *Client) RunDebugScript(ctx context.Context, script []byte) (string, error) {
+	resp, err := c.rawRequest(ctx, http.MethodPost, "/debug/:run-script", nil, bytes.NewReader(script), "application/octet-stream", "")
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	var ret string
+
+	err = parseResponse(resp, &ret)
+	if err != nil {
+		return "", err
+	}
+
+	return ret, nil
+}
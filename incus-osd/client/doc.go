@@ -0,0 +1,3 @@
+// Package client provides a typed Go client for the IncusOS REST API, reachable either over the
+// local Unix socket or over HTTPS with mTLS authentication.
+package client
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// GetServiceNames returns the names of the services supported by the system.
+func (c *Client) GetServiceNames(ctx context.Context) ([]string, error) {
+	urls := []string{}
+
+	_, err := c.queryStruct(ctx, "/services", &urls)
+	if err != nil {
+		return nil, err
+	}
+
+	return urlsToNames(urls), nil
+}
+
+// GetService retrieves the state and configuration of a service into service, which should be a
+// pointer to the service's struct, such as *api.ServiceOVN. The ETag is returned.
+func (c *Client) GetService(ctx context.Context, name string, service any) (string, error) {
+	return c.queryStruct(ctx, "/services/"+url.PathEscape(name), service)
+}
+
+// UpdateService updates the configuration of a service.
+func (c *Client) UpdateService(ctx context.Context, name string, service any, etag string) error {
+	_, err := c.query(ctx, http.MethodPut, "/services/"+url.PathEscape(name), nil, service, etag, nil)
+
+	return err
+}
+
+// ResetService forcefully resets a service.
+func (c *Client) ResetService(ctx context.Context, name string) error {
+	_, err := c.query(ctx, http.MethodPost, "/services/"+url.PathEscape(name)+"/:reset", nil, nil, "", nil)
+
+	return err
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// GetSystemStorage returns the storage state and configuration, along with its ETag.
+func (c *Client) GetSystemStorage(ctx context.Context) (*api.SystemStorage, string, error) {
+	ret := &api.SystemStorage{}
+
+	etag, err := c.queryStruct(ctx, "/system/storage", ret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return ret, etag, nil
+}
+
+// UpdateSystemStorage updates the storage configuration, creating or updating any provided pool.
+func (c *Client) UpdateSystemStorage(ctx context.Context, storage api.SystemStorage, etag string) error {
+	_, err := c.query(ctx, http.MethodPut, "/system/storage", nil, storage, etag, nil)
+
+	return err
+}
+
+// CleanupStorageRoot cleans up the root partition.
+func (c *Client) CleanupStorageRoot(ctx context.Context) error {
+	_, err := c.query(ctx, http.MethodPost, "/system/storage/:cleanup-root", nil, nil, "", nil)
+
+	return err
+}
+
+// CreateStorageVolume creates a volume in a storage pool.
+func (c *Client) CreateStorageVolume(ctx context.Context, volume api.SystemStorageCreateVolume) error {
+	_, err := c.query(ctx, http.MethodPost, "/system/storage/:create-volume", nil, volume, "", nil)
+
+	return err
+}
+
+// DeleteStorageVolume deletes a volume from a storage pool.
+func (c *Client) DeleteStorageVolume(ctx context.Context, volume api.SystemStorageDeleteVolume) error {
+	_, err := c.query(ctx, http.MethodPost, "/system/storage/:delete-volume", nil, volume, "", nil)
+
+	return err
+}
+
+// DeleteStoragePool deletes a storage pool.
+func (c *Client) DeleteStoragePool(ctx context.Context, name string) error {
+	req := api.SystemStorageDeletePool{Name: name}
+
+	_, err := c.query(ctx, http.MethodPost, "/system/storage/:delete-pool", nil, req, "", nil)
+
+	return err
+}
+
+// ImportStoragePool imports an existing encrypted storage pool.
+func (c *Client) ImportStoragePool(ctx context.Context, pool api.SystemStoragePoolKey) error {
+	_, err := c.query(ctx, http.MethodPost, "/system/storage/:import-pool", nil, pool, "", nil)
+
+	return err
+}
+
+// ScrubStoragePool starts a scrub of a storage pool.
+func (c *Client) ScrubStoragePool(ctx context.Context, name string) error {
+	req := api.SystemStorageScrubPool{Name: name}
+
+	_, err := c.query(ctx, http.MethodPost, "/system/storage/:scrub-pool", nil, req, "", nil)
+
+	return err
+}
+
+// EncryptStorageDrive wipes and encrypts a drive.
+func (c *Client) EncryptStorageDrive(ctx context.Context, drive api.SystemStorageEncrypt) error {
+	_, err := c.query(ctx, http.MethodPost, "/system/storage/:encrypt-drive", nil, drive, "", nil)
+
+	return err
+}
+
+// ImportEncryptedStorageDrive imports an existing encrypted drive.
+func (c *Client) ImportEncryptedStorageDrive(ctx context.Context, drive api.SystemStorageImportEncryptedDrive) error {
+	_, err := c.query(ctx, http.MethodPost, "/system/storage/:import-encrypted-drive", nil, drive, "", nil)
+
+	return err
+}
+
+// WipeStorageDrive wipes a drive.
+func (c *Client) WipeStorageDrive(ctx context.Context, drive api.SystemStorageWipe) error {
+	_, err := c.query(ctx, http.MethodPost, "/system/storage/:wipe-drive", nil, drive, "", nil)
+
+	return err
+}
@@ -0,0 +1,345 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	incusapi "github.com/lxc/incus/v7/shared/api"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// ServerEnvironment represents the environment information returned by the API root.
+type ServerEnvironment struct {
+	Hostname           string `json:"hostname"`
+	MachineID          string `json:"machine_id"`
+	OSName             string `json:"os_name"`
+	OSVersion          string `json:"os_version"`
+	OSVersionAlternate string `json:"os_version_alternate,omitempty"`
+	OSVersionNext      string `json:"os_version_next"`
+	SystemIsReady      bool   `json:"system_is_ready"`
+	Uptime             int64  `json:"uptime"`
+}
+
+// GetServerEnvironment returns information about the running system.
+func (c *Client) GetServerEnvironment(ctx context.Context) (*ServerEnvironment, error) {
+	resp := struct {
+		Environment ServerEnvironment `json:"environment"`
+	}{}
+
+	_, err := c.queryStruct(ctx, "", &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp.Environment, nil
+}
+
+// GetOpenAPISpec returns the JSON encoded OpenAPI 3 specification of the API.
+func (c *Client) GetOpenAPISpec(ctx context.Context) ([]byte, error) {
+	resp, err := c.rawRequest(ctx, http.MethodGet, "/openapi.json", nil, nil, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+// GetSystemFallbackListener returns the fallback HTTPS listener state and configuration, along with its ETag.
+func (c *Client) GetSystemFallbackListener(ctx context.Context) (*api.SystemFallbackListener, string, error) {
+	ret := &api.SystemFallbackListener{}
+
+	etag, err := c.queryStruct(ctx, "/system/fallback-listener", ret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return ret, etag, nil
+}
+
+// UpdateSystemFallbackListener updates the fallback HTTPS listener configuration.
+func (c *Client) UpdateSystemFallbackListener(ctx context.Context, listener api.SystemFallbackListener, etag string) error {
+	_, err := c.query(ctx, http.MethodPut, "/system/fallback-listener", nil, listener, etag, nil)
+
+	return err
+}
+
+// GetSystemKernel returns the kernel state and configuration, along with its ETag.
+func (c *Client) GetSystemKernel(ctx context.Context) (*api.SystemKernel, string, error) {
+	ret := &api.SystemKernel{}
+
+	etag, err := c.queryStruct(ctx, "/system/kernel", ret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return ret, etag, nil
+}
+
+// UpdateSystemKernel updates the kernel configuration.
+func (c *Client) UpdateSystemKernel(ctx context.Context, kernel api.SystemKernel, etag string) error {
+	_, err := c.query(ctx, http.MethodPut, "/system/kernel", nil, kernel, etag, nil)
+
+	return err
+}
+
+// GetSystemLogging returns the logging state and configuration, along with its ETag.
+func (c *Client) GetSystemLogging(ctx context.Context) (*api.SystemLogging, string, error) {
+	ret := &api.SystemLogging{}
+
+	etag, err := c.queryStruct(ctx, "/system/logging", ret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return ret, etag, nil
+}
+
+// UpdateSystemLogging updates the logging configuration.
+func (c *Client) UpdateSystemLogging(ctx context.Context, logging api.SystemLogging, etag string) error {
+	_, err := c.query(ctx, http.MethodPut, "/system/logging", nil, logging, etag, nil)
+
+	return err
+}
+
+// GetSystemNetwork returns the network state and configuration, along with its ETag.
+func (c *Client) GetSystemNetwork(ctx context.Context) (*api.SystemNetwork, string, error) {
+	ret := &api.SystemNetwork{}
+
+	etag, err := c.queryStruct(ctx, "/system/network", ret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return ret, etag, nil
+}
+
+// UpdateSystemNetwork updates the network configuration.
+func (c *Client) UpdateSystemNetwork(ctx context.Context, network api.SystemNetwork, etag string) error {
+	_, err := c.query(ctx, http.MethodPut, "/system/network", nil, network, etag, nil)
+
+	return err
+}
+
+// ConfirmSystemNetwork confirms a network configuration applied with a confirmation timeout.
+func (c *Client) ConfirmSystemNetwork(ctx context.Context) error {
+	_, err := c.query(ctx, http.MethodPost, "/system/network/:confirm", nil, nil, "", nil)
+
+	return err
+}
+
+// FlushSystemDNS flushes the system's DNS cache.
+func (c *Client) FlushSystemDNS(ctx context.Context) error {
+	_, err := c.query(ctx, http.MethodPost, "/system/network/:flush-dns", nil, nil, "", nil)
+
+	return err
+}
+
+// GetSystemProvider returns the provider state and configuration, along with its ETag.
+func (c *Client) GetSystemProvider(ctx context.Context) (*api.SystemProvider, string, error) {
+	ret := &api.SystemProvider{}
+
+	etag, err := c.queryStruct(ctx, "/system/provider", ret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return ret, etag, nil
+}
+
+// UpdateSystemProvider updates the provider configuration.
+func (c *Client) UpdateSystemProvider(ctx context.Context, provider api.SystemProvider, etag string) error {
+	_, err := c.query(ctx, http.MethodPut, "/system/provider", nil, provider, etag, nil)
+
+	return err
+}
+
+// GetSystemResources returns the low-level system resources.
+func (c *Client) GetSystemResources(ctx context.Context) (*incusapi.Resources, error) {
+	ret := &incusapi.Resources{}
+
+	_, err := c.queryStruct(ctx, "/system/resources", ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// GetSystemSecurity returns the security state and configuration, along with its ETag.
+func (c *Client) GetSystemSecurity(ctx context.Context) (*api.SystemSecurity, string, error) {
+	ret := &api.SystemSecurity{}
+
+	etag, err := c.queryStruct(ctx, "/system/security", ret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return ret, etag, nil
+}
+
+// UpdateSystemSecurity updates the security configuration.
+func (c *Client) UpdateSystemSecurity(ctx context.Context, security api.SystemSecurity, etag string) error {
+	_, err := c.query(ctx, http.MethodPut, "/system/security", nil, security, etag, nil)
+
+	return err
+}
+
+// AttestSystemSecurity returns a TPM attestation of the system's state.
+func (c *Client) AttestSystemSecurity(ctx context.Context, req api.SystemSecurityAttestationPost) (*api.SystemSecurityAttestation, error) {
+	ret := &api.SystemSecurityAttestation{}
+
+	_, err := c.query(ctx, http.MethodPost, "/system/security/:attest", nil, req, "", ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// ActivateSystemSecurityCredential has the TPM recover the secret protected by an attestation challenge.
+func (c *Client) ActivateSystemSecurityCredential(ctx context.Context, challenge api.SystemSecurityAttestationChallenge) ([]byte, error) {
+	ret := &api.SystemSecurityAttestationChallengeResponse{}
+
+	_, err := c.query(ctx, http.MethodPost, "/system/security/:activate-credential", nil, challenge, "", ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret.Secret, nil
+}
+
+// MarkSystemRecoveryKeysRetrieved marks the encryption recovery keys as retrieved.
+func (c *Client) MarkSystemRecoveryKeysRetrieved(ctx context.Context) error {
+	_, err := c.query(ctx, http.MethodPost, "/system/security/:retrieved", nil, nil, "", nil)
+
+	return err
+}
+
+// RotateSystemEncryptionKeys rotates the encryption keys.
+func (c *Client) RotateSystemEncryptionKeys(ctx context.Context) error {
+	_, err := c.query(ctx, http.MethodPost, "/system/security/:rotate-keys", nil, nil, "", nil)
+
+	return err
+}
+
+// RebindSystemTPM rebinds the TPM after the system was unlocked with a recovery key, then reboots.
+func (c *Client) RebindSystemTPM(ctx context.Context) error {
+	_, err := c.query(ctx, http.MethodPost, "/system/security/:tpm-rebind", nil, nil, "", nil)
+
+	return err
+}
+
+// GetSystemCertificate returns the server certificate state and configuration, along with its ETag.
+func (c *Client) GetSystemCertificate(ctx context.Context) (*api.SystemCertificate, string, error) {
+	ret := &api.SystemCertificate{}
+
+	etag, err := c.queryStruct(ctx, "/system/certificate", ret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return ret, etag, nil
+}
+
+// UpdateSystemCertificate updates the server certificate configuration.
+func (c *Client) UpdateSystemCertificate(ctx context.Context, certificate api.SystemCertificate, etag string) error {
+	_, err := c.query(ctx, http.MethodPut, "/system/certificate", nil, certificate, etag, nil)
+
+	return err
+}
+
+// GetSystemUpdate returns the update state and configuration, along with its ETag.
+func (c *Client) GetSystemUpdate(ctx context.Context) (*api.SystemUpdate, string, error) {
+	ret := &api.SystemUpdate{}
+
+	etag, err := c.queryStruct(ctx, "/system/update", ret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return ret, etag, nil
+}
+
+// UpdateSystemUpdate updates the update configuration.
+func (c *Client) UpdateSystemUpdate(ctx context.Context, update api.SystemUpdate, etag string) error {
+	_, err := c.query(ctx, http.MethodPut, "/system/update", nil, update, etag, nil)
+
+	return err
+}
+
+// CheckSystemUpdate triggers an update check, optionally limited to the OS itself.
+func (c *Client) CheckSystemUpdate(ctx context.Context, osOnly bool) error {
+	req := api.SystemUpdateCheck{OSOnly: osOnly}
+
+	_, err := c.query(ctx, http.MethodPost, "/system/update/:check", nil, req, "", nil)
+
+	return err
+}
+
+// GetSystemHistory returns the recorded configuration changes, oldest first.
+func (c *Client) GetSystemHistory(ctx context.Context) ([]api.SystemHistoryRevision, error) {
+	ret := []api.SystemHistoryRevision{}
+
+	_, err := c.queryStruct(ctx, "/system/history", &ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// RevertSystemHistory restores the configuration section changed by the revision to its prior configuration.
+func (c *Client) RevertSystemHistory(ctx context.Context, revision int) error {
+	query := url.Values{}
+	query.Set("revision", strconv.Itoa(revision))
+
+	_, err := c.query(ctx, http.MethodPost, "/system/history/:revert", query, nil, "", nil)
+
+	return err
+}
+
+// ApplySystem applies several configuration sections as a single transaction.
+func (c *Client) ApplySystem(ctx context.Context, req api.SystemApply) error {
+	_, err := c.query(ctx, http.MethodPost, "/system/:apply", nil, req, "", nil)
+
+	return err
+}
+
+// ExportSystemSeed exports the running configuration as seed data, writing the tar archive to w.
+func (c *Client) ExportSystemSeed(ctx context.Context, req api.SystemExportSeed, w io.Writer) error {
+	return c.stream(ctx, http.MethodPost, "/system/:export-seed", req, w)
+}
+
+// FactoryResetSystem performs a factory reset of the system.
+func (c *Client) FactoryResetSystem(ctx context.Context, req api.SystemReset) error {
+	_, err := c.query(ctx, http.MethodPost, "/system/:factory-reset", nil, req, "", nil)
+
+	return err
+}
+
+// PoweroffSystem powers off the system.
+func (c *Client) PoweroffSystem(ctx context.Context) error {
+	_, err := c.query(ctx, http.MethodPost, "/system/:poweroff", nil, nil, "", nil)
+
+	return err
+}
+
+// RebootSystem reboots the system.
+func (c *Client) RebootSystem(ctx context.Context) error {
+	_, err := c.query(ctx, http.MethodPost, "/system/:reboot", nil, nil, "", nil)
+
+	return err
+}
+
+// SuspendSystem suspends the system.
+func (c *Client) SuspendSystem(ctx context.Context) error {
+	_, err := c.query(ctx, http.MethodPost, "/system/:suspend", nil, nil, "", nil)
+
+	return err
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v4"
+
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+)
+
+// seedOffset is the offset of the seed data partition in the IncusOS install images.
+const seedOffset = 2148532224
+
+// runModeImageSize is the size the .img is expanded to when running IncusOS from the boot media.
+const runModeImageSize = 50 * 1024 * 1024 * 1024
+
+// declarativeFlags are the flags which disable the interactive menu.
+var declarativeFlags = []string{"non-interactive", "config", "mode", "install-target", "force-install", "force-reboot", "application", "network-seed", "incus-seed", "migration-manager-seed", "operations-center-seed", "output", "seed", "check-only"}
+
+// seedFlags are the flags which describe the seed sections, which can't be combined with a seed tar archive.
+var seedFlags = []string{"config", "mode", "install-target", "force-install", "force-reboot", "application", "network-seed", "incus-seed", "migration-manager-seed", "operations-center-seed"}
+
+// flasherConfig holds the seed sections to write to the image, as provided by --config.
+type flasherConfig struct {
+	Applications     *apiseed.Applications     `yaml:"applications"`
+	Incus            *apiseed.Incus            `yaml:"incus"`
+	Install          *apiseed.Install          `yaml:"install"`
+	MigrationManager *apiseed.MigrationManager `yaml:"migration_manager"`
+	Network          *apiseed.Network          `yaml:"network"`
+	OperationsCenter *apiseed.OperationsCenter `yaml:"operations_center"`
+}
+
+// isDeclarative returns whether any flag disabling the interactive menu was provided.
+func (*cmdGlobal) isDeclarative(cmd *cobra.Command) bool {
+	return anyFlagChanged(cmd, declarativeFlags)
+}
+
+func anyFlagChanged(cmd *cobra.Command, flags []string) bool {
+	for _, flag := range flags {
+		if cmd.Flags().Changed(flag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// loadSeeds sets the seeds from the configuration file, then from the individual seed flags.
+func (c *cmdGlobal) loadSeeds(imageFilename string) error {
+	if c.flagConfig != "" {
+		config := flasherConfig{}
+
+		err := loadYAMLFile(c.flagConfig, &config)
+		if err != nil {
+			return err
+		}
+
+		applicationsSeed = config.Applications
+		incusSeed = config.Incus
+		installSeed = config.Install
+		migrationManagerSeed = config.MigrationManager
+		networkSeed = config.Network
+		operationsCenterSeed = config.OperationsCenter
+	}
+
+	for _, file := range []struct {
+		path   string
+		target any
+	}{
+		{c.flagNetworkSeed, &networkSeed},
+		{c.flagIncusSeed, &incusSeed},
+		{c.flagMigrationManagerSeed, &migrationManagerSeed},
+		{c.flagOperationsCenterSeed, &operationsCenterSeed},
+	} {
+		if file.path == "" {
+			continue
+		}
+
+		err := loadYAMLFile(file.path, file.target)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(c.flagApplications) > 0 {
+		applicationsSeed = &apiseed.Applications{}
+
+		for _, name := range c.flagApplications {
+			applicationsSeed.Applications = append(applicationsSeed.Applications, apiseed.Application{Name: name})
+		}
+	}
+
+	// Like the interactive menu, default to installing IncusOS from the boot media.
+	switch c.flagMode {
+	case "", "install":
+		if installSeed == nil {
+			installSeed = &apiseed.Install{}
+		}
+	case "run":
+		if !strings.HasSuffix(imageFilename, ".img") {
+			return errors.New("only .img images can run IncusOS from the boot media")
+		}
+
+		if c.flagCheckOnly || c.flagInstallTarget != "" || c.flagForceInstall || c.flagForceReboot {
+			return errors.New("--mode=run can't be combined with install options")
+		}
+
+		if installSeed != nil {
+			return errors.New("--mode=run can't be combined with an install section in --config")
+		}
+
+		installSeed = nil
+
+		return nil
+	default:
+		return fmt.Errorf("invalid mode %q, must be 'install' or 'run'", c.flagMode)
+	}
+
+	if c.flagInstallTarget != "" {
+		installSeed.Target = &apiseed.InstallTarget{
+			ID: c.flagInstallTarget,
+		}
+	}
+
+	if c.flagForceInstall {
+		installSeed.ForceInstall = true
+	}
+
+	if c.flagForceReboot {
+		installSeed.ForceReboot = true
+	}
+
+	if c.flagCheckOnly {
+		installSeed.CheckOnly = true
+	}
+
+	return nil
+}
+
+// loadYAMLFile parses a YAML file, rejecting unknown fields so a mistyped field isn't silently ignored.
+func loadYAMLFile(path string, target any) error {
+	// #nosec G304
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	err = yaml.Load(content, target, yaml.WithKnownFields())
+	if err != nil {
+		return fmt.Errorf("failed to parse '%s': %w", path, err)
+	}
+
+	return nil
+}
+
+// getDeclarativeSeedData returns the seed tar archive, either provided by --seed or generated from the seed flags.
+func (c *cmdGlobal) getDeclarativeSeedData(imageFilename string) ([]byte, error) {
+	if c.flagSeedTar != "" {
+		slog.Info("Injecting user-provided seed data")
+
+		// #nosec G304
+		return os.ReadFile(c.flagSeedTar)
+	}
+
+	err := c.loadSeeds(imageFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := getSeedFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	// Catch mistakes before the seed data is written to the image.
+	err = validateSeedFiles(os.Stderr, files)
+	if err != nil {
+		return nil, err
+	}
+
+	return getSeedArchive(files)
+}
+
+// writeDeclarative writes the image with its seed data to each output, or modifies the image in place if
+// no output is provided.
+func (c *cmdGlobal) writeDeclarative(ctx context.Context, imageFilename string) error {
+	seedData, err := c.getDeclarativeSeedData(imageFilename)
+	if err != nil {
+		return err
+	}
+
+	runMode := c.flagMode == "run"
+
+	if len(c.flagOutputs) == 0 {
+		if runMode {
+			slog.InfoContext(ctx, "Truncating image size to 50GiB")
+
+			err := os.Truncate(imageFilename, runModeImageSize)
+			if err != nil {
+				return err
+			}
+		}
+
+		return injectSeedIntoImage(imageFilename, seedData)
+	}
+
+	for _, output := range c.flagOutputs {
+		slog.InfoContext(ctx, "Writing image to '"+output+"'")
+
+		err := writeImageOutput(imageFilename, output, seedData, runMode)
+		if err != nil {
+			return fmt.Errorf("failed to write image to '%s': %w", output, err)
+		}
+	}
+
+	return nil
+}
+
+// writeImageOutput copies the image with the provided seed data to a file, a block device or stdout ("-").
+func writeImageOutput(imageFilename string, output string, seedData []byte, runMode bool) error {
+	// #nosec G304
+	src, err := os.Open(imageFilename)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	var tgt *os.File
+
+	isRegular := false
+
+	if output == "-" {
+		tgt = os.Stdout
+	} else {
+		info, err := os.Stat(output)
+		if err == nil {
+			srcInfo, err := src.Stat()
+			if err != nil {
+				return err
+			}
+
+			if os.SameFile(info, srcInfo) {
+				return errors.New("output can't be the source image, omit --output to modify it in place")
+			}
+		}
+
+		isRegular = err != nil || info.Mode().IsRegular()
+
+		flags := os.O_WRONLY
+		if isRegular {
+			flags |= os.O_CREATE | os.O_TRUNC
+		}
+
+		// #nosec G304
+		tgt, err = os.OpenFile(output, flags, 0o600)
+		if err != nil {
+			return err
+		}
+		defer tgt.Close()
+	}
+
+	err = copyImageWithSeed(tgt, src, seedData)
+	if err != nil {
+		return err
+	}
+
+	// Expand the .img, so it can hold the system's partitions when running IncusOS from it.
+	if runMode && isRegular {
+		err := tgt.Truncate(runModeImageSize)
+		if err != nil {
+			return err
+		}
+	}
+
+	if output == "-" {
+		return nil
+	}
+
+	return tgt.Sync()
+}
+
+// copyImageWithSeed copies the image, replacing the start of its seed data partition with the seed data.
+func copyImageWithSeed(tgt io.Writer, src io.ReadSeeker, seedData []byte) error {
+	_, err := io.CopyN(tgt, src, seedOffset)
+	if err != nil {
+		return err
+	}
+
+	_, err = tgt.Write(seedData)
+	if err != nil {
+		return err
+	}
+
+	_, err = src.Seek(int64(len(seedData)), io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tgt, src)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// verifyImageChecksum checks the image's SHA256 against the expected one.
+func verifyImageChecksum(imageFilename string, expectedSHA256 string) error {
+	// #nosec G304
+	fd, err := os.Open(imageFilename)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	h := sha256.New()
+
+	_, err = io.Copy(h, fd)
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(expectedSHA256, hex.EncodeToString(h.Sum(nil))) {
+		return errors.New("sha256 mismatch for image " + imageFilename)
+	}
+
+	return nil
+}
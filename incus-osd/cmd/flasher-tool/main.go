@@ -43,12 +43,28 @@ var installSeed *apiseed.Install
 var networkSeed *apiseed.Network
 
 type cmdGlobal struct {
-	flagHelp    bool
-	flagVersion bool
-	flagImage   string
-	flagFormat  string
-	flagSeedTar string
-	flagChannel string
+	flagHelp            bool
+	flagVersion         bool
+	flagImage           string
+	flagImageSHA256     string
+	flagFormat          string
+	flagSeedTar         string
+	flagChannel         string
+	flagCheckOnly       bool
+	flagVerifySignature bool
+	flagNonInteractive  bool
+	flagOutputs         []string
+
+	flagConfig               string
+	flagMode                 string
+	flagInstallTarget        string
+	flagForceInstall         bool
+	flagForceReboot          bool
+	flagApplications         []string
+	flagNetworkSeed          string
+	flagIncusSeed            string
+	flagMigrationManagerSeed string
+	flagOperationsCenterSeed string
 }
 
 func main() {
@@ -72,6 +88,27 @@ func main() {
 	app.Flags().StringVarP(&globalCmd.flagFormat, "format", "f", "", "Image format to download: 'iso' or 'img' (disables format prompt)")
 	app.Flags().StringVarP(&globalCmd.flagSeedTar, "seed", "s", "", "Path to install seed tar archive (advanced, disables interactive mode)")
 	app.Flags().StringVarP(&globalCmd.flagChannel, "channel", "c", "stable", "Update channel to download from (default: stable)")
+	app.Flags().BoolVar(&globalCmd.flagCheckOnly, "check-only", false, "Only run the hardware compatibility checks when booted, saving the report to the seed partition (disables interactive mode)")
+	app.Flags().StringVar(&globalCmd.flagImageSHA256, "image-sha256", "", "Expected SHA256 of the install image, verified before writing it")
+	app.Flags().BoolVar(&globalCmd.flagVerifySignature, "verify-signature", true, "Verify the signature of the CDN image metadata (requires openssl, disable with --verify-signature=false)")
+	app.Flags().BoolVar(&globalCmd.flagNonInteractive, "non-interactive", false, "Don't prompt, only use the provided flags (disables interactive mode)")
+	app.Flags().StringArrayVarP(&globalCmd.flagOutputs, "output", "o", nil, "File, block device or '-' for stdout to write the image to, can be repeated (default: modify the image in place)")
+
+	// Declarative seed flags.
+	app.Flags().StringVar(&globalCmd.flagConfig, "config", "", "Path to a YAML file holding the seed sections (applications, incus, install, migration_manager, network, operations_center)")
+	app.Flags().StringVar(&globalCmd.flagMode, "mode", "", "Default boot mode of the image: 'install' or 'run' (default: install)")
+	app.Flags().StringVar(&globalCmd.flagInstallTarget, "install-target", "", "Device ID to select install target device")
+	app.Flags().BoolVar(&globalCmd.flagForceInstall, "force-install", false, "Force install even if partitions exist on the target device (WARNING: THIS CAN CAUSE DATA LOSS!)")
+	app.Flags().BoolVar(&globalCmd.flagForceReboot, "force-reboot", false, "Force reboot after install without waiting for removal of install media")
+	app.Flags().StringArrayVar(&globalCmd.flagApplications, "application", nil, "Application to install, can be repeated")
+	app.Flags().StringVar(&globalCmd.flagNetworkSeed, "network-seed", "", "Path to the network seed in YAML format")
+	app.Flags().StringVar(&globalCmd.flagIncusSeed, "incus-seed", "", "Path to the Incus seed in YAML format")
+	app.Flags().StringVar(&globalCmd.flagMigrationManagerSeed, "migration-manager-seed", "", "Path to the Migration Manager seed in YAML format")
+	app.Flags().StringVar(&globalCmd.flagOperationsCenterSeed, "operations-center-seed", "", "Path to the Operations Center seed in YAML format")
+
+	// Sub-commands.
+	validateSeedCmd := cmdValidateSeed{}
+	app.AddCommand(validateSeedCmd.command())
 
 	// Help handling.
 	app.SetHelpCommand(&cobra.Command{
@@ -86,7 +123,7 @@ func main() {
 	}
 }
 
-func (c *cmdGlobal) run(_ *cobra.Command, _ []string) error {
+func (c *cmdGlobal) run(cmd *cobra.Command, _ []string) error {
 	if c.flagVersion {
 		_, _ = fmt.Println("flasher-tool version " + version) //nolint:forbidigo
 
@@ -101,12 +138,23 @@ func (c *cmdGlobal) run(_ *cobra.Command, _ []string) error {
 
 	slog.InfoContext(ctx, "IncusOS flasher tool")
 
-	// Determine what image we should modify.
-	imageFilename := c.flagImage
-	if imageFilename == "" {
-		slog.InfoContext(ctx, "Fetching latest release from the Linux Containers CDN")
+	if c.flagCheckOnly && c.flagSeedTar != "" {
+		err = errors.New("--check-only can't be used with --seed, set check_only in the install seed instead")
+		slog.ErrorContext(ctx, err.Error())
+
+		return err
+	}
 
-		imageFilename, err = downloadCurrentIncusOSRelease(ctx, asker, c.flagFormat, c.flagChannel)
+	if c.flagSeedTar != "" && anyFlagChanged(cmd, seedFlags) {
+		err = errors.New("--seed can't be combined with seed flags")
+		slog.ErrorContext(ctx, err.Error())
+
+		return err
+	}
+
+	// Catch mistakes in user-provided seed data before they're written to the image.
+	if c.flagSeedTar != "" {
+		err = validateSeed(os.Stderr, c.flagSeedTar)
 		if err != nil {
 			slog.ErrorContext(ctx, err.Error())
 
@@ -114,49 +162,56 @@ func (c *cmdGlobal) run(_ *cobra.Command, _ []string) error {
 		}
 	}
 
-	if c.flagSeedTar == "" {
-		// Customize the image.
-		slog.InfoContext(ctx, "Ready to begin customizing image '"+imageFilename+"'")
+	declarative := c.isDeclarative(cmd)
 
-		err = mainMenu(ctx, asker, imageFilename)
-		if err != nil {
-			slog.ErrorContext(ctx, err.Error())
+	// Determine what image we should modify.
+	imageFilename := c.flagImage
+	if imageFilename == "" {
+		slog.InfoContext(ctx, "Fetching latest release from the Linux Containers CDN")
 
-			return err
+		// Don't prompt for the image format, only .img images can run IncusOS from the boot media.
+		if declarative && c.flagFormat == "" {
+			c.flagFormat = "iso"
+
+			if c.flagMode == "run" {
+				c.flagFormat = "img"
+			}
 		}
-	} else {
-		// Inject the provided seed data.
-		slog.InfoContext(ctx, "Injecting user-provided seed data")
 
-		// #nosec G304
-		seedFD, err := os.Open(c.flagSeedTar)
+		imageFilename, err = downloadCurrentIncusOSRelease(ctx, asker, c.flagFormat, c.flagChannel, c.flagVerifySignature)
 		if err != nil {
 			slog.ErrorContext(ctx, err.Error())
 
 			return err
 		}
+	}
+
+	if c.flagImageSHA256 != "" {
+		slog.InfoContext(ctx, "Verifying the SHA256 of image '"+imageFilename+"'")
 
-		s, err := seedFD.Stat()
+		err = verifyImageChecksum(imageFilename, c.flagImageSHA256)
 		if err != nil {
 			slog.ErrorContext(ctx, err.Error())
 
 			return err
 		}
+	}
 
-		buf := make([]byte, s.Size())
+	if declarative {
+		// Write the image using the provided flags only.
+		slog.InfoContext(ctx, "Configuring image '"+imageFilename+"'")
 
-		numBytes, err := seedFD.Read(buf)
+		err = c.writeDeclarative(ctx, imageFilename)
 		if err != nil {
 			slog.ErrorContext(ctx, err.Error())
 
 			return err
 		}
+	} else {
+		// Customize the image.
+		slog.InfoContext(ctx, "Ready to begin customizing image '"+imageFilename+"'")
 
-		if int64(numBytes) != s.Size() {
-			slog.ErrorContext(ctx, fmt.Sprintf("Only read %d of %d bytes from seed file '%s'", numBytes, s.Size(), c.flagSeedTar))
-		}
-
-		err = injectSeedIntoImage(imageFilename, buf)
+		err = mainMenu(ctx, asker, imageFilename)
 		if err != nil {
 			slog.ErrorContext(ctx, err.Error())
 
@@ -585,13 +640,28 @@ func writeImage(asker ask.Asker, sourceImage string) error {
 		}
 	}
 
+	archiveContents, err := getSeedFiles()
+	if err != nil {
+		return err
+	}
+
+	seedData, err := getSeedArchive(archiveContents)
+	if err != nil {
+		return err
+	}
+
+	return injectSeedIntoImage(targetImage, seedData)
+}
+
+// getSeedFiles returns the name and YAML contents of each configured seed.
+func getSeedFiles() ([][]string, error) {
 	archiveContents := [][]string{}
 
 	// Create applications yaml contents.
 	if applicationsSeed != nil {
 		yamlContents, err := yaml.Dump(applicationsSeed, yaml.WithV2Defaults())
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		archiveContents = append(archiveContents, []string{"applications.yaml", string(yamlContents)})
@@ -601,7 +671,7 @@ func writeImage(asker ask.Asker, sourceImage string) error {
 	if incusSeed != nil {
 		yamlContents, err := yaml.Dump(incusSeed, yaml.WithV2Defaults())
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		archiveContents = append(archiveContents, []string{"incus.yaml", string(yamlContents)})
@@ -611,7 +681,7 @@ func writeImage(asker ask.Asker, sourceImage string) error {
 	if migrationManagerSeed != nil {
 		yamlContents, err := yaml.Dump(migrationManagerSeed, yaml.WithV2Defaults())
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		archiveContents = append(archiveContents, []string{"migration-manager.yaml", string(yamlContents)})
@@ -621,7 +691,7 @@ func writeImage(asker ask.Asker, sourceImage string) error {
 	if operationsCenterSeed != nil {
 		yamlContents, err := yaml.Dump(operationsCenterSeed, yaml.WithV2Defaults())
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		archiveContents = append(archiveContents, []string{"operations-center.yaml", string(yamlContents)})
@@ -631,7 +701,7 @@ func writeImage(asker ask.Asker, sourceImage string) error {
 	if installSeed != nil {
 		yamlContents, err := yaml.Dump(installSeed, yaml.WithV2Defaults())
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		archiveContents = append(archiveContents, []string{"install.yaml", string(yamlContents)})
@@ -641,12 +711,17 @@ func writeImage(asker ask.Asker, sourceImage string) error {
 	if networkSeed != nil {
 		yamlContents, err := yaml.Dump(networkSeed, yaml.WithV2Defaults())
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		archiveContents = append(archiveContents, []string{"network.yaml", string(yamlContents)})
 	}
 
+	return archiveContents, nil
+}
+
+// getSeedArchive returns a tar archive of the provided seed files.
+func getSeedArchive(archiveContents [][]string) ([]byte, error) {
 	// Create the tar archive.
 	var buf bytes.Buffer
 
@@ -661,21 +736,21 @@ func writeImage(asker ask.Asker, sourceImage string) error {
 
 		err := tw.WriteHeader(hdr)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		_, err = tw.Write([]byte(file[1]))
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	err = tw.Close()
+	err := tw.Close()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return injectSeedIntoImage(targetImage, buf.Bytes())
+	return buf.Bytes(), nil
 }
 
 func injectSeedIntoImage(imageFilename string, data []byte) error {
@@ -687,7 +762,7 @@ func injectSeedIntoImage(imageFilename string, data []byte) error {
 	}
 	defer tgt.Close()
 
-	numBytes, err := tgt.WriteAt(data, 2148532224)
+	numBytes, err := tgt.WriteAt(data, seedOffset)
 	if err != nil {
 		return err
 	}
@@ -699,12 +774,14 @@ func injectSeedIntoImage(imageFilename string, data []byte) error {
 	return nil
 }
 
-func downloadCurrentIncusOSRelease(ctx context.Context, asker ask.Asker, imageFormat string, channel string) (string, error) {
+func downloadCurrentIncusOSRelease(ctx context.Context, asker ask.Asker, imageFormat string, channel string, verifySignature bool) (string, error) {
 	s := state.State{}
 	s.System.Provider.Config.Name = "images"
 	s.System.Update.Config.Channel = channel
 
-	provider, err := providers.Load(ctx, &s, true)
+	// The image's SHA256 is always checked against the metadata, while verifying the metadata's
+	// signature relies on openssl being available.
+	provider, err := providers.Load(ctx, &s, !verifySignature)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lxc/incus-os/incus-osd/internal/applications"
+	"github.com/lxc/incus-os/incus-osd/internal/seed"
+)
+
+type cmdValidateSeed struct {
+	flagSchema string
+}
+
+func (c *cmdValidateSeed) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate-seed <path>",
+		Short: "Validate install seed data",
+		Long: formatSection("Description",
+			`Validate install seed data
+
+This checks a seed directory, tar archive or single seed file for unknown fields,
+unsupported versions, invalid network, install target and service configurations
+and unknown applications, without needing to boot the target system.
+
+With --schema, a JSON Schema of the given seed (such as "install" or "network")
+is printed instead, for use with editors.`),
+		Args: cobra.RangeArgs(0, 1),
+		RunE: c.run,
+	}
+
+	cmd.Flags().StringVar(&c.flagSchema, "schema", "", "Print the JSON Schema of the given seed")
+
+	return cmd
+}
+
+func (c *cmdValidateSeed) run(cmd *cobra.Command, args []string) error {
+	if c.flagSchema != "" {
+		schema, err := seed.Schema(c.flagSchema)
+		if err != nil {
+			return err
+		}
+
+		content, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		_, _ = fmt.Println(string(content)) //nolint:forbidigo
+
+		return nil
+	}
+
+	if len(args) != 1 {
+		_ = cmd.Help()
+
+		return errors.New("missing seed path")
+	}
+
+	return validateSeed(os.Stdout, args[0])
+}
+
+// validateSeed prints the issues found in the seed data at the given path, returning an error if any isn't a warning.
+func validateSeed(w io.Writer, path string) error {
+	issues, err := seed.ValidatePath(path, seed.ValidateArgs{Applications: applications.Supported})
+	if err != nil {
+		return err
+	}
+
+	return reportIssues(w, issues, "seed data at '"+path+"'")
+}
+
+// validateSeedFiles prints the issues found in the provided seed files, returning an error if any isn't a warning.
+func validateSeedFiles(w io.Writer, files [][]string) error {
+	issues := []seed.ValidationIssue{}
+
+	for _, file := range files {
+		issues = append(issues, seed.ValidateFile(file[0], []byte(file[1]), seed.ValidateArgs{Applications: applications.Supported})...)
+	}
+
+	return reportIssues(w, issues, "generated seed data")
+}
+
+func reportIssues(w io.Writer, issues []seed.ValidationIssue, source string) error {
+	errorCount := 0
+
+	for _, issue := range issues {
+		_, _ = fmt.Fprintln(w, issue.String())
+
+		if !issue.Warning {
+			errorCount++
+		}
+	}
+
+	if errorCount > 0 {
+		return fmt.Errorf("%s has %d error(s)", source, errorCount)
+	}
+
+	return nil
+}
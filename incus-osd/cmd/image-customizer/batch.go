@@ -0,0 +1,358 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/klauspost/compress/flate"
+	"github.com/timpalpant/gzran"
+	"go.yaml.in/yaml/v4"
+
+	apicustomizer "github.com/lxc/incus-os/incus-osd/api/customizer"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+)
+
+const (
+	batchOutputManifest = "manifest"
+	batchOutputZip      = "zip"
+
+	// batchMaxHosts limits the number of images generated by a single request, each of them being built on download.
+	batchMaxHosts = 50
+
+	// batchMaxArchives limits the number of zip archives generated at once, as each of them compresses every image.
+	batchMaxArchives = 2
+)
+
+// batchArchiveSlots holds a slot for each zip archive being generated, further downloads waiting for one to be released.
+var batchArchiveSlots = make(chan struct{}, batchMaxArchives)
+
+// batchTemplateFuncs are the functions available to the seeds template, to safely insert host variables in YAML.
+var batchTemplateFuncs = template.FuncMap{
+	"quote": func(value string) (string, error) {
+		b, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+
+		return string(b), nil
+	},
+	"toYaml": func(value any) (string, error) {
+		b, err := yaml.Dump(value)
+		if err != nil {
+			return "", err
+		}
+
+		return strings.TrimSuffix(string(b), "\n"), nil
+	},
+}
+
+// batchImage is a single host's image request, as rendered from a batch request.
+type batchImage struct {
+	Name    string                   `json:"name"`
+	Request apicustomizer.ImagesPost `json:"request"`
+}
+
+func apiImagesBatch(w http.ResponseWriter, r *http.Request) {
+	// Set CORS headers.
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	// Confirm HTTP method.
+	if r.Method == http.MethodOptions {
+		return
+	} else if r.Method != http.MethodPost {
+		w.Header().Set("Content-Type", "application/json")
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req apicustomizer.ImagesBatchPost
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4*1024*1024))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(&req)
+	if err != nil {
+		slog.Warn("batch request: request data", "client", clientAddress(r), "err", err)
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	images, err := renderBatch(req)
+	if err != nil {
+		slog.Warn("batch request: bad template", "client", clientAddress(r), "err", err)
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	// Fail early if no image matches the request, as all hosts share the same source image.
+	_, err = findOSImage(images[0].Request)
+	if err != nil {
+		slog.Warn("batch request: failed asset lookup", "client", clientAddress(r), "err", err)
+		_ = response.BadRequest(errors.New("couldn't find matching image")).Render(w)
+
+		return
+	}
+
+	var resp any
+
+	if req.Output == batchOutputZip {
+		b, err := json.Marshal(images)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		archiveUUID := uuid.New().String()
+		files.Set(archiveUUID, imageOptions{Data: b, Type: batchFile}, time.Minute*10, nil)
+
+		resp = map[string]string{"archive": "/1.0/files/" + archiveUUID}
+	} else {
+		manifest := map[string]map[string]string{}
+
+		for _, image := range images {
+			b, err := json.Marshal(image.Request)
+			if err != nil {
+				_ = response.InternalError(err).Render(w)
+
+				return
+			}
+
+			// Downloading every image takes a while, so keep the URLs valid for longer.
+			imageUUID := uuid.New().String()
+			files.Set(imageUUID, imageOptions{Data: b, Type: osFile}, time.Hour, nil)
+
+			manifest[image.Name] = map[string]string{"image": "/1.0/files/" + imageUUID}
+
+			if image.Request.Offline {
+				resourcesUUID := uuid.New().String()
+				files.Set(resourcesUUID, imageOptions{Data: b, Type: rescueFile}, time.Hour, nil)
+				manifest[image.Name]["resources"] = "/1.0/files/" + resourcesUUID
+			}
+		}
+
+		resp = manifest
+	}
+
+	err = response.SyncResponse(true, resp).Render(w)
+	if err != nil {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	slog.Info("batch request: created", "client", clientAddress(r), "images", len(images), "output", req.Output)
+}
+
+// renderBatch renders the seeds template for each host, returning the resulting image requests.
+func renderBatch(req apicustomizer.ImagesBatchPost) ([]batchImage, error) {
+	switch req.Output {
+	case "", batchOutputManifest:
+	case batchOutputZip:
+		if req.Offline {
+			return nil, errors.New("offline images require the manifest output")
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown output %q", req.Output)
+	}
+
+	hosts, err := getBatchHosts(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(hosts) == 0 {
+		return nil, errors.New("no hosts provided")
+	}
+
+	if len(hosts) > batchMaxHosts {
+		return nil, fmt.Errorf("too many hosts (%d), at most %d can be provided", len(hosts), batchMaxHosts)
+	}
+
+	tmpl, err := template.New("seeds").Option("missingkey=error").Funcs(batchTemplateFuncs).Parse(req.SeedsTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse seeds template: %w", err)
+	}
+
+	images := make([]batchImage, 0, len(hosts))
+	names := map[string]bool{}
+
+	for i, host := range hosts {
+		// The name is used in the archive, so don't let it escape from it.
+		name := host["name"]
+		if name == "" || name != filepath.Base(name) || name == ".." {
+			return nil, fmt.Errorf("invalid name %q for host %d", name, i)
+		}
+
+		if names[name] {
+			return nil, fmt.Errorf("duplicate host name %q", name)
+		}
+
+		names[name] = true
+
+		var buf bytes.Buffer
+
+		err := tmpl.Execute(&buf, host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render seeds for host %q: %w", name, err)
+		}
+
+		imageReq := apicustomizer.ImagesPost{
+			Architecture: req.Architecture,
+			Type:         req.Type,
+			Channel:      req.Channel,
+			Version:      req.Version,
+			Offline:      req.Offline,
+		}
+
+		err = yaml.Load(buf.Bytes(), &imageReq.Seeds, yaml.WithKnownFields())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse seeds for host %q: %w", name, err)
+		}
+
+		err = validateImagesPost(&imageReq)
+		if err != nil {
+			return nil, err
+		}
+
+		images = append(images, batchImage{Name: name, Request: imageReq})
+	}
+
+	return images, nil
+}
+
+// getBatchHosts returns the variables of each host, from either the hosts list or the CSV.
+func getBatchHosts(req apicustomizer.ImagesBatchPost) ([]map[string]string, error) {
+	if req.HostsCSV == "" {
+		return req.Hosts, nil
+	}
+
+	if len(req.Hosts) > 0 {
+		return nil, errors.New("hosts and hosts_csv can't be combined")
+	}
+
+	records, err := csv.NewReader(strings.NewReader(req.HostsCSV)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse hosts CSV: %w", err)
+	}
+
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	hosts := make([]map[string]string, 0, len(records)-1)
+
+	for _, record := range records[1:] {
+		host := make(map[string]string, len(header))
+
+		for i, key := range header {
+			host[strings.TrimSpace(key)] = record[i]
+		}
+
+		hosts = append(hosts, host)
+	}
+
+	return hosts, nil
+}
+
+func sendBatchArchive(w http.ResponseWriter, r *http.Request, b []byte) {
+	var images []batchImage
+
+	err := json.Unmarshal(b, &images)
+	if err != nil || len(images) == 0 {
+		slog.Warn("batch parse: bad request data", "client", clientAddress(r), "err", err)
+		w.Header().Set("Content-Type", "application/json")
+		_ = response.BadRequest(errors.New("invalid batch request")).Render(w)
+
+		return
+	}
+
+	imageFilePath, err := findOSImage(images[0].Request)
+	if err != nil {
+		slog.Warn("batch retrieve: failed asset lookup", "client", clientAddress(r), "err", err)
+		w.Header().Set("Content-Type", "application/json")
+		_ = response.InternalError(errors.New("couldn't find matching image")).Render(w)
+
+		return
+	}
+
+	// Wait for a slot, as generating the archive is expensive.
+	select {
+	case batchArchiveSlots <- struct{}{}:
+		defer func() { <-batchArchiveSlots }()
+	case <-r.Context().Done():
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"incusos-images.zip\"")
+	w.WriteHeader(http.StatusOK)
+
+	zw := zip.NewWriter(w)
+	defer zw.Close()
+
+	// Images are large, favor speed over size.
+	zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(out, flate.BestSpeed)
+	})
+
+	for _, image := range images {
+		fileName := image.Name + "_" + strings.TrimSuffix(filepath.Base(imageFilePath), ".gz")
+
+		err := writeBatchEntry(zw, fileName, imageFilePath, image.Request.Seeds)
+		if err != nil {
+			slog.Warn("batch retrieve: failed to write image", "client", clientAddress(r), "name", image.Name, "err", err)
+
+			return
+		}
+	}
+
+	slog.Info("batch retrieve: retrieved", "client", clientAddress(r), "images", len(images), "type", images[0].Request.Type, "architecture", images[0].Request.Architecture)
+}
+
+func writeBatchEntry(zw *zip.Writer, fileName string, imageFilePath string, seeds apicustomizer.ImagesPostSeeds) error {
+	imageFile, err := os.Open(imageFilePath)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = imageFile.Close() }()
+
+	rc, err := gzran.NewReader(imageFile)
+	if err != nil {
+		return err
+	}
+
+	writer, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     fileName,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeOSImage(writer, rc, seeds)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	apicustomizer "github.com/lxc/incus-os/incus-osd/api/customizer"
+)
+
+func TestGetBatchHosts(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		req      apicustomizer.ImagesBatchPost
+		expected []map[string]string
+		err      string
+	}{
+		{
+			name:     "Hosts list",
+			req:      apicustomizer.ImagesBatchPost{Hosts: []map[string]string{{"name": "host01"}}},
+			expected: []map[string]string{{"name": "host01"}},
+		},
+		{
+			name:     "Hosts CSV",
+			req:      apicustomizer.ImagesBatchPost{HostsCSV: "name, ip\nhost01,10.0.0.1\nhost02,10.0.0.2\n"},
+			expected: []map[string]string{{"name": "host01", "ip": "10.0.0.1"}, {"name": "host02", "ip": "10.0.0.2"}},
+		},
+		{
+			name: "Hosts CSV without any line",
+			req:  apicustomizer.ImagesBatchPost{HostsCSV: "\n"},
+		},
+		{
+			name: "Hosts list and CSV",
+			req:  apicustomizer.ImagesBatchPost{Hosts: []map[string]string{{"name": "host01"}}, HostsCSV: "name\nhost02\n"},
+			err:  "hosts and hosts_csv can't be combined",
+		},
+		{
+			name: "Hosts CSV with a missing field",
+			req:  apicustomizer.ImagesBatchPost{HostsCSV: "name,ip\nhost01\n"},
+			err:  "failed to parse hosts CSV: record on line 2: wrong number of fields",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			hosts, err := getBatchHosts(tc.req)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, hosts)
+		})
+	}
+}
+
+func TestRenderBatch(t *testing.T) {
+	t.Parallel()
+
+	newRequest := func(seedsTemplate string, hosts ...map[string]string) apicustomizer.ImagesBatchPost {
+		return apicustomizer.ImagesBatchPost{
+			Architecture:  imageArchitectureX86_64,
+			Type:          imageTypeISO,
+			SeedsTemplate: seedsTemplate,
+			Hosts:         hosts,
+		}
+	}
+
+	hostnameTemplate := "network:\n  dns:\n    hostname: {{ .name }}\n"
+
+	tooManyHosts := make([]map[string]string, batchMaxHosts+1)
+	for i := range tooManyHosts {
+		tooManyHosts[i] = map[string]string{"name": fmt.Sprintf("host%02d", i)}
+	}
+
+	cases := []struct {
+		name      string
+		req       apicustomizer.ImagesBatchPost
+		hostnames map[string]string
+		err       string
+	}{
+		{
+			name:      "Templated hostnames",
+			req:       newRequest(hostnameTemplate, map[string]string{"name": "host01"}, map[string]string{"name": "host02"}),
+			hostnames: map[string]string{"host01": "host01", "host02": "host02"},
+		},
+		{
+			name:      "Quoted value",
+			req:       newRequest("network:\n  dns:\n    hostname: {{ .hostname | quote }}\n", map[string]string{"name": "host01", "hostname": "a: b # c"}),
+			hostnames: map[string]string{"host01": "a: b # c"},
+		},
+		{
+			name:      "YAML value",
+			req:       newRequest("network:\n  dns:\n    hostname: {{ toYaml .hostname }}\n", map[string]string{"name": "host01", "hostname": "'quoted' value"}),
+			hostnames: map[string]string{"host01": "'quoted' value"},
+		},
+		{
+			name: "Unknown output",
+			req:  apicustomizer.ImagesBatchPost{Output: "tar"},
+			err:  `unknown output "tar"`,
+		},
+		{
+			name: "Offline zip",
+			req:  apicustomizer.ImagesBatchPost{Output: batchOutputZip, Offline: true},
+			err:  "offline images require the manifest output",
+		},
+		{
+			name: "No hosts",
+			req:  newRequest(hostnameTemplate),
+			err:  "no hosts provided",
+		},
+		{
+			name: "Too many hosts",
+			req:  newRequest(hostnameTemplate, tooManyHosts...),
+			err:  "too many hosts (51), at most 50 can be provided",
+		},
+		{
+			name: "Invalid host name",
+			req:  newRequest(hostnameTemplate, map[string]string{"name": "../host01"}),
+			err:  `invalid name "../host01" for host 0`,
+		},
+		{
+			name: "Duplicate host name",
+			req:  newRequest(hostnameTemplate, map[string]string{"name": "host01"}, map[string]string{"name": "host01"}),
+			err:  `duplicate host name "host01"`,
+		},
+		{
+			name: "Missing variable",
+			req:  newRequest("network:\n  dns:\n    hostname: {{ .hostname }}\n", map[string]string{"name": "host01"}),
+			err:  `failed to render seeds for host "host01": template: seeds:3:17: executing "seeds" at <.hostname>: map has no entry for key "hostname"`,
+		},
+		{
+			name: "Unknown seed field",
+			req:  newRequest("network:\n  foo: bar\n", map[string]string{"name": "host01"}),
+			err:  `failed to parse seeds for host "host01": yaml: construct errors: line 2: field foo not found in type seed.Network`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			images, err := renderBatch(tc.req)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, images, len(tc.hostnames))
+
+			for _, image := range images {
+				require.Equal(t, "stable", image.Request.Channel)
+				require.NotNil(t, image.Request.Seeds.Network)
+				require.Equal(t, tc.hostnames[image.Name], image.Request.Seeds.Network.DNS.Hostname)
+			}
+		})
+	}
+}
@@ -48,6 +48,7 @@ const (
 	osFile     = "os"
 	updateFile = "update"
 	rescueFile = "rescue"
+	batchFile  = "batch"
 )
 
 type imageOptions struct {
@@ -118,6 +119,7 @@ func do(ctx context.Context) error {
 	router.HandleFunc("/1.0", apiRoot10)
 	router.HandleFunc("/1.0/certificate", apiCertificate)
 	router.HandleFunc("/1.0/images", apiImages)
+	router.HandleFunc("/1.0/images/batch", apiImagesBatch)
 	router.HandleFunc("/1.0/updates", apiUpdates)
 	router.HandleFunc("/1.0/files/{uuid}", apiFiles)
 	router.HandleFunc("/1.0/oidc", apiOIDC)
@@ -387,6 +389,8 @@ func apiFiles(w http.ResponseWriter, r *http.Request) {
 		sendUpdateTarball(w, r, opts.Data)
 	case osFile:
 		sendOSImage(w, r, opts.Data)
+	case batchFile:
+		sendBatchArchive(w, r, opts.Data)
 	default:
 		w.Header().Set("Content-Type", "application/json")
 
@@ -407,43 +411,7 @@ func sendOSImage(w http.ResponseWriter, r *http.Request, b []byte) {
 		return
 	}
 
-	// Determine source image type.
-	var fileType apiupdate.UpdateFileType
-
-	switch req.Type {
-	case imageTypeISO:
-		fileType = apiupdate.UpdateFileTypeImageISO
-	case imageTypeRaw:
-		fileType = apiupdate.UpdateFileTypeImageRaw
-	default:
-		w.Header().Set("Content-Type", "application/json")
-		_ = response.BadRequest(fmt.Errorf("unknown file type %q", req.Type)).Render(w)
-
-		return
-	}
-
-	if !slices.Contains([]apiupdate.UpdateFileArchitecture{imageArchitectureX86_64, imageArchitectureAARCH64}, req.Architecture) {
-		w.Header().Set("Content-Type", "application/json")
-		_ = response.BadRequest(errors.New("invalid image architecture")).Render(w)
-
-		return
-	}
-
-	// Set default values.
-	if req.Channel == "" {
-		req.Channel = "stable"
-	}
-
-	// Offline systems shouldn't be checking for updates.
-	if req.Offline {
-		if req.Seeds.Update == nil {
-			req.Seeds.Update = &apiseed.Update{Version: "1"}
-		}
-
-		req.Seeds.Update.CheckFrequency = "never"
-	}
-
-	metaIndex, err := parseIndex()
+	err = validateImagesPost(&req)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		_ = response.BadRequest(err).Render(w)
@@ -451,28 +419,15 @@ func sendOSImage(w http.ResponseWriter, r *http.Request, b []byte) {
 		return
 	}
 
-	version, assets, err := filterAssets(*metaIndex, apicustomizer.UpdateFilter{
-		Channel:       req.Channel,
-		Version:       req.Version,
-		Components:    []apiupdate.UpdateFileComponent{},
-		Types:         []apiupdate.UpdateFileType{fileType},
-		Architectures: []apiupdate.UpdateFileArchitecture{req.Architecture},
-	})
-	if err != nil || len(assets) != 1 {
-		log := slog.Default()
-		if err != nil {
-			log = log.With("err", err)
-		}
-
-		log.Warn("image retrieve: failed asset lookup", "client", clientAddress(r))
+	imageFilePath, err := findOSImage(req)
+	if err != nil {
+		slog.Warn("image retrieve: failed asset lookup", "client", clientAddress(r), "err", err)
 
 		_ = response.InternalError(errors.New("couldn't find matching image")).Render(w)
 
 		return
 	}
 
-	imageFilePath := filepath.Join(os.Args[1], version, assets[0])
-
 	// Open the image file.
 	imageFile, err := os.Open(imageFilePath)
 	if err != nil {
@@ -503,6 +458,75 @@ func sendOSImage(w http.ResponseWriter, r *http.Request, b []byte) {
 	writer := pgzip.NewWriter(w)
 	defer writer.Close()
 
+	err = writeOSImage(writer, rc, req.Seeds)
+	if err != nil {
+		return
+	}
+
+	slog.Info("image retrieve: retrieved", "client", clientAddress(r), "type", req.Type, "architecture", req.Architecture)
+}
+
+// validateImagesPost checks the image type and architecture, and applies the default values.
+func validateImagesPost(req *apicustomizer.ImagesPost) error {
+	switch req.Type {
+	case imageTypeISO, imageTypeRaw:
+	default:
+		return fmt.Errorf("unknown file type %q", req.Type)
+	}
+
+	if !slices.Contains([]apiupdate.UpdateFileArchitecture{imageArchitectureX86_64, imageArchitectureAARCH64}, req.Architecture) {
+		return errors.New("invalid image architecture")
+	}
+
+	// Set default values.
+	if req.Channel == "" {
+		req.Channel = "stable"
+	}
+
+	// Offline systems shouldn't be checking for updates.
+	if req.Offline {
+		if req.Seeds.Update == nil {
+			req.Seeds.Update = &apiseed.Update{Version: "1"}
+		}
+
+		req.Seeds.Update.CheckFrequency = "never"
+	}
+
+	return nil
+}
+
+// findOSImage returns the path of the source image matching a validated image request.
+func findOSImage(req apicustomizer.ImagesPost) (string, error) {
+	fileType := apiupdate.UpdateFileTypeImageISO
+	if req.Type == imageTypeRaw {
+		fileType = apiupdate.UpdateFileTypeImageRaw
+	}
+
+	metaIndex, err := parseIndex()
+	if err != nil {
+		return "", err
+	}
+
+	version, assets, err := filterAssets(*metaIndex, apicustomizer.UpdateFilter{
+		Channel:       req.Channel,
+		Version:       req.Version,
+		Components:    []apiupdate.UpdateFileComponent{},
+		Types:         []apiupdate.UpdateFileType{fileType},
+		Architectures: []apiupdate.UpdateFileArchitecture{req.Architecture},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(assets) != 1 {
+		return "", fmt.Errorf("found %d matching images", len(assets))
+	}
+
+	return filepath.Join(os.Args[1], version, assets[0]), nil
+}
+
+// writeOSImage writes the uncompressed image, replacing the start of its seed data partition with the seeds.
+func writeOSImage(writer io.Writer, rc io.ReadSeeker, seeds apicustomizer.ImagesPostSeeds) error {
 	// Write leading part.
 	remainder := int64(2148532224)
 
@@ -519,22 +543,22 @@ func sendOSImage(w http.ResponseWriter, r *http.Request, b []byte) {
 				break
 			}
 
-			return
+			return err
 		}
 
 		remainder -= n
 	}
 
 	// Write seed file.
-	seedSize, err := writeSeed(writer, req.Seeds)
+	seedSize, err := writeSeed(writer, seeds)
 	if err != nil {
-		return
+		return err
 	}
 
 	// Write trailing part.
 	_, err = rc.Seek(int64(seedSize), 1)
 	if err != nil {
-		return
+		return err
 	}
 
 	for {
@@ -544,11 +568,11 @@ func sendOSImage(w http.ResponseWriter, r *http.Request, b []byte) {
 				break
 			}
 
-			return
+			return err
 		}
 	}
 
-	slog.Info("image retrieve: retrieved", "client", clientAddress(r), "type", req.Type, "architecture", req.Architecture)
+	return nil
 }
 
 func sendRescueImage(w http.ResponseWriter, r *http.Request, imageUUID string, b []byte) {
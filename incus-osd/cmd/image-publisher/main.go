@@ -46,6 +46,10 @@ func main() {
 	pruneCmd := cmdPrune{global: &globalCmd}
 	app.AddCommand(pruneCmd.command())
 
+	// resign sub-command.
+	resignCmd := cmdResign{global: &globalCmd}
+	app.AddCommand(resignCmd.command())
+
 	// severity sub-command.
 	severityCmd := cmdSeverity{global: &globalCmd}
 	app.AddCommand(severityCmd.command())
@@ -0,0 +1,738 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"debug/pe"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/foxboron/go-uefi/authenticode"
+	"github.com/lxc/incus/v7/shared/subprocess"
+	"github.com/smallstep/pkcs7"
+	"github.com/spf13/cobra"
+)
+
+// espOffset is the offset of the ESP partition in the raw install image.
+const espOffset = 1048576
+
+// ukiMeasuredSections maps the UKI sections covered by the PCR signature to their systemd-measure option.
+var ukiMeasuredSections = map[string]string{
+	".linux":   "--linux",
+	".osrel":   "--osrel",
+	".cmdline": "--cmdline",
+	".initrd":  "--initrd",
+	".ucode":   "--ucode",
+	".splash":  "--splash",
+	".dtb":     "--dtb",
+	".uname":   "--uname",
+	".sbat":    "--sbat",
+	".pcrpkey": "--pcrpkey",
+	".profile": "--profile",
+	".hwids":   "--hwids",
+}
+
+type veritySignatureMetadata struct {
+	RootHash               string `json:"rootHash"`               //nolint:tagliatelle
+	CertificateFingerprint string `json:"certificateFingerprint"` //nolint:tagliatelle
+	Signature              string `json:"signature"`
+}
+
+type cmdResign struct {
+	global *cmdGlobal
+
+	key         crypto.Signer
+	keyPath     string
+	certificate *x509.Certificate
+	publicKey   []byte
+}
+
+func (c *cmdResign) command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = "resign <source archive> <target archive>"
+	cmd.Short = "Re-signs a build with a custom Secure Boot key"
+	cmd.Long = formatSection("Description",
+		`Re-signs a build with a custom Secure Boot key
+
+This takes a local build archive and re-signs its UKIs, usr image signatures,
+system extensions and raw install image with the Secure Boot key and
+certificate provided through the SECUREBOOT_KEY and SECUREBOOT_CERTIFICATE
+environment variables. The resulting archive can then be imported with "sync".
+
+If SECUREBOOT_ENROLL points to a directory of signed PK, KEK and db EFI variable
+updates (.auth files), those replace the keys that are automatically enrolled
+when installing from the raw image. Otherwise, the automatic enrollment keys
+are removed and the custom keys must already be enrolled in the firmware.
+
+ISO images can't be re-signed and are left out of the resulting archive.
+`)
+	cmd.RunE = c.run
+
+	return cmd
+}
+
+func (c *cmdResign) run(cmd *cobra.Command, args []string) error {
+	ctx := context.TODO()
+
+	// Quick checks.
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Config.
+	c.keyPath = os.Getenv("SECUREBOOT_KEY")
+	certificatePath := os.Getenv("SECUREBOOT_CERTIFICATE")
+
+	if c.keyPath == "" || certificatePath == "" {
+		return errors.New("SECUREBOOT_KEY and SECUREBOOT_CERTIFICATE must be set")
+	}
+
+	err = c.loadSigningKey(certificatePath)
+	if err != nil {
+		return err
+	}
+
+	// Open the source archive.
+	zr, err := zip.OpenReader(args[0])
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = zr.Close() }()
+
+	// Create the target archive.
+	target, err := os.Create(args[1])
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = target.Close() }()
+
+	zw := zip.NewWriter(target)
+
+	for _, f := range zr.File {
+		assetName := f.Name
+
+		switch {
+		case strings.HasSuffix(assetName, ".iso.gz"):
+			slog.WarnContext(ctx, "Skipping ISO image which can't be re-signed", "filename", assetName)
+
+			continue
+		case strings.Contains(assetName, ".usr-x86-64-verity-sig."), strings.Contains(assetName, ".usr-arm64-verity-sig."):
+			err = c.rewrite(ctx, zw, f, c.resignVeritySignatureFile)
+		case strings.Contains(assetName, ".usr-x86-64"), strings.Contains(assetName, ".usr-arm64"):
+			err = zw.Copy(f)
+		case strings.HasSuffix(assetName, ".efi.gz"):
+			err = c.rewrite(ctx, zw, f, c.resignUKI)
+		case strings.HasSuffix(assetName, ".img.gz"):
+			err = c.rewrite(ctx, zw, f, c.resignImage)
+		case strings.HasSuffix(assetName, ".raw.gz"):
+			err = c.rewrite(ctx, zw, f, c.resignVeritySignaturePartition)
+		default:
+			err = zw.Copy(f)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to re-sign %q: %w", assetName, err)
+		}
+	}
+
+	err = zw.Close()
+	if err != nil {
+		return err
+	}
+
+	return target.Close()
+}
+
+// loadSigningKey loads the Secure Boot signing key and certificate.
+func (c *cmdResign) loadSigningKey(certificatePath string) error {
+	content, err := os.ReadFile(certificatePath)
+	if err != nil {
+		return err
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return errors.New("failed to decode certificate '" + certificatePath + "'")
+	}
+
+	c.certificate, err = x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return err
+	}
+
+	content, err = os.ReadFile(c.keyPath)
+	if err != nil {
+		return err
+	}
+
+	block, _ = pem.Decode(content)
+	if block == nil {
+		return errors.New("failed to decode private key '" + c.keyPath + "'")
+	}
+
+	var key any
+
+	key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			key, err = x509.ParseECPrivateKey(block.Bytes)
+			if err != nil {
+				return errors.New("unsupported private key format in '" + c.keyPath + "'")
+			}
+		}
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return errors.New("private key in '" + c.keyPath + "' can't be used for signing")
+	}
+
+	c.key = signer
+
+	publicKeyDer, err := x509.MarshalPKIXPublicKey(c.certificate.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	c.publicKey = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDer})
+
+	return nil
+}
+
+// rewrite decompresses an asset, applies the provided function to it and adds the result to the target archive.
+func (*cmdResign) rewrite(ctx context.Context, zw *zip.Writer, f *zip.File, fn func(context.Context, string) error) error {
+	slog.InfoContext(ctx, "Re-signing", "name", f.Name)
+
+	tmpFile, err := os.CreateTemp("", "image-publisher-resign")
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = os.Remove(tmpFile.Name()) }()
+	defer func() { _ = tmpFile.Close() }()
+
+	// Decompress the asset.
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = rc.Close() }()
+
+	gr, err := gzip.NewReader(rc)
+	if err != nil {
+		return err
+	}
+
+	for {
+		_, err := io.CopyN(tmpFile, gr, 4*1024*1024)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return err
+		}
+	}
+
+	err = tmpFile.Close()
+	if err != nil {
+		return err
+	}
+
+	// Re-sign it.
+	err = fn(ctx, tmpFile.Name())
+	if err != nil {
+		return err
+	}
+
+	// Compress it into the target archive.
+	w, err := zw.Create(f.Name)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(tmpFile.Name())
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = src.Close() }()
+
+	gw := gzip.NewWriter(w)
+
+	_, err = io.Copy(gw, src)
+	if err != nil {
+		return err
+	}
+
+	return gw.Close()
+}
+
+// resignVerityMetadata replaces the signature in the verity signature JSON metadata, keeping its padding.
+func (c *cmdResign) resignVerityMetadata(buf []byte) ([]byte, error) {
+	metadata := veritySignatureMetadata{}
+
+	err := json.Unmarshal(bytes.Trim(buf, "\x00"), &metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate a detached PKCS7 signature of the root hash, as done by systemd-repart.
+	sd, err := pkcs7.NewSignedData([]byte(metadata.RootHash))
+	if err != nil {
+		return nil, err
+	}
+
+	sd.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+
+	err = sd.SignWithoutAttr(c.certificate, c.key, pkcs7.SignerInfoConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	sd.Detach()
+
+	signature, err := sd.Finish()
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprint := sha256.Sum256(c.certificate.Raw)
+
+	metadata.CertificateFingerprint = hex.EncodeToString(fingerprint[:])
+	metadata.Signature = base64.StdEncoding.EncodeToString(signature)
+
+	newBuf, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(newBuf) > len(buf) {
+		return nil, fmt.Errorf("new verity signature metadata is larger than the available %d bytes", len(buf))
+	}
+
+	return append(newBuf, make([]byte, len(buf)-len(newBuf))...), nil
+}
+
+// resignVeritySignatureFile re-signs the verity signature of a usr image update.
+func (c *cmdResign) resignVeritySignatureFile(_ context.Context, path string) error {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	buf, err = c.resignVerityMetadata(buf)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buf, 0o644)
+}
+
+// resignVeritySignaturePartition re-signs the verity signature partition of a disk image, which is
+// always the third partition for both system extensions and the raw install image.
+func (c *cmdResign) resignVeritySignaturePartition(ctx context.Context, path string) error {
+	// Get the offset in the image to read json metadata from.
+	output, err := subprocess.RunCommandContext(ctx, "sgdisk", "-p", "-i", "3", path)
+	if err != nil {
+		return err
+	}
+
+	values := []int64{}
+
+	for _, re := range []string{`Sector size \(logical\): (\d+) bytes`, `First sector: (\d+) \(at .+\)`, `Partition size: (\d+) sectors \(.+\)`} {
+		match := regexp.MustCompile(re).FindStringSubmatch(output)
+		if len(match) != 2 {
+			return fmt.Errorf("failed to get verity signature partition details from '%s'", path)
+		}
+
+		value, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return err
+		}
+
+		values = append(values, value)
+	}
+
+	sectorSize, partitionFirstSector, partitionSize := values[0], values[1], values[2]
+
+	// #nosec G304
+	imageFile, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = imageFile.Close() }()
+
+	buf := make([]byte, sectorSize*partitionSize)
+
+	_, err = imageFile.ReadAt(buf, sectorSize*partitionFirstSector)
+	if err != nil {
+		return err
+	}
+
+	buf, err = c.resignVerityMetadata(buf)
+	if err != nil {
+		return err
+	}
+
+	_, err = imageFile.WriteAt(buf, sectorSize*partitionFirstSector)
+	if err != nil {
+		return err
+	}
+
+	return imageFile.Close()
+}
+
+// resignUKI replaces the PCR public key and signature of a UKI, then re-signs it for Secure Boot.
+func (c *cmdResign) resignUKI(ctx context.Context, path string) error {
+	tmpDir, err := os.MkdirTemp("", "image-publisher-uki")
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	publicKeyPath := filepath.Join(tmpDir, "pcrpkey")
+
+	err = os.WriteFile(publicKeyPath, c.publicKey, 0o644)
+	if err != nil {
+		return err
+	}
+
+	// Extract the measured sections, using the new public key.
+	peFile, err := pe.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = peFile.Close() }()
+
+	args := []string{"sign", "--bank=sha256", "--private-key=" + c.keyPath, "--public-key=" + publicKeyPath, "--json=short"}
+	seen := map[string]bool{}
+
+	for _, section := range peFile.Sections {
+		option, ok := ukiMeasuredSections[section.Name]
+		if !ok {
+			continue
+		}
+
+		if seen[section.Name] {
+			return errors.New("UKIs with multiple profiles aren't supported")
+		}
+
+		seen[section.Name] = true
+
+		sectionPath := publicKeyPath
+
+		if section.Name != ".pcrpkey" {
+			data, err := sectionData(section)
+			if err != nil {
+				return err
+			}
+
+			sectionPath = filepath.Join(tmpDir, strings.TrimPrefix(section.Name, "."))
+
+			err = os.WriteFile(sectionPath, data, 0o644)
+			if err != nil {
+				return err
+			}
+		}
+
+		args = append(args, option+"="+sectionPath)
+	}
+
+	err = peFile.Close()
+	if err != nil {
+		return err
+	}
+
+	if !seen[".pcrpkey"] || !seen[".linux"] {
+		return errors.New("UKI doesn't contain a kernel and PCR public key")
+	}
+
+	// Generate the new PCR signature.
+	pcrSignature, err := subprocess.RunCommandContext(ctx, "systemd-measure", args...)
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	content, err = replacePESection(content, ".pcrpkey", c.publicKey)
+	if err != nil {
+		return err
+	}
+
+	content, err = replacePESection(content, ".pcrsig", []byte(strings.TrimSpace(pcrSignature)))
+	if err != nil {
+		return err
+	}
+
+	return c.signPE(path, content)
+}
+
+// resignPE re-signs a PE binary for Secure Boot.
+func (c *cmdResign) resignPE(_ context.Context, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return c.signPE(path, content)
+}
+
+// signPE replaces any existing Authenticode signature of the PE binary with a new one and writes it to the given path.
+func (c *cmdResign) signPE(path string, content []byte) error {
+	content, err := stripAuthenticode(content)
+	if err != nil {
+		return err
+	}
+
+	peBinary, err := authenticode.Parse(bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+
+	_, err = peBinary.Sign(c.key, c.certificate)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, peBinary.Bytes(), 0o644)
+}
+
+// resignImage re-signs the EFI binaries and usr image of a raw install image and replaces the keys
+// that get automatically enrolled when the system is in setup mode.
+func (c *cmdResign) resignImage(ctx context.Context, path string) error {
+	tmpDir, err := os.MkdirTemp("", "image-publisher-esp")
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	esp := fmt.Sprintf("%s@@%d", path, espOffset)
+
+	// Re-sign the systemd-boot EFI stub and UKIs.
+	for _, dir := range []string{"::EFI/BOOT", "::EFI/systemd", "::EFI/Linux"} {
+		output, err := subprocess.RunCommandContext(ctx, "mdir", "-b", "-i", esp, dir)
+		if err != nil {
+			return err
+		}
+
+		for name := range strings.Lines(output) {
+			name = strings.TrimSpace(name)
+			if !strings.HasSuffix(strings.ToLower(name), ".efi") {
+				continue
+			}
+
+			localPath := filepath.Join(tmpDir, filepath.Base(name))
+
+			_, err := subprocess.RunCommandContext(ctx, "mcopy", "-n", "-i", esp, name, localPath)
+			if err != nil {
+				return err
+			}
+
+			if dir == "::EFI/Linux" {
+				err = c.resignUKI(ctx, localPath)
+			} else {
+				err = c.resignPE(ctx, localPath)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			_, err = subprocess.RunCommandContext(ctx, "mcopy", "-o", "-i", esp, localPath, name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Replace the Secure Boot keys.
+	_, _ = subprocess.RunCommandContext(ctx, "mdeltree", "-i", esp, "::loader/keys/auto/")
+	_, _ = subprocess.RunCommandContext(ctx, "mdeltree", "-i", esp, "::keys/")
+
+	enrollPath := os.Getenv("SECUREBOOT_ENROLL")
+	if enrollPath == "" {
+		slog.WarnContext(ctx, "No Secure Boot keys to enroll were provided, removing automatic enrollment from install image")
+	} else {
+		err := c.copyEnrollmentKeys(ctx, esp, enrollPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Re-sign the usr image.
+	return c.resignVeritySignaturePartition(ctx, path)
+}
+
+// copyEnrollmentKeys copies the signed EFI variable updates, and the matching DER certificates if
+// present, to the ESP.
+func (*cmdResign) copyEnrollmentKeys(ctx context.Context, esp string, enrollPath string) error {
+	for _, varName := range []string{"PK", "KEK", "db"} {
+		_, err := os.Stat(filepath.Join(enrollPath, varName+".auth"))
+		if err != nil {
+			return fmt.Errorf("missing %s.auth in '%s': %w", varName, enrollPath, err)
+		}
+	}
+
+	for _, ext := range []string{"auth", "der"} {
+		files, err := filepath.Glob(filepath.Join(enrollPath, "*."+ext))
+		if err != nil {
+			return err
+		}
+
+		if len(files) == 0 {
+			continue
+		}
+
+		target := "::keys"
+		if ext == "auth" {
+			target = "::loader/keys/auto"
+		}
+
+		_, err = subprocess.RunCommandContext(ctx, "mmd", "-i", esp, target)
+		if err != nil {
+			return err
+		}
+
+		_, err = subprocess.RunCommandContext(ctx, "mcopy", append([]string{"-i", esp}, append(files, target+"/")...)...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sectionData returns the data of a PE section, without any padding.
+func sectionData(section *pe.Section) ([]byte, error) {
+	data, err := section.Data()
+	if err != nil {
+		return nil, err
+	}
+
+	if section.VirtualSize < uint32(len(data)) {
+		data = data[:section.VirtualSize]
+	}
+
+	return data, nil
+}
+
+// peHeaderOffsets returns the offsets of the section headers and of the optional header's data directories.
+func peHeaderOffsets(content []byte) (int, int, error) {
+	if len(content) < 0x40 {
+		return 0, 0, errors.New("file is too small to be a PE binary")
+	}
+
+	peOffset := int(binary.LittleEndian.Uint32(content[0x3c:]))
+	optionalHeaderOffset := peOffset + 4 + 20
+
+	if len(content) < optionalHeaderOffset+2 {
+		return 0, 0, errors.New("invalid PE header")
+	}
+
+	optionalHeaderSize := int(binary.LittleEndian.Uint16(content[peOffset+4+16:]))
+
+	var dataDirectoriesOffset int
+
+	switch binary.LittleEndian.Uint16(content[optionalHeaderOffset:]) {
+	case 0x10b: // PE32
+		dataDirectoriesOffset = optionalHeaderOffset + 96
+	case 0x20b: // PE32+
+		dataDirectoriesOffset = optionalHeaderOffset + 112
+	default:
+		return 0, 0, errors.New("unsupported PE optional header")
+	}
+
+	return optionalHeaderOffset + optionalHeaderSize, dataDirectoriesOffset, nil
+}
+
+// replacePESection replaces the content of an existing PE section in place. The new content must fit
+// in the space allocated to the section.
+func replacePESection(content []byte, name string, data []byte) ([]byte, error) {
+	peFile, err := pe.NewFile(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+
+	sectionHeadersOffset, _, err := peHeaderOffsets(content)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, section := range peFile.Sections {
+		if section.Name != name {
+			continue
+		}
+
+		if uint32(len(data)) > section.Size {
+			return nil, fmt.Errorf("new %s section is larger than the existing one, the key type and size must match the original signing key", name)
+		}
+
+		// Write the data, padded with zeroes.
+		copy(content[section.Offset:section.Offset+section.Size], append(data, make([]byte, int(section.Size)-len(data))...))
+
+		// Update the section's virtual size.
+		binary.LittleEndian.PutUint32(content[sectionHeadersOffset+i*40+8:], uint32(len(data))) // #nosec G115
+
+		return content, nil
+	}
+
+	return nil, fmt.Errorf("PE binary doesn't contain a %s section", name)
+}
+
+// stripAuthenticode removes the Authenticode signatures from a PE binary.
+func stripAuthenticode(content []byte) ([]byte, error) {
+	_, dataDirectoriesOffset, err := peHeaderOffsets(content)
+	if err != nil {
+		return nil, err
+	}
+
+	// The certificate table is the fifth data directory.
+	certTableOffset := dataDirectoriesOffset + 4*8
+
+	address := binary.LittleEndian.Uint32(content[certTableOffset:])
+	size := binary.LittleEndian.Uint32(content[certTableOffset+4:])
+
+	if address == 0 || size == 0 {
+		return content, nil
+	}
+
+	if int(address)+int(size) != len(content) {
+		return nil, errors.New("PE certificate table isn't at the end of the file")
+	}
+
+	// Clear the data directory entry and drop the certificate table.
+	clear(content[certTableOffset : certTableOffset+8])
+
+	return content[:address], nil
+}
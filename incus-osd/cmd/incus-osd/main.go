@@ -5,6 +5,8 @@ package main
 import (
 	"cmp"
 	"context"
+	"crypto/rand"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -23,15 +25,19 @@ import (
 	"go.yaml.in/yaml/v4"
 	"golang.org/x/sys/unix"
 
+	"github.com/lxc/incus-os/incus-osd/api"
 	"github.com/lxc/incus-os/incus-osd/certs"
 	"github.com/lxc/incus-os/incus-osd/internal/applications"
+	"github.com/lxc/incus-os/incus-osd/internal/certificate"
 	"github.com/lxc/incus-os/incus-osd/internal/install"
 	"github.com/lxc/incus-os/incus-osd/internal/kernel"
 	"github.com/lxc/incus-os/incus-osd/internal/keyring"
 	"github.com/lxc/incus-os/incus-osd/internal/nftables"
 	"github.com/lxc/incus-os/incus-osd/internal/providers"
 	"github.com/lxc/incus-os/incus-osd/internal/recovery"
+	"github.com/lxc/incus-os/incus-osd/internal/reset"
 	"github.com/lxc/incus-os/incus-osd/internal/rest"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
 	"github.com/lxc/incus-os/incus-osd/internal/secureboot"
 	"github.com/lxc/incus-os/incus-osd/internal/seed"
 	"github.com/lxc/incus-os/incus-osd/internal/services"
@@ -49,6 +55,9 @@ var (
 	runPath = "/run/incus-os/"
 )
 
+// errSecretsKeyUnseal is returned when the existing secrets key can't be unsealed.
+var errSecretsKeyUnseal = errors.New("unable to unseal the secrets key")
+
 func main() {
 	ctx := context.Background()
 
@@ -79,6 +88,19 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Load the key used to encrypt secrets at rest, before the state is loaded.
+	err = loadSecretsKey(ctx)
+	if err != nil {
+		// Keep the encrypted secrets as-is and refuse new ones, rather than losing them on the next
+		// save. This leaves the API reachable to recover, such as through a TPM rebind.
+		if errors.Is(err, errSecretsKeyUnseal) {
+			secrets.SetUnavailable(err)
+			slog.ErrorContext(ctx, "Unable to unseal the secrets key, secrets are read-only", "err", err)
+		} else {
+			slog.ErrorContext(ctx, "Unable to load the secrets key, secrets will be stored unencrypted", "err", err)
+		}
+	}
+
 	// Get persistent state.
 	s, err := state.LoadOrCreate(filepath.Join(varPath, "state.txt"))
 	if err != nil {
@@ -142,6 +164,15 @@ func main() {
 		}
 	}
 
+	// Retrieve seed data from remote sources if the system isn't configured yet and has no local seed data.
+	if !s.OS.SuccessfulBoot && s.System.Network.Config == nil {
+		err := seed.FetchRemote(ctx)
+		if err != nil {
+			tui.EarlyError("unable to retrieve remote seed data: "+err.Error(), s.OS.Name)
+			os.Exit(1)
+		}
+	}
+
 	// Perform the install check here, so we don't render the TUI footer during install.
 	s.ShouldPerformInstall = install.ShouldPerformInstall()
 
@@ -240,6 +271,11 @@ func firstBootActions(ctx context.Context, s *state.State) error {
 }
 
 func run(ctx context.Context, s *state.State) error {
+	// If only requested to check the hardware compatibility, report on all requirements rather than failing on the first one.
+	if install.ShouldPerformCheck() {
+		return install.RunHardwareCheck(ctx, s.OS.Name, s.OS.RunningRelease)
+	}
+
 	// Verify that the system meets minimum requirements for running IncusOS.
 	err := install.CheckSystemRequirements(ctx)
 	if err != nil {
@@ -693,6 +729,16 @@ func startup(ctx context.Context, s *state.State) error { //nolint:revive
 		if err != nil && !seed.IsMissing(err) {
 			return err
 		}
+
+		// Apply the logging seed config (if present).
+		loggingSeed, err := seed.GetLogging(ctx)
+		if err != nil && !seed.IsMissing(err) {
+			return errors.New("unable to parse logging seed: " + err.Error())
+		}
+
+		if loggingSeed != nil {
+			s.System.Logging.Config = *loggingSeed
+		}
 	}
 
 	// Record the state of auto-unlocked LUKS devices. With some TPMs this can be slow, so cache the
@@ -774,6 +820,12 @@ func startup(ctx context.Context, s *state.State) error { //nolint:revive
 		}
 	}
 
+	// Restore the storage pools and trusted certificates preserved by a factory reset (if present).
+	err = reset.ApplyPreserved(ctx, s)
+	if err != nil {
+		return err
+	}
+
 	// Ensure any locally-defined pools are available.
 	err = setupLocalStorage(ctx, s)
 	if err != nil {
@@ -810,6 +862,16 @@ func startup(ctx context.Context, s *state.State) error { //nolint:revive
 		if updateSeed != nil {
 			s.System.Update.Config = updateSeed.SystemUpdateConfig
 		}
+
+		// Apply the storage seed config (if present).
+		storageSeed, err := seed.GetStorage(ctx)
+		if err != nil && !seed.IsMissing(err) {
+			return errors.New("unable to parse storage seed: " + err.Error())
+		}
+
+		if storageSeed != nil {
+			applyStorageSeed(ctx, s, storageSeed)
+		}
 	}
 
 	p, err := providers.Load(ctx, s, false)
@@ -822,12 +884,24 @@ func startup(ctx context.Context, s *state.State) error { //nolint:revive
 		update.Checker(ctx, s, p, true, false)
 	}
 
+	// Restore any application backup staged from the recovery partition.
+	err = recovery.ApplyApplicationBackups(ctx, s)
+	if err != nil {
+		slog.ErrorContext(ctx, "Unable to restore application backups: "+err.Error())
+	}
+
 	// Run application startup actions. Must be done after storage pools are loaded.
 	err = startApplications(ctx, s)
 	if err != nil {
 		return err
 	}
 
+	// Present any custom or ACME issued server certificate on the primary application's listener.
+	err = certificate.ApplyToPrimary(ctx, s)
+	if err != nil {
+		slog.ErrorContext(ctx, "Unable to apply the server certificate to the primary application", "err", err)
+	}
+
 	// Run periodic update checks if we have a working provider.
 	if p != nil {
 		go update.Checker(ctx, s, p, false, false)
@@ -857,17 +931,27 @@ func startup(ctx context.Context, s *state.State) error { //nolint:revive
 	// Start the job scheduler.
 	s.JobScheduler.Start()
 
+	// Report the reachability of the Tang servers without waiting for the first scheduled check.
+	systemd.LoadNetworkUnlock(s)
+
+	go func() {
+		_ = systemd.CheckNetworkUnlock(ctx)
+	}()
+
 	// Set up handler for daemon actions.
 	s.TriggerReboot = make(chan bool, 1)
 	s.TriggerShutdown = make(chan bool, 1)
 	s.TriggerSuspend = make(chan bool, 1)
 	s.TriggerUpdate = make(chan bool, 1)
+	s.TriggerCertificateUpdate = make(chan bool, 1)
 	chSignal := make(chan os.Signal, 1)
 	signal.Notify(chSignal, unix.SIGTERM)
 
 	go func() {
 		action := "exit"
 
+		var fallbackListener *util.FancyTLSListener
+
 		// Action handler.
 	waitSignal:
 		select {
@@ -892,11 +976,29 @@ func startup(ctx context.Context, s *state.State) error { //nolint:revive
 
 			goto waitSignal
 		case <-s.TriggerFallbackListener:
-			err := startFallbackListener(ctx, s)
+			// The listener may have been requested more than once.
+			if fallbackListener != nil {
+				goto waitSignal
+			}
+
+			var err error
+
+			fallbackListener, err = startFallbackListener(ctx, s)
 			if err != nil {
 				slog.ErrorContext(ctx, "Failed to start fallback HTTPS listener", "err", err)
 			}
 
+			goto waitSignal
+		case <-s.TriggerCertificateUpdate:
+			if fallbackListener != nil {
+				cert, err := getServerCertificate(ctx, s)
+				if err != nil {
+					slog.ErrorContext(ctx, "Failed to reload fallback HTTPS listener certificate", "err", err)
+				} else {
+					fallbackListener.Config(*cert)
+				}
+			}
+
 			goto waitSignal
 		}
 
@@ -936,6 +1038,18 @@ func registerJobs(s *state.State) error {
 		return err
 	}
 
+	// Register the certificate renewal job.
+	err = s.JobScheduler.RegisterJob(certificate.RenewalJob, certificate.RenewalSchedule, certificate.RenewJob(s))
+	if err != nil {
+		return err
+	}
+
+	// Register the network unlock reachability check.
+	err = s.JobScheduler.RegisterJob(systemd.NetworkUnlockCheckJob, systemd.NetworkUnlockCheckSchedule, systemd.CheckNetworkUnlockJob())
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -966,6 +1080,25 @@ func setTimezone(ctx context.Context) error {
 	return systemd.SetTimezone(ctx, config.Time)
 }
 
+// applyStorageSeed sets the scrub schedule and creates the pools of the storage seed. Failing to create
+// a pool doesn't prevent the system from starting, so that it can be fixed through the API.
+func applyStorageSeed(ctx context.Context, s *state.State, config *api.SystemStorageConfig) {
+	if config.ScrubSchedule != "" {
+		s.System.Storage.Config.ScrubSchedule = config.ScrubSchedule
+	}
+
+	for _, pool := range config.Pools {
+		if storage.PoolExists(ctx, pool.Name) {
+			continue
+		}
+
+		err := zfs.CreateZpool(ctx, pool, s)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed creating storage pool from seed", "name", pool.Name, "err", err)
+		}
+	}
+}
+
 func setupLocalStorage(ctx context.Context, s *state.State) error {
 	slog.InfoContext(ctx, "Bringing up the local storage")
 
@@ -990,6 +1123,12 @@ func setupLocalStorage(ctx context.Context, s *state.State) error {
 		}
 	}
 
+	// If installed to mirrored boot drives, make sure the second drive is up to date.
+	err = storage.SyncBootMirror(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "Unable to sync boot mirror drive: "+err.Error())
+	}
+
 	apps, err := applications.GetInstalled(ctx, s)
 	if err != nil {
 		return err
@@ -1162,17 +1301,30 @@ func configureIncusAgent(ctx context.Context, s *state.State) error {
 	return nil
 }
 
-func startFallbackListener(ctx context.Context, s *state.State) error {
+// getServerCertificate returns the server certificate for the fallback HTTPS listener. A custom or ACME
+// issued certificate is preferred, otherwise the primary application's certificate is used so we don't
+// trigger potential connection security warnings.
+func getServerCertificate(ctx context.Context, s *state.State) (*tls.Certificate, error) {
+	serverCert, err := certificate.Get()
+	if err == nil {
+		return serverCert, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		slog.WarnContext(ctx, "Failed to load server certificate, using the primary application's certificate", "err", err)
+	}
+
 	// Get the primary application, requiring that it be initialized.
 	app, err := applications.GetPrimary(ctx, s, true)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Get server TLS certificates from the primary application so we don't trigger potential connection security warnings.
-	serverCert, err := app.GetServerCertificate()
+	return app.GetServerCertificate()
+}
+
+func startFallbackListener(ctx context.Context, s *state.State) (*util.FancyTLSListener, error) {
+	serverCert, err := getServerCertificate(ctx, s)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	listenAddress := s.System.FallbackListener.Config.ListenAddress
@@ -1186,16 +1338,20 @@ func startFallbackListener(ctx context.Context, s *state.State) error {
 
 	tcpListener, err := listenConfig.Listen(ctx, "tcp", listenAddress)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Start the fallback HTTPS server.
-	server, err := rest.NewServer(ctx, s, util.NewFancyTLSListener(tcpListener, *serverCert))
+	tlsListener := util.NewFancyTLSListener(tcpListener, *serverCert)
+
+	server, err := rest.NewServer(ctx, s, tlsListener)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	s.FallbackListenerMutex.Lock()
 	s.System.FallbackListener.State.Active = true
+	s.FallbackListenerMutex.Unlock()
 
 	slog.InfoContext(ctx, "Fallback HTTPS listener started on "+tcpListener.Addr().String())
 
@@ -1204,7 +1360,44 @@ func startFallbackListener(ctx context.Context, s *state.State) error {
 		_ = server.Serve()
 	}()
 
-	return nil
+	return tlsListener, nil
+}
+
+// loadSecretsKey unseals the key used to encrypt secrets at rest, generating and sealing a new one if needed.
+// If the existing key can't be unsealed, errSecretsKeyUnseal is returned and the key is left in place.
+func loadSecretsKey(ctx context.Context) error {
+	sealed, err := os.ReadFile(secrets.KeyPath)
+	if err == nil {
+		key, err := systemd.DecryptCredential(ctx, secrets.KeyCredentialName, sealed)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errSecretsKeyUnseal, err)
+		}
+
+		return secrets.SetKey(key)
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	key := make([]byte, secrets.KeySize)
+
+	_, err = rand.Read(key)
+	if err != nil {
+		return err
+	}
+
+	sealed, err = systemd.EncryptCredential(ctx, secrets.KeyCredentialName, key)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(secrets.KeyPath, sealed, 0o600)
+	if err != nil {
+		return err
+	}
+
+	return secrets.SetKey(key)
 }
 
 func configureConsoleDevices(ctx context.Context, s *state.State) error {
@@ -1219,6 +1412,34 @@ func configureConsoleDevices(ctx context.Context, s *state.State) error {
 		s.System.Kernel.Config.Console = kernelSeed.Console
 	}
 
+	// On first boot, copy the rest of the kernel configuration from the seed, without overriding
+	// anything already set. It's then applied along with the existing configuration.
+	if !s.KernelSeedApplied {
+		config := &s.System.Kernel.Config
+
+		if len(config.BlacklistModules) == 0 {
+			config.BlacklistModules = kernelSeed.BlacklistModules
+		}
+
+		if config.CPU == nil {
+			config.CPU = kernelSeed.CPU
+		}
+
+		if config.Memory == nil {
+			config.Memory = kernelSeed.Memory
+		}
+
+		if config.Network == nil {
+			config.Network = kernelSeed.Network
+		}
+
+		if config.PCI == nil {
+			config.PCI = kernelSeed.PCI
+		}
+
+		s.KernelSeedApplied = true
+	}
+
 	// Set any configured baud speeds.
 	for _, console := range s.System.Kernel.Config.Console {
 		if console.BaudRate != 0 {
@@ -30,6 +30,10 @@ func main() {
 		switch os.Args[1] {
 		case "measure-pcrs":
 			err = measurePCRs()
+		case "network-install":
+			err = networkInstall()
+		case "network-unlock":
+			err = networkUnlock()
 		case "seal-pcr15":
 			err = sealPCR15()
 		case "validate-pe-binaries":
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lxc/incus/v7/shared/osarch"
+	"github.com/lxc/incus/v7/shared/subprocess"
+	"golang.org/x/sys/unix"
+
+	apiupdate "github.com/lxc/incus-os/incus-osd/api/images"
+	"github.com/lxc/incus-os/incus-osd/certs"
+	"github.com/lxc/incus-os/incus-osd/internal/storage"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+	"github.com/lxc/incus-os/incus-osd/internal/util"
+)
+
+// networkInstallDHCPOption is the private DHCP option which can provide the URL of the install image server.
+const networkInstallDHCPOption = 225
+
+// networkInstall retrieves the install image matching the running release from an image server, verifies it
+// against the server's signed index and attaches it as a read-only loop device, from which the boot then
+// continues as if running from install media. This only happens when the server is set on the kernel command
+// line, or when the system was booted from the network and DHCP provides the server.
+func networkInstall() error {
+	ctx := context.Background()
+
+	cmdline, err := os.ReadFile("/proc/cmdline")
+	if err != nil {
+		return err
+	}
+
+	serverURL := ""
+
+	for field := range strings.FieldsSeq(string(cmdline)) {
+		value, found := strings.CutPrefix(field, "incusos.install_url=")
+		if found {
+			serverURL = value
+		}
+	}
+
+	if serverURL == "" {
+		// When booted from a disk, the firmware reports the partition the UKI was loaded from.
+		partUUID, err := util.ReadEFIVariable("LoaderDevicePartUUID")
+		if err != nil {
+			return err
+		}
+
+		if len(partUUID) != 0 {
+			return nil
+		}
+	}
+
+	// Bring up basic networking.
+	err = systemd.StartTemporaryDHCP(ctx, []int{networkInstallDHCPOption}, 60*time.Second)
+	if err != nil {
+		if serverURL != "" {
+			return err
+		}
+
+		return nil
+	}
+
+	if serverURL == "" {
+		value, err := systemd.GetDHCPLeaseOption(networkInstallDHCPOption)
+		if err != nil {
+			return err
+		}
+
+		serverURL = strings.TrimRight(string(value), "\x00")
+		if serverURL == "" {
+			return nil
+		}
+	}
+
+	u, err := url.Parse(serverURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return fmt.Errorf("invalid install image server URL %q", serverURL)
+	}
+
+	serverURL = strings.TrimSuffix(serverURL, "/")
+
+	// The install image must match the running release, so its /usr partition can be found.
+	_, version, err := systemd.GetCurrentRelease(ctx)
+	if err != nil {
+		return err
+	}
+
+	if version == "" {
+		return errors.New("unable to determine the running release")
+	}
+
+	client := &http.Client{}
+
+	file, err := getInstallImageFile(ctx, client, serverURL, version)
+	if err != nil {
+		return err
+	}
+
+	imageURL := serverURL + "/" + version + "/" + file.Filename
+
+	// Store the image in memory.
+	err = os.MkdirAll(storage.NetworkInstallPath, 0o700)
+	if err != nil {
+		return err
+	}
+
+	err = unix.Mount("tmpfs", storage.NetworkInstallPath, "tmpfs", 0, "mode=0700,size=90%")
+	if err != nil {
+		return err
+	}
+
+	imagePath := filepath.Join(storage.NetworkInstallPath, "install.img")
+
+	err = downloadInstallImage(ctx, client, imageURL, file.Sha256, imagePath)
+	if err != nil {
+		_ = unix.Unmount(storage.NetworkInstallPath, 0)
+
+		return fmt.Errorf("while downloading %s, got error '%s'", imageURL, err.Error())
+	}
+
+	err = os.WriteFile(filepath.Join(storage.NetworkInstallPath, "source"), []byte(imageURL+"\n"), 0o600)
+	if err != nil {
+		return err
+	}
+
+	// Attach the image, udev will then pick up its partitions.
+	_, err = subprocess.RunCommandContext(ctx, "losetup", "--find", "--read-only", "--partscan", imagePath)
+
+	return err
+}
+
+// getInstallImageFile returns the raw install image for the given release and the local architecture
+// from the server's signed index.
+func getInstallImageFile(ctx context.Context, client *http.Client, serverURL string, version string) (*apiupdate.UpdateFile, error) {
+	archName, err := osarch.ArchitectureGetLocal()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, serverURL+"/index.sjson", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("server failed to return expected file")
+	}
+
+	bodyContents, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the signed index.
+	embeddedCerts, err := certs.GetEmbeddedCertificates()
+	if err != nil {
+		return nil, err
+	}
+
+	verified, err := util.VerifySMIME(ctx, []*x509.Certificate{embeddedCerts.UpdateCACertificate}, bodyContents)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &apiupdate.Index{}
+
+	err = json.NewDecoder(bytes.NewReader(verified.Bytes())).Decode(index)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, update := range index.Updates {
+		if update.Version != version {
+			continue
+		}
+
+		for _, file := range update.Files {
+			if file.Component != apiupdate.UpdateFileComponentOS || file.Type != apiupdate.UpdateFileTypeImageRaw {
+				continue
+			}
+
+			if file.Architecture != "" && string(file.Architecture) != archName {
+				continue
+			}
+
+			return &file, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no install image found for release %s", version)
+}
+
+// downloadInstallImage decompresses the install image while downloading it, checking its hash. Blocks of
+// zeros are skipped, keeping the resulting file sparse.
+func downloadInstallImage(ctx context.Context, client *http.Client, imageURL string, expectedSHA256 string, target string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("unexpected HTTP status: " + resp.Status)
+	}
+
+	h := sha256.New()
+
+	body, err := gzip.NewReader(io.TeeReader(resp.Body, h))
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	// #nosec G304
+	fd, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	buf := make([]byte, 4*1024*1024)
+	zeros := make([]byte, len(buf))
+	size := int64(0)
+
+	for {
+		n, err := io.ReadFull(body, buf)
+		if n > 0 {
+			if bytes.Equal(buf[:n], zeros[:n]) {
+				_, err := fd.Seek(int64(n), io.SeekCurrent)
+				if err != nil {
+					return err
+				}
+			} else {
+				_, err := fd.Write(buf[:n])
+				if err != nil {
+					return err
+				}
+			}
+
+			size += int64(n)
+		}
+
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+
+			return err
+		}
+	}
+
+	// Set the final size, in case the image ends with zeros.
+	err = fd.Truncate(size)
+	if err != nil {
+		return err
+	}
+
+	// Make sure the whole compressed stream was hashed.
+	_, err = io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if expectedSHA256 != hex.EncodeToString(h.Sum(nil)) {
+		return errors.New("sha256 mismatch for install image")
+	}
+
+	return nil
+}
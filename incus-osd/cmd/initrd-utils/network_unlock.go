@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/ini.v1"
+
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+	"github.com/lxc/incus-os/incus-osd/internal/tang"
+	"github.com/lxc/incus-os/incus-osd/internal/util"
+)
+
+// networkUnlockNetworkConfig is a basic DHCP configuration used to reach the Tang servers from the initrd.
+const networkUnlockNetworkConfig = `[Match]
+Type=ether
+Kind=!*
+
+[Network]
+DHCP=yes
+`
+
+// networkUnlock answers the systemd password prompts for the root LUKS volume using the secret
+// recovered from a Tang server, if the volume is bound to one. Depending on the configured mode,
+// the secret is either a fallback passphrase or the TPM2 PIN.
+func networkUnlock() error {
+	ctx := context.Background()
+
+	token, _, err := util.GetNetworkUnlockToken(ctx, "/dev/gpt-auto-root-luks")
+	if err != nil {
+		return err
+	}
+
+	// Nothing to do if the volume isn't bound to a Tang server.
+	if token == nil {
+		return nil
+	}
+
+	// Bring up basic networking.
+	err = os.MkdirAll("/run/systemd/network", 0o755)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile("/run/systemd/network/99-network-unlock.network", []byte(networkUnlockNetworkConfig), 0o644)
+	if err != nil {
+		return err
+	}
+
+	err = systemd.StartUnit(ctx, "systemd-networkd.service")
+	if err != nil {
+		return err
+	}
+
+	var secret []byte
+
+	answered := map[string]bool{}
+
+	for {
+		// Stop once the root volume has been unlocked.
+		_, err := os.Stat("/dev/mapper/root")
+		if err == nil {
+			return nil
+		}
+
+		// Keep trying to recover the secret until the network comes up and a Tang server responds.
+		if secret == nil {
+			secret, err = tang.RecoverAny(ctx, token.Bindings)
+			if err != nil {
+				time.Sleep(2 * time.Second)
+
+				continue
+			}
+		}
+
+		prompts, err := filepath.Glob("/run/systemd/ask-password/ask.*")
+		if err != nil {
+			return err
+		}
+
+		for _, prompt := range prompts {
+			if answered[prompt] {
+				continue
+			}
+
+			answerPasswordPrompt(prompt, secret)
+
+			answered[prompt] = true
+		}
+
+		time.Sleep(1 * time.Second)
+	}
+}
+
+// answerPasswordPrompt replies to a pending cryptsetup password prompt, following systemd's password agent protocol.
+func answerPasswordPrompt(prompt string, secret []byte) {
+	cfg, err := ini.Load(prompt)
+	if err != nil {
+		return
+	}
+
+	section := cfg.Section("Ask")
+
+	if !strings.HasPrefix(section.Key("Id").String(), "cryptsetup:") {
+		return
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: section.Key("Socket").String(), Net: "unixgram"})
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	_, _ = conn.Write(append([]byte("+"), secret...))
+}
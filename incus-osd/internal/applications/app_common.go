@@ -144,6 +144,16 @@ func (*common) StartupWeight() int {
 	return -1
 }
 
+// SetServerCertificate replaces the certificate presented on the application's public port.
+// An empty certificate restores the application's own certificate.
+func (*common) SetServerCertificate(_ context.Context, certPEM string, _ string) error {
+	if certPEM == "" {
+		return nil
+	}
+
+	return ErrServerCertificateNotSupported
+}
+
 // SetVersions sets the actual and available versions for the application.
 func (a *common) SetVersions(version string, availableVersions []string) {
 	a.appState.Version = version
@@ -327,6 +327,138 @@ func (a *incus) SetFriendlyVersion(ctx context.Context) error {
 	return nil
 }
 
+// SetServerCertificate replaces the local server certificate presented on the Incus API. The
+// certificate generated by Incus is kept aside and restored once an empty certificate is provided.
+//
+// Clustered servers present the cluster certificate instead, which is shared by all members and
+// can't be replaced from a single one.
+func (a *incus) SetServerCertificate(ctx context.Context, certPEM string, keyPEM string) error {
+	certFile := "/var/lib/incus/server.crt"
+	keyFile := "/var/lib/incus/server.key"
+	origCert := certFile + ".orig"
+	origKey := keyFile + ".orig"
+
+	restore := certPEM == ""
+
+	if restore {
+		// Nothing to restore if a custom certificate was never applied.
+		_, err := os.Stat(origCert)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+
+			return err
+		}
+
+		cert, err := os.ReadFile(origCert)
+		if err != nil {
+			return err
+		}
+
+		key, err := os.ReadFile(origKey)
+		if err != nil {
+			return err
+		}
+
+		certPEM = string(cert)
+		keyPEM = string(key)
+	} else {
+		// Connect to Incus.
+		c, err := incusclient.ConnectIncusUnixWithContext(ctx, "", nil)
+		if err != nil {
+			return err
+		}
+
+		server, _, err := c.GetServer()
+		if err != nil {
+			return err
+		}
+
+		if server.Environment.ServerClustered {
+			return fmt.Errorf("%w: clustered Incus servers present the shared cluster certificate", ErrServerCertificateNotSupported)
+		}
+
+		// Keep the certificate generated by Incus the first time it gets replaced.
+		_, err = os.Stat(origCert)
+		if errors.Is(err, os.ErrNotExist) {
+			cert, err := os.ReadFile(certFile)
+			if err != nil {
+				return err
+			}
+
+			key, err := os.ReadFile(keyFile)
+			if err != nil {
+				return err
+			}
+
+			err = os.WriteFile(origKey, key, 0o600)
+			if err != nil {
+				return err
+			}
+
+			err = os.WriteFile(origCert, cert, 0o644)
+			if err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+	}
+
+	// Skip restarting Incus if the certificate is already in place, such as on startup.
+	currentCert, err := os.ReadFile(certFile)
+	if err != nil {
+		return err
+	}
+
+	if string(currentCert) != certPEM {
+		files := []struct {
+			path    string
+			content string
+			mode    os.FileMode
+		}{
+			{keyFile, keyPEM, 0o600},
+			{certFile, certPEM, 0o644},
+		}
+
+		for _, f := range files {
+			err := os.WriteFile(f.path+".new", []byte(f.content), f.mode)
+			if err != nil {
+				return err
+			}
+
+			err = os.Rename(f.path+".new", f.path)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Incus only loads its server certificate on startup.
+		if a.IsRunning(ctx) {
+			err := a.Restart(ctx)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Once restored, the certificate generated by Incus no longer needs to be kept aside.
+	if restore {
+		err := os.Remove(origCert)
+		if err != nil {
+			return err
+		}
+
+		err = os.Remove(origKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (*incus) Struct() any {
 	return &api.ApplicationIncus{}
 }
@@ -19,6 +19,9 @@ var Supported = []string{"debug", "gpu-support", incusVersionStable, incusVersio
 // ErrNoPrimary is returned when the system doesn't yet have a primary application.
 var ErrNoPrimary = errors.New("no primary application")
 
+// ErrServerCertificateNotSupported is returned when the application can't use a custom server certificate.
+var ErrServerCertificateNotSupported = errors.New("application doesn't support a custom server certificate")
+
 // Load retrieves and returns the application specific logic.
 func Load(_ context.Context, s *state.State, name string) (Application, error) {
 	var app Application
@@ -36,6 +36,7 @@ type Application interface { //nolint:interfacebloat
 	Restart(ctx context.Context) error
 	RestoreBackup(archive io.Reader) error
 	SetFriendlyVersion(ctx context.Context) error
+	SetServerCertificate(ctx context.Context, certPEM string, keyPEM string) error
 	SetVersions(version string, availableVersions []string)
 	Struct() any
 	Start(ctx context.Context) error
@@ -0,0 +1,273 @@
+package auth
+
+import (
+	"context"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/lxc/incus/v7/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+const (
+	ctxEndorsementKeyPath = "/run/incus-os/tpm-ek.ctx"
+	ctxAttestationKeyPath = "/run/incus-os/tpm-ak.ctx"
+
+	endorsementPublicPath = "/run/incus-os/tpm-ek.pub"
+
+	attestationPrivatePath = "/var/lib/incus-os/tpm-ak.priv"
+	attestationPublicPath  = "/var/lib/incus-os/tpm-ak.pub"
+
+	// NV index holding the certificate of the RSA 2048 endorsement key, as defined by the TCG.
+	endorsementCertificateIndex = "0x01c00002"
+
+	// Magic value and version of the credential files used by tpm2-tools.
+	credentialMagic   = 0xbadcc0de
+	credentialVersion = 1
+)
+
+// legacyAttestationPaths lists the files of the attestation key previously created under the
+// storage hierarchy, along with its cached certification.
+var legacyAttestationPaths = []string{
+	"/var/lib/incus-os/tpm-attest.priv",
+	"/var/lib/incus-os/tpm-attest.pub",
+	"/var/lib/incus-os/tpm-attest.pem",
+	"/var/lib/incus-os/tpm-attest.certify",
+	"/var/lib/incus-os/tpm-attest.certify.sig",
+}
+
+func ensureEndorsementKey(ctx context.Context) error {
+	// The endorsement key is derived from the endorsement seed, so is the same every time it's created.
+	_, err := os.Stat(ctxEndorsementKeyPath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		_, err = subprocess.RunCommandContext(ctx, "tpm2_createek", "-c", ctxEndorsementKeyPath, "-G", "rsa", "-u", endorsementPublicPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// withEndorsementSession runs the provided function with a policy session satisfying the endorsement
+// hierarchy's authorization, as required to use the endorsement key.
+func withEndorsementSession(ctx context.Context, f func(session string) error) error {
+	tmpDir, err := os.MkdirTemp("", "incus-os-session")
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(tmpDir)
+
+	sessionPath := filepath.Join(tmpDir, "session.ctx")
+
+	_, err = subprocess.RunCommandContext(ctx, "tpm2_startauthsession", "--policy-session", "-S", sessionPath)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _, _ = subprocess.RunCommandContext(context.Background(), "tpm2_flushcontext", sessionPath) }()
+
+	_, err = subprocess.RunCommandContext(ctx, "tpm2_policysecret", "-S", sessionPath, "-c", "e")
+	if err != nil {
+		return err
+	}
+
+	return f("session:" + sessionPath)
+}
+
+func ensureAttestationKey(ctx context.Context) error {
+	err := ensureEndorsementKey(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Remove the previous attestation key, which wasn't tied to the endorsement key.
+	for _, path := range legacyAttestationPaths {
+		err := os.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	// Check for an attestation key.
+	_, err = os.Stat(attestationPrivatePath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		// Create a new restricted signing key under the endorsement key, which the TPM only allows to
+		// sign structures it generated itself.
+		_, err = subprocess.RunCommandContext(ctx, "tpm2_createak", "-C", ctxEndorsementKeyPath, "-c", ctxAttestationKeyPath, "-G", "ecc", "-g", "sha256", "-s", "ecdsa", "-u", attestationPublicPath, "-r", attestationPrivatePath)
+
+		return err
+	}
+
+	// Load the key.
+	return withEndorsementSession(ctx, func(session string) error {
+		_, err := subprocess.RunCommandContext(ctx, "tpm2_load", "-C", ctxEndorsementKeyPath, "-P", session, "-u", attestationPublicPath, "-r", attestationPrivatePath, "-c", ctxAttestationKeyPath)
+
+		return err
+	})
+}
+
+// getEndorsementCertificate returns the endorsement key certificate provisioned in the TPM by its manufacturer.
+func getEndorsementCertificate(ctx context.Context) ([]byte, error) {
+	tmpDir, err := os.MkdirTemp("", "incus-os-ek")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.RemoveAll(tmpDir)
+
+	certPath := filepath.Join(tmpDir, "ek.crt")
+
+	_, err = subprocess.RunCommandContext(ctx, "tpm2_nvread", "-C", "o", "-o", certPath, endorsementCertificateIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// The NV index may be padded past the end of the certificate.
+	var cert asn1.RawValue
+
+	_, err = asn1.Unmarshal(content, &cert)
+	if err != nil {
+		return nil, err
+	}
+
+	return cert.FullBytes, nil
+}
+
+// Attest generates a TPM quote over PCRs 0-15, qualified by the provided nonce and signed by
+// the system's attestation key. The event log isn't included in the returned attestation.
+func Attest(ctx context.Context, nonce []byte) (*api.SystemSecurityAttestation, error) {
+	err := ensureAttestationKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	attestationKey, err := os.ReadFile(attestationPublicPath)
+	if err != nil {
+		return nil, err
+	}
+
+	endorsementKey, err := os.ReadFile(endorsementPublicPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Not all TPMs come with an endorsement key certificate, in which case the attestation can't be verified.
+	endorsementCertificate, _ := getEndorsementCertificate(ctx)
+
+	tmpDir, err := os.MkdirTemp("", "incus-os-attest")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.RemoveAll(tmpDir)
+
+	quotePath := filepath.Join(tmpDir, "quote.msg")
+	signaturePath := filepath.Join(tmpDir, "quote.sig")
+	pcrsPath := filepath.Join(tmpDir, "quote.pcrs")
+
+	_, err = subprocess.RunCommandContext(ctx, "tpm2_quote", "-c", ctxAttestationKeyPath, "-l", "sha256:0,1,2,3,4,5,6,7,8,9,10,11,12,13,14,15", "-q", hex.EncodeToString(nonce), "-g", "sha256", "-m", quotePath, "-s", signaturePath, "-f", "plain", "-o", pcrsPath, "-F", "values")
+	if err != nil {
+		return nil, err
+	}
+
+	quote, err := os.ReadFile(quotePath)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := os.ReadFile(signaturePath)
+	if err != nil {
+		return nil, err
+	}
+
+	rawPCRs, err := os.ReadFile(pcrsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rawPCRs) != api.SystemSecurityAttestationPCRCount*32 {
+		return nil, errors.New("unexpected length of quoted PCR values")
+	}
+
+	pcrs := make([][]byte, 0, api.SystemSecurityAttestationPCRCount)
+	for i := range api.SystemSecurityAttestationPCRCount {
+		pcrs = append(pcrs, rawPCRs[i*32:(i+1)*32])
+	}
+
+	return &api.SystemSecurityAttestation{
+		Quote:                  quote,
+		Signature:              signature,
+		PCRs:                   pcrs,
+		AttestationKey:         attestationKey,
+		EndorsementKey:         endorsementKey,
+		EndorsementCertificate: endorsementCertificate,
+	}, nil
+}
+
+// ActivateCredential recovers the secret protected by a challenge generated for the system's endorsement
+// and attestation keys, proving to the verifier that both keys live in the same TPM.
+func ActivateCredential(ctx context.Context, challenge api.SystemSecurityAttestationChallenge) ([]byte, error) {
+	err := ensureAttestationKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "incus-os-credential")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.RemoveAll(tmpDir)
+
+	credentialPath := filepath.Join(tmpDir, "credential")
+	secretPath := filepath.Join(tmpDir, "secret")
+
+	// Write the challenge in the format expected by tpm2-tools.
+	credential := binary.BigEndian.AppendUint32(nil, credentialMagic)
+	credential = binary.BigEndian.AppendUint32(credential, credentialVersion)
+
+	for _, field := range [][]byte{challenge.Credential, challenge.EncryptedSecret} {
+		if len(field) == 0 || len(field) > 0xffff {
+			return nil, errors.New("invalid credential challenge")
+		}
+
+		credential = binary.BigEndian.AppendUint16(credential, uint16(len(field)))
+		credential = append(credential, field...)
+	}
+
+	err = os.WriteFile(credentialPath, credential, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	err = withEndorsementSession(ctx, func(session string) error {
+		_, err := subprocess.RunCommandContext(ctx, "tpm2_activatecredential", "-c", ctxAttestationKeyPath, "-C", ctxEndorsementKeyPath, "-P", session, "-i", credentialPath, "-o", secretPath)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return os.ReadFile(secretPath)
+}
@@ -30,7 +30,7 @@ const (
 	PEMPath = "/var/lib/incus-os/tpm-auth.pem"
 )
 
-func ensureSigningKey(ctx context.Context) error {
+func ensurePrimary(ctx context.Context) error {
 	// Setup a primary context if missing.
 	_, err := os.Stat(ctxPrimaryPath)
 	if err != nil {
@@ -44,6 +44,15 @@ func ensureSigningKey(ctx context.Context) error {
 		}
 	}
 
+	return nil
+}
+
+func ensureSigningKey(ctx context.Context) error {
+	err := ensurePrimary(ctx)
+	if err != nil {
+		return err
+	}
+
 	// Check of a signing key.
 	_, err = os.Stat(privatePath)
 	if err != nil {
@@ -15,6 +15,7 @@ import (
 
 	"github.com/lxc/incus-os/incus-osd/api"
 	"github.com/lxc/incus-os/incus-osd/internal/applications"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
 	"github.com/lxc/incus-os/incus-osd/internal/secureboot"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
 	"github.com/lxc/incus-os/incus-osd/internal/systemd"
@@ -73,6 +74,11 @@ func GetOSBackup() ([]byte, error) {
 			return nil, errors.New("backup cannot contain directories")
 		}
 
+		// The secrets key is sealed to the local TPM, so is useless on any other system.
+		if isSecretsKey(file.Name()) {
+			continue
+		}
+
 		err := writeFile(file)
 		if err != nil {
 			return nil, err
@@ -215,6 +221,11 @@ func ApplyOSBackup(ctx context.Context, s *state.State, buf io.Reader, skipOptio
 			continue
 		}
 
+		// Never restore a secrets key, always keep the local one.
+		if isSecretsKey(filename) {
+			continue
+		}
+
 		// Write file to disk.
 		err = writeFile("/var/lib/incus-os/"+filename, tr)
 		if err != nil {
@@ -222,6 +233,13 @@ func ApplyOSBackup(ctx context.Context, s *state.State, buf io.Reader, skipOptio
 		}
 	}
 
+	// Keep the existing secrets key. Secrets from a backup of another system can't be decrypted
+	// with it and will be dropped, requiring them to be provided again.
+	err = copyFile("/var/lib/incus-os.bak/"+filepath.Base(secrets.KeyPath), secrets.KeyPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
 	// Process the new state and make necessary adjustments to the system
 	// so the actual system state matches.
 	err = processNewState(ctx, s, skipOptions)
@@ -242,6 +260,11 @@ func ApplyOSBackup(ctx context.Context, s *state.State, buf io.Reader, skipOptio
 	return systemd.SystemReboot(ctx)
 }
 
+// isSecretsKey returns whether the file is the sealed secrets key.
+func isSecretsKey(filename string) bool {
+	return filename == filepath.Base(secrets.KeyPath)
+}
+
 func processNewState(ctx context.Context, s *state.State, skipOptions []string) error {
 	newState, err := state.LoadOrCreate("/var/lib/incus-os/state.txt")
 	if err != nil {
@@ -348,6 +371,15 @@ func processNewState(ctx context.Context, s *state.State, skipOptions []string)
 			return err
 		}
 
+		// Wiping all password slots would also remove the network-bound unlock passphrase, so
+		// remove the existing Tang bindings beforehand and apply the restored ones once done.
+		networkUnlock := newState.System.Security.Config.NetworkUnlock
+
+		err = systemd.ConfigureNetworkUnlock(ctx, s, nil)
+		if err != nil {
+			return err
+		}
+
 		for name, volume := range luksVolumes {
 			err := systemd.WipeAllRecoveryKeys(ctx, name, volume)
 			if err != nil {
@@ -364,6 +396,13 @@ func processNewState(ctx context.Context, s *state.State, skipOptions []string)
 				return err
 			}
 		}
+
+		if networkUnlock != nil {
+			err := systemd.ConfigureNetworkUnlock(ctx, s, networkUnlock)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	// Update the hostname.
@@ -0,0 +1,366 @@
+package certificate
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+const accountKeyPath = "/var/lib/incus-os/acme-account.key"
+
+// issueTimeout bounds the time spent on a single certificate request, including challenge validation.
+const issueTimeout = 5 * time.Minute
+
+// httpClient is used for requests to DNS webhooks.
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// issue requests a new certificate from the ACME server and installs it.
+func issue(ctx context.Context, config *api.SystemCertificateACME) error {
+	ctx, cancel := context.WithTimeout(ctx, issueTimeout)
+	defer cancel()
+
+	accountKey, err := getAccountKey()
+	if err != nil {
+		return err
+	}
+
+	directory := config.Directory
+	if directory == "" {
+		directory = api.ACMEDefaultDirectory
+	}
+
+	client := &acme.Client{
+		Key:          accountKey,
+		DirectoryURL: directory,
+		UserAgent:    "incus-osd",
+	}
+
+	// Register the account, which is a no-op if it already exists.
+	account := &acme.Account{}
+	if config.Email != "" {
+		account.Contact = []string{"mailto:" + config.Email}
+	}
+
+	_, err = client.Register(ctx, account, acme.AcceptTOS)
+	if err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return fmt.Errorf("failed to register ACME account: %w", err)
+	}
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(config.Domains...))
+	if err != nil {
+		return fmt.Errorf("failed to create ACME order: %w", err)
+	}
+
+	// Setup the challenge responder.
+	var responder challengeResponder
+
+	switch config.Challenge {
+	case api.ACMEChallengeHTTP01:
+		httpResponder, err := newHTTPResponder(ctx, config.HTTPListenAddress)
+		if err != nil {
+			return err
+		}
+
+		defer httpResponder.Close()
+
+		responder = httpResponder
+	case api.ACMEChallengeDNS01:
+		responder = &dnsResponder{webhook: config.DNSWebhook}
+	default:
+		return fmt.Errorf("unsupported ACME challenge '%s'", config.Challenge)
+	}
+
+	// Complete each pending authorization.
+	for _, authzURL := range order.AuthzURLs {
+		err := authorize(ctx, client, authzURL, string(config.Challenge), responder)
+		if err != nil {
+			return err
+		}
+	}
+
+	order, err = client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return fmt.Errorf("failed to wait for ACME order: %w", err)
+	}
+
+	// Generate a new key and request the certificate.
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: config.Domains[0]},
+		DNSNames: config.Domains,
+	}, key)
+	if err != nil {
+		return err
+	}
+
+	chain, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve ACME certificate: %w", err)
+	}
+
+	var certPEM bytes.Buffer
+
+	for _, der := range chain {
+		err = pem.Encode(&certPEM, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+		if err != nil {
+			return err
+		}
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+
+	_, err = ParseKeyPair(certPEM.Bytes(), keyPEM)
+	if err != nil {
+		return fmt.Errorf("invalid ACME certificate: %w", err)
+	}
+
+	slog.InfoContext(ctx, "Issued new server certificate", "domains", strings.Join(config.Domains, ", "))
+
+	return install(certPEM.Bytes(), keyPEM)
+}
+
+// authorize completes a single ACME authorization using the requested challenge type.
+func authorize(ctx context.Context, client *acme.Client, authzURL string, challengeType string, responder challengeResponder) error {
+	authz, err := client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return err
+	}
+
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	var challenge *acme.Challenge
+
+	for _, c := range authz.Challenges {
+		if c.Type == challengeType {
+			challenge = c
+
+			break
+		}
+	}
+
+	if challenge == nil {
+		return fmt.Errorf("ACME server doesn't offer the %s challenge for '%s'", challengeType, authz.Identifier.Value)
+	}
+
+	cleanup, err := responder.Present(ctx, client, authz.Identifier.Value, challenge.Token)
+	if err != nil {
+		return fmt.Errorf("failed to prepare %s challenge for '%s': %w", challengeType, authz.Identifier.Value, err)
+	}
+
+	defer cleanup()
+
+	_, err = client.Accept(ctx, challenge)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.WaitAuthorization(ctx, authz.URI)
+	if err != nil {
+		return fmt.Errorf("failed to validate '%s': %w", authz.Identifier.Value, err)
+	}
+
+	return nil
+}
+
+// getAccountKey returns the ACME account key, generating it if needed.
+func getAccountKey() (crypto.Signer, error) {
+	content, err := os.ReadFile(accountKeyPath)
+	if err == nil {
+		block, _ := pem.Decode(content)
+		if block == nil {
+			return nil, errors.New("failed to decode ACME account key")
+		}
+
+		return x509.ParseECPrivateKey(block.Bytes)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.WriteFile(accountKeyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// challengeResponder prepares the response to an ACME challenge, returning a function to clean it up.
+type challengeResponder interface {
+	Present(ctx context.Context, client *acme.Client, domain string, token string) (func(), error)
+}
+
+// httpResponder answers http-01 challenges from a temporary HTTP server.
+type httpResponder struct {
+	server *http.Server
+
+	mu        sync.Mutex
+	responses map[string]string
+}
+
+func newHTTPResponder(ctx context.Context, listenAddress string) (*httpResponder, error) {
+	if listenAddress == "" {
+		listenAddress = ":80"
+	}
+
+	listenConfig := net.ListenConfig{}
+
+	listener, err := listenConfig.Listen(ctx, "tcp", listenAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &httpResponder{
+		responses: map[string]string{},
+	}
+
+	r.server = &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			r.mu.Lock()
+			response, ok := r.responses[req.URL.Path]
+			r.mu.Unlock()
+
+			if !ok {
+				http.NotFound(w, req)
+
+				return
+			}
+
+			_, _ = w.Write([]byte(response))
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		_ = r.server.Serve(listener)
+	}()
+
+	return r, nil
+}
+
+// Present makes the challenge response available over HTTP.
+func (r *httpResponder) Present(_ context.Context, client *acme.Client, _ string, token string) (func(), error) {
+	response, err := client.HTTP01ChallengeResponse(token)
+	if err != nil {
+		return nil, err
+	}
+
+	path := client.HTTP01ChallengePath(token)
+
+	r.mu.Lock()
+	r.responses[path] = response
+	r.mu.Unlock()
+
+	return func() {
+		r.mu.Lock()
+		delete(r.responses, path)
+		r.mu.Unlock()
+	}, nil
+}
+
+// Close stops the temporary HTTP server.
+func (r *httpResponder) Close() {
+	_ = r.server.Close()
+}
+
+// dnsResponder answers dns-01 challenges by having a webhook create the TXT record.
+type dnsResponder struct {
+	webhook *api.SystemCertificateACMEDNSWebhook
+}
+
+// Present creates the TXT record through the webhook.
+func (r *dnsResponder) Present(ctx context.Context, client *acme.Client, domain string, token string) (func(), error) {
+	value, err := client.DNS01ChallengeRecord(token)
+	if err != nil {
+		return nil, err
+	}
+
+	fqdn := "_acme-challenge." + strings.TrimPrefix(domain, "*.") + "."
+
+	err = r.call(ctx, "present", fqdn, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		// Use a fresh context so the record is removed even if the request timed out.
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		err := r.call(cleanupCtx, "cleanup", fqdn, value)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to clean up ACME DNS record", "fqdn", fqdn, "err", err)
+		}
+	}, nil
+}
+
+func (r *dnsResponder) call(ctx context.Context, action string, fqdn string, value string) error {
+	body, err := json.Marshal(map[string]string{"fqdn": fqdn, "value": value})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(r.webhook.URL, "/")+"/"+action, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if r.webhook.Username != "" || r.webhook.Password != "" {
+		req.SetBasicAuth(r.webhook.Username, r.webhook.Password)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("DNS webhook returned %s", resp.Status)
+	}
+
+	return nil
+}
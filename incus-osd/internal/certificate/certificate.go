@@ -0,0 +1,321 @@
+package certificate
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/applications"
+	"github.com/lxc/incus-os/incus-osd/internal/scheduling"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+const (
+	certPath = "/var/lib/incus-os/server.crt"
+	keyPath  = "/var/lib/incus-os/server.key"
+
+	// RenewalJob represents the job to renew ACME issued certificates.
+	RenewalJob scheduling.JobName = "certificate_renewal"
+
+	// RenewalSchedule runs the renewal job daily.
+	RenewalSchedule = "0 3 * * *"
+)
+
+// ErrInvalidCertificate is returned when the provided certificate or key can't be used.
+var ErrInvalidCertificate = errors.New("invalid certificate")
+
+// ParseKeyPair parses a PEM-encoded certificate and key, checking that they match and that the certificate is usable for a TLS server.
+func ParseKeyPair(certPEM []byte, keyPEM []byte) (*tls.Certificate, error) {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(cert.Leaf.NotAfter) {
+		return nil, errors.New("certificate has expired")
+	}
+
+	isServer := func(usage x509.ExtKeyUsage) bool {
+		return usage == x509.ExtKeyUsageServerAuth || usage == x509.ExtKeyUsageAny
+	}
+
+	if len(cert.Leaf.ExtKeyUsage) > 0 && !slices.ContainsFunc(cert.Leaf.ExtKeyUsage, isServer) {
+		return nil, errors.New("certificate can't be used for server authentication")
+	}
+
+	return &cert, nil
+}
+
+// NeedsRenewal returns whether the certificate is in the last third of its lifetime.
+func NeedsRenewal(cert *x509.Certificate, now time.Time) bool {
+	lifetime := cert.NotAfter.Sub(cert.NotBefore)
+
+	return now.After(cert.NotAfter.Add(-lifetime / 3))
+}
+
+// Get returns the installed server certificate, or os.ErrNotExist if the primary application's certificate should be used.
+func Get() (*tls.Certificate, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cert, nil
+}
+
+// GetState returns information about the server certificate currently in use.
+func GetState(s *state.State, fallback *tls.Certificate) api.SystemCertificateState {
+	ret := api.SystemCertificateState{
+		Source:       api.CertificateSourceApplication,
+		RenewalError: s.System.Certificate.State.RenewalError,
+	}
+
+	cert, err := Get()
+	if err == nil {
+		if s.System.Certificate.Config.ACME != nil {
+			ret.Source = api.CertificateSourceACME
+		} else {
+			ret.Source = api.CertificateSourceCustom
+		}
+	} else {
+		cert = fallback
+	}
+
+	if cert == nil || cert.Leaf == nil {
+		return ret
+	}
+
+	fp := sha256.Sum256(cert.Leaf.Raw)
+
+	ret.Fingerprint = hex.EncodeToString(fp[:])
+	ret.Subject = cert.Leaf.Subject.String()
+	ret.Issuer = cert.Leaf.Issuer.String()
+	ret.DNSNames = cert.Leaf.DNSNames
+	ret.NotAfter = cert.Leaf.NotAfter
+
+	return ret
+}
+
+// Apply validates and applies a new server certificate configuration.
+//
+// A new certificate is requested from the ACME server if the ACME configuration changed, or if no
+// certificate was issued yet. When a custom certificate is provided without a key, the current key
+// is kept as long as the certificate itself is unchanged.
+func Apply(ctx context.Context, s *state.State, config api.SystemCertificateConfig) error {
+	err := config.Validate()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidCertificate, err)
+	}
+
+	current := s.System.Certificate.Config
+
+	// Keep the installed certificate around in case the primary application rejects the new one.
+	oldCertPEM, oldKeyPEM, err := read()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case config.ACME != nil:
+		_, err := Get()
+		if err != nil || current.ACME == nil || !reflect.DeepEqual(*current.ACME, *config.ACME) {
+			err := issue(ctx, config.ACME)
+			if err != nil {
+				return err
+			}
+		}
+
+	case config.Certificate != "":
+		keyPEM := []byte(config.Key)
+
+		if config.Key == "" {
+			if current.ACME != nil || config.Certificate != current.Certificate {
+				return fmt.Errorf("%w: a key must be provided along with a new certificate", ErrInvalidCertificate)
+			}
+
+			keyPEM, err = os.ReadFile(keyPath)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = ParseKeyPair([]byte(config.Certificate), keyPEM)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidCertificate, err)
+		}
+
+		err = install([]byte(config.Certificate), keyPEM)
+		if err != nil {
+			return err
+		}
+
+	default:
+		err := remove()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = ApplyToPrimary(ctx, s)
+	if err != nil {
+		restoreErr := remove()
+		if restoreErr == nil && oldCertPEM != nil {
+			restoreErr = install(oldCertPEM, oldKeyPEM)
+		}
+
+		if restoreErr == nil {
+			restoreErr = ApplyToPrimary(ctx, s)
+		}
+
+		if restoreErr != nil {
+			return fmt.Errorf("%w (restore failed: %w)", err, restoreErr)
+		}
+
+		if errors.Is(err, applications.ErrServerCertificateNotSupported) {
+			return fmt.Errorf("%w: %w", ErrInvalidCertificate, err)
+		}
+
+		return err
+	}
+
+	// The key is only ever stored on disk.
+	config.Key = ""
+
+	s.System.Certificate.Config = config
+	s.System.Certificate.State.RenewalError = ""
+
+	triggerUpdate(s)
+
+	return nil
+}
+
+// Renew requests a new certificate from the ACME server if the current one is due for renewal.
+func Renew(ctx context.Context, s *state.State) error {
+	config := s.System.Certificate.Config.ACME
+	if config == nil {
+		return nil
+	}
+
+	cert, err := Get()
+	if err == nil && !NeedsRenewal(cert.Leaf, time.Now()) {
+		return nil
+	}
+
+	err = issue(ctx, config)
+	if err != nil {
+		s.System.Certificate.State.RenewalError = err.Error()
+		_ = s.Save()
+
+		return err
+	}
+
+	err = ApplyToPrimary(ctx, s)
+	if err != nil {
+		s.System.Certificate.State.RenewalError = err.Error()
+		_ = s.Save()
+
+		return err
+	}
+
+	s.System.Certificate.State.RenewalError = ""
+	_ = s.Save()
+
+	triggerUpdate(s)
+
+	return nil
+}
+
+// ApplyToPrimary presents the installed server certificate on the primary application's listener,
+// or restores the application's own certificate if none is installed.
+func ApplyToPrimary(ctx context.Context, s *state.State) error {
+	certPEM, keyPEM, err := read()
+	if err != nil {
+		return err
+	}
+
+	app, err := applications.GetPrimary(ctx, s, true)
+	if err != nil {
+		if errors.Is(err, applications.ErrNoPrimary) {
+			return nil
+		}
+
+		return err
+	}
+
+	return app.SetServerCertificate(ctx, string(certPEM), string(keyPEM))
+}
+
+// RenewJob returns the scheduled job function for certificate renewal.
+func RenewJob(s *state.State) scheduling.JobFunc {
+	return func(ctx context.Context) error {
+		return Renew(ctx, s)
+	}
+}
+
+// read returns the installed certificate and key, or nil if none is installed.
+func read() ([]byte, []byte, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, nil
+		}
+
+		return nil, nil, err
+	}
+
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return certPEM, keyPEM, nil
+}
+
+// install writes the certificate and key to disk.
+func install(certPEM []byte, keyPEM []byte) error {
+	err := os.WriteFile(keyPath, keyPEM, 0o600)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(certPath, certPEM, 0o644)
+}
+
+// remove deletes any installed certificate and key.
+func remove() error {
+	for _, path := range []string{certPath, keyPath} {
+		err := os.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// triggerUpdate notifies the daemon that the HTTPS listener should reload its certificate.
+func triggerUpdate(s *state.State) {
+	select {
+	case s.TriggerCertificateUpdate <- true:
+	default:
+	}
+}
@@ -0,0 +1,89 @@
+package certificate_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/internal/certificate"
+)
+
+// newKeyPair returns a PEM-encoded self-signed certificate and key.
+func newKeyPair(t *testing.T, notBefore time.Time, notAfter time.Time, usage []x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "incus-os.example.com"},
+		DNSNames:     []string{"incus-os.example.com"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		ExtKeyUsage:  usage,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestParseKeyPair(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	// Valid server certificate.
+	certPEM, keyPEM := newKeyPair(t, now.Add(-time.Hour), now.Add(time.Hour), []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth})
+	cert, err := certificate.ParseKeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	require.Equal(t, []string{"incus-os.example.com"}, cert.Leaf.DNSNames)
+
+	// No extended key usage.
+	certPEM, keyPEM = newKeyPair(t, now.Add(-time.Hour), now.Add(time.Hour), nil)
+	_, err = certificate.ParseKeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	// Mismatched key.
+	_, otherKeyPEM := newKeyPair(t, now.Add(-time.Hour), now.Add(time.Hour), nil)
+	_, err = certificate.ParseKeyPair(certPEM, otherKeyPEM)
+	require.Error(t, err)
+
+	// Expired certificate.
+	certPEM, keyPEM = newKeyPair(t, now.Add(-2*time.Hour), now.Add(-time.Hour), nil)
+	_, err = certificate.ParseKeyPair(certPEM, keyPEM)
+	require.ErrorContains(t, err, "expired")
+
+	// Client-only certificate.
+	certPEM, keyPEM = newKeyPair(t, now.Add(-time.Hour), now.Add(time.Hour), []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth})
+	_, err = certificate.ParseKeyPair(certPEM, keyPEM)
+	require.ErrorContains(t, err, "server authentication")
+}
+
+func TestNeedsRenewal(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cert := &x509.Certificate{
+		NotBefore: start,
+		NotAfter:  start.Add(90 * 24 * time.Hour),
+	}
+
+	require.False(t, certificate.NeedsRenewal(cert, start.Add(24*time.Hour)))
+	require.False(t, certificate.NeedsRenewal(cert, start.Add(59*24*time.Hour)))
+	require.True(t, certificate.NeedsRenewal(cert, start.Add(61*24*time.Hour)))
+	require.True(t, certificate.NeedsRenewal(cert, start.Add(91*24*time.Hour)))
+}
@@ -0,0 +1,2 @@
+// Package certificate manages the server certificate used by the HTTPS listener, either provided by the user or issued through ACME.
+package certificate
@@ -0,0 +1,2 @@
+// Package history records configuration changes as numbered revisions, allowing them to be reverted.
+package history
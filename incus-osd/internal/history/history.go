@@ -0,0 +1,212 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// MaxRevisions is the number of revisions kept, after which the oldest ones are dropped.
+const MaxRevisions = 50
+
+// Sections whose changes are recorded, named after their API path.
+const (
+	SectionKernel  = "system/kernel"
+	SectionLogging = "system/logging"
+	SectionNetwork = "system/network"
+	SectionStorage = "system/storage"
+	SectionUpdate  = "system/update"
+)
+
+// ErrRevisionNotFound is returned when the requested revision doesn't exist.
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// ServiceSection returns the section name for the configuration of a service.
+func ServiceSection(name string) string {
+	return "services/" + name
+}
+
+// Record adds a new revision for a configuration section change, dropping the oldest revisions
+// once more than MaxRevisions are recorded. Nothing is recorded if the configuration is unchanged.
+func Record(s *state.State, actor string, section string, prior any, current any) error {
+	diff, err := Diff(prior, current)
+	if err != nil {
+		return err
+	}
+
+	if diff == "" {
+		return nil
+	}
+
+	priorConfig, err := json.Marshal(prior)
+	if err != nil {
+		return err
+	}
+
+	revision := 1
+	if len(s.History) > 0 {
+		revision = s.History[len(s.History)-1].Revision + 1
+	}
+
+	s.History = append(s.History, state.HistoryRevision{
+		SystemHistoryRevision: api.SystemHistoryRevision{
+			Revision:  revision,
+			Timestamp: time.Now(),
+			Actor:     actor,
+			Section:   section,
+			Diff:      diff,
+		},
+		PriorConfig: string(priorConfig),
+	})
+
+	if len(s.History) > MaxRevisions {
+		s.History = slices.Clone(s.History[len(s.History)-MaxRevisions:])
+	}
+
+	return nil
+}
+
+// List returns all recorded revisions, oldest first.
+func List(s *state.State) []api.SystemHistoryRevision {
+	ret := make([]api.SystemHistoryRevision, 0, len(s.History))
+
+	for _, revision := range s.History {
+		revision.Revertible = revision.PriorConfig != ""
+
+		ret = append(ret, revision.SystemHistoryRevision)
+	}
+
+	return ret
+}
+
+// Get returns a single revision.
+func Get(s *state.State, revision int) (*state.HistoryRevision, error) {
+	for _, r := range s.History {
+		if r.Revision == revision {
+			return &r, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %d", ErrRevisionNotFound, revision)
+}
+
+// Diff returns the changes between two configurations, as one line per removed or added value
+// with any secrets replaced by their placeholder. An empty string means both are identical.
+func Diff(prior any, current any) (string, error) {
+	before, err := flatten(prior)
+	if err != nil {
+		return "", err
+	}
+
+	after, err := flatten(current)
+	if err != nil {
+		return "", err
+	}
+
+	keys := make([]string, 0, len(before)+len(after))
+
+	for key := range before {
+		keys = append(keys, key)
+	}
+
+	for key := range after {
+		_, ok := before[key]
+		if !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	var b strings.Builder
+
+	for _, key := range keys {
+		beforeValue, inBefore := before[key]
+		afterValue, inAfter := after[key]
+
+		if inBefore && inAfter && beforeValue == afterValue {
+			continue
+		}
+
+		if inBefore {
+			_, _ = fmt.Fprintf(&b, "-%s: %s\n", key, beforeValue)
+		}
+
+		if inAfter {
+			_, _ = fmt.Fprintf(&b, "+%s: %s\n", key, afterValue)
+		}
+	}
+
+	return b.String(), nil
+}
+
+// flatten returns every value of a redacted configuration, keyed by its path.
+func flatten(v any) (map[string]string, error) {
+	redacted, err := secrets.Redact(v)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := json.Marshal(redacted)
+	if err != nil {
+		return nil, err
+	}
+
+	var data any
+
+	err = json.Unmarshal(content, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := map[string]string{}
+
+	err = flattenHelper(ret, "", data)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func flattenHelper(ret map[string]string, key string, v any) error {
+	switch value := v.(type) {
+	case nil:
+	case map[string]any:
+		for k, child := range value {
+			childKey := k
+			if key != "" {
+				childKey = key + "." + k
+			}
+
+			err := flattenHelper(ret, childKey, child)
+			if err != nil {
+				return err
+			}
+		}
+	case []any:
+		for i, child := range value {
+			err := flattenHelper(ret, fmt.Sprintf("%s[%d]", key, i), child)
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		content, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+
+		ret[key] = string(content)
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package history_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/history"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	prior := api.SystemKernelConfig{
+		BlacklistModules: []string{"nouveau"},
+	}
+
+	current := api.SystemKernelConfig{
+		BlacklistModules: []string{"nouveau", "radeon"},
+	}
+
+	diff, err := history.Diff(prior, current)
+	require.NoError(t, err)
+	require.Equal(t, "+blacklist_modules[1]: \"radeon\"\n", diff)
+
+	diff, err = history.Diff(current, current)
+	require.NoError(t, err)
+	require.Empty(t, diff)
+
+	// Secrets don't show up in the diff.
+	diff, err = history.Diff(api.ServiceTailscaleConfig{AuthKey: "tskey-old"}, api.ServiceTailscaleConfig{AuthKey: "tskey-new"})
+	require.NoError(t, err)
+	require.Contains(t, diff, "-auth_key: ")
+	require.Contains(t, diff, "+auth_key: ")
+	require.NotContains(t, diff, "tskey")
+}
+
+func TestRecord(t *testing.T) {
+	t.Parallel()
+
+	s := &state.State{}
+
+	// Unchanged configurations aren't recorded.
+	err := history.Record(s, "unix socket", history.SectionKernel, api.SystemKernelConfig{}, api.SystemKernelConfig{})
+	require.NoError(t, err)
+	require.Empty(t, s.History)
+
+	for i := range history.MaxRevisions + 5 {
+		prior := api.SystemKernelConfig{BlacklistModules: []string{fmt.Sprintf("module%d", i)}}
+		current := api.SystemKernelConfig{BlacklistModules: []string{fmt.Sprintf("module%d", i+1)}}
+
+		err := history.Record(s, "unix socket", history.SectionKernel, prior, current)
+		require.NoError(t, err)
+	}
+
+	// Only the most recent revisions are kept, with their numbers preserved.
+	revisions := history.List(s)
+	require.Len(t, revisions, history.MaxRevisions)
+	require.Equal(t, 6, revisions[0].Revision)
+	require.Equal(t, history.MaxRevisions+5, revisions[len(revisions)-1].Revision)
+	require.Equal(t, history.SectionKernel, revisions[0].Section)
+	require.True(t, revisions[0].Revertible)
+
+	// The prior configuration is kept.
+	revision, err := history.Get(s, 6)
+	require.NoError(t, err)
+
+	var prior api.SystemKernelConfig
+
+	err = json.Unmarshal([]byte(revision.PriorConfig), &prior)
+	require.NoError(t, err)
+	require.Equal(t, []string{"module5"}, prior.BlacklistModules)
+
+	_, err = history.Get(s, 1)
+	require.ErrorIs(t, err, history.ErrRevisionNotFound)
+}
@@ -0,0 +1,124 @@
+package install
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lxc/incus/v7/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/internal/util"
+)
+
+// setFirstBootOption puts the firmware boot option added by "bootctl install" for the given ESP first
+// in the boot order, and selects it for the next boot. This is needed when installing from the network,
+// as the firmware would otherwise keep booting from the network.
+func setFirstBootOption(ctx context.Context, espPartition string) error {
+	output, err := subprocess.RunCommandContext(ctx, "lsblk", "-ndo", "PARTUUID", espPartition)
+	if err != nil {
+		return err
+	}
+
+	partUUID, err := uuid.Parse(strings.TrimSpace(output))
+	if err != nil {
+		return fmt.Errorf("invalid partition UUID for %q: %w", espPartition, err)
+	}
+
+	order, err := util.ReadEFIVariable("BootOrder")
+	if err != nil {
+		return err
+	}
+
+	if len(order)%2 != 0 {
+		return errors.New("invalid firmware boot order")
+	}
+
+	// Look for the boot option referencing the ESP.
+	index := -1
+
+	for i := 0; i < len(order); i += 2 {
+		option, err := util.ReadEFIVariable(fmt.Sprintf("Boot%04X", binary.LittleEndian.Uint16(order[i:i+2])))
+		if err != nil {
+			return err
+		}
+
+		if bootOptionUsesPartition(option, partUUID) {
+			index = i
+
+			break
+		}
+	}
+
+	if index == -1 {
+		return fmt.Errorf("no firmware boot option found for %q", espPartition)
+	}
+
+	entry := order[index : index+2]
+
+	if index > 0 {
+		newOrder := make([]byte, 0, len(order))
+		newOrder = append(newOrder, entry...)
+		newOrder = append(newOrder, order[:index]...)
+		newOrder = append(newOrder, order[index+2:]...)
+
+		err = util.WriteEFIVariable(ctx, "BootOrder", newOrder)
+		if err != nil {
+			return err
+		}
+	}
+
+	return util.WriteEFIVariable(ctx, "BootNext", entry)
+}
+
+// bootOptionUsesPartition returns whether an EFI load option references the GPT partition with the given UUID.
+func bootOptionUsesPartition(option []byte, partUUID uuid.UUID) bool {
+	// The load option holds its attributes and the length of its device path list, followed by its
+	// NULL-terminated UTF-16 description and the device paths.
+	if len(option) < 6 {
+		return false
+	}
+
+	pathsLength := int(binary.LittleEndian.Uint16(option[4:6]))
+
+	offset := 6
+	for offset+1 < len(option) && (option[offset] != 0 || option[offset+1] != 0) {
+		offset += 2
+	}
+
+	offset += 2
+	if offset+pathsLength > len(option) {
+		return false
+	}
+
+	// GUIDs are stored with their first three fields in little-endian order.
+	signature := make([]byte, 0, 16)
+	signature = binary.LittleEndian.AppendUint32(signature, binary.BigEndian.Uint32(partUUID[0:4]))
+	signature = binary.LittleEndian.AppendUint16(signature, binary.BigEndian.Uint16(partUUID[4:6]))
+	signature = binary.LittleEndian.AppendUint16(signature, binary.BigEndian.Uint16(partUUID[6:8]))
+	signature = append(signature, partUUID[8:]...)
+
+	paths := option[offset : offset+pathsLength]
+
+	for len(paths) >= 4 {
+		nodeType := paths[0]
+		nodeSubType := paths[1]
+
+		nodeLength := int(binary.LittleEndian.Uint16(paths[2:4]))
+		if nodeLength < 4 || nodeLength > len(paths) {
+			return false
+		}
+
+		// Hard drive media device path, using a GPT partition signature.
+		if nodeType == 0x04 && nodeSubType == 0x01 && nodeLength >= 42 && paths[41] == 0x02 && bytes.Equal(paths[24:40], signature) {
+			return true
+		}
+
+		paths = paths[nodeLength:]
+	}
+
+	return false
+}
@@ -0,0 +1,389 @@
+package install
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lxc/incus/v7/shared/osarch"
+	"github.com/lxc/incus/v7/shared/resources"
+	"github.com/lxc/incus/v7/shared/subprocess"
+
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+	"github.com/lxc/incus-os/incus-osd/internal/kernel"
+	"github.com/lxc/incus-os/incus-osd/internal/secureboot"
+	"github.com/lxc/incus-os/incus-osd/internal/seed"
+	"github.com/lxc/incus-os/incus-osd/internal/storage"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+	"github.com/lxc/incus-os/incus-osd/internal/tui"
+	"github.com/lxc/incus-os/incus-osd/internal/util"
+)
+
+// ShouldPerformCheck returns true if the install seed only requests the hardware compatibility checks.
+func ShouldPerformCheck() bool {
+	installSeed, err := seed.GetInstall()
+
+	return err == nil && installSeed.CheckOnly
+}
+
+// RunHardwareCheck runs all the hardware compatibility checks, without stopping at the first unmet requirement,
+// and saves the resulting report to the seed partition. The system is powered off once the install media is removed.
+func RunHardwareCheck(ctx context.Context, osName string, osVersion string) error {
+	t, err := tui.GetTUI(nil)
+	if err != nil {
+		return err
+	}
+
+	modal := t.AddModal(osName+" Hardware Check", "install-check")
+	slog.InfoContext(ctx, "Checking hardware compatibility")
+	modal.Update("Checking hardware compatibility.")
+
+	installSeed, err := seed.GetInstall()
+	if err != nil {
+		modal.Update("[red]Error: " + err.Error())
+
+		return err
+	}
+
+	sourceDevice, _, _, err := getSourceDevice(ctx)
+	if err != nil {
+		modal.Update("[red]Error: " + err.Error())
+
+		return err
+	}
+
+	report, err := getCheckReport(ctx, sourceDevice, installSeed)
+	if err != nil {
+		modal.Update("[red]Error: " + err.Error())
+
+		return err
+	}
+
+	report.Version = osVersion
+
+	// Send the report to the install report URL, if any.
+	newReporter(ctx, installSeed.Report).send(ctx, apiseed.InstallReportEvent{Phase: reportPhaseCheck, Message: "Hardware compatibility check complete", Check: report})
+
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		modal.Update("[red]Error: " + err.Error())
+
+		return err
+	}
+
+	summary := "This system meets all the requirements to install " + osName + "."
+	if !report.Compatible {
+		summary = "[red]This system doesn't meet the requirements to install " + osName + ":[white]\n - " + strings.Join(report.Problems, "\n - ")
+	}
+
+	if len(report.Warnings) > 0 {
+		summary += "\n[yellow]Warnings:[white]\n - " + strings.Join(report.Warnings, "\n - ")
+	}
+
+	partition, err := seed.SaveFile(ctx, seed.CheckReportFile, content)
+	if err != nil {
+		// The seed partition may not be writable, such as when booting from an ISO image, so fall back to
+		// writing the full report to the console and journal.
+		slog.WarnContext(ctx, "Unable to save the hardware compatibility report, writing it to the console instead", "err", err)
+
+		err = t.Print("Hardware compatibility report:\n" + string(content) + "\n")
+		if err != nil {
+			slog.ErrorContext(ctx, "Unable to write the hardware compatibility report to the console", "err", err)
+		}
+
+		modal.Update(summary + "\n[yellow]The report couldn't be saved to the seed partition and was written to the console instead.[white]\nRemove the install media to power off the system.")
+	} else {
+		slog.InfoContext(ctx, "Hardware compatibility report saved", "partition", partition, "file", seed.CheckReportFile, "compatible", report.Compatible)
+		modal.Update(summary + "\nReport saved as " + seed.CheckReportFile + " on " + partition + ".\nRemove the install media to power off the system.")
+	}
+
+	// Wait for the install media to be removed before powering off, so the checks aren't run again.
+	partitionPath := fmt.Sprintf("%s%s1", sourceDevice, GetPartitionPrefix(sourceDevice))
+	if sourceDevice == cdromMappedDevice {
+		partitionPath = cdromDevice
+	}
+
+	for {
+		_, err := os.Stat(partitionPath)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				break
+			}
+
+			return err
+		}
+
+		time.Sleep(1 * time.Second)
+	}
+
+	return systemd.SystemPowerOff(ctx)
+}
+
+// getCheckReport gathers the hardware details and runs all the compatibility checks.
+func getCheckReport(ctx context.Context, sourceDevice string, installSeed *apiseed.Install) (*apiseed.InstallCheckReport, error) {
+	report := &apiseed.InstallCheckReport{
+		Timestamp: time.Now().UTC(),
+		System:    getHardwareSummary(ctx),
+		TPM:       getCheckTPM(ctx),
+		Disks:     []apiseed.InstallCheckDisk{},
+		NICs:      []apiseed.InstallCheckNIC{},
+	}
+
+	var err error
+
+	report.SecureBoot, err = getCheckSecureBoot()
+	if err != nil {
+		return nil, err
+	}
+
+	report.CPU, err = getCheckCPU(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := resources.GetResources()
+	if err != nil {
+		return nil, err
+	}
+
+	report.Memory = r.Memory.Total
+	report.CPU.Threads = r.CPU.Total
+
+	if len(r.CPU.Sockets) > 0 {
+		report.CPU.Model = r.CPU.Sockets[0].Name
+	}
+
+	for _, card := range r.Network.Cards {
+		nic := apiseed.InstallCheckNIC{
+			Vendor:        card.Vendor,
+			Product:       card.Product,
+			PCIAddress:    card.PCIAddress,
+			Driver:        card.Driver,
+			DriverVersion: card.DriverVersion,
+		}
+
+		for _, port := range card.Ports {
+			nic.Ports = append(nic.Ports, port.ID)
+
+			if port.Address != "" {
+				nic.MACAddresses = append(nic.MACAddresses, port.Address)
+			}
+		}
+
+		report.NICs = append(report.NICs, nic)
+	}
+
+	// Check every disk, including those that were rejected outright.
+	targets, rejected, err := getAllDisks(ctx, sourceDevice)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, target := range targets {
+		report.Disks = append(report.Disks, checkDisk(target, installSeed))
+	}
+
+	for _, entry := range rejected {
+		report.Disks = append(report.Disks, apiseed.InstallCheckDisk{
+			ID:         entry.device.ID,
+			Device:     entry.device.KName,
+			Subsystems: entry.device.Subsystems,
+			Size:       entry.device.Size,
+			Reasons:    []string{entry.reason},
+		})
+	}
+
+	report.Problems, report.Warnings = getCheckProblems(report, installSeed)
+	report.Compatible = len(report.Problems) == 0
+
+	return report, nil
+}
+
+// getCheckTPM returns the details of the TPM, if any.
+func getCheckTPM(ctx context.Context) apiseed.InstallCheckTPM {
+	ret := apiseed.InstallCheckTPM{
+		Software: secureboot.GetSWTPMInUse(),
+	}
+
+	_, err := os.Stat("/sys/class/tpm/tpm0")
+	ret.Present = err == nil
+
+	_, err = subprocess.RunCommandContext(ctx, "tpm2_selftest")
+	ret.Working = err == nil
+
+	version, err := os.ReadFile("/sys/class/tpm/tpm0/tpm_version_major")
+	if err == nil {
+		ret.Version = strings.TrimSpace(string(version))
+	}
+
+	// Each active PCR bank is exposed as a "pcr-<algorithm>" directory.
+	entries, err := os.ReadDir("/sys/class/tpm/tpm0")
+	if err == nil {
+		for _, entry := range entries {
+			bank, found := strings.CutPrefix(entry.Name(), "pcr-")
+			if found {
+				ret.PCRBanks = append(ret.PCRBanks, bank)
+			}
+		}
+	}
+
+	return ret
+}
+
+// getCheckSecureBoot returns the Secure Boot state.
+func getCheckSecureBoot() (apiseed.InstallCheckSecureBoot, error) {
+	ret := apiseed.InstallCheckSecureBoot{}
+
+	var err error
+
+	ret.Enabled, err = secureboot.Enabled()
+	if err != nil {
+		return ret, err
+	}
+
+	ret.AuditMode, err = secureboot.InAuditMode()
+	if err != nil {
+		return ret, err
+	}
+
+	setupMode, err := util.ReadEFIVariable("SetupMode")
+	if err != nil {
+		return ret, err
+	}
+
+	ret.SetupMode = len(setupMode) > 0 && setupMode[0] == 1
+
+	return ret, nil
+}
+
+// getCheckCPU returns the CPU architecture and features.
+func getCheckCPU(ctx context.Context) (apiseed.InstallCheckCPU, error) {
+	ret := apiseed.InstallCheckCPU{}
+
+	var err error
+
+	ret.Architecture, err = osarch.ArchitectureGetLocal()
+	if err != nil {
+		return ret, err
+	}
+
+	if ret.Architecture == "x86_64" {
+		ret.Baseline = kernel.CPUBaseline(ctx)
+	}
+
+	// The feature flags are listed as "flags" on x86_64 and "Features" on aarch64.
+	cpuinfo, err := os.ReadFile("/proc/cpuinfo")
+	if err != nil {
+		return ret, err
+	}
+
+	for line := range strings.SplitSeq(string(cpuinfo), "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+
+		key = strings.TrimSpace(key)
+		if key == "flags" || key == "Features" {
+			ret.Features = strings.Fields(value)
+
+			break
+		}
+	}
+
+	_, err = os.Stat("/dev/kvm")
+	ret.Virtualization = err == nil || slices.Contains(ret.Features, "vmx") || slices.Contains(ret.Features, "svm")
+
+	return ret, nil
+}
+
+// checkDisk returns the compatibility details of a potential install target.
+func checkDisk(device storage.BlockDevices, installSeed *apiseed.Install) apiseed.InstallCheckDisk {
+	ret := apiseed.InstallCheckDisk{
+		ID:         device.ID,
+		Device:     device.KName,
+		Subsystems: device.Subsystems,
+		Size:       device.Size,
+	}
+
+	if device.Size < 50*1024*1024*1024 {
+		ret.Reasons = append(ret.Reasons, fmt.Sprintf("too small (%0.2fGiB), must be at least 50GiB", float64(device.Size)/(1024.0*1024.0*1024.0)))
+	}
+
+	if installSeed != nil && installSeed.Target != nil {
+		_, _, err := getTargetDevice([]storage.BlockDevices{device}, installSeed.Target)
+		if err != nil {
+			ret.Reasons = append(ret.Reasons, "doesn't match the install seed target selectors")
+		}
+	}
+
+	if installSeed != nil && installSeed.Layout != nil {
+		_, err := getPartitionLayout(installSeed.Layout, int64(device.Size))
+		if err != nil {
+			ret.Reasons = append(ret.Reasons, err.Error())
+		}
+	}
+
+	ret.Suitable = len(ret.Reasons) == 0
+
+	return ret
+}
+
+// getCheckProblems returns the unmet requirements, as well as those only met by running in a degraded state.
+func getCheckProblems(report *apiseed.InstallCheckReport, installSeed *apiseed.Install) ([]string, []string) {
+	problems := []string{}
+
+	// Security, using the same checks as when installing.
+	physicalTPM := report.TPM.Working && !report.TPM.Software
+
+	securityProblems, warnings := getSecurityProblems(report.SecureBoot.Enabled, report.TPM.Working, physicalTPM, installSeed)
+	for _, err := range securityProblems {
+		problems = append(problems, err.Error())
+	}
+
+	if report.SecureBoot.AuditMode {
+		warnings = append(warnings, "Secure Boot is in audit mode")
+	}
+
+	if physicalTPM && report.TPM.Version != "" && report.TPM.Version != "2" {
+		problems = append(problems, "TPM version "+report.TPM.Version+" isn't supported, a TPM 2.0 is required")
+	}
+
+	if physicalTPM && !slices.Contains(report.TPM.PCRBanks, "sha256") {
+		problems = append(problems, "TPM doesn't have an active sha256 PCR bank")
+	}
+
+	// CPU and memory.
+	if !slices.Contains([]string{"x86_64", "aarch64"}, report.CPU.Architecture) {
+		problems = append(problems, "unsupported architecture "+report.CPU.Architecture)
+	}
+
+	if slices.Contains([]string{"x86_64_v1", "x86_64_v2"}, report.CPU.Baseline) {
+		problems = append(problems, "CPU only supports "+report.CPU.Baseline+", x86_64_v3 is required")
+	}
+
+	if !report.CPU.Virtualization {
+		warnings = append(warnings, "hardware virtualization isn't available, virtual machines won't be supported")
+	}
+
+	if report.Memory < 4*1024*1024*1024 {
+		problems = append(problems, fmt.Sprintf("not enough memory (%0.2fGiB), must be at least 4GiB", float64(report.Memory)/(1024.0*1024.0*1024.0)))
+	}
+
+	// Disks and network.
+	if !slices.ContainsFunc(report.Disks, func(d apiseed.InstallCheckDisk) bool { return d.Suitable }) {
+		problems = append(problems, "no suitable install disk found")
+	}
+
+	if !slices.ContainsFunc(report.NICs, func(n apiseed.InstallCheckNIC) bool { return len(n.Ports) > 0 }) {
+		problems = append(problems, "no network port found")
+	}
+
+	return problems, warnings
+}
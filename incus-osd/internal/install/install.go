@@ -31,7 +31,9 @@ import (
 
 // Install holds information necessary to perform an installation.
 type Install struct {
-	config *apiseed.Install
+	config   *apiseed.Install
+	layout   *storage.PartitionLayout
+	reporter *reporter
 }
 
 var cdromDevice = "/dev/sr0"
@@ -41,7 +43,25 @@ var cdromMappedDevice = "/dev/mapper/sr0"
 var cdromRegex = regexp.MustCompile(`^/dev/sr(\d+)`)
 
 // CheckSystemRequirements verifies that the system meets the minimum requirements for running IncusOS.
-func CheckSystemRequirements(ctx context.Context) error { //nolint:revive
+func CheckSystemRequirements(ctx context.Context) error {
+	err := checkSystemRequirements(ctx)
+
+	// When installing, report the result of the checks.
+	installSeed, seedErr := seed.GetInstall()
+	if seedErr == nil && installSeed.Report != nil {
+		r := newReporter(ctx, installSeed.Report)
+
+		if err != nil {
+			r.fail(ctx, err)
+		} else {
+			r.send(ctx, apiseed.InstallReportEvent{Phase: reportPhaseRequirements, Message: "System requirements met"})
+		}
+	}
+
+	return err
+}
+
+func checkSystemRequirements(ctx context.Context) error { //nolint:revive
 	// Check if Secure Boot is enabled.
 	sbEnabled, err := secureboot.Enabled()
 	if err != nil {
@@ -54,11 +74,6 @@ func CheckSystemRequirements(ctx context.Context) error { //nolint:revive
 	// Determine if there's a working physical TPM.
 	workingPhysicalTPM := tpmErr == nil && !secureboot.GetSWTPMInUse()
 
-	// If Secure Boot is disabled and there's no working physical TPM, refuse to continue.
-	if !sbEnabled && !workingPhysicalTPM {
-		return errors.New("cannot run if Secure Boot is disabled and no physical TPM is present")
-	}
-
 	// Ensure the security seed, if present, isn't attempting to set any encryption recovery keys.
 	_, err = seed.GetSecurity(ctx)
 	if err != nil && !seed.IsMissing(err) {
@@ -71,30 +86,14 @@ func CheckSystemRequirements(ctx context.Context) error { //nolint:revive
 		return errors.New("unable to get install seed: " + err.Error())
 	}
 
-	// Validate that the install seed, if present, doesn't attempt to configure an invalid degraded security state.
-	if installSeed != nil && installSeed.Security != nil {
-		if installSeed.Security.MissingTPM && installSeed.Security.MissingSecureBoot {
-			return errors.New("install seed cannot enable both Secure Boot and TPM degraded security options")
-		}
-
-		// Return an error if there's a physical TPM but the install seed wants to configure swtpm.
-		if workingPhysicalTPM && installSeed.Security.MissingTPM {
-			return errors.New("a physical TPM was found, but install seed wants to configure a swtpm-backed TPM")
-		}
-
-		// Return an error if Secure Boot is enabled but the install seed expects it to be disabled.
-		if sbEnabled && installSeed.Security.MissingSecureBoot {
-			return errors.New("Secure Boot is enabled, but install seed expects it to be disabled") //nolint:staticcheck
-		}
+	// Refuse to continue if the Secure Boot and TPM state or the install seed's degraded security options are invalid.
+	problems, _ := getSecurityProblems(sbEnabled, tpmErr == nil, workingPhysicalTPM, installSeed)
+	if len(problems) > 0 {
+		return errors.Join(problems...)
 	}
 
 	// If Secure Boot is enabled, but there's no working TPM, attempt to initialize swtpm for use on next boot.
 	if sbEnabled && tpmErr != nil {
-		// Return an error if there's no working TPM and the install seed doesn't allow using swtpm.
-		if installSeed != nil && (installSeed.Security == nil || !installSeed.Security.MissingTPM) {
-			return errors.New("no working TPM found, but install seed doesn't allow for use of swtpm")
-		}
-
 		err := configureSWTPM(ctx, installSeed != nil)
 		if err != nil {
 			return err
@@ -103,11 +102,6 @@ func CheckSystemRequirements(ctx context.Context) error { //nolint:revive
 
 	// If Secure Boot is disabled and there's a working physical TPM, allow running.
 	if !sbEnabled && workingPhysicalTPM {
-		// Return an error if Secure Boot is disabled and the install seed doesn't allow this.
-		if installSeed != nil && (installSeed.Security == nil || !installSeed.Security.MissingSecureBoot) {
-			return errors.New("Secure Boot is disabled, but install seed doesn't allow this") //nolint:staticcheck
-		}
-
 		// Only display warning during install or first boot.
 		_, err := os.Stat("/boot/sb-disabled")
 		if err != nil && errors.Is(err, os.ErrNotExist) {
@@ -135,12 +129,7 @@ func CheckSystemRequirements(ctx context.Context) error { //nolint:revive
 	// If we aren't going to perform an install but systemd-repart failed or we're running from a CDROM,
 	// display an appropriate error message to the user.
 	if !ShouldPerformInstall() && (systemd.IsFailed(ctx, "systemd-repart") || runningFromCDROM()) {
-		source := sourceDevice
-		if source == cdromMappedDevice {
-			source = cdromDevice
-		}
-
-		sourceDeviceID, err := storage.DeviceToID(ctx, source, true)
+		sourceDeviceID, err := getSourceDeviceID(ctx, sourceDevice)
 		if err != nil {
 			return err
 		}
@@ -182,7 +171,7 @@ func CheckSystemRequirements(ctx context.Context) error { //nolint:revive
 		}
 
 		if len(contents) != 0 {
-			return errors.New("install media detected, but the system is already installed; please remove USB/CDROM and reboot the system")
+			return errors.New("install media detected, but the system is already installed; please remove USB/CDROM or disable network boot and reboot the system")
 		}
 
 		// Sanity check: If /var/lib/incus-os/recovery.root.key exists, that means we've booted with an install
@@ -290,6 +279,34 @@ func CheckSystemRequirements(ctx context.Context) error { //nolint:revive
 			return fmt.Errorf("target device '%s' is too small (%0.2fGiB), must be at least 50GiB", targetDeviceID, float64(targetDeviceSize)/(1024.0*1024.0*1024.0))
 		}
 
+		// Verify the requested partition layout, if any, fits on the target device.
+		_, err = getPartitionLayout(installSeed.Layout, int64(targetDeviceSize))
+		if err != nil {
+			return err
+		}
+
+		// If mirroring the boot partitions, verify the mirror target device is at least as large as the target device.
+		if installSeed.MirrorTarget != nil {
+			mirrorDevice, mirrorDeviceSize, err := getMirrorDevice(targets, targetDevice, installSeed.MirrorTarget)
+			if err != nil {
+				devices := []string{}
+				for _, t := range targets {
+					devices = append(devices, t.ID)
+				}
+
+				return errors.New(err.Error() + " (detected devices: " + strings.Join(devices, ", ") + ")")
+			}
+
+			if mirrorDeviceSize < targetDeviceSize {
+				mirrorDeviceID, err := storage.DeviceToID(ctx, mirrorDevice, true)
+				if err != nil {
+					return err
+				}
+
+				return fmt.Errorf("mirror target device '%s' is too small (%0.2fGiB), must be at least as large as the target device (%0.2fGiB)", mirrorDeviceID, float64(mirrorDeviceSize)/(1024.0*1024.0*1024.0), float64(targetDeviceSize)/(1024.0*1024.0*1024.0))
+			}
+		}
+
 		// If an applications seed is present, ensure at least one application is defined.
 		apps, _ := seed.GetApplications(ctx)
 		if apps != nil {
@@ -302,6 +319,58 @@ func CheckSystemRequirements(ctx context.Context) error { //nolint:revive
 	return nil
 }
 
+// getSecurityProblems returns all the unmet security requirements for the given Secure Boot and TPM state, as well as
+// those only met by running in a degraded state. The degraded security options are only required when installing,
+// otherwise their absence is just a warning.
+func getSecurityProblems(sbEnabled bool, tpmWorking bool, physicalTPM bool, installSeed *apiseed.Install) ([]error, []string) {
+	problems := []error{}
+	warnings := []string{}
+
+	// If Secure Boot is disabled and there's no working physical TPM, refuse to continue.
+	if !sbEnabled && !physicalTPM {
+		problems = append(problems, errors.New("cannot run if Secure Boot is disabled and no physical TPM is present"))
+	}
+
+	security := &apiseed.InstallSecurity{}
+	if installSeed != nil && installSeed.Security != nil {
+		security = installSeed.Security
+	}
+
+	requireOptions := installSeed != nil && !installSeed.CheckOnly
+
+	// Validate that the install seed doesn't attempt to configure an invalid degraded security state.
+	if security.MissingTPM && security.MissingSecureBoot {
+		problems = append(problems, errors.New("install seed cannot enable both Secure Boot and TPM degraded security options"))
+	}
+
+	if physicalTPM && security.MissingTPM {
+		problems = append(problems, errors.New("a physical TPM was found, but install seed wants to configure a swtpm-backed TPM"))
+	}
+
+	if sbEnabled && security.MissingSecureBoot {
+		problems = append(problems, errors.New("Secure Boot is enabled, but install seed expects it to be disabled")) //nolint:staticcheck
+	}
+
+	// Check that the install seed allows for the degraded security state the system would run in.
+	if sbEnabled && !physicalTPM {
+		if !tpmWorking && requireOptions && !security.MissingTPM {
+			problems = append(problems, errors.New("no working TPM found, but install seed doesn't allow for use of swtpm"))
+		} else {
+			warnings = append(warnings, "no working physical TPM found, requiring the missing_tpm install option")
+		}
+	}
+
+	if !sbEnabled && physicalTPM {
+		if requireOptions && !security.MissingSecureBoot {
+			problems = append(problems, errors.New("Secure Boot is disabled, but install seed doesn't allow this")) //nolint:staticcheck
+		} else {
+			warnings = append(warnings, "Secure Boot is disabled, requiring the missing_secure_boot install option")
+		}
+	}
+
+	return problems, warnings
+}
+
 // ShouldPerformInstall checks for the presence of an install.{json,yaml} file in the
 // seed partition to indicate if we should attempt to install incus-osd to a local disk.
 func ShouldPerformInstall() bool {
@@ -326,6 +395,17 @@ func NewInstall() (*Install, error) {
 
 // DoInstall performs the necessary steps for installing incus-osd to a local disk.
 func (i *Install) DoInstall(ctx context.Context, osName string) error {
+	i.reporter = newReporter(ctx, i.config.Report)
+
+	err := i.doInstall(ctx, osName)
+	if err != nil {
+		i.reporter.fail(ctx, err)
+	}
+
+	return err
+}
+
+func (i *Install) doInstall(ctx context.Context, osName string) error {
 	t, err := tui.GetTUI(nil)
 	if err != nil {
 		return err
@@ -349,19 +429,34 @@ func (i *Install) DoInstall(ctx context.Context, osName string) error {
 		return err
 	}
 
-	targetDevice, _, err := getTargetDevice(targets, i.config.Target)
+	i.reporter.setDisks(targets)
+
+	targetDevice, targetDeviceSize, err := getTargetDevice(targets, i.config.Target)
 	if err != nil {
 		modal.Update("[red]Error: " + err.Error())
 
 		return err
 	}
 
-	source := sourceDevice
-	if source == cdromMappedDevice {
-		source = cdromDevice
+	i.layout, err = getPartitionLayout(i.config.Layout, int64(targetDeviceSize))
+	if err != nil {
+		modal.Update("[red]Error: " + err.Error())
+
+		return err
+	}
+
+	mirrorDevice := ""
+
+	if i.config.MirrorTarget != nil {
+		mirrorDevice, _, err = getMirrorDevice(targets, targetDevice, i.config.MirrorTarget)
+		if err != nil {
+			modal.Update("[red]Error: " + err.Error())
+
+			return err
+		}
 	}
 
-	sourceDeviceID, err := storage.DeviceToID(ctx, source, true)
+	sourceDeviceID, err := getSourceDeviceID(ctx, sourceDevice)
 	if err != nil {
 		modal.Update("[red]Error: " + err.Error())
 
@@ -375,10 +470,24 @@ func (i *Install) DoInstall(ctx context.Context, osName string) error {
 		return err
 	}
 
-	slog.InfoContext(ctx, "Installing "+osName, "source", sourceDeviceID, "target", targetDeviceID)
-	modal.Update(fmt.Sprintf("Installing "+osName+" from %s to %s.", sourceDeviceID, targetDeviceID))
+	if mirrorDevice != "" {
+		mirrorDeviceID, err := storage.DeviceToID(ctx, mirrorDevice, true)
+		if err != nil {
+			modal.Update("[red]Error: " + err.Error())
+
+			return err
+		}
+
+		slog.InfoContext(ctx, "Installing "+osName, "source", sourceDeviceID, "target", targetDeviceID, "mirror", mirrorDeviceID)
+		modal.Update(fmt.Sprintf("Installing "+osName+" from %s to %s, with boot partitions mirrored to %s.", sourceDeviceID, targetDeviceID, mirrorDeviceID))
+		i.reporter.send(ctx, apiseed.InstallReportEvent{Phase: reportPhaseTarget, Message: "Installing from " + sourceDeviceID, Target: targetDeviceID, MirrorTarget: mirrorDeviceID})
+	} else {
+		slog.InfoContext(ctx, "Installing "+osName, "source", sourceDeviceID, "target", targetDeviceID)
+		modal.Update(fmt.Sprintf("Installing "+osName+" from %s to %s.", sourceDeviceID, targetDeviceID))
+		i.reporter.send(ctx, apiseed.InstallReportEvent{Phase: reportPhaseTarget, Message: "Installing from " + sourceDeviceID, Target: targetDeviceID})
+	}
 
-	err = i.performInstall(ctx, modal, sourceDevice, targetDevice, sourceIsReadonly)
+	err = i.performInstall(ctx, modal, sourceDevice, targetDevice, mirrorDevice, sourceIsReadonly)
 	if err != nil {
 		modal.Update("[red]Error: " + err.Error())
 
@@ -386,6 +495,25 @@ func (i *Install) DoInstall(ctx context.Context, osName string) error {
 	}
 
 	slog.InfoContext(ctx, osName+" was successfully installed")
+	i.reporter.send(ctx, apiseed.InstallReportEvent{Phase: reportPhaseDone, Message: osName + " was successfully installed"})
+
+	// When installing from the network, there's no install media to remove.
+	networkSource, err := storage.GetNetworkInstallSource()
+	if err != nil {
+		return err
+	}
+
+	if networkSource != "" {
+		slog.InfoContext(ctx, "Rebooting in five seconds to complete the installation")
+		modal.Update(osName + " was successfully installed.\nRebooting in five seconds to complete the installation.")
+
+		unix.Sync()
+
+		time.Sleep(5 * time.Second)
+
+		return systemd.SystemReboot(ctx)
+	}
+
 	slog.InfoContext(ctx, "Please remove the install media to complete the installation")
 	modal.Update(osName + " was successfully installed.\nPlease remove the install media to complete the installation.")
 
@@ -413,6 +541,26 @@ func runningFromCDROM() bool {
 	return true
 }
 
+// getSourceDeviceID returns a description of the install source suitable for display, either the URL of
+// the install image when installing from the network or the source device's ID.
+func getSourceDeviceID(ctx context.Context, sourceDevice string) (string, error) {
+	networkSource, err := storage.GetNetworkInstallSource()
+	if err != nil {
+		return "", err
+	}
+
+	if networkSource != "" {
+		return networkSource, nil
+	}
+
+	source := sourceDevice
+	if source == cdromMappedDevice {
+		source = cdromDevice
+	}
+
+	return storage.DeviceToID(ctx, source, true)
+}
+
 // getSourceDevice determines the underlying device incus-osd is running on and if it is read-only.
 func getSourceDevice(ctx context.Context) (string, bool, int, error) {
 	// Check if we're running from a CDROM.
@@ -449,8 +597,22 @@ func getSourceDevice(ctx context.Context) (string, bool, int, error) {
 	return underlyingDevice, isReadonlyInstallFS, lsblkOutput.BlockDevices[0].Size, nil
 }
 
+// rejectedDisk is a disk that can't be an install target, along with the reason why.
+type rejectedDisk struct {
+	device storage.BlockDevices
+	reason string
+}
+
 // getAllTargets returns a list of all potential install target devices.
 func getAllTargets(ctx context.Context, sourceDevice string) ([]storage.BlockDevices, error) {
+	targets, _, err := getAllDisks(ctx, sourceDevice)
+
+	return targets, err
+}
+
+// getAllDisks returns a list of all potential install target devices, as well as the other disks that were found
+// along with the reason they were rejected.
+func getAllDisks(ctx context.Context, sourceDevice string) ([]storage.BlockDevices, []rejectedDisk, error) {
 	ret := []storage.BlockDevices{}
 
 	// Get NVME drives first.
@@ -458,12 +620,12 @@ func getAllTargets(ctx context.Context, sourceDevice string) ([]storage.BlockDev
 
 	output, err := subprocess.RunCommandContext(ctx, "lsblk", "-N", "-iJnpb", "-e", "1,2", "-o", "KNAME,ID_LINK,SIZE,SUBSYSTEMS")
 	if err != nil {
-		return []storage.BlockDevices{}, err
+		return []storage.BlockDevices{}, nil, err
 	}
 
 	err = json.Unmarshal([]byte(output), &nvmeTargets)
 	if err != nil {
-		return []storage.BlockDevices{}, err
+		return []storage.BlockDevices{}, nil, err
 	}
 
 	ret = append(ret, nvmeTargets.BlockDevices...)
@@ -473,12 +635,12 @@ func getAllTargets(ctx context.Context, sourceDevice string) ([]storage.BlockDev
 
 	output, err = subprocess.RunCommandContext(ctx, "lsblk", "-S", "-iJnpb", "-e", "1,2", "-o", "KNAME,ID_LINK,SIZE,SUBSYSTEMS")
 	if err != nil {
-		return []storage.BlockDevices{}, err
+		return []storage.BlockDevices{}, nil, err
 	}
 
 	err = json.Unmarshal([]byte(output), &scsiTargets)
 	if err != nil {
-		return []storage.BlockDevices{}, err
+		return []storage.BlockDevices{}, nil, err
 	}
 
 	ret = append(ret, scsiTargets.BlockDevices...)
@@ -489,12 +651,12 @@ func getAllTargets(ctx context.Context, sourceDevice string) ([]storage.BlockDev
 	// MMC block devices have major number 179 (https://www.kernel.org/doc/Documentation/admin-guide/devices.txt)
 	output, err = subprocess.RunCommandContext(ctx, "lsblk", "-I", "179", "-iJnpb", "-o", "KNAME,ID_LINK,SIZE,SUBSYSTEMS")
 	if err != nil {
-		return []storage.BlockDevices{}, err
+		return []storage.BlockDevices{}, nil, err
 	}
 
 	err = json.Unmarshal([]byte(output), &mmcTargets)
 	if err != nil {
-		return []storage.BlockDevices{}, err
+		return []storage.BlockDevices{}, nil, err
 	}
 
 	ret = append(ret, mmcTargets.BlockDevices...)
@@ -504,42 +666,52 @@ func getAllTargets(ctx context.Context, sourceDevice string) ([]storage.BlockDev
 
 	output, err = subprocess.RunCommandContext(ctx, "lsblk", "-v", "-iJnpb", "-e", "1,2", "-o", "KNAME,ID_LINK,SIZE,SUBSYSTEMS")
 	if err != nil {
-		return []storage.BlockDevices{}, err
+		return []storage.BlockDevices{}, nil, err
 	}
 
 	err = json.Unmarshal([]byte(output), &virtualTargets)
 	if err != nil {
-		return []storage.BlockDevices{}, err
+		return []storage.BlockDevices{}, nil, err
 	}
 
 	ret = append(ret, virtualTargets.BlockDevices...)
 
 	// Filter out devices that are known to not be valid targets.
 	filtered := make([]storage.BlockDevices, 0, len(ret))
+	rejected := []rejectedDisk{}
+
 	for _, entry := range ret {
 		if entry.KName == sourceDevice {
+			rejected = append(rejected, rejectedDisk{entry, "install media"})
+
 			continue
 		}
 
 		if entry.ID == "" {
 			// Skip devices that don't have a link ID, such as mmcblk0boot0.
+			rejected = append(rejected, rejectedDisk{entry, "no device ID"})
+
 			continue
 		}
 
 		if storage.IsBMC(entry) {
 			// Skip all BMC virtual devices.
+			rejected = append(rejected, rejectedDisk{entry, "BMC virtual device"})
+
 			continue
 		}
 
 		if cdromRegex.MatchString(entry.KName) {
 			// Ignore all CDROM devices.
+			rejected = append(rejected, rejectedDisk{entry, "CDROM device"})
+
 			continue
 		}
 
 		if slices.ContainsFunc(filtered, func(a storage.BlockDevices) bool {
 			return a.ID == entry.ID
 		}) {
-			// Skip any duplicate device IDs.
+			// Skip any duplicate device IDs, which aren't reported.
 			continue
 		}
 
@@ -550,14 +722,14 @@ func getAllTargets(ctx context.Context, sourceDevice string) ([]storage.BlockDev
 			// Convert the "disk/by-id" symlink to a nicer "mapper" one.
 			linkDest, err := os.Readlink("/dev/disk/by-id/" + entry.ID)
 			if err != nil {
-				return []storage.BlockDevices{}, err
+				return []storage.BlockDevices{}, nil, err
 			}
 
 			mappedDev := filepath.Join("/dev/disk/by-id/", linkDest)
 
 			mappedDev, err = util.ResolveMapperSymlink(ctx, mappedDev)
 			if err != nil {
-				return []storage.BlockDevices{}, err
+				return []storage.BlockDevices{}, nil, err
 			}
 
 			entry.KName = mappedDev
@@ -566,7 +738,7 @@ func getAllTargets(ctx context.Context, sourceDevice string) ([]storage.BlockDev
 		filtered = append(filtered, entry)
 	}
 
-	return filtered, nil
+	return filtered, rejected, nil
 }
 
 // getTargetDevice determines the install target based on the provided potential targets and install seed (if any),
@@ -682,8 +854,29 @@ func getTargetDevice(potentialTargets []storage.BlockDevices, seedTarget *apisee
 	return "", -1, errors.New("more than one target device matched provided install seed selectors")
 }
 
-// performInstall performs the steps to install incus-osd from the given target to the source device.
-func (i *Install) performInstall(ctx context.Context, modal *tui.Modal, sourceDevice string, targetDevice string, sourceIsReadonly bool) error { //nolint:revive
+// getMirrorDevice determines the second install target, used to mirror the boot partitions and "local" storage pool,
+// from the potential targets other than the already selected target device, and returns its device path along with
+// its size in bytes.
+func getMirrorDevice(potentialTargets []storage.BlockDevices, targetDevice string, seedTarget *apiseed.InstallTarget) (string, int, error) {
+	remainingTargets := []storage.BlockDevices{}
+
+	for _, device := range potentialTargets {
+		if device.KName != targetDevice {
+			remainingTargets = append(remainingTargets, device)
+		}
+	}
+
+	mirrorDevice, mirrorDeviceSize, err := getTargetDevice(remainingTargets, seedTarget)
+	if err != nil {
+		return "", -1, errors.New("unable to select mirror target device: " + err.Error())
+	}
+
+	return mirrorDevice, mirrorDeviceSize, nil
+}
+
+// performInstall performs the steps to install incus-osd from the given target to the source device,
+// optionally mirroring the boot partitions to a second device.
+func (i *Install) performInstall(ctx context.Context, modal *tui.Modal, sourceDevice string, targetDevice string, mirrorDevice string, sourceIsReadonly bool) error { //nolint:revive
 	// Get architecture name.
 	archName, err := osarch.ArchitectureGetLocal()
 	if err != nil {
@@ -694,42 +887,40 @@ func (i *Install) performInstall(ctx context.Context, modal *tui.Modal, sourceDe
 		return fmt.Errorf("unsupported architecture %q", archName)
 	}
 
-	// Get the target device by-id path.
-	targetDeviceID, err := storage.DeviceToID(ctx, targetDevice, true)
-	if err != nil {
-		return err
+	// Check that the target device, and mirror device if any, is empty or that we can overwrite it.
+	devices := []string{targetDevice}
+	if mirrorDevice != "" {
+		devices = append(devices, mirrorDevice)
 	}
 
-	// Check if the target device already has a partition table.
-	output, err := subprocess.RunCommandContext(ctx, "sgdisk", "-v", targetDevice)
-	if err != nil {
-		// If the device has no main partition table, but does have a backup, assume it's been
-		// partially wiped with something like `dd if=/dev/zero of=/dev/sda ...` and proceed with install.
-		if !strings.Contains(err.Error(), "Caution: invalid main GPT header, but valid backup; regenerating main header") {
+	zapDevices := make([]bool, len(devices))
+
+	for idx, device := range devices {
+		zapDevices[idx], err = i.checkDevice(ctx, device)
+		if err != nil {
 			return err
 		}
-
-		// Set ForceInstall to true in this case since the install should continue.
-		i.config.ForceInstall = true
 	}
 
-	if !strings.Contains(output, "Creating new GPT entries in memory") && !i.config.ForceInstall {
-		return fmt.Errorf("a partition table already exists on device '%s', and `ForceInstall` from install configuration isn't true", targetDeviceID)
-	}
-
-	// At this point, the target device either has no GPT table, or we will be force-installing over any existing data.
+	// At this point, the devices either have no GPT table, or we will be force-installing over any existing data.
+	for idx, device := range devices {
+		// Zap any existing GPT table on the device.
+		if zapDevices[idx] {
+			// Don't check return status, since sgdisk always returns an error if there's a mismatch
+			// between the main and backup GPT tables.
+			_, _ = subprocess.RunCommandContext(ctx, "sgdisk", "-Z", device)
+		}
 
-	// Zap any existing GPT table on the target device.
-	if i.config.ForceInstall {
-		// Don't check return status, since sgdisk always returns an error if there's a mismatch
-		// between the main and backup GPT tables.
-		_, _ = subprocess.RunCommandContext(ctx, "sgdisk", "-Z", targetDevice)
-	}
+		// Before starting the install, wipe the device.
+		deviceID, err := storage.DeviceToID(ctx, device, true)
+		if err != nil {
+			return err
+		}
 
-	// Before starting the install, wipe the target device.
-	err = storage.WipeDrive(ctx, targetDeviceID, false)
-	if err != nil {
-		return err
+		err = storage.WipeDrive(ctx, deviceID, false)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Turn off swap and unmount /boot.
@@ -752,7 +943,7 @@ func (i *Install) performInstall(ctx context.Context, modal *tui.Modal, sourceDe
 		actualSourceDevice = cdromDevice
 	}
 
-	output, err = subprocess.RunCommandContext(ctx, "sgdisk", "-i", "9", actualSourceDevice)
+	output, err := subprocess.RunCommandContext(ctx, "sgdisk", "-i", "9", actualSourceDevice)
 	if err != nil {
 		return err
 	}
@@ -884,6 +1075,8 @@ func (i *Install) performInstall(ctx context.Context, modal *tui.Modal, sourceDe
 	// Copy the partition contents. We skip the first (ESP) partition, because we've copied
 	// everything in that partition above.
 	for idx := 2; idx <= numPartitionsToCopy; idx++ {
+		i.reporter.send(ctx, apiseed.InstallReportEvent{Phase: reportPhaseCopy, Message: fmt.Sprintf("Copying partition %d of %d", idx, numPartitionsToCopy), Progress: float64(idx-1) / float64(numPartitionsToCopy)})
+
 		err := doCopy(ctx, modal, sourceDevice, sourcePartitionPrefix, targetDevice, targetPartitionPrefix, idx, numPartitionsToCopy)
 		if err != nil {
 			return err
@@ -907,6 +1100,8 @@ func (i *Install) performInstall(ctx context.Context, modal *tui.Modal, sourceDe
 		return err
 	}
 
+	i.reporter.send(ctx, apiseed.InstallReportEvent{Phase: reportPhaseTPM, Message: "Preparing the TPM and boot loader"})
+
 	// If swtpm state was configured, move it to the ESP partition.
 	_, err = os.Stat("/tmp/swtpm/")
 	if err == nil {
@@ -943,11 +1138,58 @@ func (i *Install) performInstall(ctx context.Context, modal *tui.Modal, sourceDe
 		return err
 	}
 
+	// When installing from the network, make sure the installed system is booted rather than the network.
+	networkSource, err := storage.GetNetworkInstallSource()
+	if err != nil {
+		return err
+	}
+
+	if networkSource != "" {
+		err = setFirstBootOption(ctx, targetDevice+GetPartitionPrefix(targetDevice)+"1")
+		if err != nil {
+			return fmt.Errorf("unable to update the firmware boot order: %w", err)
+		}
+	}
+
+	// Record the partition sizes to be used by systemd-repart on first boot.
+	if i.layout != nil {
+		err = storage.WritePartitionLayout("/boot", *i.layout)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Record the mirrored drives, so the "local" pool can be mirrored on first boot and updates kept in sync.
+	if mirrorDevice != "" {
+		targetDeviceID, err := storage.DeviceToID(ctx, targetDevice, true)
+		if err != nil {
+			return err
+		}
+
+		mirrorDeviceID, err := storage.DeviceToID(ctx, mirrorDevice, true)
+		if err != nil {
+			return err
+		}
+
+		err = storage.WriteBootMirror("/boot", []string{targetDeviceID, mirrorDeviceID})
+		if err != nil {
+			return err
+		}
+	}
+
 	err = unix.Unmount("/boot", 0)
 	if err != nil {
 		return err
 	}
 
+	// Copy the boot partitions to the mirror device.
+	if mirrorDevice != "" {
+		err = installMirror(ctx, modal, targetDevice, mirrorDevice)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Set the "IncusOSInstallComplete" UEFI variable.
 	f, err := os.Create("/sys/firmware/efi/efivars/IncusOSInstallComplete-12f075e0-2d07-493d-811a-00920a72c04c")
 	if err != nil {
@@ -963,6 +1205,107 @@ func (i *Install) performInstall(ctx context.Context, modal *tui.Modal, sourceDe
 	return nil
 }
 
+// installMirror copies the boot partitions of the freshly installed target device to the mirror device, so the system can
+// also boot from it. The encrypted swap and root partitions are created by systemd-repart on first boot and are specific
+// to the drive the system boots from, so aren't mirrored; the space for them is left free on the mirror device, followed
+// by the mirror of the "local" pool which is added on first boot.
+func installMirror(ctx context.Context, modal *tui.Modal, targetDevice string, mirrorDevice string) error {
+	modal.Update("Cloning GPT partitions to mirror device.")
+
+	for idx := 1; idx <= 8; idx++ {
+		err := copyPartitionDefinition(ctx, targetDevice, mirrorDevice, idx)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The /usr partitions keep their unique GUIDs, which are used to locate the /usr image at boot, but the ESP
+	// and seed partitions get their own so the drive the system booted from can be told apart.
+	_, err := subprocess.RunCommandContext(ctx, "sgdisk", "-u", "1:R", "-u", "2:R", "-c", "2:seed-mirror", mirrorDevice)
+	if err != nil {
+		return err
+	}
+
+	targetPartitionPrefix := GetPartitionPrefix(targetDevice)
+	mirrorPartitionPrefix := GetPartitionPrefix(mirrorDevice)
+
+	// Format the mirror ESP partition and copy the files from the target, including the boot loader.
+	modal.Update("Copying ESP partition data to mirror device.")
+
+	_, err = subprocess.RunCommandContext(ctx, "mkfs.vfat", "-n", "ESP", mirrorDevice+mirrorPartitionPrefix+"1")
+	if err != nil {
+		return err
+	}
+
+	err = unix.Mount(targetDevice+targetPartitionPrefix+"1", "/tmp/sourceESP", "vfat", 0, "ro")
+	if err != nil {
+		return err
+	}
+
+	err = unix.Mount(mirrorDevice+mirrorPartitionPrefix+"1", "/tmp/targetESP", "vfat", 0, "")
+	if err != nil {
+		_ = unix.Unmount("/tmp/sourceESP", 0)
+
+		return err
+	}
+
+	_, err = subprocess.RunCommandContext(ctx, "sh", "-c", "cp -ar /tmp/sourceESP/* /tmp/targetESP/")
+	if err != nil {
+		return err
+	}
+
+	err = unix.Unmount("/tmp/sourceESP", 0)
+	if err != nil {
+		return err
+	}
+
+	err = unix.Unmount("/tmp/targetESP", 0)
+	if err != nil {
+		return err
+	}
+
+	// Copy the remaining partitions.
+	for idx := 2; idx <= 8; idx++ {
+		err := doCopy(ctx, modal, targetDevice, targetPartitionPrefix, mirrorDevice, mirrorPartitionPrefix, idx, 8)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkDevice checks that the given device doesn't already hold a partition table, unless ForceInstall is set, and
+// returns whether any existing partition table should be zapped.
+func (i *Install) checkDevice(ctx context.Context, device string) (bool, error) {
+	// Get the device by-id path.
+	deviceID, err := storage.DeviceToID(ctx, device, true)
+	if err != nil {
+		return false, err
+	}
+
+	forceInstall := i.config.ForceInstall
+
+	// Check if the device already has a partition table.
+	output, err := subprocess.RunCommandContext(ctx, "sgdisk", "-v", device)
+	if err != nil {
+		// If the device has no main partition table, but does have a backup, assume it's been
+		// partially wiped with something like `dd if=/dev/zero of=/dev/sda ...` and proceed with install.
+		if !strings.Contains(err.Error(), "Caution: invalid main GPT header, but valid backup; regenerating main header") {
+			return false, err
+		}
+
+		// Force the install in this case since it should continue.
+		forceInstall = true
+	}
+
+	if !strings.Contains(output, "Creating new GPT entries in memory") && !forceInstall {
+		return false, fmt.Errorf("a partition table already exists on device '%s', and `ForceInstall` from install configuration isn't true", deviceID)
+	}
+
+	return forceInstall, nil
+}
+
 // Copy partition definitions to target device. We can't just do a `sgdisk -R target source`
 // because the install media may have a different sector size than the target device (for example,
 // if the installer is running from a CDROM).
@@ -1134,12 +1477,12 @@ func (i *Install) rebootUponDeviceRemoval(_ context.Context, device string) erro
 }
 
 // GetPartitionPrefix returns the necessary partition prefix, if any, for a give device.
-// nvme and mmc devices have partitions named "pN", while traditional disk partitions are just "N".
+// nvme, mmc and loop devices have partitions named "pN", while traditional disk partitions are just "N".
 // Multipath devices have their partitions named "-partN".
 func GetPartitionPrefix(device string) string {
 	cdromMatched, _ := regexp.MatchString(`/mapper/sr\d+`, device)
 
-	if strings.Contains(device, "/nvme") || strings.Contains(device, "/mmcblk") || cdromMatched {
+	if strings.Contains(device, "/nvme") || strings.Contains(device, "/mmcblk") || strings.Contains(device, "/loop") || cdromMatched {
 		return "p"
 	}
 
@@ -1,8 +1,15 @@
 package install
 
 import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 
 	"github.com/lxc/incus-os/incus-osd/api/seed"
@@ -179,3 +186,325 @@ func TestGetTargetDeviceWithSeed(t *testing.T) {
 	require.Equal(t, "/dev/sdd", target)
 	require.Equal(t, 53, size)
 }
+
+func TestGetMirrorDevice(t *testing.T) {
+	t.Parallel()
+
+	devs := []storage.BlockDevices{
+		{
+			KName:      "/dev/nvme0n1",
+			ID:         "/dev/disk/by-id/nvme-Samsung_SSD_990_PRO_1TB_S1",
+			Size:       1000,
+			Subsystems: "block:nvme:pci",
+			RM:         false,
+		},
+		{
+			KName:      "/dev/nvme1n1",
+			ID:         "/dev/disk/by-id/nvme-Samsung_SSD_990_PRO_1TB_S2",
+			Size:       1000,
+			Subsystems: "block:nvme:pci",
+			RM:         false,
+		},
+		{
+			KName:      "/dev/sda",
+			ID:         "/dev/disk/by-id/scsi-0QEMU_QEMU_HARDDISK_incus_disk1",
+			Size:       4000,
+			Subsystems: "block:scsi:virtio:pci",
+			RM:         false,
+		},
+	}
+
+	// The same selector as the main target picks the other matching drive.
+	tgt := &seed.InstallTarget{Bus: "nvme"}
+
+	target, _, err := getTargetDevice(devs, tgt)
+	require.EqualError(t, err, "more than one target device matched provided install seed selectors")
+	require.Empty(t, target)
+
+	target, _, err = getTargetDevice(devs, &seed.InstallTarget{ID: "S1"})
+	require.NoError(t, err)
+	require.Equal(t, "/dev/nvme0n1", target)
+
+	mirror, size, err := getMirrorDevice(devs, target, tgt)
+	require.NoError(t, err)
+	require.Equal(t, "/dev/nvme1n1", mirror)
+	require.Equal(t, 1000, size)
+
+	// The main target is never picked as the mirror.
+	_, _, err = getMirrorDevice(devs, target, &seed.InstallTarget{ID: "S1"})
+	require.EqualError(t, err, "unable to select mirror target device: no target device matched provided install seed selectors")
+
+	// Sort orders apply to the remaining drives.
+	mirror, _, err = getMirrorDevice(devs, target, &seed.InstallTarget{SortOrder: "largest"})
+	require.NoError(t, err)
+	require.Equal(t, "/dev/sda", mirror)
+
+	// No drives left.
+	_, _, err = getMirrorDevice(devs[:1], target, tgt)
+	require.EqualError(t, err, "unable to select mirror target device: no potential install devices found")
+}
+
+func TestGetPartitionLayout(t *testing.T) {
+	t.Parallel()
+
+	gib := int64(1024 * 1024 * 1024)
+
+	// No layout keeps the defaults.
+	layout, err := getPartitionLayout(nil, 100*gib)
+	require.NoError(t, err)
+	require.Nil(t, layout)
+
+	// Sizes are parsed, unset sizes are left to the defaults.
+	layout, err = getPartitionLayout(&seed.InstallLayout{RootSize: "50GiB", SwapSize: "8GiB"}, 100*gib)
+	require.NoError(t, err)
+	require.Equal(t, &storage.PartitionLayout{RootSize: 50 * gib, SwapSize: 8 * gib}, layout)
+
+	// Reserving space limits the "local" pool to the rest of the disk.
+	layout, err = getPartitionLayout(&seed.InstallLayout{ReservedSize: "40GiB"}, 100*gib)
+	require.NoError(t, err)
+	require.Equal(t, &storage.PartitionLayout{LocalDataSize: 26 * gib}, layout)
+
+	// Minimums are enforced.
+	_, err = getPartitionLayout(&seed.InstallLayout{RootSize: "10GiB"}, 100*gib)
+	require.EqualError(t, err, "root_size \"10GiB\" is too small, must be at least 25.00GiB")
+
+	_, err = getPartitionLayout(&seed.InstallLayout{SwapSize: "foo"}, 100*gib)
+	require.Error(t, err)
+
+	// The layout must fit on the target device.
+	_, err = getPartitionLayout(&seed.InstallLayout{RootSize: "60GiB", LocalPoolSize: "50GiB"}, 100*gib)
+	require.EqualError(t, err, "partition layout requires at least 119.00GiB, but the target device is only 100.00GiB")
+}
+
+func TestReporter(t *testing.T) {
+	t.Parallel()
+
+	events := []seed.InstallReportEvent{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := seed.InstallReportEvent{}
+
+		err := json.NewDecoder(r.Body).Decode(&event)
+		if err != nil || r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		events = append(events, event)
+	}))
+	defer server.Close()
+
+	// Invalid configurations are rejected.
+	_, err := getReportClient(&seed.InstallReport{URL: "ftp://example.com"})
+	require.Error(t, err)
+
+	_, err = getReportClient(&seed.InstallReport{URL: server.URL, Certificate: "foo"})
+	require.Error(t, err)
+
+	client, err := getReportClient(&seed.InstallReport{URL: server.URL})
+	require.NoError(t, err)
+
+	// A reporter without a configuration doesn't send anything.
+	r := newReporter(t.Context(), nil)
+	r.setDisks([]storage.BlockDevices{{ID: "nvme-disk1"}})
+	r.send(t.Context(), seed.InstallReportEvent{Phase: reportPhaseDone})
+
+	r = &reporter{url: server.URL, client: client, hardware: &seed.InstallReportHardware{Serial: "ABC123"}}
+	r.setDisks([]storage.BlockDevices{{ID: "nvme-disk1"}, {ID: "nvme-disk2"}})
+	r.send(t.Context(), seed.InstallReportEvent{Phase: reportPhaseTarget, Target: "nvme-disk1"})
+	r.fail(t.Context(), errors.New("some error"))
+
+	require.Len(t, events, 2)
+	require.Equal(t, reportPhaseTarget, events[0].Phase)
+	require.Equal(t, "nvme-disk1", events[0].Target)
+	require.Equal(t, "ABC123", events[0].Hardware.Serial)
+	require.Equal(t, []string{"nvme-disk1", "nvme-disk2"}, events[0].Hardware.Disks)
+	require.False(t, events[0].Timestamp.IsZero())
+	require.Equal(t, reportPhaseFailed, events[1].Phase)
+	require.Equal(t, "some error", events[1].Error)
+}
+
+func TestCheckDisk(t *testing.T) {
+	t.Parallel()
+
+	gib := 1024 * 1024 * 1024
+
+	dev := storage.BlockDevices{
+		KName:      "/dev/nvme0n1",
+		ID:         "nvme-eui.1234",
+		Size:       100 * gib,
+		Subsystems: "block:nvme:pci",
+	}
+
+	// A large enough disk is suitable without a seed.
+	disk := checkDisk(dev, nil)
+	require.True(t, disk.Suitable)
+	require.Empty(t, disk.Reasons)
+
+	// Each unmet requirement is reported.
+	dev.Size = 40 * gib
+	disk = checkDisk(dev, &seed.Install{Target: &seed.InstallTarget{Bus: "scsi"}, Layout: &seed.InstallLayout{RootSize: "50GiB"}})
+	require.False(t, disk.Suitable)
+	require.Equal(t, []string{
+		"too small (40.00GiB), must be at least 50GiB",
+		"doesn't match the install seed target selectors",
+		"partition layout requires at least 69.00GiB, but the target device is only 40.00GiB",
+	}, disk.Reasons)
+}
+
+func TestGetCheckProblems(t *testing.T) {
+	t.Parallel()
+
+	report := &seed.InstallCheckReport{
+		TPM:        seed.InstallCheckTPM{Present: true, Working: true, Version: "2", PCRBanks: []string{"sha1", "sha256"}},
+		SecureBoot: seed.InstallCheckSecureBoot{Enabled: true},
+		CPU:        seed.InstallCheckCPU{Architecture: "x86_64", Baseline: "x86_64_v3", Virtualization: true},
+		Memory:     8 * 1024 * 1024 * 1024,
+		Disks:      []seed.InstallCheckDisk{{Suitable: true}},
+		NICs:       []seed.InstallCheckNIC{{Ports: []string{"enp5s0"}}},
+	}
+
+	installSeed := &seed.Install{CheckOnly: true}
+
+	// A fully compatible system.
+	problems, warnings := getCheckProblems(report, installSeed)
+	require.Empty(t, problems)
+	require.Empty(t, warnings)
+
+	// Degraded security is only a warning.
+	report.SecureBoot.Enabled = false
+
+	problems, warnings = getCheckProblems(report, installSeed)
+	require.Empty(t, problems)
+	require.Equal(t, []string{"Secure Boot is disabled, requiring the missing_secure_boot install option"}, warnings)
+
+	// Invalid degraded security options are reported the same way as when installing.
+	installSeed.Security = &seed.InstallSecurity{MissingTPM: true}
+
+	problems, _ = getCheckProblems(report, installSeed)
+	require.Equal(t, []string{"a physical TPM was found, but install seed wants to configure a swtpm-backed TPM"}, problems)
+
+	// All unmet requirements are reported at once.
+	installSeed.Security = nil
+	report.TPM.Working = false
+	report.CPU.Baseline = "x86_64_v2"
+	report.Memory = 2 * 1024 * 1024 * 1024
+	report.Disks[0].Suitable = false
+	report.NICs = nil
+
+	problems, _ = getCheckProblems(report, installSeed)
+	require.Equal(t, []string{
+		"cannot run if Secure Boot is disabled and no physical TPM is present",
+		"CPU only supports x86_64_v2, x86_64_v3 is required",
+		"not enough memory (2.00GiB), must be at least 4GiB",
+		"no suitable install disk found",
+		"no network port found",
+	}, problems)
+}
+
+func TestGetSecurityProblems(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name        string
+		sbEnabled   bool
+		tpmWorking  bool
+		physicalTPM bool
+		installSeed *seed.Install
+		problems    []string
+		warnings    []string
+	}{
+		{
+			name:        "secure",
+			sbEnabled:   true,
+			tpmWorking:  true,
+			physicalTPM: true,
+			installSeed: &seed.Install{},
+		},
+		{
+			name:      "live boot without a TPM",
+			sbEnabled: true,
+			warnings:  []string{"no working physical TPM found, requiring the missing_tpm install option"},
+		},
+		{
+			name:        "install without a TPM",
+			sbEnabled:   true,
+			installSeed: &seed.Install{},
+			problems:    []string{"no working TPM found, but install seed doesn't allow for use of swtpm"},
+		},
+		{
+			name:        "install with swtpm allowed",
+			sbEnabled:   true,
+			installSeed: &seed.Install{Security: &seed.InstallSecurity{MissingTPM: true}},
+			warnings:    []string{"no working physical TPM found, requiring the missing_tpm install option"},
+		},
+		{
+			name:        "install without Secure Boot",
+			tpmWorking:  true,
+			physicalTPM: true,
+			installSeed: &seed.Install{},
+			problems:    []string{"Secure Boot is disabled, but install seed doesn't allow this"},
+		},
+		{
+			name:        "conflicting options",
+			sbEnabled:   true,
+			tpmWorking:  true,
+			physicalTPM: true,
+			installSeed: &seed.Install{Security: &seed.InstallSecurity{MissingTPM: true, MissingSecureBoot: true}},
+			problems: []string{
+				"install seed cannot enable both Secure Boot and TPM degraded security options",
+				"a physical TPM was found, but install seed wants to configure a swtpm-backed TPM",
+				"Secure Boot is enabled, but install seed expects it to be disabled",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			problems, warnings := getSecurityProblems(tc.sbEnabled, tc.tpmWorking, tc.physicalTPM, tc.installSeed)
+
+			messages := []string{}
+			for _, err := range problems {
+				messages = append(messages, err.Error())
+			}
+
+			require.Equal(t, append([]string{}, tc.problems...), messages)
+			require.Equal(t, append([]string{}, tc.warnings...), warnings)
+		})
+	}
+}
+
+func TestBootOptionUsesPartition(t *testing.T) {
+	t.Parallel()
+
+	partUUID := uuid.MustParse("01234567-89ab-cdef-0123-456789abcdef")
+
+	// Hard drive media device path for a GPT partition, followed by a file path and the end node.
+	hardDrive := make([]byte, 42)
+	hardDrive[0] = 0x04
+	hardDrive[1] = 0x01
+	binary.LittleEndian.PutUint16(hardDrive[2:4], 42)
+	copy(hardDrive[24:40], []byte{0x67, 0x45, 0x23, 0x01, 0xab, 0x89, 0xef, 0xcd, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef})
+	hardDrive[40] = 0x02
+	hardDrive[41] = 0x02
+
+	filePath := []byte{0x04, 0x04, 0x08, 0x00, 'a', 0x00, 0x00, 0x00}
+	end := []byte{0x7f, 0xff, 0x04, 0x00}
+
+	paths := slices.Concat(hardDrive, filePath, end)
+
+	option := binary.LittleEndian.AppendUint32(nil, 1)
+	option = binary.LittleEndian.AppendUint16(option, uint16(len(paths)))
+	option = append(option, 'L', 0x00, 'i', 0x00, 'n', 0x00, 0x00, 0x00)
+	option = append(option, paths...)
+
+	require.True(t, bootOptionUsesPartition(option, partUUID))
+	require.False(t, bootOptionUsesPartition(option, uuid.MustParse("01234567-89ab-cdef-0123-456789abcdee")))
+
+	// Truncated options are ignored.
+	require.False(t, bootOptionUsesPartition(option[:len(option)-10], partUUID))
+	require.False(t, bootOptionUsesPartition(option[:4], partUUID))
+}
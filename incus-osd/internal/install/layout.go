@@ -0,0 +1,103 @@
+package install
+
+import (
+	"fmt"
+
+	"github.com/lxc/incus/v7/shared/units"
+
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+	"github.com/lxc/incus-os/incus-osd/internal/storage"
+)
+
+const (
+	// Upper bound of the space used by the ESP, seed and both /usr partition sets.
+	systemPartitionsSize = 5 * 1024 * 1024 * 1024
+
+	defaultRootSize = 25 * 1024 * 1024 * 1024
+	defaultSwapSize = 4 * 1024 * 1024 * 1024
+)
+
+// getPartitionLayout checks the partition layout from the install seed against the size of the target
+// device, and returns the resulting partition sizes, or nil if the default layout should be used.
+func getPartitionLayout(layout *apiseed.InstallLayout, deviceSize int64) (*storage.PartitionLayout, error) {
+	if layout == nil {
+		return nil, nil
+	}
+
+	ret := &storage.PartitionLayout{}
+
+	var err error
+
+	ret.RootSize, err = parseLayoutSize("root_size", layout.RootSize, storage.MinRootPartitionSize)
+	if err != nil {
+		return nil, err
+	}
+
+	ret.SwapSize, err = parseLayoutSize("swap_size", layout.SwapSize, storage.MinSwapPartitionSize)
+	if err != nil {
+		return nil, err
+	}
+
+	ret.LocalDataSize, err = parseLayoutSize("local_pool_size", layout.LocalPoolSize, storage.MinLocalDataPartitionSize)
+	if err != nil {
+		return nil, err
+	}
+
+	reservedSize, err := parseLayoutSize("reserved_size", layout.ReservedSize, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Determine the space needed by everything but the "local" pool.
+	requiredSize := int64(systemPartitionsSize) + reservedSize
+
+	if ret.RootSize > 0 {
+		requiredSize += ret.RootSize
+	} else {
+		requiredSize += defaultRootSize
+	}
+
+	if ret.SwapSize > 0 {
+		requiredSize += ret.SwapSize
+	} else {
+		requiredSize += defaultSwapSize
+	}
+
+	localPoolSize := ret.LocalDataSize
+	if localPoolSize == 0 {
+		localPoolSize = storage.MinLocalDataPartitionSize
+	}
+
+	if requiredSize+localPoolSize > deviceSize {
+		return nil, fmt.Errorf("partition layout requires at least %0.2fGiB, but the target device is only %0.2fGiB", float64(requiredSize+localPoolSize)/(1024.0*1024.0*1024.0), float64(deviceSize)/(1024.0*1024.0*1024.0))
+	}
+
+	// If space is reserved, size the "local" pool so that it doesn't span the rest of the disk.
+	if ret.LocalDataSize == 0 && reservedSize > 0 {
+		ret.LocalDataSize = (deviceSize - requiredSize) / (1024 * 1024) * (1024 * 1024)
+	}
+
+	return ret, nil
+}
+
+// parseLayoutSize parses a partition size from the install seed, returning zero if not set.
+func parseLayoutSize(name string, value string, minimum int64) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	size, err := units.ParseByteSizeString(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", name, value, err)
+	}
+
+	if size < 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+
+	if size < minimum {
+		return 0, fmt.Errorf("%s %q is too small, must be at least %0.2fGiB", name, value, float64(minimum)/(1024.0*1024.0*1024.0))
+	}
+
+	return size, nil
+}
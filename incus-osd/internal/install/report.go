@@ -0,0 +1,202 @@
+package install
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/lxc/incus/v7/shared/resources"
+
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+	"github.com/lxc/incus-os/incus-osd/internal/storage"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+// Install phases sent to the install report URL.
+const (
+	reportPhaseRequirements = "requirements"
+	reportPhaseTarget       = "target"
+	reportPhaseCopy         = "copy"
+	reportPhaseTPM          = "tpm"
+	reportPhaseDone         = "done"
+	reportPhaseFailed       = "failed"
+	reportPhaseCheck        = "check"
+)
+
+// reportNetworkStarted records whether basic networking was brought up to reach the install report URL.
+var reportNetworkStarted bool
+
+// reporter sends the install progress and result to the install report URL, if any. Failing to
+// reach the URL is logged, but never interrupts the install.
+type reporter struct {
+	url      string
+	client   *http.Client
+	hardware *apiseed.InstallReportHardware
+}
+
+// newReporter returns a reporter for the given install report configuration. A nil configuration
+// returns a reporter that doesn't send anything.
+func newReporter(ctx context.Context, config *apiseed.InstallReport) *reporter {
+	if config == nil || config.URL == "" {
+		return &reporter{}
+	}
+
+	client, err := getReportClient(config)
+	if err != nil {
+		slog.WarnContext(ctx, "Unable to configure install reporting", "err", err)
+
+		return &reporter{}
+	}
+
+	// The install doesn't otherwise need any network configuration.
+	if !reportNetworkStarted {
+		err := systemd.StartTemporaryDHCP(ctx, nil, 30*time.Second)
+		if err != nil {
+			slog.WarnContext(ctx, "Unable to configure network for install reporting", "err", err)
+		}
+
+		reportNetworkStarted = true
+	}
+
+	return &reporter{
+		url:      config.URL,
+		client:   client,
+		hardware: getHardwareSummary(ctx),
+	}
+}
+
+// getReportClient returns an HTTP client for the install report URL, using the client certificate if provided.
+func getReportClient(config *apiseed.InstallReport) (*http.Client, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid install report URL %q: %w", config.URL, err)
+	}
+
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return nil, fmt.Errorf("invalid install report URL %q: must be http or https", config.URL)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if config.Certificate != "" || config.Key != "" {
+		cert, err := tls.X509KeyPair([]byte(config.Certificate), []byte(config.Key))
+		if err != nil {
+			return nil, fmt.Errorf("invalid install report client certificate: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}, nil
+}
+
+// getHardwareSummary returns a summary of the system's hardware, used to identify the system being installed.
+func getHardwareSummary(ctx context.Context) *apiseed.InstallReportHardware {
+	ret := &apiseed.InstallReportHardware{}
+
+	r, err := resources.GetResources()
+	if err != nil {
+		slog.WarnContext(ctx, "Unable to get system resources for install reporting", "err", err)
+
+		return ret
+	}
+
+	ret.Vendor = r.System.Vendor
+	ret.Product = r.System.Product
+	ret.Serial = r.System.Serial
+	ret.UUID = r.System.UUID
+	ret.CPUThreads = r.CPU.Total
+	ret.Memory = r.Memory.Total
+
+	if len(r.CPU.Sockets) > 0 {
+		ret.CPU = r.CPU.Sockets[0].Name
+	}
+
+	for _, card := range r.Network.Cards {
+		for _, port := range card.Ports {
+			if port.Address != "" {
+				ret.MACAddresses = append(ret.MACAddresses, port.Address)
+			}
+		}
+	}
+
+	return ret
+}
+
+// setDisks records the potential install disks in the hardware summary.
+func (r *reporter) setDisks(targets []storage.BlockDevices) {
+	if r.hardware == nil {
+		return
+	}
+
+	r.hardware.Disks = make([]string, 0, len(targets))
+
+	for _, target := range targets {
+		r.hardware.Disks = append(r.hardware.Disks, target.ID)
+	}
+}
+
+// send reports an install phase transition.
+func (r *reporter) send(ctx context.Context, event apiseed.InstallReportEvent) {
+	if r.client == nil {
+		return
+	}
+
+	event.Timestamp = time.Now().UTC()
+	event.Hardware = r.hardware
+
+	err := r.post(ctx, event)
+	if err != nil {
+		slog.WarnContext(ctx, "Unable to send install report", "phase", event.Phase, "err", err)
+	}
+}
+
+// fail reports that the install failed.
+func (r *reporter) fail(ctx context.Context, err error) {
+	r.send(ctx, apiseed.InstallReportEvent{
+		Phase: reportPhaseFailed,
+		Error: err.Error(),
+	})
+}
+
+func (r *reporter) post(ctx context.Context, event apiseed.InstallReportEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	_ = resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.New("unexpected HTTP status: " + resp.Status)
+	}
+
+	return nil
+}
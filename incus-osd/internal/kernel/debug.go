@@ -29,7 +29,7 @@ func GetDebugInfo(ctx context.Context) (*api.DebugKernel, error) {
 	info.Architecture = unix.ByteSliceToString(uts.Machine[:])
 
 	// Retrieve the CPU baseline.
-	info.CPUBaseline = cpuBaseline(ctx)
+	info.CPUBaseline = CPUBaseline(ctx)
 
 	// Retrieve the module list.
 	info.Modules, err = getModules()
@@ -40,8 +40,8 @@ func GetDebugInfo(ctx context.Context) (*api.DebugKernel, error) {
 	return info, nil
 }
 
-// cpuBaseline determines the x86_64 micro-architecture baseline from the dynamic loader.
-func cpuBaseline(ctx context.Context) string {
+// CPUBaseline determines the x86_64 micro-architecture baseline from the dynamic loader.
+func CPUBaseline(ctx context.Context) string {
 	output, err := subprocess.RunCommandContext(ctx, "/lib64/ld-linux-x86-64.so.2", "--help")
 	if err != nil {
 		return ""
@@ -0,0 +1,2 @@
+// Package openapi provides the OpenAPI 3 specification of the REST API, converted from the Swagger 2.0 one generated from the source annotations.
+package openapi
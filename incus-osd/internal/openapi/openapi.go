@@ -0,0 +1,264 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+
+	"go.yaml.in/yaml/v4"
+)
+
+// Version is the OpenAPI version of the generated specification.
+const Version = "3.0.3"
+
+//go:generate swagger generate spec -o rest-api.yaml -w ../../cmd/incus-osd -m -x github.com/lxc/incus/v7 -x github.com/FuturFusion/migration-manager -x github.com/FuturFusion/operations-center
+
+// swaggerSpec is the Swagger 2.0 specification generated from the source annotations, which is
+// also published with the documentation.
+//
+//go:embed rest-api.yaml
+var swaggerSpec []byte
+
+var spec = sync.OnceValues(func() ([]byte, error) {
+	return Convert(swaggerSpec)
+})
+
+// Spec returns the OpenAPI 3 specification of the REST API, encoded as JSON.
+func Spec() ([]byte, error) {
+	return spec()
+}
+
+// Convert converts a YAML or JSON encoded Swagger 2.0 specification into a JSON encoded OpenAPI 3 one.
+func Convert(swagger []byte) ([]byte, error) {
+	in := map[string]any{}
+
+	err := yaml.Unmarshal(swagger, &in)
+	if err != nil {
+		return nil, err
+	}
+
+	if in["swagger"] != "2.0" {
+		return nil, errors.New("unsupported specification version")
+	}
+
+	responses := map[string]any{}
+	for name, resp := range asMap(in["responses"]) {
+		responses[name] = convertResponse(asMap(resp), []string{"application/json"})
+	}
+
+	paths := map[string]any{}
+
+	for path, item := range asMap(in["paths"]) {
+		ops := map[string]any{}
+		for method, op := range asMap(item) {
+			ops[method] = convertOperation(asMap(op))
+		}
+
+		paths[path] = ops
+	}
+
+	out := map[string]any{
+		"openapi": Version,
+		"info":    in["info"],
+		"servers": []any{
+			map[string]any{"url": "/", "description": "Local Unix socket or fallback listener"},
+			map[string]any{"url": "/os", "description": "Proxied through the primary application"},
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas":   asMap(in["definitions"]),
+			"responses": responses,
+		},
+	}
+
+	return json.Marshal(rewriteRefs(out))
+}
+
+// convertOperation converts an operation, moving its body parameter into a request body and
+// wrapping parameter and response schemas with their media types.
+func convertOperation(op map[string]any) map[string]any {
+	consumes := mediaTypes(op["consumes"])
+	produces := mediaTypes(op["produces"])
+
+	out := map[string]any{}
+
+	for key, value := range op {
+		switch key {
+		case "consumes", "produces":
+			// Replaced by the media types of the request body and responses.
+		case "parameters":
+			params := []any{}
+
+			for _, p := range asList(value) {
+				param := asMap(p)
+
+				if param["in"] == "body" {
+					body := map[string]any{
+						"content": content(consumes, asMap(param["schema"])),
+					}
+
+					copyKeys(body, param, "description", "required")
+					out["requestBody"] = body
+
+					continue
+				}
+
+				params = append(params, convertParameter(param))
+			}
+
+			if len(params) > 0 {
+				out["parameters"] = params
+			}
+		case "responses":
+			responses := map[string]any{}
+			for code, resp := range asMap(value) {
+				responses[code] = convertResponse(asMap(resp), produces)
+			}
+
+			out["responses"] = responses
+		default:
+			out[key] = value
+		}
+	}
+
+	return out
+}
+
+// convertParameter converts a path or query parameter, moving its type information into a schema.
+func convertParameter(param map[string]any) map[string]any {
+	out := map[string]any{}
+	schema := map[string]any{}
+
+	for key, value := range param {
+		switch key {
+		case "name", "in", "description", "required":
+			out[key] = value
+		case "collectionFormat":
+			// Handled below.
+		default:
+			schema[key] = value
+		}
+	}
+
+	// Swagger 2.0 defaults to comma-separated arrays.
+	if schema["type"] == "array" && (param["collectionFormat"] == nil || param["collectionFormat"] == "csv") {
+		out["style"] = "form"
+		out["explode"] = false
+	}
+
+	out["schema"] = schema
+
+	return out
+}
+
+// convertResponse converts a response, wrapping its schema with the produced media types.
+func convertResponse(resp map[string]any, produces []string) map[string]any {
+	if resp["$ref"] != nil {
+		return resp
+	}
+
+	out := map[string]any{}
+
+	for key, value := range resp {
+		if key == "schema" {
+			out["content"] = content(produces, asMap(value))
+
+			continue
+		}
+
+		out[key] = value
+	}
+
+	return out
+}
+
+// content returns the media type map for a schema. When multiple media types are provided, file
+// schemas are only used for the non-JSON ones and other schemas only for JSON.
+func content(types []string, schema map[string]any) map[string]any {
+	isFile := schema["type"] == "file"
+	if isFile {
+		schema = map[string]any{"type": "string", "format": "binary"}
+	}
+
+	out := map[string]any{}
+
+	for _, mediaType := range types {
+		if len(types) > 1 && isFile == (mediaType == "application/json") {
+			continue
+		}
+
+		out[mediaType] = map[string]any{"schema": schema}
+	}
+
+	return out
+}
+
+// rewriteRefs recursively points Swagger 2.0 references to the matching OpenAPI 3 components.
+func rewriteRefs(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			ref, ok := item.(string)
+			if key == "$ref" && ok {
+				ref = strings.Replace(ref, "#/definitions/", "#/components/schemas/", 1)
+				v[key] = strings.Replace(ref, "#/responses/", "#/components/responses/", 1)
+
+				continue
+			}
+
+			v[key] = rewriteRefs(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = rewriteRefs(item)
+		}
+	}
+
+	return value
+}
+
+func mediaTypes(value any) []string {
+	ret := []string{}
+
+	for _, item := range asList(value) {
+		mediaType, ok := item.(string)
+		if ok {
+			ret = append(ret, mediaType)
+		}
+	}
+
+	if len(ret) == 0 {
+		return []string{"application/json"}
+	}
+
+	return ret
+}
+
+func copyKeys(dst map[string]any, src map[string]any, keys ...string) {
+	for _, key := range keys {
+		value, ok := src[key]
+		if ok {
+			dst[key] = value
+		}
+	}
+}
+
+func asMap(value any) map[string]any {
+	ret, ok := value.(map[string]any)
+	if !ok {
+		return map[string]any{}
+	}
+
+	return ret
+}
+
+func asList(value any) []any {
+	ret, ok := value.([]any)
+	if !ok {
+		return nil
+	}
+
+	return ret
+}
@@ -0,0 +1,270 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/internal/openapi"
+)
+
+// Types of the api package which are only used by internal endpoints.
+var internalTypes = []string{"DebugTUI", "InternalSecureBootCertificates"}
+
+type document struct {
+	OpenAPI    string                               `json:"openapi"`
+	Paths      map[string]map[string]map[string]any `json:"paths"`
+	Components struct {
+		Schemas   map[string]any `json:"schemas"`
+		Responses map[string]any `json:"responses"`
+	} `json:"components"`
+}
+
+func loadSpec(t *testing.T) ([]byte, *document) {
+	t.Helper()
+
+	spec, err := openapi.Spec()
+	require.NoError(t, err)
+
+	doc := &document{}
+
+	err = json.Unmarshal(spec, doc)
+	require.NoError(t, err)
+
+	return spec, doc
+}
+
+// collectRefs returns all the references found in a decoded JSON value.
+func collectRefs(value any) []string {
+	refs := []string{}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			ref, ok := item.(string)
+			if key == "$ref" && ok {
+				refs = append(refs, ref)
+
+				continue
+			}
+
+			refs = append(refs, collectRefs(item)...)
+		}
+	case []any:
+		for _, item := range v {
+			refs = append(refs, collectRefs(item)...)
+		}
+	}
+
+	return refs
+}
+
+func TestSpec(t *testing.T) {
+	t.Parallel()
+
+	spec, doc := loadSpec(t)
+	require.Equal(t, openapi.Version, doc.OpenAPI)
+
+	// All references must point to existing components.
+	raw := map[string]any{}
+
+	err := json.Unmarshal(spec, &raw)
+	require.NoError(t, err)
+
+	for _, ref := range collectRefs(raw) {
+		name, ok := strings.CutPrefix(ref, "#/components/schemas/")
+		if ok {
+			require.Contains(t, doc.Components.Schemas, name)
+
+			continue
+		}
+
+		name, ok = strings.CutPrefix(ref, "#/components/responses/")
+		require.True(t, ok, "Unexpected reference %q", ref)
+		require.Contains(t, doc.Components.Responses, name)
+	}
+
+	// JSON request bodies must be described by a schema, not just an example.
+	for path, ops := range doc.Paths {
+		for method, op := range ops {
+			body, ok := op["requestBody"].(map[string]any)
+			if !ok {
+				continue
+			}
+
+			content, ok := body["content"].(map[string]any)["application/json"].(map[string]any)
+			if !ok {
+				continue
+			}
+
+			schema, _ := content["schema"].(map[string]any)
+			require.True(t, schema["$ref"] != nil || schema["properties"] != nil, "Untyped request body for %s %s", method, path)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	t.Parallel()
+
+	_, err := openapi.Convert([]byte(`openapi: "3.0.3"`))
+	require.Error(t, err)
+
+	spec, err := openapi.Convert([]byte(`
+swagger: "2.0"
+info:
+  title: Test
+  version: "1.0"
+definitions:
+  Item:
+    type: object
+paths:
+  /1.0/items:
+    post:
+      consumes:
+        - application/json
+      parameters:
+        - in: query
+          name: skip
+          type: array
+          items:
+            type: string
+        - in: body
+          name: item
+          required: true
+          schema:
+            $ref: "#/definitions/Item"
+      responses:
+        "200":
+          $ref: "#/responses/Empty"
+  /1.0/items/:export:
+    get:
+      produces:
+        - application/json
+        - application/gzip
+      responses:
+        "200":
+          description: Archive
+          schema:
+            type: file
+responses:
+  Empty:
+    description: Empty response
+    schema:
+      type: object
+`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+  "openapi": "3.0.3",
+  "info": {"title": "Test", "version": "1.0"},
+  "servers": [
+    {"url": "/", "description": "Local Unix socket or fallback listener"},
+    {"url": "/os", "description": "Proxied through the primary application"}
+  ],
+  "components": {
+    "schemas": {"Item": {"type": "object"}},
+    "responses": {"Empty": {"description": "Empty response", "content": {"application/json": {"schema": {"type": "object"}}}}}
+  },
+  "paths": {
+    "/1.0/items": {
+      "post": {
+        "parameters": [{"in": "query", "name": "skip", "style": "form", "explode": false, "schema": {"type": "array", "items": {"type": "string"}}}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Item"}}}},
+        "responses": {"200": {"$ref": "#/components/responses/Empty"}}
+      }
+    },
+    "/1.0/items/:export": {
+      "get": {
+        "responses": {"200": {"description": "Archive", "content": {"application/gzip": {"schema": {"type": "string", "format": "binary"}}}}}
+      }
+    }
+  }
+}`, string(spec))
+}
+
+func TestDrift(t *testing.T) {
+	t.Parallel()
+
+	_, doc := loadSpec(t)
+
+	// Every public route must be documented, and every documented path must be routed.
+	fset := token.NewFileSet()
+
+	server, err := parser.ParseFile(fset, "../rest/server.go", nil, 0)
+	require.NoError(t, err)
+
+	routes := []string{}
+
+	ast.Inspect(server, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "HandleFunc" || len(call.Args) == 0 {
+			return true
+		}
+
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok {
+			return true
+		}
+
+		route, err := strconv.Unquote(lit.Value)
+		require.NoError(t, err)
+
+		if !strings.HasPrefix(route, "/internal/") {
+			routes = append(routes, route)
+		}
+
+		return true
+	})
+
+	require.NotEmpty(t, routes)
+
+	for _, route := range routes {
+		require.Contains(t, doc.Paths, route, "Undocumented route")
+	}
+
+	for path := range doc.Paths {
+		require.Contains(t, routes, path, "Documented path isn't routed")
+	}
+
+	// Every type of the api package must be documented.
+	entries, err := os.ReadDir("../../api")
+	require.NoError(t, err)
+
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".go") || strings.HasSuffix(entry.Name(), "_test.go") {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, filepath.Join("../../api", entry.Name()), nil, 0)
+		require.NoError(t, err)
+
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+
+			for _, typeSpec := range gen.Specs {
+				name := typeSpec.(*ast.TypeSpec).Name.Name //nolint:forcetypeassert
+				if !ast.IsExported(name) || slices.Contains(internalTypes, name) {
+					continue
+				}
+
+				require.Contains(t, doc.Components.Schemas, name, "Undocumented type")
+			}
+		}
+	}
+}
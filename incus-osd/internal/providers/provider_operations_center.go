@@ -185,9 +185,13 @@ func (p *operationsCenter) Register(ctx context.Context) error {
 		}
 
 		// Also trust the certificate on the fallback listener.
+		p.state.FallbackListenerMutex.Lock()
+
 		if !slices.Contains(p.state.System.FallbackListener.Config.TrustedClientCertificates, registrationResp.ClientCertificate) {
 			p.state.System.FallbackListener.Config.TrustedClientCertificates = append(p.state.System.FallbackListener.Config.TrustedClientCertificates, registrationResp.ClientCertificate)
 		}
+
+		p.state.FallbackListenerMutex.Unlock()
 	}
 
 	// Log our successful registration and save state.
@@ -0,0 +1,341 @@
+package recovery
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/lxc/incus-os/incus-osd/certs"
+	"github.com/lxc/incus-os/incus-osd/internal/applications"
+	"github.com/lxc/incus-os/incus-osd/internal/backup"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/util"
+)
+
+// backupStagingPath holds the application backups waiting to be restored, along with a record of the last
+// applied backup manifest. It lives outside of /var/lib/incus-os/ so it's kept across an OS backup restore.
+const backupStagingPath = "/var/lib/incus-os-recovery/"
+
+// backupManifest describes the backups to restore from the recovery partition.
+type backupManifest struct {
+	OS           *backupFile           `json:"os"`
+	Applications map[string]backupFile `json:"applications"`
+	Skip         []string              `json:"skip"`
+}
+
+// backupFile references a backup archive in the backup/ folder of the recovery partition.
+type backupFile struct {
+	Filename string `json:"filename"`
+	Sha256   string `json:"sha256"`
+}
+
+// validate checks that the manifest references at least one backup, and only known applications.
+func (m *backupManifest) validate() error {
+	if m.OS == nil && len(m.Applications) == 0 {
+		return errors.New("backup manifest doesn't reference any backup")
+	}
+
+	files := make([]backupFile, 0, len(m.Applications)+1)
+
+	if m.OS != nil {
+		files = append(files, *m.OS)
+	}
+
+	for name, file := range m.Applications {
+		if !slices.Contains(applications.Supported, name) {
+			return errors.New("unknown application '" + name + "' in backup manifest")
+		}
+
+		files = append(files, file)
+	}
+
+	for _, file := range files {
+		// Don't let someone feed us a path traversal escape attack.
+		if file.Filename == "" || file.Filename != filepath.Base(file.Filename) {
+			return errors.New("invalid backup filename '" + file.Filename + "'")
+		}
+
+		if file.Sha256 == "" {
+			return errors.New("missing sha256 for backup file " + file.Filename)
+		}
+	}
+
+	return nil
+}
+
+// restoreBackup restores the backups described in the backup/ folder of the recovery partition. The backup
+// manifest must either be signed, or come with one of the system's encryption recovery keys.
+//
+// Application backups are staged and applied by ApplyApplicationBackups once the storage pools are available,
+// while an OS backup restore reboots the system.
+func restoreBackup(ctx context.Context, s *state.State, mountDir string) error {
+	backupDir := filepath.Join(mountDir, "backup")
+
+	manifest, digest, err := getBackupManifest(ctx, s, backupDir)
+	if err != nil {
+		return err
+	}
+
+	if manifest == nil {
+		return nil
+	}
+
+	// Don't restore the same backup on every boot while the recovery media remains attached.
+	applied, err := os.ReadFile(filepath.Join(backupStagingPath, "applied"))
+	if err == nil && string(applied) == digest {
+		slog.InfoContext(ctx, "Backup from recovery partition already restored, skipping")
+
+		return nil
+	}
+
+	err = manifest.validate()
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Verifying each backup file")
+
+	if manifest.OS != nil {
+		err := verifyBackupFile(backupDir, *manifest.OS)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, file := range manifest.Applications {
+		err := verifyBackupFile(backupDir, file)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Stage the application backups.
+	err = os.RemoveAll(backupStagingPath)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Join(backupStagingPath, "applications"), 0o700)
+	if err != nil {
+		return err
+	}
+
+	for name, file := range manifest.Applications {
+		err := copyBackupFile(filepath.Join(backupDir, file.Filename), filepath.Join(backupStagingPath, "applications", name+".tar.gz"))
+		if err != nil {
+			_ = os.RemoveAll(backupStagingPath)
+
+			return err
+		}
+	}
+
+	// Record the backup as applied once staged, so it isn't staged again. Any application backup which fails
+	// to restore is kept staged by ApplyApplicationBackups until it's successfully restored.
+	err = os.WriteFile(filepath.Join(backupStagingPath, "applied"), []byte(digest), 0o600)
+	if err != nil {
+		_ = os.RemoveAll(backupStagingPath)
+
+		return err
+	}
+
+	if manifest.OS == nil {
+		return nil
+	}
+
+	slog.InfoContext(ctx, "Restoring OS backup from recovery partition")
+
+	// #nosec G304
+	fd, err := os.Open(filepath.Join(backupDir, manifest.OS.Filename))
+	if err != nil {
+		_ = os.RemoveAll(backupStagingPath)
+
+		return err
+	}
+	defer fd.Close()
+
+	// On success, the system is rebooted and any application backup is restored on the next boot.
+	err = backup.ApplyOSBackup(ctx, s, fd, manifest.Skip)
+	if err != nil {
+		_ = os.RemoveAll(backupStagingPath)
+
+		return err
+	}
+
+	return nil
+}
+
+// getBackupManifest returns the authenticated backup manifest and its digest, or nil if no backup is present.
+func getBackupManifest(ctx context.Context, s *state.State, backupDir string) (*backupManifest, string, error) {
+	var content []byte
+
+	signedContent, err := os.ReadFile(filepath.Join(backupDir, "manifest.sjson"))
+	if err == nil {
+		slog.InfoContext(ctx, "Backup manifest detected, verifying signature")
+
+		// Load the embedded certificates.
+		embeddedCerts, err := certs.GetEmbeddedCertificates()
+		if err != nil {
+			return nil, "", err
+		}
+
+		// Validate the signed manifest using the Support intermediate CA.
+		verified, err := util.VerifySMIME(ctx, []*x509.Certificate{embeddedCerts.SupportCACertificate}, signedContent)
+		if err != nil {
+			return nil, "", err
+		}
+
+		content = verified.Bytes()
+	} else {
+		content, err = os.ReadFile(filepath.Join(backupDir, "manifest.json"))
+		if err != nil {
+			// If no manifest is present, nothing to do.
+			return nil, "", nil //nolint:nilerr
+		}
+
+		slog.InfoContext(ctx, "Unsigned backup manifest detected, verifying recovery key")
+
+		recoveryKey, err := os.ReadFile(filepath.Join(backupDir, "recovery.key"))
+		if err != nil {
+			return nil, "", errors.New("unsigned backup manifest requires a recovery key")
+		}
+
+		if !s.IsEncryptionRecoveryKey(strings.TrimSpace(string(recoveryKey))) {
+			return nil, "", errors.New("recovery key doesn't match any of the system's encryption recovery keys")
+		}
+	}
+
+	manifest := &backupManifest{}
+
+	err = json.Unmarshal(content, manifest)
+	if err != nil {
+		return nil, "", err
+	}
+
+	digest := sha256.Sum256(content)
+
+	return manifest, hex.EncodeToString(digest[:]), nil
+}
+
+func verifyBackupFile(backupDir string, file backupFile) error {
+	// #nosec G304
+	fd, err := os.Open(filepath.Join(backupDir, file.Filename))
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	h := sha256.New()
+
+	_, err = io.Copy(h, fd)
+	if err != nil {
+		return err
+	}
+
+	if file.Sha256 != hex.EncodeToString(h.Sum(nil)) {
+		return errors.New("sha256 mismatch for file " + file.Filename)
+	}
+
+	return nil
+}
+
+func copyBackupFile(srcPath string, dstPath string) error {
+	// #nosec G304
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// #nosec G304
+	dst, err := os.OpenFile(dstPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	_, err = io.Copy(dst, src)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ApplyApplicationBackups restores any application backup staged from the recovery partition. This must be
+// done once the storage pools are available, but before the applications are started.
+func ApplyApplicationBackups(ctx context.Context, s *state.State) error {
+	stagingDir := filepath.Join(backupStagingPath, "applications")
+
+	entries, err := os.ReadDir(stagingDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	failed := []string{}
+
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".tar.gz")
+
+		err := applyApplicationBackup(ctx, s, name, filepath.Join(stagingDir, entry.Name()))
+		if err != nil {
+			// Don't prevent the other applications from being restored, and keep the backup staged so
+			// it's restored again on the next boot.
+			slog.ErrorContext(ctx, "Unable to restore application backup from recovery partition", "name", name, "err", err)
+
+			failed = append(failed, name)
+
+			continue
+		}
+
+		err = os.Remove(filepath.Join(stagingDir, entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.Save()
+	if err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		return errors.New("unable to restore the backup of " + strings.Join(failed, ", ") + ", keeping it staged for the next boot")
+	}
+
+	return os.Remove(stagingDir)
+}
+
+func applyApplicationBackup(ctx context.Context, s *state.State, name string, archivePath string) error {
+	app, err := applications.Load(ctx, s, name)
+	if err != nil {
+		return err
+	}
+
+	if !app.IsInstalled() {
+		return errors.New("application isn't installed")
+	}
+
+	slog.InfoContext(ctx, "Restoring application backup from recovery partition", "name", name)
+
+	// #nosec G304
+	fd, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	return app.RestoreBackup(fd)
+}
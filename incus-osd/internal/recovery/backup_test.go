@@ -0,0 +1,33 @@
+package recovery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupManifestValidate(t *testing.T) {
+	t.Parallel()
+
+	// A manifest must reference at least one backup.
+	manifest := &backupManifest{}
+	require.EqualError(t, manifest.validate(), "backup manifest doesn't reference any backup")
+
+	// Only known applications can be restored.
+	manifest = &backupManifest{Applications: map[string]backupFile{"foo": {Filename: "foo.tar.gz", Sha256: "abcd"}}}
+	require.EqualError(t, manifest.validate(), "unknown application 'foo' in backup manifest")
+
+	// Backup files must be in the backup folder.
+	manifest = &backupManifest{OS: &backupFile{Filename: "../os.tar.gz", Sha256: "abcd"}}
+	require.EqualError(t, manifest.validate(), "invalid backup filename '../os.tar.gz'")
+
+	// Backup files must have a checksum.
+	manifest = &backupManifest{OS: &backupFile{Filename: "os.tar.gz"}}
+	require.EqualError(t, manifest.validate(), "missing sha256 for backup file os.tar.gz")
+
+	manifest = &backupManifest{
+		OS:           &backupFile{Filename: "os.tar.gz", Sha256: "abcd"},
+		Applications: map[string]backupFile{"incus": {Filename: "incus.tar.gz", Sha256: "ef01"}},
+	}
+	require.NoError(t, manifest.validate())
+}
@@ -1,2 +1,2 @@
-// Package recovery implements logic for running recovery hotfix scripts and updates from removable media.
+// Package recovery implements logic for running recovery hotfix scripts, updates and backup restores from removable media.
 package recovery
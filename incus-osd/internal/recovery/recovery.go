@@ -30,9 +30,10 @@ import (
 
 // CheckRunRecovery checks if a partition labeled "RESCUE_DATA" is present. If so,
 // and if the filesystem is vfat or iso9660, it will mount the partition and first
-// run any hotfix.sh script, then apply any updates in the update/ folder. Both
-// the hotfix script and update metadata is verified to have been properly signed
-// by the expected certificate.
+// run any hotfix.sh script, then apply any updates in the update/ folder, and
+// finally restore any backups in the backup/ folder. The hotfix script and update
+// metadata are verified to have been properly signed by the expected certificate,
+// while the backup manifest must be signed or come with a recovery key.
 func CheckRunRecovery(ctx context.Context, s *state.State) error {
 	device := "/dev/disk/by-partlabel/RESCUE_DATA"
 
@@ -86,6 +87,12 @@ func CheckRunRecovery(ctx context.Context, s *state.State) error {
 		return err
 	}
 
+	// Restore the backup(s), if any.
+	err = restoreBackup(ctx, s, mountDir)
+	if err != nil {
+		return err
+	}
+
 	slog.InfoContext(ctx, "Recovery actions completed")
 
 	return nil
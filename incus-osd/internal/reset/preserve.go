@@ -0,0 +1,202 @@
+package reset
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+	"github.com/lxc/incus-os/incus-osd/internal/seed"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+	"github.com/lxc/incus-os/incus-osd/internal/zfs"
+)
+
+// poolKeyCredentialName returns the name a preserved storage pool's encryption key is sealed under.
+func poolKeyCredentialName(pool string) string {
+	return "incus-os-pool-" + pool
+}
+
+// checkPreservedSections verifies that all the given sections of the current configuration can be preserved.
+func checkPreservedSections(sections []string) error {
+	for _, section := range sections {
+		if !slices.Contains(api.SystemResetPreserveSections, section) {
+			return errors.New("unknown section '" + section + "' to preserve")
+		}
+	}
+
+	return nil
+}
+
+// sealPoolKeys seals the storage pools' encryption keys to the TPM, bound to PCR 7 and the signed PCR 11 policy, so
+// they're never written in plaintext to the seed partition. The sealed keys are returned base64-encoded.
+func sealPoolKeys(ctx context.Context, poolKeys map[string]string) (map[string]string, error) {
+	ret := make(map[string]string, len(poolKeys))
+
+	for name, key := range poolKeys {
+		// The "local" pool lives on the main system drive and is wiped.
+		if name == "local" {
+			continue
+		}
+
+		sealed, err := systemd.EncryptTPMCredential(ctx, poolKeyCredentialName(name), []byte(key))
+		if err != nil {
+			return nil, fmt.Errorf("unable to seal the encryption key of storage pool %q: %w", name, err)
+		}
+
+		ret[name] = base64.StdEncoding.EncodeToString(sealed)
+	}
+
+	return ret, nil
+}
+
+// unsealPoolKey returns the encryption key of a preserved storage pool, as sealed by sealPoolKeys.
+func unsealPoolKey(ctx context.Context, pool api.SystemStoragePoolKey) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(pool.EncryptionKey)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := systemd.DecryptCredential(ctx, poolKeyCredentialName(pool.Name), sealed)
+	if err != nil {
+		return "", err
+	}
+
+	return string(key), nil
+}
+
+// getPreservedSeeds returns the seeds which restore the given sections of the current configuration on first boot
+// following the factory reset. The storage pools' encryption keys must already be sealed by sealPoolKeys.
+func getPreservedSeeds(s *state.State, sections []string, poolKeys map[string]string) (map[string][]byte, error) {
+	err := checkPreservedSections(sections)
+	if err != nil {
+		return nil, err
+	}
+
+	seeds := map[string]any{}
+
+	if slices.Contains(sections, api.SystemResetPreserveNetwork) && s.System.Network.Config != nil {
+		seeds["network"] = &apiseed.Network{
+			SystemNetworkConfig: *s.System.Network.Config,
+			Version:             "1",
+		}
+	}
+
+	if slices.Contains(sections, api.SystemResetPreserveProvider) && s.System.Provider.Config.Name != "" {
+		seeds["provider"] = &apiseed.Provider{
+			SystemProviderConfig: s.System.Provider.Config,
+			Version:              "1",
+		}
+	}
+
+	// Storage pools and trusted certificates have no dedicated seed, so are held in the reset seed.
+	resetSeed := &apiseed.Reset{Version: "1"}
+
+	if slices.Contains(sections, api.SystemResetPreserveStorage) {
+		// The "local" pool lives on the main system drive and is wiped.
+		for name, key := range poolKeys {
+			if name == "local" {
+				continue
+			}
+
+			resetSeed.Pools = append(resetSeed.Pools, api.SystemStoragePoolKey{
+				Name:          name,
+				Type:          "zfs",
+				EncryptionKey: key,
+			})
+		}
+
+		slices.SortFunc(resetSeed.Pools, func(a, b api.SystemStoragePoolKey) int {
+			return cmp.Compare(a.Name, b.Name)
+		})
+	}
+
+	if slices.Contains(sections, api.SystemResetPreserveCertificates) {
+		resetSeed.TrustedClientCertificates = s.System.FallbackListener.Config.TrustedClientCertificates
+	}
+
+	if len(resetSeed.Pools) > 0 || len(resetSeed.TrustedClientCertificates) > 0 {
+		seeds["reset"] = resetSeed
+	}
+
+	ret := make(map[string][]byte, len(seeds))
+
+	for name, content := range seeds {
+		data, err := json.Marshal(content)
+		if err != nil {
+			return nil, err
+		}
+
+		ret[name] = data
+	}
+
+	return ret, nil
+}
+
+// ApplyPreserved restores the storage pools and trusted certificates preserved by a factory reset, if any. As the
+// reset seed holds the pools' sealed encryption keys, it's removed and wiped from the seed partition once all the
+// pools are imported, and otherwise kept so the remaining pools are imported on the next boot.
+func ApplyPreserved(ctx context.Context, s *state.State) error {
+	resetSeed, err := seed.GetReset(ctx)
+	if err != nil {
+		if seed.IsMissing(err) {
+			return nil
+		}
+
+		return errors.New("unable to parse reset seed: " + err.Error())
+	}
+
+	// A pool failing to import shouldn't prevent the system from starting.
+	failedPools := []string{}
+
+	for _, pool := range resetSeed.Pools {
+		// Skip pools imported on a previous boot.
+		if zfs.HasKey(pool.Name) {
+			continue
+		}
+
+		slog.InfoContext(ctx, "Importing storage pool preserved by the factory reset", "name", pool.Name)
+
+		key, err := unsealPoolKey(ctx, pool)
+		if err != nil {
+			slog.ErrorContext(ctx, "Unable to unseal the encryption key of storage pool preserved by the factory reset", "name", pool.Name, "err", err)
+
+			failedPools = append(failedPools, pool.Name)
+
+			continue
+		}
+
+		err = zfs.ImportExistingPool(ctx, pool.Name, key)
+		if err != nil {
+			slog.ErrorContext(ctx, "Unable to import storage pool preserved by the factory reset", "name", pool.Name, "err", err)
+
+			failedPools = append(failedPools, pool.Name)
+		}
+	}
+
+	for _, cert := range resetSeed.TrustedClientCertificates {
+		if !slices.Contains(s.System.FallbackListener.Config.TrustedClientCertificates, cert) {
+			s.System.FallbackListener.Config.TrustedClientCertificates = append(s.System.FallbackListener.Config.TrustedClientCertificates, cert)
+		}
+	}
+
+	err = s.Save()
+	if err != nil {
+		return err
+	}
+
+	// Keep the encryption keys of the pools which couldn't be imported.
+	if len(failedPools) > 0 {
+		slog.WarnContext(ctx, "Keeping the reset seed until all preserved storage pools are imported", "pools", failedPools)
+
+		return nil
+	}
+
+	return seed.Remove(ctx, "reset")
+}
@@ -0,0 +1,47 @@
+package reset
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+func TestGetPreservedSeeds(t *testing.T) {
+	t.Parallel()
+
+	s := &state.State{}
+	s.System.Network.Config = &api.SystemNetworkConfig{}
+	s.System.Provider.Config.Name = "operations-center"
+	s.System.FallbackListener.Config.TrustedClientCertificates = []string{"cert"}
+
+	poolKeys := map[string]string{"local": "a", "tank": "b", "data": "c"}
+
+	// Nothing is preserved by default.
+	seeds, err := getPreservedSeeds(s, nil, poolKeys)
+	require.NoError(t, err)
+	require.Empty(t, seeds)
+
+	// Unknown sections are rejected.
+	_, err = getPreservedSeeds(s, []string{"network", "foo"}, poolKeys)
+	require.EqualError(t, err, "unknown section 'foo' to preserve")
+
+	// Each section is written to its seed.
+	seeds, err = getPreservedSeeds(s, api.SystemResetPreserveSections, poolKeys)
+	require.NoError(t, err)
+	require.Len(t, seeds, 3)
+	require.Contains(t, seeds, "network")
+	require.Contains(t, seeds, "provider")
+
+	// The "local" pool isn't preserved.
+	resetSeed := &apiseed.Reset{}
+
+	err = json.Unmarshal(seeds["reset"], resetSeed)
+	require.NoError(t, err)
+	require.Equal(t, []api.SystemStoragePoolKey{{Name: "data", Type: "zfs", EncryptionKey: "c"}, {Name: "tank", Type: "zfs", EncryptionKey: "b"}}, resetSeed.Pools)
+	require.Equal(t, []string{"cert"}, resetSeed.TrustedClientCertificates)
+}
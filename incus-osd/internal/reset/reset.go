@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/lxc/incus/v7/shared/subprocess"
@@ -15,14 +16,20 @@ import (
 
 	"github.com/lxc/incus-os/incus-osd/api"
 	"github.com/lxc/incus-os/incus-osd/internal/install"
+	"github.com/lxc/incus-os/incus-osd/internal/secureboot"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
 	"github.com/lxc/incus-os/incus-osd/internal/storage"
+	"github.com/lxc/incus-os/incus-osd/internal/zfs"
 )
 
 // PerformOSFactoryReset performs an OS-level factory reset.
 // !!! THIS WILL RESULT IN THE DESTRUCTION OF ALL DATA CREATED BY !!!
 // !!! IncusOS, ANY APPLICATIONS, AND ANY ZFS DATASETS CREATED IN !!!
 // !!! THE "local" POOL.                                          !!!
-func PerformOSFactoryReset(ctx context.Context, resetSeed *api.SystemReset) error {
+//
+// Sections of the current configuration listed in the reset's Preserve field are written to the seed
+// partition, and restored when the system configures itself after rebooting.
+func PerformOSFactoryReset(ctx context.Context, s *state.State, resetSeed *api.SystemReset) error {
 	// systemd v258 introduced the factory-reset.target, which in
 	// theory should automate the following steps. However, trixie
 	// shipped with systemd v257. Potentially we could use a backported
@@ -57,14 +64,32 @@ func PerformOSFactoryReset(ctx context.Context, resetSeed *api.SystemReset) erro
 		}
 	}
 
-	// First, write the seed data. Do this first, since any errors encountered won't cause
-	// the currently running system to break.
+	// Get the configuration to preserve, if any.
+	err = checkPreservedSections(resetSeed.Preserve)
+	if err != nil {
+		return err
+	}
+
+	poolKeys := map[string]string{}
+
+	if slices.Contains(resetSeed.Preserve, api.SystemResetPreserveStorage) {
+		// The pools' encryption keys are sealed to the TPM, which can't be done with a swtpm as its state is wiped.
+		if secureboot.GetSWTPMInUse() {
+			return errors.New("storage pools can't be preserved when using a swtpm-backed TPM")
+		}
+
+		poolKeys, err = zfs.GetZpoolEncryptionKeys()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Read any existing seed data and augment it with provided seed update(s), if any.
 	partitionPrefix := install.GetPartitionPrefix(underlyingDevice)
 	seedPartition := underlyingDevice + partitionPrefix + "2"
 	seeds := make(map[string][]byte)
 
 	if !resetSeed.WipeExistingSeeds {
-		// Read any existing seed data and augment it with provided seed update(s), if any.
 		existingSeeds, err := getExistingSeeds(seedPartition)
 		if err != nil {
 			return err
@@ -88,6 +113,44 @@ func PerformOSFactoryReset(ctx context.Context, resetSeed *api.SystemReset) erro
 		}
 	}
 
+	// Beyond this point, we start making destructive changes to the system.
+	// If an error is encountered, we'll likely end up with a bricked system.
+
+	// First, wipe the TPM. This is done before writing the seed data, as the preserved storage pools'
+	// encryption keys are sealed to the TPM and couldn't be unsealed once it's cleared.
+	_, err = subprocess.RunCommandContext(ctx, "tpm2_clear")
+	if err != nil {
+		// Some systems return errors when trying to clear the TPM. As a workaround,
+		// allow the user to indicate we should accept this error and continue.
+		if !resetSeed.AllowTPMResetFailure {
+			return err
+		}
+	}
+
+	// Second, write the seed data, adding the preserved configuration unless replaced by provided seed data.
+	sealedPoolKeys, err := sealPoolKeys(ctx, poolKeys)
+	if err != nil {
+		return err
+	}
+
+	preservedSeeds, err := getPreservedSeeds(s, resetSeed.Preserve, sealedPoolKeys)
+	if err != nil {
+		return err
+	}
+
+	for seed, seedData := range preservedSeeds {
+		_, found := resetSeed.Seeds[seed]
+		if found {
+			continue
+		}
+
+		for _, ext := range []string{".json", ".yaml", ".yml"} {
+			delete(seeds, seed+ext)
+		}
+
+		seeds[seed+".json"] = seedData
+	}
+
 	// #nosec G304
 	f, err := os.Create(seedPartition)
 	if err != nil {
@@ -120,19 +183,6 @@ func PerformOSFactoryReset(ctx context.Context, resetSeed *api.SystemReset) erro
 		return err
 	}
 
-	// Beyond this point, we start making destructive changes to the system.
-	// If an error is encountered, we'll likely end up with a bricked system.
-
-	// Second, wipe the TPM.
-	_, err = subprocess.RunCommandContext(ctx, "tpm2_clear")
-	if err != nil {
-		// Some systems return errors when trying to clear the TPM. As a workaround,
-		// allow the user to indicate we should accept this error and continue.
-		if !resetSeed.AllowTPMResetFailure {
-			return err
-		}
-	}
-
 	// Third, wipe any configuration that might exist if the system was operating in a degraded
 	// security state. If the system is still in a degraded security state when it reboots, first-boot
 	// logic will take care of re-configuring the system as appropriate.
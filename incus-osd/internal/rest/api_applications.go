@@ -16,6 +16,7 @@ import (
 	"github.com/lxc/incus-os/incus-osd/api"
 	"github.com/lxc/incus-os/incus-osd/internal/applications"
 	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
 	"github.com/lxc/incus-os/incus-osd/internal/update"
 )
 
@@ -71,8 +72,7 @@ import (
 //	    description: Application to be installed
 //	    required: true
 //	    schema:
-//	      type: object
-//	      example: {"name": "gpu-support"}
+//	      $ref: "#/definitions/ApplicationPost"
 //	responses:
 //	  "200":
 //	    $ref: "#/responses/EmptySyncResponse"
@@ -117,11 +117,7 @@ func (s *Server) apiApplications(w http.ResponseWriter, r *http.Request) {
 		_ = response.SyncResponse(true, urls).Render(w)
 
 	case http.MethodPost:
-		type applicationPost struct {
-			Name string `json:"name"`
-		}
-
-		app := &applicationPost{}
+		app := &api.ApplicationPost{}
 
 		counter := &countWrapper{ReadCloser: r.Body}
 
@@ -311,6 +307,8 @@ func (s *Server) apiApplications(w http.ResponseWriter, r *http.Request) {
 //	    $ref: "#/responses/EmptySyncResponse"
 //	  "404":
 //	    $ref: "#/responses/NotFound"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
 func (s *Server) apiApplicationsEndpoint(w http.ResponseWriter, r *http.Request) {
@@ -361,8 +359,23 @@ func (s *Server) apiApplicationsEndpoint(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		_ = response.SyncResponse(true, resp).Render(w)
+		_ = response.SyncResponseETag(true, resp, configOf(resp)).Render(w)
 	case http.MethodPut:
+		current, err := app.Get(r.Context())
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		// Ensure the configuration wasn't modified since it was retrieved.
+		err = response.EtagCheck(r, configOf(current))
+		if err != nil {
+			_ = response.EtagFailure(err).Render(w)
+
+			return
+		}
+
 		dest := app.Struct()
 
 		decoder := json.NewDecoder(r.Body)
@@ -374,6 +387,20 @@ func (s *Server) apiApplicationsEndpoint(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
+		// Keep the current value of any secret sent back as a placeholder.
+		err = secrets.Restore(dest, current)
+		if err != nil {
+			if errors.Is(err, secrets.ErrUnknownPlaceholder) || errors.Is(err, secrets.ErrUnavailable) {
+				_ = response.BadRequest(err).Render(w)
+
+				return
+			}
+
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
 		err = app.UpdateConfig(r.Context(), dest)
 		if err != nil {
 			_ = response.InternalError(err).Render(w)
@@ -683,8 +710,7 @@ func (s *Server) apiApplicationsRestart(w http.ResponseWriter, r *http.Request)
 //	    description: Backup configuration
 //	    required: false
 //	    schema:
-//	      type: object
-//	      example: {"complete":true}
+//	      $ref: "#/definitions/ApplicationBackup"
 //	responses:
 //	  "200":
 //	    description: gzip'ed tar archive
@@ -721,11 +747,7 @@ func (s *Server) apiApplicationsBackup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	type backupStruct struct {
-		Complete bool `json:"complete"`
-	}
-
-	config := &backupStruct{}
+	config := &api.ApplicationBackup{}
 
 	counter := &countWrapper{ReadCloser: r.Body}
 
@@ -979,8 +1001,7 @@ func (s *Server) apiApplicationsCheckUpdate(w http.ResponseWriter, r *http.Reque
 //	    description: Optional exact version to switch to
 //	    required: false
 //	    schema:
-//	      type: object
-//	      example: {"version": "202603201831"}
+//	      $ref: "#/definitions/ApplicationSwitchVersion"
 //	responses:
 //	  "200":
 //	    $ref: "#/responses/EmptySyncResponse"
@@ -1016,11 +1037,7 @@ func (s *Server) apiApplicationsSwitchVersion(w http.ResponseWriter, r *http.Req
 	}
 
 	// Get the specific application version to switch to, if specified.
-	type versionInfo struct {
-		Version string `json:"version"`
-	}
-
-	vi := &versionInfo{}
+	vi := &api.ApplicationSwitchVersion{}
 
 	counter := &countWrapper{ReadCloser: r.Body}
 
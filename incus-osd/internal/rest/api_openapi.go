@@ -0,0 +1,44 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/lxc/incus-os/incus-osd/internal/openapi"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+)
+
+// swagger:operation GET /1.0/openapi.json server server_get_openapi
+//
+//	Get the OpenAPI specification
+//
+//	Returns the OpenAPI 3 specification of this API. Unlike other endpoints, the document isn't
+//	wrapped in a sync response, allowing it to be used as-is by client generators.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: OpenAPI 3 specification
+//	    schema:
+//	      type: object
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (*Server) apiOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	spec, err := openapi.Spec()
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_, _ = w.Write(spec)
+}
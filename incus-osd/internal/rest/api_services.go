@@ -2,11 +2,14 @@ package rest
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/url"
 	"slices"
 
+	"github.com/lxc/incus-os/incus-osd/internal/history"
 	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
 	"github.com/lxc/incus-os/incus-osd/internal/services"
 )
 
@@ -145,6 +148,8 @@ func (s *Server) apiServices(w http.ResponseWriter, r *http.Request) {
 //	    $ref: "#/responses/EmptySyncResponse"
 //	  "404":
 //	    $ref: "#/responses/NotFound"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
 func (s *Server) apiServicesEndpoint(w http.ResponseWriter, r *http.Request) {
@@ -177,9 +182,24 @@ func (s *Server) apiServicesEndpoint(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		_ = response.SyncResponse(true, resp).Render(w)
+		_ = response.SyncResponseETag(true, resp, configOf(resp)).Render(w)
 
 	case http.MethodPut:
+		current, err := srv.Get(r.Context())
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		// Ensure the configuration wasn't modified since it was retrieved.
+		err = response.EtagCheck(r, configOf(current))
+		if err != nil {
+			_ = response.EtagFailure(err).Render(w)
+
+			return
+		}
+
 		dest := srv.Struct()
 
 		decoder := json.NewDecoder(r.Body)
@@ -191,6 +211,20 @@ func (s *Server) apiServicesEndpoint(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// Keep the current value of any secret sent back as a placeholder.
+		err = secrets.Restore(dest, current)
+		if err != nil {
+			if errors.Is(err, secrets.ErrUnknownPlaceholder) || errors.Is(err, secrets.ErrUnavailable) {
+				_ = response.BadRequest(err).Render(w)
+
+				return
+			}
+
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
 		err = srv.Update(r.Context(), dest)
 		if err != nil {
 			_ = response.InternalError(err).Render(w)
@@ -198,6 +232,9 @@ func (s *Server) apiServicesEndpoint(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		s.recordHistory(r, history.ServiceSection(name), configOf(current), configOf(dest))
+		_ = s.state.Save()
+
 		_ = response.EmptySyncResponse.Render(w)
 	default:
 		_ = response.NotImplemented(nil).Render(w)
@@ -0,0 +1,363 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/history"
+	"github.com/lxc/incus-os/incus-osd/internal/kernel"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
+	"github.com/lxc/incus-os/incus-osd/internal/seed"
+	"github.com/lxc/incus-os/incus-osd/internal/services"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+// applySection holds a single configuration section being applied as part of a transaction.
+type applySection struct {
+	name     string
+	prior    any
+	current  any
+	apply    func(ctx context.Context) error
+	rollback func(ctx context.Context) error
+}
+
+// swagger:operation POST /1.0/system/:apply system system_post_apply
+//
+//	Apply several configuration sections at once
+//
+//	Applies the provided configuration sections as a single transaction. All sections are
+//	validated before any change is made, then applied in dependency order (kernel, network,
+//	services, logging and update). If any section fails to apply, all sections are rolled
+//	back to their prior configuration.
+//
+//	The ETags returned when retrieving each section can be provided, keyed by the section's
+//	path (such as "system/network" or "services/lvm"), in which case nothing is applied if
+//	any of those sections was modified in the meantime.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: configuration
+//	    description: Configuration sections to apply
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/SystemApply"
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemApply(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	req := &api.SystemApply{}
+
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	// Validate all the sections before making any change.
+	sections, err := s.prepareApply(r.Context(), req)
+	if err != nil {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	if len(sections) == 0 {
+		_ = response.BadRequest(errors.New("no configuration section provided")).Render(w)
+
+		return
+	}
+
+	// Ensure none of the sections were modified since they were retrieved.
+	for name := range req.ETags {
+		if !slices.ContainsFunc(sections, func(section applySection) bool { return section.name == name }) {
+			_ = response.BadRequest(fmt.Errorf("ETag provided for section %q which isn't being applied", name)).Render(w)
+
+			return
+		}
+	}
+
+	for _, section := range sections {
+		err := response.EtagMatch(section.prior, req.ETags[section.name])
+		if err != nil {
+			_ = response.EtagFailure(fmt.Errorf("section %q: %w", section.name, err)).Render(w)
+
+			return
+		}
+	}
+
+	// Ensure any state updates are persisted.
+	defer s.state.Save()
+
+	for i, section := range sections {
+		slog.InfoContext(r.Context(), "Applying configuration", "section", section.name)
+
+		err = section.apply(r.Context())
+		if err == nil {
+			continue
+		}
+
+		slog.ErrorContext(r.Context(), "Failed to apply configuration, rolling back", "section", section.name, "err", err.Error())
+
+		// Roll back all the sections applied so far, including the failed one which may
+		// have been partially applied, in reverse order.
+		failedRollbacks := []string{}
+
+		for j := i; j >= 0; j-- {
+			rollbackErr := sections[j].rollback(r.Context())
+			if rollbackErr != nil {
+				slog.ErrorContext(r.Context(), "Failed to roll back configuration", "section", sections[j].name, "err", rollbackErr.Error())
+
+				failedRollbacks = append(failedRollbacks, sections[j].name)
+			}
+		}
+
+		if len(failedRollbacks) > 0 {
+			_ = response.InternalError(fmt.Errorf("failed to apply %q configuration (%w), and failed to roll back: %s", section.name, err, strings.Join(failedRollbacks, ", "))).Render(w)
+
+			return
+		}
+
+		_ = response.InternalError(fmt.Errorf("failed to apply %q configuration, all changes were rolled back: %w", section.name, err)).Render(w)
+
+		return
+	}
+
+	for _, section := range sections {
+		s.recordHistory(r, section.name, section.prior, section.current)
+	}
+
+	_ = response.EmptySyncResponse.Render(w)
+}
+
+// prepareApply validates the requested configuration, returning the sections to apply in dependency order.
+func (s *Server) prepareApply(ctx context.Context, req *api.SystemApply) ([]applySection, error) {
+	sections := []applySection{}
+
+	// Kernel.
+	if req.Kernel != nil {
+		applyKernel := func(config api.SystemKernelConfig) func(context.Context) error {
+			return func(ctx context.Context) error {
+				err := kernel.ApplyKernelConfiguration(ctx, config)
+				if err != nil {
+					return err
+				}
+
+				s.state.System.Kernel.Config = config
+
+				return nil
+			}
+		}
+
+		sections = append(sections, applySection{
+			name:     history.SectionKernel,
+			prior:    s.state.System.Kernel.Config,
+			current:  *req.Kernel,
+			apply:    applyKernel(*req.Kernel),
+			rollback: applyKernel(s.state.System.Kernel.Config),
+		})
+	}
+
+	// Network.
+	if req.Network != nil {
+		if s.state.NetworkConfigurationPending {
+			return nil, errors.New("a pending network configuration must first be confirmed before a new configuration can be applied")
+		}
+
+		if req.Network.ConfirmationTimeout != "" {
+			return nil, errors.New("a confirmation timeout can't be used when applying multiple configuration sections")
+		}
+
+		if seed.NetworkConfigHasEmptyDevices(*req.Network) {
+			return nil, errors.New("network configuration has no devices defined")
+		}
+
+		// Keep the current value of any secret sent back as a placeholder.
+		err := secrets.Restore(req.Network, s.state.System.Network.Config)
+		if err != nil {
+			return nil, err
+		}
+
+		err = systemd.ValidateNetworkConfiguration(req.Network, false)
+		if err != nil {
+			return nil, err
+		}
+
+		applyNetwork := func(config *api.SystemNetworkConfig) func(context.Context) error {
+			return func(ctx context.Context) error {
+				return applyNetworkConfiguration(ctx, s.state, config, 30*time.Second)
+			}
+		}
+
+		sections = append(sections, applySection{
+			name:     history.SectionNetwork,
+			prior:    s.state.System.Network.Config,
+			current:  req.Network,
+			apply:    applyNetwork(req.Network),
+			rollback: applyNetwork(s.state.System.Network.Config),
+		})
+	}
+
+	// Services, in their startup order.
+	supported := services.Supported(s.state)
+
+	for name := range req.Services {
+		if !slices.Contains(supported, name) {
+			return nil, fmt.Errorf("unknown service %q", name)
+		}
+	}
+
+	for _, name := range supported {
+		config, ok := req.Services[name]
+		if !ok {
+			continue
+		}
+
+		section, err := s.prepareApplyService(ctx, name, config)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %q service configuration: %w", name, err)
+		}
+
+		sections = append(sections, *section)
+	}
+
+	// Logging.
+	if req.Logging != nil {
+		applyLogging := func(config api.SystemLoggingConfig) func(context.Context) error {
+			return func(ctx context.Context) error {
+				err := systemd.SetSyslog(ctx, config.Syslog)
+				if err != nil {
+					return err
+				}
+
+				s.state.System.Logging.Config = config
+
+				return nil
+			}
+		}
+
+		sections = append(sections, applySection{
+			name:     history.SectionLogging,
+			prior:    s.state.System.Logging.Config,
+			current:  *req.Logging,
+			apply:    applyLogging(*req.Logging),
+			rollback: applyLogging(s.state.System.Logging.Config),
+		})
+	}
+
+	// Update.
+	if req.Update != nil {
+		err := req.Update.Validate()
+		if err != nil {
+			return nil, err
+		}
+
+		applyUpdate := func(config api.SystemUpdateConfig) func(context.Context) error {
+			return func(_ context.Context) error {
+				s.state.System.Update.Config = config
+
+				return nil
+			}
+		}
+
+		sections = append(sections, applySection{
+			name:     history.SectionUpdate,
+			prior:    s.state.System.Update.Config,
+			current:  *req.Update,
+			apply:    applyUpdate(*req.Update),
+			rollback: applyUpdate(s.state.System.Update.Config),
+		})
+	}
+
+	return sections, nil
+}
+
+// prepareApplyService validates the configuration of a single service.
+func (s *Server) prepareApplyService(ctx context.Context, name string, config json.RawMessage) (*applySection, error) {
+	srv, err := services.Load(ctx, s.state, name)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := srv.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get a copy of the current service struct to roll back to.
+	prior := srv.Struct()
+
+	content, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(content, prior)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the new configuration.
+	dest := srv.Struct()
+
+	content, err = json.Marshal(map[string]json.RawMessage{"config": config})
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(content, dest)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the current value of any secret sent back as a placeholder.
+	err = secrets.Restore(dest, current)
+	if err != nil {
+		return nil, err
+	}
+
+	err = srv.Validate(ctx, dest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &applySection{
+		name:    history.ServiceSection(name),
+		prior:   configOf(current),
+		current: configOf(dest),
+		apply: func(ctx context.Context) error {
+			return srv.Update(ctx, dest)
+		},
+		rollback: func(ctx context.Context) error {
+			return srv.Update(ctx, prior)
+		},
+	}, nil
+}
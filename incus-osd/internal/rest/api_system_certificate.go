@@ -0,0 +1,160 @@
+package rest
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/applications"
+	"github.com/lxc/incus-os/incus-osd/internal/certificate"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
+)
+
+// swagger:operation GET /1.0/system/certificate system system_get_certificate
+//
+//	Get the server certificate configuration
+//
+//	Returns the configuration and details of the server certificate presented by the HTTPS listener.
+//	The private key is never returned.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: State and configuration for the server certificate
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/SystemCertificate"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+
+// swagger:operation PUT /1.0/system/certificate system system_put_certificate
+//
+//	Update the server certificate configuration
+//
+//	Configures the server certificate presented by the HTTPS listener. Either provide a PEM-encoded
+//	certificate and key, or an ACME configuration to have a certificate issued and automatically
+//	renewed using the http-01 or dns-01 challenge. When neither is provided, the server certificate
+//	of the primary application is used.
+//
+//	When updating other fields, the key may be omitted as long as the certificate is unchanged.
+//
+//	The certificate is also presented on the listener of the primary application (the local Incus
+//	server certificate). The configuration is rejected if the primary application doesn't support
+//	this, such as a clustered Incus server.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: configuration
+//	    description: Server certificate configuration
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/SystemCertificate"
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemCertificate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		// Get the primary application's certificate, which is used if no other certificate is installed.
+		var appCert *tls.Certificate
+
+		app, err := applications.GetPrimary(r.Context(), s.state, true)
+		if err == nil {
+			appCert, _ = app.GetServerCertificate()
+		}
+
+		ret := api.SystemCertificate{
+			Config: s.state.System.Certificate.Config,
+			State:  certificate.GetState(s.state, appCert),
+		}
+
+		// Return the current server certificate state.
+		_ = response.SyncResponseETag(true, ret, ret.Config).Render(w)
+	case http.MethodPut:
+		// Ensure the configuration wasn't modified since it was retrieved.
+		err := response.EtagCheck(r, s.state.System.Certificate.Config)
+		if err != nil {
+			_ = response.EtagFailure(err).Render(w)
+
+			return
+		}
+
+		certificateStruct := &api.SystemCertificate{}
+
+		counter := &countWrapper{ReadCloser: r.Body}
+
+		err = json.NewDecoder(counter).Decode(certificateStruct)
+		if err != nil && counter.n > 0 {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		// Keep the current value of any secret sent back as a placeholder.
+		err = secrets.Restore(&certificateStruct.Config, s.state.System.Certificate.Config)
+		if err != nil {
+			if errors.Is(err, secrets.ErrUnknownPlaceholder) || errors.Is(err, secrets.ErrUnavailable) {
+				_ = response.BadRequest(err).Render(w)
+
+				return
+			}
+
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		err = certificate.Apply(r.Context(), s.state, certificateStruct.Config)
+		if err != nil {
+			if errors.Is(err, certificate.ErrInvalidCertificate) {
+				_ = response.BadRequest(err).Render(w)
+
+				return
+			}
+
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		_ = response.EmptySyncResponse.Render(w)
+	default:
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+	}
+
+	_ = s.state.Save()
+}
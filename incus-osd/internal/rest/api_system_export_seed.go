@@ -0,0 +1,130 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"slices"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+	"github.com/lxc/incus-os/incus-osd/internal/applications"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/seed"
+	"github.com/lxc/incus-os/incus-osd/internal/storage"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+// swagger:operation POST /1.0/system/:export-seed system system_post_export_seed
+//
+//	Export the running configuration as seed data
+//
+//	Returns a tar archive of seed files reproducing the running configuration, which can be used to install an identical system.
+//
+//	The archive holds the install, network, applications, incus, services, update, provider and kernel seeds. Secrets are redacted unless explicitly included.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	  - application/octet-stream
+//	parameters:
+//	  - in: body
+//	    name: configuration
+//	    description: Export options
+//	    required: false
+//	    schema:
+//	      $ref: "#/definitions/SystemExportSeed"
+//	responses:
+//	  "200":
+//	    description: Seed tar archive
+//	    schema:
+//	      type: file
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemExportSeed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	req := &api.SystemExportSeed{}
+
+	counter := &countWrapper{ReadCloser: r.Body}
+
+	err := json.NewDecoder(counter).Decode(req)
+	if err != nil && counter.n > 0 {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	args := seed.ExportArgs{
+		IncludeSecrets: req.IncludeSecrets,
+	}
+
+	// Get the installed applications.
+	apps, err := applications.GetInstalled(r.Context(), s.state)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	for _, app := range apps {
+		args.Applications = append(args.Applications, app.Name())
+	}
+
+	slices.Sort(args.Applications)
+
+	// Target the current boot drive, or any drive on the same bus if generalizing.
+	info, err := storage.GetStorageInfo(r.Context())
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	for _, drive := range info.Drives {
+		if !drive.Boot {
+			continue
+		}
+
+		if req.Generalize {
+			args.InstallTarget = &apiseed.InstallTarget{Bus: drive.Bus}
+		} else {
+			args.InstallTarget = &apiseed.InstallTarget{ID: filepath.Base(drive.ID)}
+		}
+
+		break
+	}
+
+	// Match network interfaces by name rather than MAC address if generalizing.
+	if req.Generalize {
+		args.InterfaceName = func(mac string) (string, error) {
+			return systemd.GetPredictableInterfaceName(r.Context(), mac)
+		}
+	}
+
+	archive, err := seed.Export(r.Context(), s.state, args)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+
+	_, err = w.Write(archive)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+}
@@ -72,22 +72,35 @@ import (
 //	    $ref: "#/responses/EmptySyncResponse"
 //	  "400":
 //	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
 func (s *Server) apiSystemFallbackListener(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
+	s.state.FallbackListenerMutex.Lock()
+	defer s.state.FallbackListenerMutex.Unlock()
+
 	switch r.Method {
 	case http.MethodGet:
 		// Return the current system fallback listener state.
-		_ = response.SyncResponse(true, s.state.System.FallbackListener).Render(w)
+		_ = response.SyncResponseETag(true, s.state.System.FallbackListener, s.state.System.FallbackListener.Config).Render(w)
 	case http.MethodPut:
+		// Ensure the configuration wasn't modified since it was retrieved.
+		err := response.EtagCheck(r, s.state.System.FallbackListener.Config)
+		if err != nil {
+			_ = response.EtagFailure(err).Render(w)
+
+			return
+		}
+
 		// Update the fallback listener configuration.
 		fallbackListenerStruct := &api.SystemFallbackListener{}
 
 		counter := &countWrapper{ReadCloser: r.Body}
 
-		err := json.NewDecoder(counter).Decode(fallbackListenerStruct)
+		err = json.NewDecoder(counter).Decode(fallbackListenerStruct)
 		if err != nil && counter.n > 0 {
 			_ = response.BadRequest(err).Render(w)
 
@@ -0,0 +1,176 @@
+package rest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/incus-os/incus-osd/internal/history"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+)
+
+// swagger:operation GET /1.0/system/history system system_get_history
+//
+//	Get the configuration history
+//
+//	Returns the recorded configuration changes, oldest first.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: List of configuration revisions
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of configuration revisions
+//	          items:
+//	            $ref: "#/definitions/SystemHistoryRevision"
+func (s *Server) apiSystemHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, history.List(s.state)).Render(w)
+}
+
+// swagger:operation POST /1.0/system/history/:revert system system_post_history_revert
+//
+//	Revert a configuration change
+//
+//	Restores the configuration section changed by the provided revision to what it was prior to
+//	that change. The revert is itself recorded as a new revision.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: revision
+//	    description: The revision to revert
+//	    required: true
+//	    type: integer
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemHistoryRevert(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	revisionNumber, err := strconv.Atoi(r.FormValue("revision"))
+	if err != nil {
+		_ = response.BadRequest(errors.New("invalid revision provided: " + err.Error())).Render(w)
+
+		return
+	}
+
+	revision, err := history.Get(s.state, revisionNumber)
+	if err != nil {
+		_ = response.NotFound(err).Render(w)
+
+		return
+	}
+
+	if revision.PriorConfig == "" {
+		_ = response.BadRequest(errors.New("the configuration prior to this revision is no longer available")).Render(w)
+
+		return
+	}
+
+	// Find the handler for the section.
+	var handler http.HandlerFunc
+
+	serviceName, isService := strings.CutPrefix(revision.Section, history.ServiceSection(""))
+
+	switch {
+	case revision.Section == history.SectionKernel:
+		handler = s.apiSystemKernel
+	case revision.Section == history.SectionLogging:
+		handler = s.apiSystemLogging
+	case revision.Section == history.SectionNetwork:
+		handler = s.apiSystemNetwork
+	case revision.Section == history.SectionStorage:
+		handler = s.apiSystemStorage
+	case revision.Section == history.SectionUpdate:
+		handler = s.apiSystemUpdate
+	case isService:
+		handler = s.apiServicesEndpoint
+	default:
+		_ = response.InternalError(errors.New("unsupported configuration section '" + revision.Section + "'")).Render(w)
+
+		return
+	}
+
+	// Apply the prior configuration through the same code path as a regular update, so that it
+	// goes through the same validation and gets recorded as a new revision.
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPut, "/1.0/"+revision.Section, strings.NewReader(`{"config":`+revision.PriorConfig+`}`))
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	req.TLS = r.TLS
+	req.Header.Set("Content-Type", "application/json")
+
+	if isService {
+		req.SetPathValue("name", serviceName)
+	}
+
+	handler(w, req)
+}
+
+// recordHistory records a configuration change. Failing to do so doesn't fail the change itself.
+func (s *Server) recordHistory(r *http.Request, section string, prior any, current any) {
+	err := history.Record(s.state, requestActor(r), section, prior, current)
+	if err != nil {
+		slog.WarnContext(r.Context(), "Failed to record configuration history", "section", section, "err", err.Error())
+	}
+}
+
+// requestActor returns a description of who made the request, identifying remote clients by
+// their certificate fingerprint.
+func requestActor(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return "local"
+	}
+
+	fingerprint := sha256.Sum256(r.TLS.PeerCertificates[0].Raw)
+
+	return "certificate " + hex.EncodeToString(fingerprint[:])[:12]
+}
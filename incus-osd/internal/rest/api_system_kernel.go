@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/history"
 	"github.com/lxc/incus-os/incus-osd/internal/kernel"
 	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
 )
@@ -63,6 +64,8 @@ import (
 //	    $ref: "#/responses/EmptySyncResponse"
 //	  "400":
 //	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
 func (s *Server) apiSystemKernel(w http.ResponseWriter, r *http.Request) {
@@ -79,11 +82,19 @@ func (s *Server) apiSystemKernel(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Return the current kernel state.
-		_ = response.SyncResponse(true, s.state.System.Kernel).Render(w)
+		_ = response.SyncResponseETag(true, s.state.System.Kernel, s.state.System.Kernel.Config).Render(w)
 	case http.MethodPut:
+		// Ensure the configuration wasn't modified since it was retrieved.
+		err := response.EtagCheck(r, s.state.System.Kernel.Config)
+		if err != nil {
+			_ = response.EtagFailure(err).Render(w)
+
+			return
+		}
+
 		kernelData := &api.SystemKernel{}
 
-		err := json.NewDecoder(r.Body).Decode(kernelData)
+		err = json.NewDecoder(r.Body).Decode(kernelData)
 		if err != nil {
 			_ = response.BadRequest(err).Render(w)
 
@@ -99,6 +110,7 @@ func (s *Server) apiSystemKernel(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Persist the configuration.
+		s.recordHistory(r, history.SectionKernel, s.state.System.Kernel.Config, kernelData.Config)
 		s.state.System.Kernel.Config = kernelData.Config
 
 		_ = response.EmptySyncResponse.Render(w)
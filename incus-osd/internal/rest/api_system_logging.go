@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/history"
 	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
 	"github.com/lxc/incus-os/incus-osd/internal/systemd"
 )
@@ -63,6 +64,8 @@ import (
 //	    $ref: "#/responses/EmptySyncResponse"
 //	  "400":
 //	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
 func (s *Server) apiSystemLogging(w http.ResponseWriter, r *http.Request) {
@@ -71,11 +74,19 @@ func (s *Server) apiSystemLogging(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
 		// Return the current logging state.
-		_ = response.SyncResponse(true, s.state.System.Logging).Render(w)
+		_ = response.SyncResponseETag(true, s.state.System.Logging, s.state.System.Logging.Config).Render(w)
 	case http.MethodPut:
+		// Ensure the configuration wasn't modified since it was retrieved.
+		err := response.EtagCheck(r, s.state.System.Logging.Config)
+		if err != nil {
+			_ = response.EtagFailure(err).Render(w)
+
+			return
+		}
+
 		loggingData := &api.SystemLogging{}
 
-		err := json.NewDecoder(r.Body).Decode(loggingData)
+		err = json.NewDecoder(r.Body).Decode(loggingData)
 		if err != nil {
 			_ = response.BadRequest(err).Render(w)
 
@@ -91,6 +102,7 @@ func (s *Server) apiSystemLogging(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Persist the configuration.
+		s.recordHistory(r, history.SectionLogging, s.state.System.Logging.Config, loggingData.Config)
 		s.state.System.Logging.Config = loggingData.Config
 
 		_ = response.EmptySyncResponse.Render(w)
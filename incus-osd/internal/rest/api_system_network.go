@@ -9,9 +9,11 @@ import (
 	"time"
 
 	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/history"
 	"github.com/lxc/incus-os/incus-osd/internal/nftables"
 	"github.com/lxc/incus-os/incus-osd/internal/providers"
 	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
 	"github.com/lxc/incus-os/incus-osd/internal/seed"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
 	"github.com/lxc/incus-os/incus-osd/internal/systemd"
@@ -73,11 +75,25 @@ import (
 //	    $ref: "#/responses/EmptySyncResponse"
 //	  "400":
 //	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
 func (s *Server) apiSystemNetwork(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
+	// If no timezone has been set, default to UTC. This is done for all requests so that the
+	// ETag of the configuration is consistent.
+	if s.state.System.Network.Config != nil {
+		if s.state.System.Network.Config.Time == nil {
+			s.state.System.Network.Config.Time = &api.SystemNetworkTime{}
+		}
+
+		if s.state.System.Network.Config.Time.Timezone == "" {
+			s.state.System.Network.Config.Time.Timezone = "UTC"
+		}
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		// Refresh network state; needed to get current LLDP info.
@@ -88,29 +104,42 @@ func (s *Server) apiSystemNetwork(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// If no timezone has been set, default to UTC.
-		if s.state.System.Network.Config.Time == nil {
-			s.state.System.Network.Config.Time = &api.SystemNetworkTime{}
-		}
+		// Return the current network state.
+		_ = response.SyncResponseETag(true, s.state.System.Network, s.state.System.Network.Config).Render(w)
+	case http.MethodPut:
+		// Ensure the configuration wasn't modified since it was retrieved.
+		err := response.EtagCheck(r, s.state.System.Network.Config)
+		if err != nil {
+			_ = response.EtagFailure(err).Render(w)
 
-		if s.state.System.Network.Config.Time.Timezone == "" {
-			s.state.System.Network.Config.Time.Timezone = "UTC"
+			return
 		}
 
-		// Return the current network state.
-		_ = response.SyncResponse(true, s.state.System.Network).Render(w)
-	case http.MethodPut:
 		// Replace the existing network configuration.
 		newConfig := &api.SystemNetwork{}
 
 		// Populate the network configuration from request's body.
-		err := json.NewDecoder(r.Body).Decode(newConfig)
+		err = json.NewDecoder(r.Body).Decode(newConfig)
 		if err != nil {
 			_ = response.BadRequest(err).Render(w)
 
 			return
 		}
 
+		// Keep the current value of any secret sent back as a placeholder.
+		err = secrets.Restore(newConfig, s.state.System.Network)
+		if err != nil {
+			if errors.Is(err, secrets.ErrUnknownPlaceholder) || errors.Is(err, secrets.ErrUnavailable) {
+				_ = response.BadRequest(err).Render(w)
+
+				return
+			}
+
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
 		// Don't allow applying a new network configuration if a prior network configuration
 		// is still waiting for confirmation.
 		if s.state.NetworkConfigurationPending {
@@ -147,6 +176,8 @@ func (s *Server) apiSystemNetwork(w http.ResponseWriter, r *http.Request) {
 			newConfig.Config.ConfirmationTimeout = ""
 		}
 
+		priorConfig := s.state.System.Network.Config
+
 		// If a confirmation timeout is defined, start a background function that will roll back changes
 		// unless the user confirms them before the timeout expires.
 		if confirmationTimeout > 0 {
@@ -169,20 +200,14 @@ func (s *Server) apiSystemNetwork(w http.ResponseWriter, r *http.Request) {
 					if err != nil {
 						slog.WarnContext(ctx, "Invalid network configuration detected, rolling back to prior known-good state")
 
-						err = applyNetworkConfiguration(ctx, s.state, s.state.PriorNetworkConfig, 30*time.Second)
-						if err != nil {
-							slog.ErrorContext(ctx, "Failed to roll back network configuration: "+err.Error())
-						}
+						rollbackNetworkConfiguration(ctx, s.state)
 					}
 				case <-time.After(confirmationTimeout):
 					// At this point, the user-provided timeout has elapsed and the changes were not confirmed,
 					// so we need to roll the changes back.
 					slog.WarnContext(ctx, "Timeout expired, rolling back network configuration to prior known-good state")
 
-					err = applyNetworkConfiguration(ctx, s.state, s.state.PriorNetworkConfig, 30*time.Second)
-					if err != nil {
-						slog.ErrorContext(ctx, "Failed to roll back network configuration: "+err.Error())
-					}
+					rollbackNetworkConfiguration(ctx, s.state)
 				}
 
 				// Reset the network configuration pending state.
@@ -217,6 +242,9 @@ func (s *Server) apiSystemNetwork(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		s.recordHistory(r, history.SectionNetwork, priorConfig, s.state.System.Network.Config)
+		_ = s.state.Save()
+
 		_ = response.EmptySyncResponse.Render(w)
 	default:
 		// If none of the supported methods, return NotImplemented.
@@ -238,6 +266,23 @@ func applyNetworkConfiguration(ctx context.Context, s *state.State, networkCfg *
 	return s.Save()
 }
 
+// rollbackNetworkConfiguration restores the prior known-good network configuration, recording the change.
+func rollbackNetworkConfiguration(ctx context.Context, s *state.State) {
+	currentConfig := s.System.Network.Config
+
+	err := applyNetworkConfiguration(ctx, s, s.PriorNetworkConfig, 30*time.Second)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to roll back network configuration: "+err.Error())
+
+		return
+	}
+
+	err = history.Record(s, "system", history.SectionNetwork, currentConfig, s.System.Network.Config)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to record configuration history", "section", history.SectionNetwork, "err", err.Error())
+	}
+}
+
 // swagger:operation POST /1.0/system/network/:confirm system system_post_network_confirm
 //
 //	Confirm a new network configuration
@@ -66,6 +66,8 @@ import (
 //	    $ref: "#/responses/EmptySyncResponse"
 //	  "400":
 //	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
 func (s *Server) apiSystemProvider(w http.ResponseWriter, r *http.Request) {
@@ -74,14 +76,22 @@ func (s *Server) apiSystemProvider(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
 		// Return the current system provider state.
-		_ = response.SyncResponse(true, s.state.System.Provider).Render(w)
+		_ = response.SyncResponseETag(true, s.state.System.Provider, s.state.System.Provider.Config).Render(w)
 	case http.MethodPut:
+		// Ensure the configuration wasn't modified since it was retrieved.
+		err := response.EtagCheck(r, s.state.System.Provider.Config)
+		if err != nil {
+			_ = response.EtagFailure(err).Render(w)
+
+			return
+		}
+
 		// Apply a new system provider configuration.
 		newConfig := &api.SystemProvider{}
 		oldConfig := s.state.System.Provider.Config
 
 		// Update the system provider configuration from request's body.
-		err := json.NewDecoder(r.Body).Decode(newConfig)
+		err = json.NewDecoder(r.Body).Decode(newConfig)
 		if err != nil {
 			_ = response.BadRequest(err).Render(w)
 
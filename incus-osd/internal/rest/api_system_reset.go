@@ -13,7 +13,7 @@ import (
 //
 //	Perform a factory reset of the system
 //
-//	Factory reset the entire system and immediately reboot. This is a DESTRUCTIVE action and will wipe all installed applications, configuration, and the "local" ZFS datapool.
+//	Factory reset the entire system and immediately reboot. This is a DESTRUCTIVE action and will wipe all installed applications, configuration, and the "local" ZFS datapool, except for the configuration sections listed to be preserved.
 //
 //	---
 //	produces:
@@ -24,8 +24,7 @@ import (
 //	    description: Reset data
 //	    required: false
 //	    schema:
-//	      type: object
-//	      example: {"allow_tpm_reset_failure":false,"wipe_existing_seeds":true,"seeds":{"incus":{"apply_defaults":true}}}
+//	      $ref: "#/definitions/SystemReset"
 //	responses:
 //	  "200":
 //	    $ref: "#/responses/EmptySyncResponse"
@@ -33,7 +32,7 @@ import (
 //	    $ref: "#/responses/BadRequest"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
-func (*Server) apiSystemFactoryReset(w http.ResponseWriter, r *http.Request) {
+func (s *Server) apiSystemFactoryReset(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	if r.Method != http.MethodPost {
@@ -53,7 +52,7 @@ func (*Server) apiSystemFactoryReset(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = reset.PerformOSFactoryReset(r.Context(), resetData)
+	err = reset.PerformOSFactoryReset(r.Context(), s.state, resetData)
 	if err != nil {
 		_ = response.InternalError(err).Render(w)
 
@@ -3,15 +3,19 @@ package rest
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"reflect"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/lxc/incus-os/incus-osd/api"
 	"github.com/lxc/incus-os/incus-osd/internal/auth"
 	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
 	"github.com/lxc/incus-os/incus-osd/internal/secureboot"
 	"github.com/lxc/incus-os/incus-osd/internal/storage"
 	"github.com/lxc/incus-os/incus-osd/internal/systemd"
@@ -64,6 +68,10 @@ import (
 //	Optionally, specify one or more PEM-encoded custom CA certificates that should be added
 //	to the system's root trust. Only certificates specified in the API call will be persisted.
 //
+//	Optionally, configure network-bound unlocking of the main system drive through one or more
+//	Tang servers, either as a fallback when the TPM can't unlock the drive or as an additional
+//	requirement on top of the TPM. Omitting the configuration removes any existing binding.
+//
 //	---
 //	consumes:
 //	  - application/json
@@ -81,6 +89,8 @@ import (
 //	    $ref: "#/responses/EmptySyncResponse"
 //	  "400":
 //	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
 func (s *Server) apiSystemSecurity(w http.ResponseWriter, r *http.Request) {
@@ -155,6 +165,17 @@ func (s *Server) apiSystemSecurity(w http.ResponseWriter, r *http.Request) {
 			s.state.System.Security.State.SystemStateStatus = "system state is fully trusted"
 		}
 
+		// Report whether the stored secrets could be decrypted.
+		s.state.System.Security.State.SecretsError = ""
+
+		err = secrets.Unavailable()
+		if err != nil {
+			s.state.System.Security.State.SecretsError = err.Error()
+		}
+
+		// Get the reachability of the Tang servers from the last check.
+		s.state.System.Security.State.NetworkUnlockStatus = systemd.GetNetworkUnlockStatus()
+
 		// Get TPM public key, if it exists.
 		contents, err := os.ReadFile(auth.PEMPath)
 		if err == nil {
@@ -162,14 +183,22 @@ func (s *Server) apiSystemSecurity(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Return the current system security state.
-		_ = response.SyncResponse(true, s.state.System.Security).Render(w)
+		_ = response.SyncResponseETag(true, s.state.System.Security, s.state.System.Security.Config).Render(w)
 	case http.MethodPut:
+		// Ensure the configuration wasn't modified since it was retrieved.
+		err := response.EtagCheck(r, s.state.System.Security.Config)
+		if err != nil {
+			_ = response.EtagFailure(err).Render(w)
+
+			return
+		}
+
 		// Update the list of encryption recovery keys.
 		securityStruct := &api.SystemSecurity{}
 
 		counter := &countWrapper{ReadCloser: r.Body}
 
-		err := json.NewDecoder(counter).Decode(securityStruct)
+		err = json.NewDecoder(counter).Decode(securityStruct)
 		if err != nil && counter.n > 0 {
 			_ = response.BadRequest(err).Render(w)
 
@@ -206,6 +235,25 @@ func (s *Server) apiSystemSecurity(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		// Update the network-bound unlock configuration, if changed.
+		if !reflect.DeepEqual(securityStruct.Config.NetworkUnlock, s.state.System.Security.Config.NetworkUnlock) {
+			if securityStruct.Config.NetworkUnlock != nil {
+				err := securityStruct.Config.NetworkUnlock.Validate()
+				if err != nil {
+					_ = response.BadRequest(err).Render(w)
+
+					return
+				}
+			}
+
+			err := systemd.ConfigureNetworkUnlock(r.Context(), s.state, securityStruct.Config.NetworkUnlock)
+			if err != nil {
+				_ = response.InternalError(err).Render(w)
+
+				return
+			}
+		}
+
 		// Configure custom CA certificates, if any.
 		s.state.System.Security.Config.CustomCACerts = securityStruct.Config.CustomCACerts
 
@@ -227,6 +275,177 @@ func (s *Server) apiSystemSecurity(w http.ResponseWriter, r *http.Request) {
 	_ = s.state.Save()
 }
 
+// swagger:operation POST /1.0/system/security/:attest system system_post_security_attest
+//
+//	Attest the system state
+//
+//	Generates a TPM quote over PCRs 0 to 15, qualified by the provided nonce and signed by the
+//	system's attestation key. The quote is returned along with the TPM event log, the attestation
+//	key and the TPM's endorsement key and certificate.
+//
+//	The verifier must then confirm the attestation key lives in the same TPM as the endorsement
+//	key through the credential activation endpoint.
+//
+//	A verifier can use this to confirm the system booted the expected IncusOS image before
+//	trusting it.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: attestation
+//	    description: Attestation request
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/SystemSecurityAttestationPost"
+//	responses:
+//	  "200":
+//	    description: TPM quote and associated data
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/SystemSecurityAttestation"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemSecurityAttest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	req := api.SystemSecurityAttestationPost{}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	if len(req.Nonce) == 0 || len(req.Nonce) > api.SystemSecurityAttestationMaxNonceSize {
+		_ = response.BadRequest(fmt.Errorf("the nonce must be between 1 and %d bytes long", api.SystemSecurityAttestationMaxNonceSize)).Render(w)
+
+		return
+	}
+
+	attestation, err := auth.Attest(r.Context(), req.Nonce)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	attestation.EventLog, err = secureboot.ReadRawTPMEventLog()
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, attestation).Render(w)
+}
+
+// swagger:operation POST /1.0/system/security/:activate-credential system system_post_security_activate_credential
+//
+//	Activate an attestation credential
+//
+//	Recovers the secret protected by a credential generated for the system's endorsement and
+//	attestation keys. Only the TPM holding both keys can do so, which proves to the verifier that
+//	quotes signed by the attestation key come from the TPM identified by the endorsement key.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: challenge
+//	    description: Credential challenge
+//	    required: true
+//	    schema:
+//	      $ref: "#/definitions/SystemSecurityAttestationChallenge"
+//	responses:
+//	  "200":
+//	    description: Recovered secret
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          $ref: "#/definitions/SystemSecurityAttestationChallengeResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemSecurityActivateCredential(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	req := api.SystemSecurityAttestationChallenge{}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	if len(req.Credential) == 0 || len(req.EncryptedSecret) == 0 {
+		_ = response.BadRequest(errors.New("a credential and encrypted secret must be provided")).Render(w)
+
+		return
+	}
+
+	secret, err := auth.ActivateCredential(r.Context(), req)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, api.SystemSecurityAttestationChallengeResponse{Secret: secret}).Render(w)
+}
+
 // swagger:operation POST /1.0/system/security/:retrieved system system_post_security_retrieved
 //
 //	Mark encryption recovery keys as retrieved
@@ -262,6 +481,112 @@ func (s *Server) apiSystemSecurityRetrieved(w http.ResponseWriter, r *http.Reque
 	_ = response.EmptySyncResponse.Render(w)
 }
 
+// swagger:operation POST /1.0/system/security/:rotate-keys system system_post_security_rotate_keys
+//
+//	Rotate encryption keys
+//
+//	Replaces the IncusOS-managed encryption keys with newly generated ones. This covers the
+//	recovery keys and TPM bindings of the main system drive, the keys of any encrypted data
+//	drives and the wrapping keys of all encrypted storage pools. User-provided encryption
+//	recovery keys are left untouched.
+//
+//	Previously retrieved drive and pool keys no longer work after the rotation, so the new
+//	keys must be retrieved again. The LUKS volume keys of the main system drive aren't changed,
+//	so the data itself isn't re-encrypted.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemSecurityRotateKeys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	// The TPM is re-sealed using the current PCR values, which won't match after a pending reboot.
+	if s.state.System.Update.State.NeedsReboot {
+		_ = response.BadRequest(errors.New("encryption keys can't be rotated while a reboot is pending")).Render(w)
+
+		return
+	}
+
+	if s.state.System.Security.State.EncryptionKeysRotated == nil {
+		s.state.System.Security.State.EncryptionKeysRotated = map[string]time.Time{}
+	}
+
+	// Rotate the main system drive keys.
+	volumes, err := systemd.RotateRecoveryKeys(r.Context())
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	for _, volume := range volumes {
+		s.state.System.Security.State.EncryptionKeysRotated["volume/"+volume] = time.Now()
+	}
+
+	// Rotate the data drive keys.
+	driveKeys, err := storage.GetDriveKeys()
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	for devID := range driveKeys {
+		err := storage.RotateDriveKey(r.Context(), devID)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		s.state.System.Security.State.EncryptionKeysRotated["drive/"+devID] = time.Now()
+	}
+
+	// Rotate the storage pool keys.
+	poolKeys, err := zfs.GetZpoolEncryptionKeys()
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	for pool := range poolKeys {
+		err := zfs.RotatePoolKey(r.Context(), pool)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		s.state.System.Security.State.EncryptionKeysRotated["pool/"+pool] = time.Now()
+	}
+
+	// Any previously retrieved keys are now invalid.
+	s.state.System.Security.State.EncryptionRecoveryKeysRetrieved = false
+
+	err = s.state.Save()
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.EmptySyncResponse.Render(w)
+}
+
 // swagger:operation POST /1.0/system/security/:tpm-rebind system system_post_security_tpm_rebind
 //
 //	Reset TPM bindings
@@ -11,6 +11,7 @@ import (
 	ocapi "github.com/FuturFusion/operations-center/shared/api"
 
 	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/history"
 	"github.com/lxc/incus-os/incus-osd/internal/providers"
 	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
 	"github.com/lxc/incus-os/incus-osd/internal/scheduling"
@@ -74,6 +75,8 @@ import (
 //	    $ref: "#/responses/EmptySyncResponse"
 //	  "400":
 //	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
 func (s *Server) apiSystemStorage(w http.ResponseWriter, r *http.Request) {
@@ -94,17 +97,25 @@ func (s *Server) apiSystemStorage(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Return the current system storage state.
-		_ = response.SyncResponse(true, ret).Render(w)
+		_ = response.SyncResponseETag(true, ret, ret.Config).Render(w)
 	case http.MethodPut:
 		// Ensure any state updates are persisted.
 		defer s.state.Save()
 
+		// Ensure the configuration wasn't modified since it was retrieved.
+		err := response.EtagCheck(r, s.state.System.Storage.Config)
+		if err != nil {
+			_ = response.EtagFailure(err).Render(w)
+
+			return
+		}
+
 		// Read the new config.
 		storageStruct := &api.SystemStorage{}
 
 		counter := &countWrapper{ReadCloser: r.Body}
 
-		err := json.NewDecoder(counter).Decode(storageStruct)
+		err = json.NewDecoder(counter).Decode(storageStruct)
 		if err != nil && counter.n > 0 {
 			_ = response.BadRequest(err).Render(w)
 
@@ -125,7 +136,12 @@ func (s *Server) apiSystemStorage(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// Update scrub schedule in state.
+		// Update scrub schedule in state. Pools aren't part of the recorded history, as they
+		// aren't persisted in the state.
+		s.recordHistory(r, history.SectionStorage,
+			api.SystemStorageConfig{ScrubSchedule: s.state.System.Storage.Config.ScrubSchedule},
+			api.SystemStorageConfig{ScrubSchedule: storageStruct.Config.ScrubSchedule})
+
 		s.state.System.Storage.Config.ScrubSchedule = storageStruct.Config.ScrubSchedule
 
 		// Create or update a pool.
@@ -181,8 +197,7 @@ func (s *Server) apiSystemStorage(w http.ResponseWriter, r *http.Request) {
 //	    description: The pool to be deleted
 //	    required: true
 //	    schema:
-//	      type: object
-//	      example: {"name":"mypool"}
+//	      $ref: "#/definitions/SystemStorageDeletePool"
 //	responses:
 //	  "200":
 //	    $ref: "#/responses/EmptySyncResponse"
@@ -199,11 +214,7 @@ func (s *Server) apiSystemStorageDeletePool(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	type deleteStruct struct {
-		Name string `json:"name"`
-	}
-
-	config := &deleteStruct{}
+	config := &api.SystemStorageDeletePool{}
 
 	counter := &countWrapper{ReadCloser: r.Body}
 
@@ -417,8 +428,7 @@ func (s *Server) apiSystemStorageImportPool(w http.ResponseWriter, r *http.Reque
 //	    description: The volume to be created
 //	    required: true
 //	    schema:
-//	      type: object
-//	      example: {"pool":"local", "name":"my-volume", "quota":0, "use":"incus"}
+//	      $ref: "#/definitions/SystemStorageCreateVolume"
 //	responses:
 //	  "200":
 //	    $ref: "#/responses/EmptySyncResponse"
@@ -435,14 +445,7 @@ func (s *Server) apiSystemStorageCreateVolume(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	type createStruct struct {
-		Pool  string `json:"pool"`
-		Name  string `json:"name"`
-		Quota int    `json:"quota"`
-		Use   string `json:"use"`
-	}
-
-	config := &createStruct{}
+	config := &api.SystemStorageCreateVolume{}
 
 	counter := &countWrapper{ReadCloser: r.Body}
 
@@ -537,8 +540,7 @@ func (s *Server) apiSystemStorageCreateVolume(w http.ResponseWriter, r *http.Req
 //	    description: The volume to be deleted
 //	    required: true
 //	    schema:
-//	      type: object
-//	      example: {"pool":"local", "name":"my-volume", "force":true}
+//	      $ref: "#/definitions/SystemStorageDeleteVolume"
 //	responses:
 //	  "200":
 //	    $ref: "#/responses/EmptySyncResponse"
@@ -555,13 +557,7 @@ func (s *Server) apiSystemStorageDeleteVolume(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	type deleteStruct struct {
-		Pool  string `json:"pool"`
-		Name  string `json:"name"`
-		Force bool   `json:"force"`
-	}
-
-	config := &deleteStruct{}
+	config := &api.SystemStorageDeleteVolume{}
 
 	counter := &countWrapper{ReadCloser: r.Body}
 
@@ -632,8 +628,7 @@ func (s *Server) apiSystemStorageDeleteVolume(w http.ResponseWriter, r *http.Req
 //	    description: The pool to be scrubbed
 //	    required: true
 //	    schema:
-//	      type: object
-//	      example: {"name":"mypool"}
+//	      $ref: "#/definitions/SystemStorageScrubPool"
 //	responses:
 //	  "200":
 //	    $ref: "#/responses/EmptySyncResponse"
@@ -652,11 +647,7 @@ func (*Server) apiSystemStorageScrubPool(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	type scrubStruct struct {
-		Name string `json:"name"`
-	}
-
-	config := &scrubStruct{}
+	config := &api.SystemStorageScrubPool{}
 
 	counter := &countWrapper{ReadCloser: r.Body}
 
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/history"
 	"github.com/lxc/incus-os/incus-osd/internal/providers"
 	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
 	"github.com/lxc/incus-os/incus-osd/internal/tui"
@@ -65,19 +66,29 @@ import (
 //	    $ref: "#/responses/EmptySyncResponse"
 //	  "400":
 //	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
 func (s *Server) apiSystemUpdate(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	switch r.Method {
 	case http.MethodGet:
 		// Return the current system update state.
-		_ = response.SyncResponse(true, s.state.System.Update).Render(w)
+		_ = response.SyncResponseETag(true, s.state.System.Update, s.state.System.Update.Config).Render(w)
 	case http.MethodPut:
+		// Ensure the configuration wasn't modified since it was retrieved.
+		err := response.EtagCheck(r, s.state.System.Update.Config)
+		if err != nil {
+			_ = response.EtagFailure(err).Render(w)
+
+			return
+		}
+
 		// Apply a new system update configuration.
 		newConfig := &api.SystemUpdate{}
 
 		// Update the system update configuration from request's body.
-		err := json.NewDecoder(r.Body).Decode(newConfig)
+		err = json.NewDecoder(r.Body).Decode(newConfig)
 		if err != nil {
 			_ = response.BadRequest(err).Render(w)
 
@@ -93,6 +104,7 @@ func (s *Server) apiSystemUpdate(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Apply the updated configuration.
+		s.recordHistory(r, history.SectionUpdate, s.state.System.Update.Config, newConfig.Config)
 		s.state.System.Update.Config = newConfig.Config
 
 		_ = response.EmptySyncResponse.Render(w)
@@ -121,8 +133,7 @@ func (s *Server) apiSystemUpdate(w http.ResponseWriter, r *http.Request) {
 //	    description: If true, only check for OS updates
 //	    required: false
 //	    schema:
-//	      type: object
-//	      example: {"os_only": true}
+//	      $ref: "#/definitions/SystemUpdateCheck"
 //	responses:
 //	  "200":
 //	    $ref: "#/responses/EmptySyncResponse"
@@ -135,11 +146,7 @@ func (s *Server) apiSystemUpdateCheck(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	type checkPost struct {
-		OSOnly bool `json:"os_only"`
-	}
-
-	check := &checkPost{}
+	check := &api.SystemUpdateCheck{}
 
 	counter := &countWrapper{ReadCloser: r.Body}
 
@@ -0,0 +1,21 @@
+package rest
+
+import (
+	"reflect"
+)
+
+// configOf returns the configuration of a service or application, so that changes to its state are
+// ignored when comparing it. Values without a Config field are used as-is.
+func configOf(v any) any {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return v
+	}
+
+	config := rv.FieldByName("Config")
+	if !config.IsValid() {
+		return v
+	}
+
+	return config.Interface()
+}
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/lxc/incus/v7/shared/api"
+
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
 )
 
 // Response represents an API response.
@@ -66,6 +68,14 @@ func SyncResponsePlain(success bool, compress bool, metadata string) Response {
 }
 
 func (r *syncResponse) Render(w http.ResponseWriter) error {
+	// Never return secrets, only their placeholder.
+	metadata, err := secrets.Redact(r.metadata)
+	if err != nil {
+		return InternalError(err).Render(w)
+	}
+
+	r.metadata = metadata
+
 	// Set an appropriate ETag header
 	if r.etag != nil {
 		etag, err := etagHash(r.etag)
@@ -162,7 +172,7 @@ func (r *syncResponse) Render(w http.ResponseWriter) error {
 	enc := json.NewEncoder(w)
 	enc.SetEscapeHTML(false)
 
-	err := enc.Encode(resp)
+	err = enc.Encode(resp)
 	if err != nil {
 		return err
 	}
@@ -73,6 +73,24 @@ type swaggerConflict struct {
 	}
 }
 
+// Precondition failed
+//
+// swagger:response PreconditionFailed
+type swaggerPreconditionFailed struct {
+	// Precondition failed
+	// in: body
+	Body struct {
+		// Example: error
+		Type string `json:"type"`
+
+		// Example: precondition failed
+		Error string `json:"error"`
+
+		// Example: 412
+		ErrorCode int `json:"error_code"`
+	}
+}
+
 // Internal Server Error
 //
 // swagger:response InternalServerError
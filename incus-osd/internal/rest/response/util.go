@@ -4,16 +4,66 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
 )
 
-// etagHash hashes the provided data and returns the sha256.
+// ErrEtagMismatch is returned when the data was modified since the client retrieved it.
+var ErrEtagMismatch = errors.New("ETag doesn't match")
+
+// etagHash hashes the provided data and returns the sha256. Secrets are redacted beforehand, so the
+// hash can't be used to guess them, while still changing whenever a secret does.
 func etagHash(data any) (string, error) {
+	data, err := secrets.Redact(data)
+	if err != nil {
+		return "", err
+	}
+
 	etag := sha256.New()
 
-	err := json.NewEncoder(etag).Encode(data)
+	err = json.NewEncoder(etag).Encode(data)
 	if err != nil {
 		return "", err
 	}
 
 	return hex.EncodeToString(etag.Sum(nil)), nil
 }
+
+// EtagCheck validates the hash of the current data against the If-Match header provided by the client,
+// if any. A mismatch means the data was modified since the client retrieved it, and is reported as
+// ErrEtagMismatch. Any other error means the current data couldn't be hashed.
+func EtagCheck(r *http.Request, data any) error {
+	return EtagMatch(data, r.Header.Get("If-Match"))
+}
+
+// EtagMatch validates the hash of the current data against an ETag provided by the client, if any.
+func EtagMatch(data any, match string) error {
+	if match == "" || match == "*" {
+		return nil
+	}
+
+	hash, err := etagHash(data)
+	if err != nil {
+		return err
+	}
+
+	match = strings.Trim(strings.TrimPrefix(match, "W/"), "\"")
+	if hash != match {
+		return fmt.Errorf("%w: %s vs %s", ErrEtagMismatch, hash, match)
+	}
+
+	return nil
+}
+
+// EtagFailure returns the response for an error returned by EtagCheck.
+func EtagFailure(err error) Response {
+	if errors.Is(err, ErrEtagMismatch) {
+		return PreconditionFailed(err)
+	}
+
+	return InternalError(err)
+}
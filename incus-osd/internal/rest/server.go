@@ -8,6 +8,7 @@ import (
 	"encoding/pem"
 	"net"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -61,17 +62,23 @@ func (s *Server) Serve() error {
 	router.HandleFunc("/1.0/debug/secureboot", s.apiDebugSecureBoot)
 	router.HandleFunc("/1.0/debug/secureboot/event-log", s.apiDebugSecureBootEventLog)
 	router.HandleFunc("/1.0/debug/secureboot/:update", s.apiDebugSecureBootUpdate)
+	router.HandleFunc("/1.0/openapi.json", s.apiOpenAPI)
 	router.HandleFunc("/1.0/services", s.apiServices)
 	router.HandleFunc("/1.0/services/{name}", s.apiServicesEndpoint)
 	router.HandleFunc("/1.0/services/{name}/:reset", s.apiServicesEndpointReset)
 	router.HandleFunc("/1.0/system", s.apiSystem)
+	router.HandleFunc("/1.0/system/:apply", s.apiSystemApply)
 	router.HandleFunc("/1.0/system/:backup", s.apiSystemBackup)
+	router.HandleFunc("/1.0/system/:export-seed", s.apiSystemExportSeed)
 	router.HandleFunc("/1.0/system/:factory-reset", s.apiSystemFactoryReset)
 	router.HandleFunc("/1.0/system/:poweroff", s.apiSystemPoweroff)
 	router.HandleFunc("/1.0/system/:reboot", s.apiSystemReboot)
 	router.HandleFunc("/1.0/system/:restore", s.apiSystemRestore)
 	router.HandleFunc("/1.0/system/:suspend", s.apiSystemSuspend)
+	router.HandleFunc("/1.0/system/certificate", s.apiSystemCertificate)
 	router.HandleFunc("/1.0/system/fallback-listener", s.apiSystemFallbackListener)
+	router.HandleFunc("/1.0/system/history", s.apiSystemHistory)
+	router.HandleFunc("/1.0/system/history/:revert", s.apiSystemHistoryRevert)
 	router.HandleFunc("/1.0/system/kernel", s.apiSystemKernel)
 	router.HandleFunc("/1.0/system/logging", s.apiSystemLogging)
 	router.HandleFunc("/1.0/system/network", s.apiSystemNetwork)
@@ -80,7 +87,10 @@ func (s *Server) Serve() error {
 	router.HandleFunc("/1.0/system/provider", s.apiSystemProvider)
 	router.HandleFunc("/1.0/system/resources", s.apiSystemResources)
 	router.HandleFunc("/1.0/system/security", s.apiSystemSecurity)
+	router.HandleFunc("/1.0/system/security/:activate-credential", s.apiSystemSecurityActivateCredential)
+	router.HandleFunc("/1.0/system/security/:attest", s.apiSystemSecurityAttest)
 	router.HandleFunc("/1.0/system/security/:retrieved", s.apiSystemSecurityRetrieved)
+	router.HandleFunc("/1.0/system/security/:rotate-keys", s.apiSystemSecurityRotateKeys)
 	router.HandleFunc("/1.0/system/security/:tpm-rebind", s.apiSystemSecurityTPMRebind)
 	router.HandleFunc("/1.0/system/storage", s.apiSystemStorage)
 	router.HandleFunc("/1.0/system/storage/:cleanup-root", s.apiSystemStorageCleanupRoot)
@@ -120,7 +130,11 @@ func (s *Server) Serve() error {
 
 					clientFp := sha256.Sum256(r.TLS.PeerCertificates[0].Raw)
 
-					for _, trustedCert := range s.state.System.FallbackListener.Config.TrustedClientCertificates {
+					s.state.FallbackListenerMutex.RLock()
+					trustedCerts := slices.Clone(s.state.System.FallbackListener.Config.TrustedClientCertificates)
+					s.state.FallbackListenerMutex.RUnlock()
+
+					for _, trustedCert := range trustedCerts {
 						block, _ := pem.Decode([]byte(trustedCert))
 						if block == nil || block.Type != "CERTIFICATE" {
 							continue
@@ -0,0 +1,2 @@
+// Package secrets handles struct fields tagged as secret, redacting them from API responses and encrypting them at rest.
+package secrets
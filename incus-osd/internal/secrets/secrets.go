@@ -0,0 +1,420 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	// KeySize is the size of the key used to encrypt secrets at rest.
+	KeySize = 32
+
+	// KeyPath is the path to the TPM-sealed key used to encrypt secrets at rest.
+	KeyPath = "/var/lib/incus-os/secrets.key.cred"
+
+	// KeyCredentialName is the name the key is sealed under.
+	KeyCredentialName = "incus-os-secrets"
+)
+
+const (
+	// tagValue marks a string or string slice field as secret, using the `incusos:"secret"` struct tag.
+	tagValue = "secret"
+
+	placeholderPrefix = "[redacted:"
+	placeholderSuffix = "]"
+
+	encryptedPrefix = "secret:v1:"
+)
+
+var (
+	mu             sync.RWMutex
+	aead           cipher.AEAD
+	placeholderKey []byte
+	unavailableErr error
+
+	secretTypes sync.Map
+)
+
+// ErrUnknownPlaceholder is returned when a redacted placeholder doesn't match any current secret.
+var ErrUnknownPlaceholder = errors.New("unknown secret placeholder")
+
+// ErrUnavailable is returned when a new secret can't be stored because the key couldn't be loaded.
+var ErrUnavailable = errors.New("secrets are unavailable")
+
+// SetKey sets the key used to encrypt secrets at rest and to derive redacted placeholders.
+func SetKey(key []byte) error {
+	if len(key) != KeySize {
+		return fmt.Errorf("secrets key must be %d bytes long", KeySize)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("placeholder"))
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	aead = gcm
+	placeholderKey = mac.Sum(nil)
+
+	return nil
+}
+
+// SetUnavailable marks the secrets as unavailable because the existing key couldn't be loaded, or
+// clears that mark when err is nil. While unavailable, encrypted secrets are kept as-is through
+// decoding and encoding, and new secrets are refused rather than stored unencrypted.
+func SetUnavailable(err error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	unavailableErr = err
+}
+
+// Unavailable returns why the secrets are unavailable, or nil if they're available.
+func Unavailable() error {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return unavailableErr
+}
+
+// IsSecret returns whether the struct field is tagged as secret.
+func IsSecret(field reflect.StructField) bool {
+	return field.Tag.Get("incusos") == tagValue
+}
+
+// IsPlaceholder returns whether the value is a redacted placeholder.
+func IsPlaceholder(value string) bool {
+	return strings.HasPrefix(value, placeholderPrefix) && strings.HasSuffix(value, placeholderSuffix)
+}
+
+// Placeholder returns the redacted placeholder for a secret value. The placeholder is derived from
+// the value, so a changed secret results in a different placeholder, without revealing the secret.
+func Placeholder(value string) string {
+	mu.Lock()
+	if placeholderKey == nil {
+		// Without a key, placeholders are only stable for the lifetime of the process.
+		placeholderKey = make([]byte, sha256.Size)
+		_, _ = rand.Read(placeholderKey)
+	}
+
+	mac := hmac.New(sha256.New, placeholderKey)
+	mu.Unlock()
+
+	mac.Write([]byte(value))
+
+	return placeholderPrefix + hex.EncodeToString(mac.Sum(nil))[:16] + placeholderSuffix
+}
+
+// Encrypt encrypts a secret value for storage, binding it to the provided name. If no key is set,
+// the value is returned unchanged.
+func Encrypt(name string, value string) (string, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if unavailableErr != nil {
+		// Keep the secrets which couldn't be decrypted, and refuse to store new ones unencrypted.
+		if value == "" || strings.HasPrefix(value, encryptedPrefix) {
+			return value, nil
+		}
+
+		return "", fmt.Errorf("%w: %w", ErrUnavailable, unavailableErr)
+	}
+
+	if aead == nil || value == "" {
+		return value, nil
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(name))
+
+	return encryptedPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a secret value previously returned by Encrypt. Values that aren't encrypted,
+// such as those written before encryption was enabled, are returned unchanged, as are all values
+// while the secrets are unavailable.
+func Decrypt(name string, value string) (string, error) {
+	encoded, ok := strings.CutPrefix(value, encryptedPrefix)
+	if !ok {
+		return value, nil
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if unavailableErr != nil {
+		return value, nil
+	}
+
+	if aead == nil {
+		return "", errors.New("no key available to decrypt secret")
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("encrypted secret is too short")
+	}
+
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+
+	return string(plain), nil
+}
+
+// Redact returns a copy of the provided value with all non-empty secret fields replaced by
+// their placeholder. Values without any secret fields are returned as-is.
+func Redact(v any) (any, error) {
+	if v == nil || !hasSecrets(reflect.TypeOf(v)) {
+		return v, nil
+	}
+
+	cp, err := deepCopy(v)
+	if err != nil {
+		return nil, err
+	}
+
+	err = walk(cp, func(value string) (string, error) {
+		if value == "" {
+			return value, nil
+		}
+
+		return Placeholder(value), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cp.Interface(), nil
+}
+
+// Restore replaces any placeholders found in the secret fields of newValue, which must be a
+// pointer, with the matching secret from oldValue. This allows clients to send back a previously
+// retrieved configuration without having to provide the secrets again.
+func Restore(newValue any, oldValue any) error {
+	v := reflect.ValueOf(newValue)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return errors.New("restoring secrets requires a pointer")
+	}
+
+	if !hasSecrets(v.Type()) {
+		return nil
+	}
+
+	// Collect the current secrets.
+	known := map[string]string{}
+
+	if oldValue != nil {
+		// Work on a copy, as walking maps modifies them.
+		old, err := deepCopy(oldValue)
+		if err != nil {
+			return err
+		}
+
+		err = walk(old, func(value string) (string, error) {
+			if value != "" {
+				known[Placeholder(value)] = value
+			}
+
+			return value, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	unavailable := Unavailable()
+
+	return walk(v.Elem(), func(value string) (string, error) {
+		if !IsPlaceholder(value) {
+			// New secrets can't be stored while the secrets are unavailable.
+			_, isKnown := known[Placeholder(value)]
+			if unavailable != nil && value != "" && !isKnown {
+				return "", fmt.Errorf("%w: %w", ErrUnavailable, unavailable)
+			}
+
+			return value, nil
+		}
+
+		secret, ok := known[value]
+		if !ok {
+			return "", fmt.Errorf("%w '%s'", ErrUnknownPlaceholder, value)
+		}
+
+		return secret, nil
+	})
+}
+
+// deepCopy makes a deep copy through a JSON round trip, which also matches what's returned to clients.
+func deepCopy(v any) (reflect.Value, error) {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	cp := reflect.New(reflect.TypeOf(v))
+
+	err = json.Unmarshal(content, cp.Interface())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	return cp.Elem(), nil
+}
+
+// walk calls fn on every secret string found in v, replacing it with the returned value.
+func walk(v reflect.Value, fn func(string) (string, error)) error {
+	if !hasSecrets(v.Type()) {
+		return nil
+	}
+
+	switch v.Kind() { //nolint:exhaustive
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+
+		return walk(v.Elem(), fn)
+	case reflect.Struct:
+		for _, field := range reflect.VisibleFields(v.Type()) {
+			if !field.IsExported() || field.Anonymous {
+				continue
+			}
+
+			fieldValue := v.FieldByIndex(field.Index)
+
+			if IsSecret(field) {
+				err := apply(fieldValue, fn)
+				if err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			err := walk(fieldValue, fn)
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			err := walk(v.Index(i), fn)
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// Map values aren't addressable, so work on a copy of each.
+		for _, key := range v.MapKeys() {
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(v.MapIndex(key))
+
+			err := walk(value, fn)
+			if err != nil {
+				return err
+			}
+
+			v.SetMapIndex(key, value)
+		}
+	default:
+	}
+
+	return nil
+}
+
+// apply calls fn on a secret string or string slice.
+func apply(v reflect.Value, fn func(string) (string, error)) error {
+	switch {
+	case v.Kind() == reflect.String:
+		value, err := fn(v.String())
+		if err != nil {
+			return err
+		}
+
+		v.SetString(value)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		for i := range v.Len() {
+			err := apply(v.Index(i), fn)
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported kind '%s' for secret field", v.Kind())
+	}
+
+	return nil
+}
+
+// hasSecrets returns whether values of the provided type may hold secret fields.
+func hasSecrets(t reflect.Type) bool {
+	cached, ok := secretTypes.Load(t)
+	if ok {
+		return cached.(bool) //nolint:forcetypeassert
+	}
+
+	found := typeHasSecrets(t, map[reflect.Type]bool{})
+	secretTypes.Store(t, found)
+
+	return found
+}
+
+// typeHasSecrets walks the type definition, skipping types already being visited to handle recursive types.
+func typeHasSecrets(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if visiting[t] {
+		return false
+	}
+
+	visiting[t] = true
+
+	switch t.Kind() { //nolint:exhaustive
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return typeHasSecrets(t.Elem(), visiting)
+	case reflect.Struct:
+		for _, field := range reflect.VisibleFields(t) {
+			if !field.IsExported() || field.Anonymous {
+				continue
+			}
+
+			if IsSecret(field) || typeHasSecrets(field.Type, visiting) {
+				return true
+			}
+		}
+	default:
+	}
+
+	return false
+}
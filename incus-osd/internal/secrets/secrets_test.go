@@ -0,0 +1,158 @@
+package secrets_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
+)
+
+func TestRedactRestore(t *testing.T) {
+	t.Parallel()
+
+	orig := api.ServiceCeph{
+		Config: api.ServiceCephConfig{
+			Clusters: map[string]api.ServiceCephCluster{
+				"ceph": {
+					FSID: "abcd",
+					Keyrings: map[string]api.ServiceCephKeyring{
+						"admin": {Key: "secret-key"},
+					},
+				},
+			},
+		},
+	}
+
+	// Secrets in nested maps are redacted, without modifying the original value.
+	redacted, err := secrets.Redact(orig)
+	require.NoError(t, err)
+
+	redactedCeph, ok := redacted.(api.ServiceCeph)
+	require.True(t, ok)
+
+	placeholder := redactedCeph.Config.Clusters["ceph"].Keyrings["admin"].Key
+	require.True(t, secrets.IsPlaceholder(placeholder))
+	require.Equal(t, "abcd", redactedCeph.Config.Clusters["ceph"].FSID)
+	require.Equal(t, "secret-key", orig.Config.Clusters["ceph"].Keyrings["admin"].Key)
+
+	// Sending back the placeholder keeps the current secret.
+	err = secrets.Restore(&redactedCeph, orig)
+	require.NoError(t, err)
+	require.Equal(t, "secret-key", redactedCeph.Config.Clusters["ceph"].Keyrings["admin"].Key)
+
+	// Unknown placeholders are rejected.
+	newCeph := api.ServiceCeph{
+		Config: api.ServiceCephConfig{
+			Clusters: map[string]api.ServiceCephCluster{
+				"ceph": {
+					Keyrings: map[string]api.ServiceCephKeyring{
+						"admin": {Key: secrets.Placeholder("other-key")},
+					},
+				},
+			},
+		},
+	}
+
+	err = secrets.Restore(&newCeph, orig)
+	require.ErrorIs(t, err, secrets.ErrUnknownPlaceholder)
+
+	// String slices and pointers are handled.
+	proxy := &api.SystemNetworkProxy{
+		Servers: map[string]api.SystemNetworkProxyServer{
+			"proxy": {Host: "proxy.example.com", Password: "hunter2"},
+		},
+	}
+
+	redacted, err = secrets.Redact(proxy)
+	require.NoError(t, err)
+
+	redactedProxy, ok := redacted.(*api.SystemNetworkProxy)
+	require.True(t, ok)
+	require.Equal(t, secrets.Placeholder("hunter2"), redactedProxy.Servers["proxy"].Password)
+	require.Equal(t, "hunter2", proxy.Servers["proxy"].Password)
+
+	tokens := api.ApplicationOpenFGAConfig{APITokens: []string{"token1", ""}}
+
+	redacted, err = secrets.Redact(tokens)
+	require.NoError(t, err)
+	require.Equal(t, []string{secrets.Placeholder("token1"), ""}, redacted.(api.ApplicationOpenFGAConfig).APITokens) //nolint:forcetypeassert
+
+	// Types without secrets are returned as-is.
+	redacted, err = secrets.Redact(api.SystemStorageWipe{ID: "sda"})
+	require.NoError(t, err)
+	require.Equal(t, api.SystemStorageWipe{ID: "sda"}, redacted)
+}
+
+// TestEncryptDecrypt sets the package-wide key, so doesn't run in parallel with other tests.
+func TestEncryptDecrypt(t *testing.T) { //nolint:paralleltest
+	// Without a key, values are stored as-is.
+	value, err := secrets.Encrypt("Services.Tailscale.Config.AuthKey", "tskey")
+	require.NoError(t, err)
+	require.Equal(t, "tskey", value)
+
+	err = secrets.SetKey(make([]byte, 16))
+	require.Error(t, err)
+
+	err = secrets.SetKey(make([]byte, secrets.KeySize))
+	require.NoError(t, err)
+
+	encrypted, err := secrets.Encrypt("Services.Tailscale.Config.AuthKey", "tskey")
+	require.NoError(t, err)
+	require.NotContains(t, encrypted, "tskey")
+
+	decrypted, err := secrets.Decrypt("Services.Tailscale.Config.AuthKey", encrypted)
+	require.NoError(t, err)
+	require.Equal(t, "tskey", decrypted)
+
+	// The value is bound to its name.
+	_, err = secrets.Decrypt("Services.Netbird.Config.SetupKey", encrypted)
+	require.Error(t, err)
+
+	// Plain text values, such as those written before encryption, are passed through.
+	decrypted, err = secrets.Decrypt("Services.Tailscale.Config.AuthKey", "tskey")
+	require.NoError(t, err)
+	require.Equal(t, "tskey", decrypted)
+}
+
+// TestUnavailable marks the package-wide secrets as unavailable, so doesn't run in parallel with other tests.
+func TestUnavailable(t *testing.T) { //nolint:paralleltest
+	err := secrets.SetKey(make([]byte, secrets.KeySize))
+	require.NoError(t, err)
+
+	encrypted, err := secrets.Encrypt("Services.Tailscale.Config.AuthKey", "tskey")
+	require.NoError(t, err)
+
+	secrets.SetUnavailable(errors.New("unable to unseal the secrets key"))
+	defer secrets.SetUnavailable(nil)
+
+	// Encrypted secrets are kept as-is.
+	value, err := secrets.Decrypt("Services.Tailscale.Config.AuthKey", encrypted)
+	require.NoError(t, err)
+	require.Equal(t, encrypted, value)
+
+	value, err = secrets.Encrypt("Services.Tailscale.Config.AuthKey", encrypted)
+	require.NoError(t, err)
+	require.Equal(t, encrypted, value)
+
+	// New secrets are refused.
+	_, err = secrets.Encrypt("Services.Tailscale.Config.AuthKey", "other-tskey")
+	require.ErrorIs(t, err, secrets.ErrUnavailable)
+
+	orig := api.ServiceTailscale{Config: api.ServiceTailscaleConfig{AuthKey: encrypted}}
+
+	updated := orig
+	err = secrets.Restore(&updated, orig)
+	require.NoError(t, err)
+
+	updated.Config.AuthKey = secrets.Placeholder(encrypted)
+	err = secrets.Restore(&updated, orig)
+	require.NoError(t, err)
+	require.Equal(t, encrypted, updated.Config.AuthKey)
+
+	updated.Config.AuthKey = "other-tskey"
+	err = secrets.Restore(&updated, orig)
+	require.ErrorIs(t, err, secrets.ErrUnavailable)
+}
@@ -317,7 +317,7 @@ func appendEFIVarUpdate(ctx context.Context, efiUpdateFile string, varName strin
 	}
 
 	// Compute the new expected PCR7 value on next boot.
-	newPCR7, err := computeNewPCR7Value(eventLog)
+	newPCR7, err := computeNewPCR7Value(eventLog, nil)
 	if err != nil {
 		return err
 	}
@@ -331,7 +331,7 @@ func appendEFIVarUpdate(ctx context.Context, efiUpdateFile string, varName strin
 	}
 
 	for name, volume := range luksVolumes {
-		_, err = subprocess.RunCommandContext(ctx, "systemd-cryptenroll", "--unlock-key-file=/var/lib/incus-os/recovery."+name+".key", "--tpm2-device=auto", "--wipe-slot=tpm2", "--tpm2-pcrlock=", pcrBindingArg, volume)
+		_, err = enrollTPM2(ctx, name, volume, pcrBindingArg, "")
 		if err != nil {
 			return err
 		}
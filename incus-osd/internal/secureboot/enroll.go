@@ -0,0 +1,134 @@
+package secureboot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lxc/incus/v7/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/tang"
+	"github.com/lxc/incus-os/incus-osd/internal/util"
+)
+
+// UpdateTPMBindings re-enrolls the TPM for each LUKS volume using the current PCR values.
+// This is used when the TPM2 PIN requirement of the volumes changes, such as when enabling
+// or disabling required network-bound unlocking.
+func UpdateTPMBindings(ctx context.Context) error {
+	sbEnabled, err := Enabled()
+	if err != nil {
+		return err
+	}
+
+	pcr4, err := ReadPCR("4")
+	if err != nil {
+		return err
+	}
+
+	pcr7, err := ReadPCR("7")
+	if err != nil {
+		return err
+	}
+
+	pcrBindingArg := "--tpm2-pcrs=7:sha256=" + hex.EncodeToString(pcr7) + "+15:sha256=0000000000000000000000000000000000000000000000000000000000000000"
+
+	// When Secure Boot is disabled, we also bind to PCR4.
+	if !sbEnabled {
+		pcrBindingArg = "--tpm2-pcrs=4:sha256=" + hex.EncodeToString(pcr4) + "+7:sha256=" + hex.EncodeToString(pcr7) + "+15:sha256=0000000000000000000000000000000000000000000000000000000000000000"
+	}
+
+	luksVolumes, err := util.GetLUKSVolumePartitions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for name, volume := range luksVolumes {
+		err := rebindTPM2(ctx, name, volume, pcrBindingArg, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// enrollTPM2 binds the TPM to the LUKS volume using the provided PCR policy and returns the stderr
+// output of systemd-cryptenroll. The volume is unlocked using its IncusOS recovery key, unless a
+// LUKS passphrase is provided.
+//
+// When network-bound unlocking is required for the volume, the secret recovered from a Tang server
+// is enrolled as the TPM2 PIN, so the TPM alone can't unlock the volume.
+func enrollTPM2(ctx context.Context, name string, volume string, pcrBindingArg string, luksKey string) (string, error) {
+	env := os.Environ()
+	args := []string{"--tpm2-device=auto", "--wipe-slot=tpm2", "--tpm2-pcrlock=", pcrBindingArg}
+
+	if luksKey == "" {
+		args = append([]string{"--unlock-key-file=/var/lib/incus-os/recovery." + name + ".key"}, args...)
+	} else {
+		env = append(env, "PASSWORD="+luksKey)
+	}
+
+	token, _, err := util.GetNetworkUnlockToken(ctx, volume)
+	if err != nil {
+		return "", err
+	}
+
+	if token != nil && token.Mode == string(api.NetworkUnlockModeRequired) {
+		secret, err := tang.RecoverAny(ctx, token.Bindings)
+		if err != nil {
+			return "", fmt.Errorf("unable to recover the TPM2 PIN from any Tang server: %w", err)
+		}
+
+		env = append(env, "NEWPIN="+string(secret))
+		args = append(args, "--tpm2-with-pin=yes")
+	}
+
+	_, stderr, err := subprocess.RunCommandSplit(ctx, env, nil, "systemd-cryptenroll", append(args, volume)...)
+
+	return stderr, err
+}
+
+// rebindTPM2 binds the TPM to the LUKS volume using the provided PCR policy, even if the policy is
+// identical to the currently enrolled one.
+func rebindTPM2(ctx context.Context, name string, volume string, pcrBindingArg string, luksKey string) error {
+	stderr, err := enrollTPM2(ctx, name, volume, pcrBindingArg, luksKey)
+	if err != nil {
+		return err
+	}
+
+	// Handle an edge case where the PCR policy is identical, but the TPM is unable to unlock the LUKS keyslot.
+	// This is seen when an existing TPM is replaced by a new one, since the computed PCRs will be the same,
+	// but the internal TPM state will be different and thus unable to properly decrypt the LUKS keyslot blob.
+	//
+	// systemd-cryptenroll doesn't have a --force option to always perform a PCR bind operation, so we need to
+	// first bind a junk PCR policy, then re-apply the correct good one which will then also update the LUKS
+	// keyslot TPM blob.
+	if !strings.Contains(stderr, "This PCR set is already enrolled, executing no operation.") {
+		return nil
+	}
+
+	// Generate a random SHA256 PCR7 value.
+	randomPCR := make([]byte, 32)
+
+	_, err = rand.Read(randomPCR)
+	if err != nil {
+		return err
+	}
+
+	pcrRandomBindingArg := "--tpm2-pcrs=7:sha256=" + hex.EncodeToString(randomPCR) + "+15:sha256=0000000000000000000000000000000000000000000000000000000000000000"
+
+	// Set a bad PCR policy.
+	_, err = enrollTPM2(ctx, name, volume, pcrRandomBindingArg, luksKey)
+	if err != nil {
+		return err
+	}
+
+	// Re-bind the expected PCR policy.
+	_, err = enrollTPM2(ctx, name, volume, pcrBindingArg, luksKey)
+
+	return err
+}
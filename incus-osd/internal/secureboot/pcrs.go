@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"crypto"
-	"crypto/rand"
 	"crypto/sha256"
 	"crypto/x509"
 	"debug/pe"
@@ -111,62 +110,10 @@ func ForceUpdatePCRBindings(ctx context.Context, osName string, osVersion string
 	}
 
 	for name, volume := range luksVolumes {
-		var stderr string
-
-		if luksKey == "" {
-			_, stderr, err = subprocess.RunCommandSplit(ctx, nil, nil, "systemd-cryptenroll", "--unlock-key-file=/var/lib/incus-os/recovery."+name+".key", "--tpm2-device=auto", "--wipe-slot=tpm2", "--tpm2-pcrlock=", pcrBindingArg, volume)
-		} else {
-			_, stderr, err = subprocess.RunCommandSplit(ctx, append(os.Environ(), "PASSWORD="+luksKey), nil, "systemd-cryptenroll", "--tpm2-device=auto", "--wipe-slot=tpm2", "--tpm2-pcrlock=", pcrBindingArg, volume)
-		}
-
+		err := rebindTPM2(ctx, name, volume, pcrBindingArg, luksKey)
 		if err != nil {
 			return err
 		}
-
-		// Handle an edge case where the PCR policy is identical, but the TPM is unable to unlock the LUKS keyslot.
-		// This is seen when an existing TPM is replaced by a new one, since the computed PCRs will be the same,
-		// but the internal TPM state will be different and thus unable to properly decrypt the LUKS keyslot blob.
-		//
-		// systemd-cryptenroll doesn't have a --force option to always perform a PCR bind operation, so we need to
-		// first bind a junk PCR policy, then re-apply the correct good one which will then also update the LUKS
-		// keyslot TPM blob.
-		if strings.Contains(stderr, "This PCR set is already enrolled, executing no operation.") { //nolint:nestif
-			// Generate a random SHA256 PCR7 value.
-			randomPCR := make([]byte, 32)
-
-			_, err := rand.Read(randomPCR)
-			if err != nil {
-				return err
-			}
-
-			pcrRandomBindingArg := "--tpm2-pcrs=7:sha256=" + hex.EncodeToString(randomPCR) + "+15:sha256=0000000000000000000000000000000000000000000000000000000000000000"
-
-			if luksKey == "" {
-				// Set a bad PCR policy.
-				_, err = subprocess.RunCommandContext(ctx, "systemd-cryptenroll", "--unlock-key-file=/var/lib/incus-os/recovery."+name+".key", "--tpm2-device=auto", "--wipe-slot=tpm2", "--tpm2-pcrlock=", pcrRandomBindingArg, volume)
-				if err != nil {
-					return err
-				}
-
-				// Re-bind the expected PCR policy.
-				_, err = subprocess.RunCommandContext(ctx, "systemd-cryptenroll", "--unlock-key-file=/var/lib/incus-os/recovery."+name+".key", "--tpm2-device=auto", "--wipe-slot=tpm2", "--tpm2-pcrlock=", pcrBindingArg, volume)
-				if err != nil {
-					return err
-				}
-			} else {
-				// Set a bad PCR policy.
-				_, _, err = subprocess.RunCommandSplit(ctx, append(os.Environ(), "PASSWORD="+luksKey), nil, "systemd-cryptenroll", "--tpm2-device=auto", "--wipe-slot=tpm2", "--tpm2-pcrlock=", pcrRandomBindingArg, volume)
-				if err != nil {
-					return err
-				}
-
-				// Re-bind the expected PCR policy.
-				_, _, err = subprocess.RunCommandSplit(ctx, append(os.Environ(), "PASSWORD="+luksKey), nil, "systemd-cryptenroll", "--tpm2-device=auto", "--wipe-slot=tpm2", "--tpm2-pcrlock=", pcrBindingArg, volume)
-				if err != nil {
-					return err
-				}
-			}
-		}
 	}
 
 	// Once complete, immediately reboot the system which should then auto-unlock.
@@ -346,11 +293,18 @@ func computeVmlinuzAuthenticodeHash(ukiFile string) ([]byte, error) {
 	return authenticodeContents.Hash(crypto.SHA256), nil
 }
 
-// computeNewPCR7Value will compute the future PCR7 value after the KEK, db, and/or dbx EFI variables are updated.
+// computeNewPCR7Value will compute the future PCR7 value after the KEK, db, and/or dbx EFI variables are updated,
+// or after the systemd-boot EFI stub or UKI are signed with a different Secure Boot certificate. If no certificate
+// is provided for the UKI, it's assumed to be signed by the same certificate as the currently running one.
 // IMPORTANT: It is assumed that the provided TPM event log has already been validated.
-func computeNewPCR7Value(eventLog []tcg.Event) ([]byte, error) {
+func computeNewPCR7Value(eventLog []tcg.Event, newUKICert *x509.Certificate) ([]byte, error) {
 	actualPCR7Buf := make([]byte, 32)
 
+	authorities, err := newVariableAuthorities(eventLog, newUKICert)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, e := range eventLog {
 		if e.Index == 7 { // We only care about PCR7.
 			switch e.Type { //nolint:exhaustive
@@ -367,18 +321,20 @@ func computeNewPCR7Value(eventLog []tcg.Event) ([]byte, error) {
 				}
 			case tcg.EFIVariableAuthority:
 				// Variable authority is a certificate used to sign EFI binaries (typically systemd-boot and the IncusOS
-				// image, but also potentially third-party EFI drivers). We expect the IncusOS certificate used to sign
-				// the systemd-boot EFI stub to match what's in the TPM event log. If there's a mis-match, we are about
-				// to boot with a new Secure Boot signing key. Fetch the expected new certificate from the EFI db variable
-				// and use it for PCR7 computation.
-				buf, err := computeExpectedVariableAuthority(e.Data)
+				// image, but also potentially third-party EFI drivers). We expect the IncusOS certificates used to sign
+				// the systemd-boot EFI stub and the UKI to match what's in the TPM event log. If there's a mis-match, we
+				// are about to boot with a new Secure Boot signing key. Fetch the expected new certificates from the EFI
+				// db variable and use them for PCR7 computation.
+				bufs, err := authorities.expected(e.Data)
 				if err != nil {
 					return nil, err
 				}
 
-				actualPCR7Buf, err = extendPCRValue(actualPCR7Buf, buf, true)
-				if err != nil {
-					return nil, err
+				for _, buf := range bufs {
+					actualPCR7Buf, err = extendPCRValue(actualPCR7Buf, buf, true)
+					if err != nil {
+						return nil, err
+					}
 				}
 			default:
 				// For all other types, re-use the existing digest from the event log.
@@ -417,87 +373,219 @@ func computeExpectedVariableDriverConfig(rawBuf []byte) ([]byte, error) {
 	return v.Encode()
 }
 
-// computeExpectedVariableAuthority checks if the signature used by the systemd-boot EFI stub has
-// changed, and if so, computes the new expected value.
-func computeExpectedVariableAuthority(rawBuf []byte) ([]byte, error) {
-	v, err := tcg.ParseUEFIVariableData(bytes.NewReader(rawBuf))
+// variableAuthorities computes the expected VariableAuthority events for the IncusOS certificates.
+//
+// The first IncusOS certificate measured is the one used to verify the systemd-boot EFI stub. When
+// the UKI is signed with a different certificate, which happens when a custom Secure Boot key
+// hierarchy is used and the signing key has been rotated, a second certificate is measured when
+// the UKI is verified.
+type variableAuthorities struct {
+	stubCert   *x509.Certificate
+	ukiCert    *x509.Certificate
+	signingKey []byte
+
+	// Number of IncusOS certificates in the event log and number of those processed so far.
+	total int
+	seen  int
+}
+
+func newVariableAuthorities(eventLog []tcg.Event, newUKICert *x509.Certificate) (*variableAuthorities, error) {
+	// Get existing certificate from systemd-boot EFI stub.
+	efiFiles, err := getArchEFIFiles()
 	if err != nil {
 		return nil, err
 	}
 
-	va, err := tcg.ParseUEFIVariableAuthority(v)
+	a := &variableAuthorities{}
+
+	for _, e := range eventLog {
+		if e.Index != 7 || e.Type != tcg.EFIVariableAuthority {
+			continue
+		}
+
+		// Only load the systemd-boot certificate if there's something to compare it against.
+		if a.stubCert == nil {
+			a.stubCert, err = extractCertificateFromPE(efiFiles["bootEFI"])
+			if err != nil {
+				return nil, err
+			}
+
+			a.ukiCert = newUKICert
+
+			// The public key of the certificate which signed the currently running UKI. A missing
+			// key only means certificates can't be matched against it.
+			currentKey, err := os.ReadFile("/run/systemd/tpm2-pcr-public-key.pem")
+			if err == nil {
+				a.signingKey, _ = getPEMBytes(currentKey)
+			}
+		}
+
+		_, cert, err := parseVariableAuthority(e.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		if a.isIncusOSCertificate(cert) {
+			a.total++
+		}
+	}
+
+	return a, nil
+}
+
+// isIncusOSCertificate determines if a certificate from the event log was used to verify the
+// systemd-boot EFI stub or the UKI, rather than a third-party EFI binary.
+func (a *variableAuthorities) isIncusOSCertificate(cert *x509.Certificate) bool {
+	if cert.Equal(a.stubCert) {
+		return true
+	}
+
+	// The certificate which signed the currently running UKI.
+	if a.signingKey != nil {
+		publicKey, err := getPublicKeyPEM(cert)
+		if err == nil && bytes.Equal(publicKey, a.signingKey) {
+			return true
+		}
+	}
+
+	// Use the first four "words" of the existing certificate's Subject field to determine if the variable
+	// authority certificate we're considering is third-party or not. We can't rely on a simple whitelist of
+	// either "our" expected certificates or third-party certificates.
+	stubSubject := strings.Split(a.stubCert.Subject.String(), " ")
+	if len(stubSubject) < 4 {
+		return false
+	}
+
+	return strings.HasPrefix(cert.Subject.String(), strings.Join(stubSubject[:4], " "))
+}
+
+// expected returns the expected encoded VariableAuthority events replacing the provided one.
+func (a *variableAuthorities) expected(rawBuf []byte) ([][]byte, error) {
+	v, cert, err := parseVariableAuthority(rawBuf)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(va.Certs) != 1 {
-		return nil, fmt.Errorf("expected exactly one certificate in VariableAuthority, got %d", len(va.Certs))
+	// If this is a third-party certificate, there's nothing for us to do.
+	if !a.isIncusOSCertificate(cert) {
+		return [][]byte{rawBuf}, nil
 	}
 
-	// Get existing certificate from systemd-boot EFI stub.
-	efiFiles, err := getArchEFIFiles()
+	a.seen++
+
+	switch a.seen {
+	case 1:
+		// The certificate used to verify the systemd-boot EFI stub.
+		ret := [][]byte{rawBuf}
+
+		if !cert.Equal(a.stubCert) {
+			buf, err := encodeVariableAuthority(v, a.stubCert)
+			if err != nil {
+				return nil, err
+			}
+
+			ret[0] = buf
+		}
+
+		// If the UKI will now be verified by a different certificate, it will be measured right after.
+		if a.total == 1 && a.ukiCert != nil && !a.ukiCert.Equal(a.stubCert) {
+			buf, err := encodeVariableAuthority(v, a.ukiCert)
+			if err != nil {
+				return nil, err
+			}
+
+			ret = append(ret, buf)
+		}
+
+		return ret, nil
+	case 2:
+		// The certificate used to verify the UKI.
+		if a.ukiCert == nil || cert.Equal(a.ukiCert) {
+			return [][]byte{rawBuf}, nil
+		}
+
+		// A certificate is only measured once, so nothing is measured if the UKI is verified by the same
+		// certificate as the systemd-boot EFI stub.
+		if a.ukiCert.Equal(a.stubCert) {
+			return nil, nil
+		}
+
+		buf, err := encodeVariableAuthority(v, a.ukiCert)
+		if err != nil {
+			return nil, err
+		}
+
+		return [][]byte{buf}, nil
+	default:
+		return [][]byte{rawBuf}, nil
+	}
+}
+
+// parseVariableAuthority parses a VariableAuthority event, which is expected to contain exactly one certificate.
+func parseVariableAuthority(rawBuf []byte) (*tcg.UEFIVariableData, *x509.Certificate, error) {
+	v, err := tcg.ParseUEFIVariableData(bytes.NewReader(rawBuf))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	existingCert, err := extractCertificateFromPE(efiFiles["bootEFI"])
+	va, err := tcg.ParseUEFIVariableAuthority(v)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	// If the certificates match, no need for further updates.
-	if va.Certs[0].Equal(existingCert) {
-		return rawBuf, nil
+	if len(va.Certs) != 1 {
+		return nil, nil, fmt.Errorf("expected exactly one certificate in VariableAuthority, got %d", len(va.Certs))
 	}
 
-	// Use the first four "words" of the existing certificate's Subject field to determine if the variable
-	// authority certificate we're considering is third-party or not. We can't rely on a simple whitelist of
-	// either "our" expected certificates or third-party certificates.
-	existingCertPrefix := strings.Join(strings.Split(existingCert.Subject.String(), " ")[:4], " ")
+	return &v, &va.Certs[0], nil
+}
 
-	// If this is a third-party certificate, there's nothing for us to do.
-	if !strings.HasPrefix(va.Certs[0].Subject.String(), existingCertPrefix) {
-		return rawBuf, nil
+// encodeVariableAuthority returns the encoded VariableAuthority event for the expected certificate, based on the
+// provided event.
+func encodeVariableAuthority(v *tcg.UEFIVariableData, expectedCert *x509.Certificate) ([]byte, error) {
+	// Get the expected certificate, along with its signature owner GUID, from the db.
+	dbVar, err := util.ReadEFIVariable("db")
+	if err != nil {
+		return nil, err
 	}
 
-	// There was a mismatch between the EFI stub's certificate and the certificate in the event log.
-	// Try to get the expected certificate from the db.
-	certs, err := GetCertificatesFromVar("db")
+	dbCerts, err := parseEfiSignatureList(dbVar)
 	if err != nil {
 		return nil, err
 	}
 
 	// Find the matching certificate.
-	index := slices.IndexFunc(certs, func(c *x509.Certificate) bool {
-		return c.Equal(existingCert)
+	index := slices.IndexFunc(dbCerts, func(c parsedSignatureList) bool {
+		return c.err == nil && c.cert.Equal(expectedCert)
 	})
 	if index < 0 {
-		return nil, fmt.Errorf("failed to find matching certificate '%s' used by systemd-boot stub in EFI db variable", existingCert.Subject.String())
+		return nil, fmt.Errorf("failed to find matching certificate '%s' in EFI db variable", expectedCert.Subject.String())
 	}
 
-	// Update the variable's contents with the expected certificate value.
+	// Build the variable's contents from the signature owner GUID and the certificate.
 	var newBuf bytes.Buffer
 
-	_, err = newBuf.Write(v.VariableData[:16]) // The first 16 bytes are the signature owner GUID, which shouldn't change.
+	_, err = newBuf.Write(dbCerts[index].ownerGUID[:])
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = newBuf.Write(certs[index].Raw) // Write the new certificate's contents.
+	_, err = newBuf.Write(dbCerts[index].cert.Raw)
 	if err != nil {
 		return nil, err
 	}
 
-	if newBuf.Len() != 16+len(certs[index].Raw) {
-		return nil, fmt.Errorf("resulting buffer size (%d) != expected size (%d)", newBuf.Len(), 16+len(certs[index].Raw))
+	if newBuf.Len() != 16+len(dbCerts[index].cert.Raw) {
+		return nil, fmt.Errorf("resulting buffer size (%d) != expected size (%d)", newBuf.Len(), 16+len(dbCerts[index].cert.Raw))
 	}
 
-	// Update in-memory values.
-	v.Header.VariableDataLength = uint64(newBuf.Len()) // #nosec G115
-	v.VariableData = newBuf.Bytes()
+	// Update a copy of the in-memory values.
+	newV := *v
+	newV.Header.VariableDataLength = uint64(newBuf.Len()) // #nosec G115
+	newV.VariableData = newBuf.Bytes()
 
 	// Get the updated buffer and use for PCR calculation.
-	return v.Encode()
+	return newV.Encode()
 }
 
 // extractCertificateFromPE returns the signing certificate from a given PE binary.
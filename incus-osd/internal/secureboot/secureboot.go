@@ -12,6 +12,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -64,7 +65,9 @@ func InAuditMode() (bool, error) {
 // signing key used for the UKIs is changed:
 //
 //	1: Verify the new certificate is in db and isn't in dbx.
-//	2: Replace the existing systemd-boot EFI stub with the newly-signed one.
+//	2: Replace the existing systemd-boot EFI stub with the newly-signed one,
+//	   unless it isn't signed by a trusted certificate, as is the case when
+//	   the system uses its own Secure Boot key hierarchy.
 //	3: Compute the new PCR4 and PCR7 values expected on next boot.
 //	4: Set the new Secure Boot public key to be used by the TPM for verifying
 //	   the PCR11 policies. Since this will invalidate the current TPM state, we
@@ -95,6 +98,11 @@ func HandleSecureBootKeyChange(ctx context.Context, ukiFile string, usrImageFile
 		return err
 	}
 
+	ukiCert, err := extractCertificateFromPE(ukiFile)
+	if err != nil {
+		return err
+	}
+
 	// Part 2 -- Update the systemd-boot EFI stub.
 	if usrImageFile != "" {
 		err := updateEFIBootStub(ctx, usrImageFile)
@@ -109,7 +117,7 @@ func HandleSecureBootKeyChange(ctx context.Context, ukiFile string, usrImageFile
 		return err
 	}
 
-	newPCR7, err := computeNewPCR7Value(eventLog)
+	newPCR7, err := computeNewPCR7Value(eventLog, ukiCert)
 	if err != nil {
 		return err
 	}
@@ -136,7 +144,7 @@ func HandleSecureBootKeyChange(ctx context.Context, ukiFile string, usrImageFile
 	}
 
 	for name, volume := range luksVolumes {
-		_, err := subprocess.RunCommandContext(ctx, "systemd-cryptenroll", "--unlock-key-file=/var/lib/incus-os/recovery."+name+".key", "--tpm2-device=auto", "--wipe-slot=tpm2", "--tpm2-pcrlock=", pcrBindingArg, volume)
+		_, err := enrollTPM2(ctx, name, volume, pcrBindingArg, "")
 		if err != nil {
 			return err
 		}
@@ -197,7 +205,7 @@ func UpdatePCR4Binding(ctx context.Context, ukiFile string) error {
 	pcrBindingArg := "--tpm2-pcrs=4:sha256=" + newPCR4String + "+7:sha256=" + pcr7String + "+15:sha256=0000000000000000000000000000000000000000000000000000000000000000"
 
 	for name, volume := range luksVolumes {
-		_, err := subprocess.RunCommandContext(ctx, "systemd-cryptenroll", "--unlock-key-file=/var/lib/incus-os/recovery."+name+".key", "--tpm2-device=auto", "--wipe-slot=tpm2", "--tpm2-pcrlock=", pcrBindingArg, volume)
+		_, err := enrollTPM2(ctx, name, volume, pcrBindingArg, "")
 		if err != nil {
 			return err
 		}
@@ -433,17 +441,12 @@ outer:
 // it's just another easy check to help ensure we only install valid UKIs.)
 func validatePKICertificate(cert []byte) error {
 	certEqualityFunc := func(c *x509.Certificate) bool {
-		publicKeyDer, err := x509.MarshalPKIXPublicKey(c.PublicKey)
+		publicKey, err := getPublicKeyPEM(c)
 		if err != nil {
 			return false
 		}
 
-		publicKeyBlock := pem.Block{
-			Type:  "PUBLIC KEY",
-			Bytes: publicKeyDer,
-		}
-
-		return bytes.Equal(pem.EncodeToMemory(&publicKeyBlock), cert)
+		return bytes.Equal(publicKey, cert)
 	}
 
 	dbCerts, err := GetCertificatesFromVar("db")
@@ -499,6 +502,21 @@ func getPublicKeyFromUKI(ukiFile string) ([]byte, error) {
 	return getPEMBytes(pcrpkeyData)
 }
 
+// getPublicKeyPEM returns the PEM-encoded public key of the certificate.
+func getPublicKeyPEM(cert *x509.Certificate) ([]byte, error) {
+	publicKeyDer, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKeyBlock := pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: publicKeyDer,
+	}
+
+	return pem.EncodeToMemory(&publicKeyBlock), nil
+}
+
 // getPEMBytes attempts to decode a PEM-encoded public key from the supplied buffer
 // and returns its bytes.
 func getPEMBytes(buf []byte) ([]byte, error) {
@@ -511,6 +529,10 @@ func getPEMBytes(buf []byte) ([]byte, error) {
 }
 
 // updateEFIBootStub synchronizes the systemd-boot EFI stub when the Secure Boot signing key is rotated.
+//
+// The systemd-boot EFI stub is part of the usr image, so it can't be re-signed when a custom
+// Secure Boot key hierarchy is used. In that case the existing stub, which was signed as part
+// of the install media, is kept as long as its certificate remains in the db.
 func updateEFIBootStub(ctx context.Context, usrImageFile string) error {
 	efiFiles, err := getArchEFIFiles()
 	if err != nil {
@@ -529,6 +551,22 @@ func updateEFIBootStub(ctx context.Context, usrImageFile string) error {
 	}
 	defer unix.Unmount(mountDir, 0)
 
+	stubCert, err := extractCertificateFromPE(filepath.Join(mountDir, efiFiles["stub"]))
+	if err != nil {
+		return err
+	}
+
+	dbCerts, err := GetCertificatesFromVar("db")
+	if err != nil {
+		return err
+	}
+
+	if !slices.ContainsFunc(dbCerts, stubCert.Equal) {
+		slog.WarnContext(ctx, "New systemd-boot EFI stub isn't signed by a trusted certificate, keeping the existing one", "subject", stubCert.Subject.String())
+
+		return nil
+	}
+
 	_, err = subprocess.RunCommandContext(ctx, "cp", filepath.Join(mountDir, efiFiles["stub"]), efiFiles["systemdEFI"])
 	if err != nil {
 		return err
@@ -20,7 +20,7 @@ func TPMStatus() (api.TPMStatus, error) {
 		return "", err
 	}
 
-	computedPCR, err := computeNewPCR7Value(eventLog)
+	computedPCR, err := computeNewPCR7Value(eventLog, nil)
 	if err != nil {
 		return "", err
 	}
@@ -61,13 +61,8 @@ func GetSWTPMInUse() bool {
 	return true
 }
 
-// ReadTPMEventLog reads the raw TPM measurements and returns a parsed array of Events with SHA256 hashes.
-// The log entries are NOT verified by this function.
-func ReadTPMEventLog() ([]tcg.Event, error) {
-	var buf []byte
-
-	var err error
-
+// ReadRawTPMEventLog returns the raw TPM event log. When running swtpm, a synthesized event log is returned.
+func ReadRawTPMEventLog() ([]byte, error) {
 	rawLog, err := os.Open("/sys/kernel/security/tpm0/binary_bios_measurements")
 	if err != nil {
 		if !errors.Is(err, os.ErrNotExist) {
@@ -75,17 +70,20 @@ func ReadTPMEventLog() ([]tcg.Event, error) {
 		}
 
 		// Fallback to a synthesized TPM event log for swtpm.
-		buf, err = SynthesizeTPMEventLog()
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		defer rawLog.Close()
+		return SynthesizeTPMEventLog()
+	}
 
-		buf, err = io.ReadAll(rawLog)
-		if err != nil {
-			return nil, err
-		}
+	defer rawLog.Close()
+
+	return io.ReadAll(rawLog)
+}
+
+// ReadTPMEventLog reads the raw TPM measurements and returns a parsed array of Events with SHA256 hashes.
+// The log entries are NOT verified by this function.
+func ReadTPMEventLog() ([]tcg.Event, error) {
+	buf, err := ReadRawTPMEventLog()
+	if err != nil {
+		return nil, err
 	}
 
 	log, err := tcg.ParseEventLog(buf, tcg.ParseOpts{})
@@ -0,0 +1,242 @@
+package seed
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"reflect"
+	"slices"
+
+	"go.yaml.in/yaml/v4"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// ExportArgs holds the host-specific information needed to export the running configuration.
+type ExportArgs struct {
+	// Names of the installed applications.
+	Applications []string
+
+	// Install target matching the current boot drive, if known.
+	InstallTarget *apiseed.InstallTarget
+
+	// If set, used to replace MAC addresses in the network configuration with interface names.
+	// MAC addresses which can't be resolved are kept as-is.
+	InterfaceName func(mac string) (string, error)
+
+	// If set, secrets are exported as plain text rather than replaced by their placeholder.
+	IncludeSecrets bool
+}
+
+// exportFile holds a single seed file of an exported archive.
+type exportFile struct {
+	name string
+	seed any
+}
+
+// Export returns a seed tar archive reproducing the running configuration.
+func Export(ctx context.Context, s *state.State, args ExportArgs) ([]byte, error) {
+	files := []exportFile{}
+
+	// Install.
+	install := &apiseed.Install{
+		Version: "1",
+		Target:  args.InstallTarget,
+	}
+
+	if s.UsingSWTPM || s.SecureBootDisabled {
+		install.Security = &apiseed.InstallSecurity{
+			MissingTPM:        s.UsingSWTPM,
+			MissingSecureBoot: s.SecureBootDisabled,
+		}
+	}
+
+	files = append(files, exportFile{"install", install})
+
+	// Network.
+	if s.System.Network.Config != nil {
+		files = append(files, exportFile{"network", &apiseed.Network{
+			SystemNetworkConfig: generalizeNetwork(*s.System.Network.Config, args.InterfaceName),
+			Version:             "1",
+		}})
+	}
+
+	// Applications, along with the Incus preseed originally used, if any.
+	if len(args.Applications) > 0 {
+		applications := &apiseed.Applications{Version: "1"}
+
+		for _, name := range args.Applications {
+			applications.Applications = append(applications.Applications, apiseed.Application{Name: name})
+		}
+
+		files = append(files, exportFile{"applications", applications})
+	}
+
+	if slices.Contains(args.Applications, "incus") {
+		incus, err := GetIncus(ctx)
+		if err != nil && !IsMissing(err) {
+			return nil, err
+		}
+
+		if incus != nil {
+			files = append(files, exportFile{"incus", incus})
+		}
+	}
+
+	// Services.
+	services := &apiseed.Services{Version: "1"}
+	hasServices := false
+
+	if s.Services.ISCSI.Config.Enabled {
+		services.ISCSI = &s.Services.ISCSI.Config
+		hasServices = true
+	}
+
+	if s.Services.LVM.Config.Enabled {
+		services.LVM = &s.Services.LVM.Config
+		hasServices = true
+	}
+
+	if s.Services.Multipath.Config.Enabled {
+		services.Multipath = &s.Services.Multipath.Config
+		hasServices = true
+	}
+
+	if s.Services.Netbird.Config.Enabled {
+		services.Netbird = &s.Services.Netbird.Config
+		hasServices = true
+	}
+
+	if s.Services.NVME.Config.Enabled {
+		services.NVME = &s.Services.NVME.Config
+		hasServices = true
+	}
+
+	if s.Services.OVN.Config.Enabled {
+		services.OVN = &s.Services.OVN.Config
+		hasServices = true
+	}
+
+	if s.Services.Tailscale.Config.Enabled {
+		services.Tailscale = &s.Services.Tailscale.Config
+		hasServices = true
+	}
+
+	if s.Services.USBIP.Config.Enabled {
+		services.USBIP = &s.Services.USBIP.Config
+		hasServices = true
+	}
+
+	if hasServices {
+		files = append(files, exportFile{"services", services})
+	}
+
+	// Update.
+	files = append(files, exportFile{"update", &apiseed.Update{
+		SystemUpdateConfig: s.System.Update.Config,
+		Version:            "1",
+	}})
+
+	// Provider.
+	if s.System.Provider.Config.Name != "" {
+		files = append(files, exportFile{"provider", &apiseed.Provider{
+			SystemProviderConfig: s.System.Provider.Config,
+			Version:              "1",
+		}})
+	}
+
+	// Kernel.
+	if !reflect.DeepEqual(s.System.Kernel.Config, api.SystemKernelConfig{}) {
+		files = append(files, exportFile{"kernel", &apiseed.Kernel{
+			SystemKernelConfig: s.System.Kernel.Config,
+			Version:            "1",
+		}})
+	}
+
+	// Create the tar archive.
+	var buf bytes.Buffer
+
+	tw := tar.NewWriter(&buf)
+
+	for _, file := range files {
+		value := file.seed
+
+		if !args.IncludeSecrets {
+			var err error
+
+			value, err = secrets.Redact(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		content, err := yaml.Dump(value, yaml.WithV2Defaults())
+		if err != nil {
+			return nil, err
+		}
+
+		hdr := &tar.Header{
+			Name: file.name + ".yaml",
+			Mode: 0o600,
+			Size: int64(len(content)),
+		}
+
+		err = tw.WriteHeader(hdr)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tw.Write(content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := tw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// generalizeNetwork returns a copy of the network configuration, with MAC addresses replaced by
+// interface names if a resolver is provided.
+func generalizeNetwork(config api.SystemNetworkConfig, interfaceName func(mac string) (string, error)) api.SystemNetworkConfig {
+	if interfaceName == nil {
+		return config
+	}
+
+	resolve := func(mac string) string {
+		if mac == "" {
+			return mac
+		}
+
+		name, err := interfaceName(mac)
+		if err != nil {
+			return mac
+		}
+
+		return name
+	}
+
+	config.Interfaces = slices.Clone(config.Interfaces)
+	for i := range config.Interfaces {
+		config.Interfaces[i].Hwaddr = resolve(config.Interfaces[i].Hwaddr)
+	}
+
+	config.Bonds = slices.Clone(config.Bonds)
+	for i := range config.Bonds {
+		config.Bonds[i].Hwaddr = resolve(config.Bonds[i].Hwaddr)
+
+		config.Bonds[i].Members = slices.Clone(config.Bonds[i].Members)
+		for j := range config.Bonds[i].Members {
+			config.Bonds[i].Members[j] = resolve(config.Bonds[i].Members[j])
+		}
+	}
+
+	return config
+}
@@ -0,0 +1,125 @@
+package seed
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v4"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+
+	s := &state.State{}
+	s.UsingSWTPM = true
+	s.Services.LVM.Config.Enabled = true
+	s.Services.Tailscale.Config.Enabled = true
+	s.Services.Tailscale.Config.AuthKey = "tskey"
+	s.System.Kernel.Config.BlacklistModules = []string{"nouveau"}
+	s.System.Kernel.Config.CPU = &api.SystemKernelConfigCPU{ScalingGovernor: "performance"}
+	s.System.Update.Config.Channel = "testing"
+	s.System.Network.Config = &api.SystemNetworkConfig{
+		Interfaces: []api.SystemNetworkInterface{{Name: "uplink", Hwaddr: "10:66:6a:00:00:01"}},
+		Bonds:      []api.SystemNetworkBond{{Name: "bond0", Members: []string{"10:66:6a:00:00:02", "10:66:6a:00:00:03"}}},
+	}
+
+	interfaceName := func(mac string) (string, error) {
+		if mac == "10:66:6a:00:00:03" {
+			return "", errors.New("unknown interface")
+		}
+
+		return "enp" + mac[len(mac)-1:] + "s0", nil
+	}
+
+	archive, err := Export(t.Context(), s, ExportArgs{
+		Applications:  []string{"debug"},
+		InstallTarget: &apiseed.InstallTarget{Bus: "NVME"},
+		InterfaceName: interfaceName,
+	})
+	require.NoError(t, err)
+
+	files := readArchive(t, archive)
+
+	require.Len(t, files, 6)
+	require.Contains(t, files, "applications.yaml")
+	require.Contains(t, files, "services.yaml")
+	require.Contains(t, files, "update.yaml")
+
+	// The install seed targets the provided drive, keeping the degraded security state.
+	var install apiseed.Install
+
+	err = yaml.Load(files["install.yaml"], &install, yaml.WithKnownFields())
+	require.NoError(t, err)
+	require.Equal(t, "NVME", install.Target.Bus)
+	require.True(t, install.Security.MissingTPM)
+	require.False(t, install.Security.MissingSecureBoot)
+
+	// MAC addresses are replaced by interface names when known.
+	var network apiseed.Network
+
+	err = yaml.Load(files["network.yaml"], &network, yaml.WithKnownFields())
+	require.NoError(t, err)
+	require.Equal(t, "enp1s0", network.Interfaces[0].Hwaddr)
+	require.Equal(t, []string{"enp2s0", "10:66:6a:00:00:03"}, network.Bonds[0].Members)
+
+	// The whole kernel configuration is exported.
+	var kernel apiseed.Kernel
+
+	err = yaml.Load(files["kernel.yaml"], &kernel, yaml.WithKnownFields())
+	require.NoError(t, err)
+	require.Equal(t, []string{"nouveau"}, kernel.BlacklistModules)
+	require.Equal(t, "performance", kernel.CPU.ScalingGovernor)
+
+	// Secrets are redacted unless explicitly included.
+	var services apiseed.Services
+
+	err = yaml.Load(files["services.yaml"], &services, yaml.WithKnownFields())
+	require.NoError(t, err)
+	require.Equal(t, secrets.Placeholder("tskey"), services.Tailscale.AuthKey)
+
+	archive, err = Export(t.Context(), s, ExportArgs{IncludeSecrets: true})
+	require.NoError(t, err)
+
+	err = yaml.Load(readArchive(t, archive)["services.yaml"], &services, yaml.WithKnownFields())
+	require.NoError(t, err)
+	require.Equal(t, "tskey", services.Tailscale.AuthKey)
+
+	// The running configuration is left untouched.
+	require.Equal(t, "10:66:6a:00:00:01", s.System.Network.Config.Interfaces[0].Hwaddr)
+	require.Equal(t, "10:66:6a:00:00:02", s.System.Network.Config.Bonds[0].Members[0])
+	require.Equal(t, "tskey", s.Services.Tailscale.Config.AuthKey)
+}
+
+// readArchive returns the content of each file of a tar archive, keyed by name.
+func readArchive(t *testing.T, archive []byte) map[string][]byte {
+	t.Helper()
+
+	files := map[string][]byte{}
+
+	tr := tar.NewReader(bytes.NewReader(archive))
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		require.NoError(t, err)
+
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+
+		files[hdr.Name] = content
+	}
+
+	return files
+}
@@ -0,0 +1,110 @@
+package seed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+)
+
+// parseHostSection extracts a section from the host seed, which describes the whole host in a single file.
+func parseHostSection(partition string, section string, target any) error {
+	var host apiseed.Host
+
+	err := parseSeedFile(partition, "host", &host)
+	if err != nil {
+		return err
+	}
+
+	var value any
+
+	switch section {
+	case "applications":
+		if host.Applications != nil {
+			value = apiseed.Applications{Version: host.Version, Applications: host.Applications}
+		}
+
+	case "kernel":
+		if host.Kernel != nil {
+			value = host.Kernel
+		}
+
+	case "logging":
+		if host.Logging != nil {
+			value = host.Logging
+		}
+
+	case "network":
+		if host.Network != nil {
+			value = host.Network
+		}
+
+	case "services":
+		if host.Services != nil {
+			value, err = getHostServices(host.Services)
+			if err != nil {
+				return err
+			}
+		}
+
+	case "storage":
+		if host.Storage != nil {
+			value = host.Storage
+		}
+
+	case "update":
+		if host.Update != nil {
+			value = host.Update
+		}
+
+	default:
+	}
+
+	if value == nil {
+		return ErrNoSeedSection
+	}
+
+	content, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(target)
+}
+
+// getHostServices returns the services of the host seed which are supported by the services seed,
+// rejecting any unknown field in their configuration. Other services are only configured through
+// "incus admin os apply", so are skipped.
+func getHostServices(services map[string]any) (map[string]any, error) {
+	ret := map[string]any{}
+
+	for _, name := range slices.Sorted(maps.Keys(services)) {
+		content, err := json.Marshal(map[string]any{name: services[name]})
+		if err != nil {
+			return nil, err
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+
+		err = decoder.Decode(&apiseed.Services{})
+		if err != nil {
+			if jsonUnknownField.MatchString(err.Error()) && jsonUnknownField.FindStringSubmatch(err.Error())[1] == name {
+				continue
+			}
+
+			return nil, fmt.Errorf("invalid %q service configuration: %s", name, strings.TrimPrefix(err.Error(), "json: "))
+		}
+
+		ret[name] = services[name]
+	}
+
+	return ret, nil
+}
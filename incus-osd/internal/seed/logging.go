@@ -0,0 +1,19 @@
+package seed
+
+import (
+	"context"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// GetLogging extracts the logging configuration from the host seed, as there's no dedicated logging seed.
+func GetLogging(_ context.Context) (*api.SystemLoggingConfig, error) {
+	var config api.SystemLoggingConfig
+
+	err := parseHostSection(getSeedPath(), "logging", &config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
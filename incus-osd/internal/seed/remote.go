@@ -0,0 +1,524 @@
+package seed
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v4"
+	"golang.org/x/sys/unix"
+
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+// remoteSeedPath is where seed data retrieved from a remote source is stored, in the same format as the seed-data partition.
+const remoteSeedPath = "/run/incus-os/remote-seed.tar"
+
+// remoteSeedDHCPOption is the private DHCP option which can provide the URL of the seed data.
+const remoteSeedDHCPOption = 224
+
+// remoteSeedMaxSize is the maximum size of seed data retrieved from a remote source.
+const remoteSeedMaxSize = 64 * 1024 * 1024
+
+// RemoteConfig defines how seed data is retrieved from remote sources.
+type RemoteConfig struct {
+	// URL of the seed data. If empty, a URL provided through DHCP is used instead.
+	URL string
+
+	// SHA256 fingerprint of a certificate, usually a CA, which the server's certificate must be issued by.
+	// If empty, the system's trusted CAs are used.
+	Fingerprint string
+
+	// Ed25519 key used to verify the seed data against the signature found at "<URL>.sig".
+	PublicKey ed25519.PublicKey
+
+	// Base URL of the EC2-style metadata service.
+	MetadataURL string
+}
+
+// ParseRemoteConfig extracts the remote seed configuration from the kernel command line.
+func ParseRemoteConfig(cmdline string) (*RemoteConfig, error) {
+	config := &RemoteConfig{
+		MetadataURL: "http://169.254.169.254",
+	}
+
+	for field := range strings.FieldsSeq(cmdline) {
+		key, value, _ := strings.Cut(field, "=")
+
+		switch key {
+		case "incusos.seed_url":
+			u, err := url.Parse(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid seed URL %q: %w", value, err)
+			}
+
+			if u.Scheme != "https" && u.Scheme != "http" {
+				return nil, fmt.Errorf("invalid seed URL %q: must be http or https", value)
+			}
+
+			config.URL = value
+
+		case "incusos.seed_fingerprint":
+			fingerprint := strings.ToLower(strings.ReplaceAll(value, ":", ""))
+
+			decoded, err := hex.DecodeString(fingerprint)
+			if err != nil || len(decoded) != sha256.Size {
+				return nil, fmt.Errorf("invalid seed certificate fingerprint %q", value)
+			}
+
+			config.Fingerprint = fingerprint
+
+		case "incusos.seed_key":
+			der, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid seed signing key: %w", err)
+			}
+
+			key, err := x509.ParsePKIXPublicKey(der)
+			if err != nil {
+				return nil, fmt.Errorf("invalid seed signing key: %w", err)
+			}
+
+			publicKey, ok := key.(ed25519.PublicKey)
+			if !ok {
+				return nil, errors.New("invalid seed signing key: only Ed25519 keys are supported")
+			}
+
+			config.PublicKey = publicKey
+
+		default:
+		}
+	}
+
+	return config, nil
+}
+
+// FetchRemote retrieves seed data from a remote source when no local seed data is available.
+// The sources are tried in order: a NoCloud "cidata" volume, a URL from the kernel command line
+// or DHCP and finally an EC2-style metadata service.
+func FetchRemote(ctx context.Context) error {
+	// Seed data may have already been retrieved before the daemon was restarted.
+	_, err := os.Stat(remoteSeedPath)
+	if err == nil {
+		return nil
+	}
+
+	if hasLocalSeed() {
+		return nil
+	}
+
+	cmdline, err := os.ReadFile("/proc/cmdline")
+	if err != nil {
+		return err
+	}
+
+	config, err := ParseRemoteConfig(string(cmdline))
+	if err != nil {
+		return err
+	}
+
+	// Check for a NoCloud volume, which doesn't need any network access.
+	content, err := readCIData()
+	if err != nil {
+		return err
+	}
+
+	if content != nil {
+		// The user data may be meant for cloud-init rather than being seed data.
+		err := storeRemoteSeed(remoteSeedPath, content)
+		if err == nil {
+			slog.InfoContext(ctx, "Using seed data from NoCloud volume")
+
+			return nil
+		}
+
+		slog.WarnContext(ctx, "Ignoring NoCloud user data", "err", err)
+	}
+
+	// Bring up a temporary network configuration to reach the remote sources.
+	err = systemd.StartTemporaryDHCP(ctx, []int{remoteSeedDHCPOption}, 30*time.Second)
+	if err != nil {
+		if config.URL != "" {
+			return fmt.Errorf("unable to retrieve seed data from %q: %w", config.URL, err)
+		}
+
+		slog.DebugContext(ctx, "No network available to retrieve remote seed data", "err", err)
+
+		return nil
+	}
+
+	seedURL := config.URL
+	if seedURL == "" {
+		value, err := systemd.GetDHCPLeaseOption(remoteSeedDHCPOption)
+		if err != nil {
+			return err
+		}
+
+		seedURL = strings.TrimRight(string(value), "\x00")
+
+		// Anyone on the local network can answer DHCP requests, so only trust the URL if the seed data can be verified.
+		if seedURL != "" && config.Fingerprint == "" && config.PublicKey == nil {
+			slog.WarnContext(ctx, "Ignoring seed URL provided through DHCP, as neither incusos.seed_fingerprint nor incusos.seed_key is set", "url", seedURL)
+
+			seedURL = ""
+		}
+	}
+
+	if seedURL != "" {
+		slog.InfoContext(ctx, "Retrieving seed data", "url", seedURL)
+
+		content, err := fetchURL(ctx, config, seedURL)
+		if err != nil {
+			return fmt.Errorf("unable to retrieve seed data from %q: %w", seedURL, err)
+		}
+
+		return storeRemoteSeed(remoteSeedPath, content)
+	}
+
+	// Most environments don't have a metadata service, so failures aren't reported.
+	content, err = fetchMetadata(ctx, config.MetadataURL)
+	if err != nil {
+		slog.DebugContext(ctx, "No seed data available from metadata service", "err", err)
+
+		return nil
+	}
+
+	err = checkMetadataSeed(content)
+	if err == nil {
+		err = storeRemoteSeed(remoteSeedPath, content)
+	}
+
+	if err != nil {
+		slog.WarnContext(ctx, "Ignoring user data from metadata service", "err", err)
+
+		return nil
+	}
+
+	slog.InfoContext(ctx, "Using seed data from metadata service")
+
+	return nil
+}
+
+// hasLocalSeed checks whether a user-provided seed partition or seed data on the install media is present.
+func hasLocalSeed() bool {
+	if getSeedPath() != "/dev/disk/by-partlabel/seed-data" {
+		return true
+	}
+
+	f, err := os.Open("/dev/disk/by-partlabel/seed-data")
+	if err != nil {
+		return false
+	}
+
+	defer f.Close()
+
+	header := make([]byte, 263)
+
+	n, err := f.Read(header)
+	if err != nil {
+		return false
+	}
+
+	return n == 263 && bytes.Equal(header[257:262], []byte{'u', 's', 't', 'a', 'r'})
+}
+
+// readCIData returns the user data from a NoCloud "cidata" volume, if present.
+func readCIData() ([]byte, error) {
+	var partition string
+
+	for _, label := range []string{"cidata", "CIDATA"} {
+		_, err := os.Stat("/dev/disk/by-label/" + label)
+		if err == nil {
+			partition = "/dev/disk/by-label/" + label
+
+			break
+		}
+	}
+
+	if partition == "" {
+		return nil, nil
+	}
+
+	mountDir, err := os.MkdirTemp("", "incus-os-cidata")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(mountDir)
+
+	// Try to mount as iso9660, then as vfat.
+	err = unix.Mount(partition, mountDir, "iso9660", 0, "ro")
+	if err != nil {
+		err = unix.Mount(partition, mountDir, "vfat", 0, "ro")
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer unix.Unmount(mountDir, 0)
+
+	content, err := os.ReadFile(filepath.Join(mountDir, "user-data"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return content, nil
+}
+
+// fetchURL retrieves seed data from the given URL, verifying it according to the remote configuration.
+func fetchURL(ctx context.Context, config *RemoteConfig, seedURL string) ([]byte, error) {
+	u, err := url.Parse(seedURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "https" && config.PublicKey == nil {
+		return nil, errors.New("seed data retrieved over plain HTTP must be signed")
+	}
+
+	// Any server able to present a certificate trusted by the system could otherwise provide seed data.
+	if config.Fingerprint == "" && config.PublicKey == nil {
+		return nil, errors.New("seed data must either be signed or served with a pinned certificate")
+	}
+
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("unexpected default HTTP transport")
+	}
+
+	transport = transport.Clone()
+
+	if config.Fingerprint != "" {
+		transport.TLSClientConfig = &tls.Config{
+			// The server certificate is verified against the pinned certificate instead.
+			InsecureSkipVerify: true, //nolint:gosec
+			VerifyConnection:   verifyPinnedCertificate(config.Fingerprint, u.Hostname()),
+		}
+	}
+
+	client := &http.Client{Transport: transport, Timeout: 60 * time.Second}
+
+	content, err := httpGet(ctx, client, seedURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.PublicKey != nil {
+		signature, err := httpGet(ctx, client, seedURL+".sig", nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve seed signature: %w", err)
+		}
+
+		// Accept both raw and base64-encoded signatures.
+		if len(signature) != ed25519.SignatureSize {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+			if err == nil {
+				signature = decoded
+			}
+		}
+
+		if !ed25519.Verify(config.PublicKey, content, signature) {
+			return nil, errors.New("invalid seed signature")
+		}
+	}
+
+	return content, nil
+}
+
+// verifyPinnedCertificate returns a function checking that the server's certificate chain is issued by the pinned certificate.
+func verifyPinnedCertificate(fingerprint string, hostname string) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("no server certificate provided")
+		}
+
+		roots := x509.NewCertPool()
+		intermediates := x509.NewCertPool()
+		found := false
+
+		for _, cert := range cs.PeerCertificates {
+			sum := sha256.Sum256(cert.Raw)
+			if hex.EncodeToString(sum[:]) == fingerprint {
+				roots.AddCert(cert)
+
+				found = true
+			} else {
+				intermediates.AddCert(cert)
+			}
+		}
+
+		if !found {
+			return errors.New("server certificate chain doesn't include the pinned certificate")
+		}
+
+		_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+			DNSName:       hostname,
+			Roots:         roots,
+			Intermediates: intermediates,
+		})
+
+		return err
+	}
+}
+
+// fetchMetadata retrieves the user data from an EC2-style metadata service, preferring IMDSv2 sessions.
+func fetchMetadata(ctx context.Context, baseURL string) ([]byte, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	headers := map[string]string{}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, baseURL+"/latest/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "300")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		token, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if err != nil {
+			return nil, err
+		}
+
+		headers["X-aws-ec2-metadata-token"] = string(token)
+	}
+
+	return httpGet(ctx, client, baseURL+"/latest/user-data", headers)
+}
+
+// httpGet retrieves the content at the given URL.
+func httpGet(ctx context.Context, client *http.Client, target string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response from server: %s", resp.Status)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, remoteSeedMaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(content) > remoteSeedMaxSize {
+		return nil, errors.New("seed data is too large")
+	}
+
+	return content, nil
+}
+
+// checkMetadataSeed ensures that seed data from a metadata service, which can't be authenticated,
+// is a host description without the sections able to expose the system or its data to a third party.
+// Complete seed data must be provided through an authenticated URL instead.
+func checkMetadataSeed(content []byte) error {
+	if isSeedArchive(content) {
+		return errors.New("seed archives aren't accepted from a metadata service")
+	}
+
+	var host apiseed.Host
+
+	err := yaml.Load(content, &host, yaml.WithKnownFields())
+	if err != nil {
+		return fmt.Errorf("seed data isn't a host description: %w", err)
+	}
+
+	if host.Logging != nil {
+		return errors.New("the logging section isn't accepted from a metadata service")
+	}
+
+	if host.Services != nil {
+		return errors.New("the services section isn't accepted from a metadata service")
+	}
+
+	return nil
+}
+
+// isSeedArchive returns whether the content is a tar archive.
+func isSeedArchive(content []byte) bool {
+	return len(content) >= 263 && bytes.Equal(content[257:262], []byte{'u', 's', 't', 'a', 'r'})
+}
+
+// storeRemoteSeed writes retrieved seed data to the given path as a seed archive. The seed data
+// can either be a seed archive or a single host description, such as cloud-init user data.
+func storeRemoteSeed(path string, content []byte) error {
+	if !isSeedArchive(content) {
+		var host apiseed.Host
+
+		err := yaml.Load(content, &host, yaml.WithKnownFields())
+		if err != nil {
+			return fmt.Errorf("seed data is neither a seed archive nor a host description: %w", err)
+		}
+
+		// JSON is also valid YAML, so keep the original content.
+		var buf bytes.Buffer
+
+		tw := tar.NewWriter(&buf)
+
+		err = tw.WriteHeader(&tar.Header{
+			Name: "host.yaml",
+			Mode: 0o600,
+			Size: int64(len(content)),
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = tw.Write(content)
+		if err != nil {
+			return err
+		}
+
+		err = tw.Close()
+		if err != nil {
+			return err
+		}
+
+		content = buf.Bytes()
+	}
+
+	err := os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, content, 0o600)
+}
@@ -0,0 +1,214 @@
+package seed
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+)
+
+func TestParseRemoteConfig(t *testing.T) {
+	t.Parallel()
+
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+
+	fingerprint := sha256.Sum256([]byte("test"))
+
+	config, err := ParseRemoteConfig("rw console=ttyS0 incusos.seed_url=https://example.com/seed.tar incusos.seed_fingerprint=" + hex.EncodeToString(fingerprint[:]) + " incusos.seed_key=" + base64.StdEncoding.EncodeToString(der))
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/seed.tar", config.URL)
+	require.Equal(t, hex.EncodeToString(fingerprint[:]), config.Fingerprint)
+	require.Equal(t, publicKey, config.PublicKey)
+
+	// Nothing configured.
+	config, err = ParseRemoteConfig("rw")
+	require.NoError(t, err)
+	require.Empty(t, config.URL)
+	require.Equal(t, "http://169.254.169.254", config.MetadataURL)
+
+	// Invalid values.
+	_, err = ParseRemoteConfig("incusos.seed_url=ftp://example.com/seed.tar")
+	require.Error(t, err)
+
+	_, err = ParseRemoteConfig("incusos.seed_fingerprint=abcd")
+	require.Error(t, err)
+
+	_, err = ParseRemoteConfig("incusos.seed_key=abcd")
+	require.Error(t, err)
+}
+
+func TestFetchURL(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("version: \"1\"\n")
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	signature := ed25519.Sign(privateKey, content)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/seed.yaml", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(content)
+	})
+
+	mux.HandleFunc("/seed.yaml.sig", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(base64.StdEncoding.EncodeToString(signature)))
+	})
+
+	// HTTPS with a pinned certificate.
+	tlsServer := httptest.NewTLSServer(mux)
+	defer tlsServer.Close()
+
+	sum := sha256.Sum256(tlsServer.Certificate().Raw)
+
+	data, err := fetchURL(t.Context(), &RemoteConfig{Fingerprint: hex.EncodeToString(sum[:])}, tlsServer.URL+"/seed.yaml")
+	require.NoError(t, err)
+	require.Equal(t, content, data)
+
+	wrongSum := sha256.Sum256([]byte("wrong"))
+
+	_, err = fetchURL(t.Context(), &RemoteConfig{Fingerprint: hex.EncodeToString(wrongSum[:])}, tlsServer.URL+"/seed.yaml")
+	require.Error(t, err)
+
+	// HTTPS requires a pinned certificate or a signature.
+	_, err = fetchURL(t.Context(), &RemoteConfig{}, tlsServer.URL+"/seed.yaml")
+	require.ErrorContains(t, err, "must either be signed or served with a pinned certificate")
+
+	// The test certificate isn't trusted by the system.
+	_, err = fetchURL(t.Context(), &RemoteConfig{PublicKey: publicKey}, tlsServer.URL+"/seed.yaml")
+	require.Error(t, err)
+
+	// Plain HTTP requires a signature.
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	_, err = fetchURL(t.Context(), &RemoteConfig{}, server.URL+"/seed.yaml")
+	require.Error(t, err)
+
+	data, err = fetchURL(t.Context(), &RemoteConfig{PublicKey: publicKey}, server.URL+"/seed.yaml")
+	require.NoError(t, err)
+	require.Equal(t, content, data)
+
+	otherKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	_, err = fetchURL(t.Context(), &RemoteConfig{PublicKey: otherKey}, server.URL+"/seed.yaml")
+	require.Error(t, err)
+
+	_, err = fetchURL(t.Context(), &RemoteConfig{PublicKey: publicKey}, server.URL+"/missing.yaml")
+	require.Error(t, err)
+}
+
+func TestFetchMetadata(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /latest/api/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		_, _ = w.Write([]byte("token"))
+	})
+
+	mux.HandleFunc("GET /latest/user-data", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-aws-ec2-metadata-token") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		_, _ = w.Write([]byte("#cloud-config\nversion: \"1\"\n"))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	data, err := fetchMetadata(t.Context(), server.URL)
+	require.NoError(t, err)
+	require.Equal(t, "#cloud-config\nversion: \"1\"\n", string(data))
+
+	// No user data.
+	emptyServer := httptest.NewServer(http.NotFoundHandler())
+	defer emptyServer.Close()
+
+	_, err = fetchMetadata(t.Context(), emptyServer.URL)
+	require.Error(t, err)
+}
+
+func TestStoreRemoteSeed(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	// A host description is stored as the host seed.
+	path := filepath.Join(dir, "host.tar")
+
+	err := storeRemoteSeed(path, []byte("#cloud-config\nversion: \"1\"\nupdate:\n  channel: testing\n"))
+	require.NoError(t, err)
+
+	var update apiseed.Update
+
+	err = parseFileContents(path, "update", &update)
+	require.NoError(t, err)
+	require.Equal(t, "testing", update.Channel)
+
+	// A seed archive is stored as-is.
+	archive, err := os.ReadFile("testdata.tar")
+	require.NoError(t, err)
+
+	path = filepath.Join(dir, "seed.tar")
+
+	err = storeRemoteSeed(path, archive)
+	require.NoError(t, err)
+
+	var applications apiseed.Applications
+
+	err = parseFileContents(path, "applications", &applications)
+	require.NoError(t, err)
+	require.Len(t, applications.Applications, 2)
+
+	// Other data, such as cloud-init configuration, is rejected.
+	err = storeRemoteSeed(filepath.Join(dir, "other.tar"), []byte("#cloud-config\nusers:\n  - name: foo\n"))
+	require.Error(t, err)
+}
+
+func TestCheckMetadataSeed(t *testing.T) {
+	t.Parallel()
+
+	// Host descriptions are accepted.
+	err := checkMetadataSeed([]byte("#cloud-config\nversion: \"1\"\nupdate:\n  channel: testing\n"))
+	require.NoError(t, err)
+
+	// Seed archives are rejected, as they may hold any seed.
+	archive, err := os.ReadFile("testdata.tar")
+	require.NoError(t, err)
+
+	err = checkMetadataSeed(archive)
+	require.EqualError(t, err, "seed archives aren't accepted from a metadata service")
+
+	// Sections able to expose the system are rejected.
+	err = checkMetadataSeed([]byte("version: \"1\"\nservices:\n  tailscale:\n    enabled: true\n"))
+	require.EqualError(t, err, "the services section isn't accepted from a metadata service")
+
+	err = checkMetadataSeed([]byte("version: \"1\"\nlogging:\n  syslog:\n    address: 192.0.2.1\n"))
+	require.EqualError(t, err, "the logging section isn't accepted from a metadata service")
+}
@@ -0,0 +1,19 @@
+package seed
+
+import (
+	"context"
+
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+)
+
+// GetReset extracts the configuration preserved by a factory reset from the seed data.
+func GetReset(_ context.Context) (*apiseed.Reset, error) {
+	var config apiseed.Reset
+
+	err := parseFileContents(getSeedPath(), "reset", &config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,102 @@
+package seed
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema returns a JSON Schema describing the given seed, for use by editors.
+func Schema(name string) (map[string]any, error) {
+	newSeed, ok := seedTypes[name]
+	if !ok {
+		return nil, errors.New("unknown seed '" + name + "'")
+	}
+
+	schema := typeSchema(reflect.TypeOf(newSeed()), nil)
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "IncusOS " + name + " seed"
+
+	return schema, nil
+}
+
+// typeSchema converts a Go type into a JSON Schema, following the encoding/json rules.
+func typeSchema(t reflect.Type, parents []reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == reflect.TypeFor[time.Time]() {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() { //nolint:exhaustive
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+
+	case reflect.String:
+		return map[string]any{"type": "string"}
+
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem(), parents)}
+
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem(), parents)}
+
+	case reflect.Struct:
+		// Don't descend into recursive types.
+		for _, parent := range parents {
+			if parent == t {
+				return map[string]any{"type": "object"}
+			}
+		}
+
+		properties := map[string]any{}
+		structProperties(t, append(parents, t), properties)
+
+		return map[string]any{"type": "object", "properties": properties, "additionalProperties": false}
+
+	default:
+		return map[string]any{}
+	}
+}
+
+// structProperties adds the properties of a struct, including those of embedded structs.
+func structProperties(t reflect.Type, parents []reflect.Type, properties map[string]any) {
+	for field := range t.Fields() {
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+
+			if fieldType.Kind() == reflect.Struct {
+				structProperties(fieldType, parents, properties)
+
+				continue
+			}
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = typeSchema(field.Type, parents)
+	}
+}
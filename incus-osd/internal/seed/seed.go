@@ -17,6 +17,9 @@ import (
 	"golang.org/x/sys/unix"
 )
 
+// CheckReportFile is the name of the hardware compatibility report saved alongside the seed data.
+const CheckReportFile = "hardware-report.json"
+
 // IsMissing checks whether the provided error is an expected error for missing seed data.
 func IsMissing(e error) bool {
 	for _, entry := range []error{ErrNoSeedPartition, ErrNoSeedData, ErrNoSeedSection} {
@@ -39,26 +42,33 @@ func CleanupPostInstall(ctx context.Context, targetSeedPartition string) error {
 		}
 	}
 
-	// If external user-provided seeds are present, copy them to the target seed partition.
+	// If external user-provided or remote seeds are present, copy them to the target seed partition.
 	externalSeedPartition := getSeedPath()
 	if externalSeedPartition != "/dev/disk/by-partlabel/seed-data" { //nolint:nestif
-		// Mount the seed partition.
 		mountDir, err := os.MkdirTemp("", "incus-os-seed")
 		if err != nil {
 			return err
 		}
 		defer os.RemoveAll(mountDir)
 
-		// Try to mount as vfat.
-		err = unix.Mount(externalSeedPartition, mountDir, "vfat", 0, "ro")
-		if err != nil {
-			// Try to mount as iso9660.
-			err = unix.Mount(externalSeedPartition, mountDir, "iso9660", 0, "ro")
+		if externalSeedPartition == remoteSeedPath {
+			// Extract the remote seed archive.
+			_, err = subprocess.RunCommandContext(ctx, "tar", "-xf", remoteSeedPath, "-C", mountDir)
 			if err != nil {
 				return err
 			}
+		} else {
+			// Try to mount as vfat.
+			err = unix.Mount(externalSeedPartition, mountDir, "vfat", 0, "ro")
+			if err != nil {
+				// Try to mount as iso9660.
+				err = unix.Mount(externalSeedPartition, mountDir, "iso9660", 0, "ro")
+				if err != nil {
+					return err
+				}
+			}
+			defer unix.Unmount(mountDir, 0)
 		}
-		defer unix.Unmount(mountDir, 0)
 
 		files, err := os.ReadDir(mountDir)
 		if err != nil {
@@ -76,7 +86,7 @@ func CleanupPostInstall(ctx context.Context, targetSeedPartition string) error {
 				continue
 			}
 
-			if seedName == "install" {
+			if seedName == "install" || file.Name() == CheckReportFile {
 				continue
 			}
 
@@ -99,9 +109,131 @@ func CleanupPostInstall(ctx context.Context, targetSeedPartition string) error {
 	return nil
 }
 
+// SaveFile stores a file alongside the seed data, in the user-provided seed partition if any, otherwise in the
+// seed partition of the install media, and returns the partition it was saved to.
+func SaveFile(ctx context.Context, filename string, content []byte) (string, error) {
+	partition := getSeedPath()
+	if partition == remoteSeedPath {
+		// Remote seed data is only held in memory, so fallback to the install media.
+		partition = "/dev/disk/by-partlabel/seed-data"
+	}
+
+	tmpDir, err := os.MkdirTemp("", "incus-os-seed")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if partition != "/dev/disk/by-partlabel/seed-data" {
+		// User-provided seed partitions are mounted read-write, which only works for vfat.
+		err = unix.Mount(partition, tmpDir, "vfat", 0, "")
+		if err != nil {
+			return "", fmt.Errorf("unable to mount seed partition %q read-write: %w", partition, err)
+		}
+		defer unix.Unmount(tmpDir, 0)
+
+		err = os.WriteFile(filepath.Join(tmpDir, filename), content, 0o644)
+		if err != nil {
+			return "", err
+		}
+
+		unix.Sync()
+
+		return partition, nil
+	}
+
+	// The install media's seed partition is a raw tarball, so replace any existing copy of the file.
+	err = os.WriteFile(filepath.Join(tmpDir, filename), content, 0o644)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = subprocess.RunCommandContext(ctx, "tar", "-f", partition, "--delete", filename)
+	if err != nil && !strings.Contains(err.Error(), fmt.Sprintf("tar: %s: Not found in archive", filename)) {
+		return "", err
+	}
+
+	_, err = subprocess.RunCommandContext(ctx, "tar", "-f", partition, "-C", tmpDir, "--append", "--add-file", filename)
+	if err != nil {
+		return "", err
+	}
+
+	unix.Sync()
+
+	return partition, nil
+}
+
+// Remove deletes a seed from the seed partition of the installed system. As deleting a file from the tarball
+// leaves stale data past its new end, that space is then zeroed so the seed's content can't be recovered.
+func Remove(ctx context.Context, name string) error {
+	for _, filename := range []string{name + ".json", name + ".yaml", name + ".yml"} {
+		_, err := subprocess.RunCommandContext(ctx, "tar", "-f", "/dev/disk/by-partlabel/seed-data", "--delete", filename)
+		if err != nil && !strings.Contains(err.Error(), fmt.Sprintf("tar: %s: Not found in archive", filename)) {
+			return err
+		}
+	}
+
+	return zeroArchiveTail("/dev/disk/by-partlabel/seed-data")
+}
+
+// countingReader counts the bytes read from the underlying reader.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+
+	return n, err
+}
+
+// zeroArchiveTail zeroes everything past the end of the tarball held in the given file or partition.
+func zeroArchiveTail(path string) error {
+	// #nosec G304
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Read through the tarball to find its end, including the end-of-archive marker.
+	cr := &countingReader{r: f}
+	tr := tar.NewReader(cr)
+
+	for {
+		_, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return err
+		}
+	}
+
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	zeroes := make([]byte, 1024*1024)
+
+	for offset := cr.n; offset < size; offset += int64(len(zeroes)) {
+		_, err := f.WriteAt(zeroes[:min(int64(len(zeroes)), size-offset)], offset)
+		if err != nil {
+			return err
+		}
+	}
+
+	return f.Sync()
+}
+
 // getSeedPath defines the path to the expected seed configuration. It will first search for any
-// disk with a "SEED_DATA" label, which would be externally provided by the user. If not found,
-// defaults to the "seed-data" partition that exists on install media.
+// disk with a "SEED_DATA" label, which would be externally provided by the user, then for seed
+// data retrieved from a remote source. If not found, defaults to the "seed-data" partition that
+// exists on install media.
 func getSeedPath() string {
 	_, err := os.Stat("/dev/disk/by-partlabel/SEED_DATA")
 	if err == nil {
@@ -113,11 +245,32 @@ func getSeedPath() string {
 		return "/dev/disk/by-label/SEED_DATA"
 	}
 
+	_, err = os.Stat(remoteSeedPath)
+	if err == nil {
+		return remoteSeedPath
+	}
+
 	return "/dev/disk/by-partlabel/seed-data"
 }
 
 // parseFileContents searches for a given file in the seed configuration and returns its contents as a byte array if found.
+// If the file is missing, the matching section of the host seed is used instead, if any.
 func parseFileContents(partition string, filename string, target any) error {
+	err := parseSeedFile(partition, filename, target)
+	if err == nil || !IsMissing(err) || filename == "host" {
+		return err
+	}
+
+	hostErr := parseHostSection(partition, filename, target)
+	if hostErr != nil && IsMissing(hostErr) {
+		return err
+	}
+
+	return hostErr
+}
+
+// parseSeedFile searches for a given file in the seed configuration and returns its contents as a byte array if found.
+func parseSeedFile(partition string, filename string, target any) error {
 	// First, try to get seed data by mounting a user-provided seed.
 	err := parseFileContentsFromUserPartition(partition, filename, target)
 	if err == nil {
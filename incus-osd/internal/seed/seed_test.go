@@ -1,6 +1,10 @@
 package seed
 
 import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -52,3 +56,95 @@ func TestBadYAMLFields(t *testing.T) {
 
 	require.Error(t, err, "line 3: field disable_everything not found in type seed.InstallSecurity")
 }
+
+func TestHostSeed(t *testing.T) {
+	t.Parallel()
+
+	// Sections without a dedicated seed file are taken from the host seed.
+	var services apiseed.Services
+
+	err := parseFileContents("testdata.tar", "services", &services)
+
+	require.NoError(t, err)
+	require.NotNil(t, services.LVM)
+	require.True(t, services.LVM.Enabled)
+
+	var update apiseed.Update
+
+	err = parseFileContents("testdata.tar", "update", &update)
+
+	require.NoError(t, err)
+	require.Equal(t, "testing", update.Channel)
+	require.Equal(t, "12h", update.CheckFrequency)
+
+	// Dedicated seed files take precedence over the host seed.
+	var apps apiseed.Applications
+
+	err = parseFileContents("testdata.tar", "applications", &apps)
+
+	require.NoError(t, err)
+	require.Equal(t, "foo", apps.Applications[0].Name)
+
+	// Sections missing from the host seed are still reported as missing.
+	var provider apiseed.Provider
+
+	err = parseFileContents("testdata.tar", "provider", &provider)
+
+	require.ErrorIs(t, err, ErrNoSeedSection)
+}
+
+func TestHostServices(t *testing.T) {
+	t.Parallel()
+
+	// Services only configured through "incus admin os apply" are skipped.
+	services, err := getHostServices(map[string]any{
+		"lvm":  map[string]any{"enabled": true},
+		"ceph": map[string]any{"enabled": true},
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"lvm": map[string]any{"enabled": true}}, services)
+
+	// Unknown fields of supported services are rejected.
+	_, err = getHostServices(map[string]any{
+		"lvm": map[string]any{"enable": true},
+	})
+
+	require.EqualError(t, err, `invalid "lvm" service configuration: unknown field "enable"`)
+}
+
+func TestZeroArchiveTail(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	tw := tar.NewWriter(&buf)
+
+	err := tw.WriteHeader(&tar.Header{Name: "network.json", Mode: 0o600, Size: 2})
+	require.NoError(t, err)
+
+	_, err = tw.Write([]byte("{}"))
+	require.NoError(t, err)
+
+	err = tw.Close()
+	require.NoError(t, err)
+
+	end := buf.Len()
+
+	// Simulate the stale data left past the end of the tarball after deleting a file.
+	buf.Write(bytes.Repeat([]byte("secret"), 1000))
+
+	path := filepath.Join(t.TempDir(), "seed-data")
+
+	err = os.WriteFile(path, buf.Bytes(), 0o600)
+	require.NoError(t, err)
+
+	err = zeroArchiveTail(path)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Len(t, content, buf.Len())
+	require.Equal(t, buf.Bytes()[:end], content[:end])
+	require.Equal(t, make([]byte, len(content)-end), content[end:])
+}
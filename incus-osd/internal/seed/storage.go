@@ -0,0 +1,19 @@
+package seed
+
+import (
+	"context"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// GetStorage extracts the storage configuration from the host seed, as there's no dedicated storage seed.
+func GetStorage(_ context.Context) (*api.SystemStorageConfig, error) {
+	var config api.SystemStorageConfig
+
+	err := parseHostSection(getSeedPath(), "storage", &config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
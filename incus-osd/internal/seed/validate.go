@@ -0,0 +1,682 @@
+package seed
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/incus/v7/shared/units"
+	"go.yaml.in/yaml/v4"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
+	"github.com/lxc/incus-os/incus-osd/internal/storage"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+// ValidationIssue describes a single problem found while validating seed data.
+type ValidationIssue struct {
+	File    string
+	Line    int // Line within the file, or zero if not known.
+	Message string
+	Warning bool // Warnings don't prevent the seed from being used, but likely indicate a mistake.
+}
+
+// String returns the issue formatted as "file:line: message".
+func (i ValidationIssue) String() string {
+	location := i.File
+	if i.Line > 0 {
+		location += ":" + strconv.Itoa(i.Line)
+	}
+
+	level := "error"
+	if i.Warning {
+		level = "warning"
+	}
+
+	return location + ": " + level + ": " + i.Message
+}
+
+// ValidateArgs holds the information needed to validate seed data.
+type ValidateArgs struct {
+	// Names of the supported applications. If empty, application names aren't checked.
+	Applications []string
+}
+
+// seedTypes maps the name of each known seed to its structure.
+var seedTypes = map[string]func() any{
+	"applications":      func() any { return &apiseed.Applications{} },
+	"host":              func() any { return &apiseed.Host{} },
+	"incus":             func() any { return &apiseed.Incus{} },
+	"install":           func() any { return &apiseed.Install{} },
+	"kernel":            func() any { return &apiseed.Kernel{} },
+	"migration-manager": func() any { return &apiseed.MigrationManager{} },
+	"network":           func() any { return &apiseed.Network{} },
+	"operations-center": func() any { return &apiseed.OperationsCenter{} },
+	"provider":          func() any { return &apiseed.Provider{} },
+	"reset":             func() any { return &apiseed.Reset{} },
+	"security":          func() any { return &apiseed.Security{} },
+	"services":          func() any { return &apiseed.Services{} },
+	"update":            func() any { return &apiseed.Update{} },
+}
+
+// hostSections lists the sections of the host seed which are used in place of a missing dedicated seed.
+var hostSections = []string{"applications", "kernel", "network", "services", "update"}
+
+// ValidatePath validates the seed data at the given path, which can be a directory, a tar archive or a single seed file.
+func ValidatePath(path string, args ValidateArgs) ([]ValidationIssue, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{}
+
+	if info.IsDir() { //nolint:nestif
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() {
+				continue
+			}
+
+			content, err := os.ReadFile(filepath.Join(path, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+
+			files[entry.Name()] = content
+		}
+	} else {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(content) < 263 || !bytes.Equal(content[257:262], []byte{'u', 's', 't', 'a', 'r'}) {
+			return ValidateFile(filepath.Base(path), content, args), nil
+		}
+
+		tr := tar.NewReader(bytes.NewReader(content))
+		for {
+			hdr, err := tr.Next()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+
+				return nil, err
+			}
+
+			if hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+
+			fileContent, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+
+			// Some invocations of tar prefix files with "./", which is ignored when reading the seed.
+			files[strings.TrimPrefix(hdr.Name, "./")] = fileContent
+		}
+	}
+
+	issues := []ValidationIssue{}
+	seeds := map[string][]string{}
+
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		issues = append(issues, ValidateFile(name, files[name], args)...)
+
+		seedName, ok := seedFileName(name)
+		if ok {
+			seeds[seedName] = append(seeds[seedName], name)
+		}
+	}
+
+	// Only one file is used for each seed.
+	for _, seedName := range slices.Sorted(maps.Keys(seeds)) {
+		if len(seeds[seedName]) > 1 {
+			issues = append(issues, ValidationIssue{
+				File:    seeds[seedName][0],
+				Message: fmt.Sprintf("multiple files provide the %q seed (%s), only one of them will be used", seedName, strings.Join(seeds[seedName], ", ")),
+				Warning: true,
+			})
+		}
+	}
+
+	// Sections of the host seed are ignored when a dedicated seed is present.
+	for _, hostFile := range seeds["host"] {
+		var root yaml.Node
+
+		err := yaml.Load(files[hostFile], &root)
+		if err != nil {
+			continue
+		}
+
+		for _, section := range hostSections {
+			line := nodeLine(&root, section)
+			if line == 0 || len(seeds[section]) == 0 {
+				continue
+			}
+
+			issues = append(issues, ValidationIssue{
+				File:    hostFile,
+				Line:    line,
+				Message: fmt.Sprintf("the %q section is ignored at seed time, as %s is present", section, seeds[section][0]),
+				Warning: true,
+			})
+		}
+	}
+
+	return issues, nil
+}
+
+// ValidateFile validates the content of a single seed file, named as it would be within the seed.
+func ValidateFile(filename string, content []byte, args ValidateArgs) []ValidationIssue {
+	v := &validator{file: filename, args: args}
+
+	seedName, ok := seedFileName(filename)
+	if !ok {
+		v.warnf(nil, "not a JSON or YAML file, ignored")
+
+		return v.issues
+	}
+
+	newSeed, ok := seedTypes[seedName]
+	if !ok {
+		v.warnf(nil, "unknown seed %q, ignored", seedName)
+
+		return v.issues
+	}
+
+	// An empty install seed is valid and triggers an install with default settings.
+	if len(bytes.TrimSpace(content)) == 0 {
+		if seedName != "install" {
+			v.errorf(nil, "empty seed file")
+		}
+
+		return v.issues
+	}
+
+	// Decode the seed the same way it's done at runtime.
+	config := newSeed()
+
+	if strings.HasSuffix(filename, ".json") {
+		v.decodeJSON(content, config)
+	} else {
+		v.decodeYAML(content, config)
+	}
+
+	if len(v.issues) > 0 {
+		return v.issues
+	}
+
+	// JSON being valid YAML, the node tree is used for both to locate fields.
+	err := yaml.Load(content, &v.root)
+	if err != nil {
+		v.root = yaml.Node{}
+	}
+
+	// The version isn't currently checked at runtime, so only warn about it.
+	version := nodeValue(&v.root, "version")
+	if version == nil {
+		v.warnf(nil, "missing version, expected \"1\"")
+	} else if version.Value != "1" {
+		v.warnf([]any{"version"}, "unsupported version %q, expected \"1\"", version.Value)
+	}
+
+	// Secrets redacted when exporting the configuration would otherwise be used as-is.
+	v.checkPlaceholders(&v.root)
+
+	switch seed := config.(type) {
+	case *apiseed.Applications:
+		v.checkApplications(seed.Applications)
+
+	case *apiseed.Host:
+		v.checkHost(seed)
+
+	case *apiseed.Install:
+		v.checkInstall(seed)
+
+	case *apiseed.Network:
+		v.checkNetwork(seed.SystemNetworkConfig, nil)
+
+	case *apiseed.Security:
+		if seed.NetworkUnlock != nil {
+			err := seed.NetworkUnlock.Validate()
+			if err != nil {
+				v.errorf([]any{"network_unlock"}, "%s", err.Error())
+			}
+		}
+
+	case *apiseed.Services:
+		v.checkServices(seed, nil)
+
+	case *apiseed.Update:
+		v.checkUpdate(seed.SystemUpdateConfig, nil)
+
+	default:
+	}
+
+	return v.issues
+}
+
+// seedFileName returns the seed name of a file, and whether it has a supported extension.
+func seedFileName(filename string) (string, bool) {
+	for _, ext := range []string{".json", ".yaml", ".yml"} {
+		name, found := strings.CutSuffix(filename, ext)
+		if found {
+			return name, true
+		}
+	}
+
+	return filename, false
+}
+
+// nodeLine returns the line of the element at the given path, made of mapping keys and sequence indexes.
+// If the element doesn't exist, the line of its closest existing parent is returned.
+func nodeLine(node *yaml.Node, path ...any) int {
+	line, _ := walkNode(node, path)
+
+	return line
+}
+
+// nodeValue returns the element at the given path, or nil if it doesn't exist.
+func nodeValue(node *yaml.Node, path ...any) *yaml.Node {
+	_, value := walkNode(node, path)
+
+	return value
+}
+
+// walkNode follows the given path, returning the line of the last element found and the element itself if found.
+func walkNode(node *yaml.Node, path []any) (int, *yaml.Node) {
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return 0, nil
+		}
+
+		node = node.Content[0]
+	}
+
+	line := 0
+
+	for _, element := range path {
+		var next *yaml.Node
+
+		switch key := element.(type) {
+		case string:
+			if node.Kind != yaml.MappingNode {
+				return line, nil
+			}
+
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == key {
+					line = node.Content[i].Line
+					next = node.Content[i+1]
+
+					break
+				}
+			}
+
+		case int:
+			if node.Kind != yaml.SequenceNode || key >= len(node.Content) {
+				return line, nil
+			}
+
+			next = node.Content[key]
+			line = next.Line
+
+		default:
+		}
+
+		if next == nil {
+			return line, nil
+		}
+
+		node = next
+	}
+
+	return line, node
+}
+
+// validator accumulates the issues found in a single seed file.
+type validator struct {
+	file   string
+	args   ValidateArgs
+	root   yaml.Node
+	issues []ValidationIssue
+}
+
+func (v *validator) add(line int, warning bool, format string, args ...any) {
+	v.issues = append(v.issues, ValidationIssue{
+		File:    v.file,
+		Line:    line,
+		Message: fmt.Sprintf(format, args...),
+		Warning: warning,
+	})
+}
+
+func (v *validator) errorf(path []any, format string, args ...any) {
+	v.add(nodeLine(&v.root, path...), false, format, args...)
+}
+
+func (v *validator) warnf(path []any, format string, args ...any) {
+	v.add(nodeLine(&v.root, path...), true, format, args...)
+}
+
+var jsonUnknownField = regexp.MustCompile(`^json: unknown field "(.*)"$`)
+
+func (v *validator) decodeJSON(content []byte, target any) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(target)
+	if err == nil {
+		return
+	}
+
+	var syntaxErr *json.SyntaxError
+
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		v.add(offsetLine(content, syntaxErr.Offset), false, "%s", syntaxErr.Error())
+
+	case errors.As(err, &typeErr):
+		v.add(offsetLine(content, typeErr.Offset), false, "invalid value for %q: expected %s, got %s", typeErr.Field, typeErr.Type.String(), typeErr.Value)
+
+	case jsonUnknownField.MatchString(err.Error()):
+		// The decoder doesn't report where unknown fields are, so look for the first key with that name.
+		field := jsonUnknownField.FindStringSubmatch(err.Error())[1]
+
+		var root yaml.Node
+
+		_ = yaml.Load(content, &root)
+
+		v.add(findKeyLine(&root, field), false, "unknown field %q", field)
+
+	default:
+		v.add(0, false, "%s", err.Error())
+	}
+}
+
+func (v *validator) decodeYAML(content []byte, target any) {
+	err := yaml.Load(content, target, yaml.WithKnownFields())
+	if err == nil {
+		return
+	}
+
+	var loadErrs *yaml.LoadErrors
+
+	var loadErr *yaml.LoadError
+
+	switch {
+	case errors.As(err, &loadErrs):
+		for _, entry := range loadErrs.Errors {
+			v.add(entry.Mark.Line, false, "%s", entry.Message)
+		}
+
+	case errors.As(err, &loadErr):
+		v.add(loadErr.Mark.Line, false, "%s", loadErr.Message)
+
+	default:
+		v.add(0, false, "%s", err.Error())
+	}
+}
+
+// offsetLine converts a byte offset into a line number.
+func offsetLine(content []byte, offset int64) int {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+
+	return bytes.Count(content[:offset], []byte{'\n'}) + 1
+}
+
+// findKeyLine returns the line of the first mapping key with the given name.
+func findKeyLine(node *yaml.Node, key string) int {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				return node.Content[i].Line
+			}
+		}
+	}
+
+	for _, child := range node.Content {
+		line := findKeyLine(child, key)
+		if line > 0 {
+			return line
+		}
+	}
+
+	return 0
+}
+
+func (v *validator) checkPlaceholders(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && secrets.IsPlaceholder(node.Value) {
+		v.add(node.Line, false, "redacted secret %q, must be replaced by its actual value", node.Value)
+	}
+
+	for _, child := range node.Content {
+		v.checkPlaceholders(child)
+	}
+}
+
+func (v *validator) checkApplications(apps []apiseed.Application) {
+	seen := []string{}
+
+	for i, app := range apps {
+		path := []any{"applications", i, "name"}
+
+		if app.Name == "" {
+			v.errorf(path, "missing application name")
+
+			continue
+		}
+
+		if len(v.args.Applications) > 0 && !slices.Contains(v.args.Applications, app.Name) {
+			v.errorf(path, "unknown application %q, supported applications are: %s", app.Name, strings.Join(v.args.Applications, ", "))
+		}
+
+		if slices.Contains(seen, app.Name) {
+			v.warnf(path, "application %q is listed more than once", app.Name)
+		}
+
+		seen = append(seen, app.Name)
+	}
+}
+
+func (v *validator) checkInstall(install *apiseed.Install) {
+	if install.ForceInstallConfirmation != "" && !install.ForceInstall {
+		v.warnf([]any{"force_install_confirmation"}, "force_install_confirmation is only used when force_install is set")
+	}
+
+	if install.Security != nil && install.Security.MissingTPM && install.Security.MissingSecureBoot {
+		v.errorf([]any{"security"}, "install seed cannot enable both Secure Boot and TPM degraded security options")
+	}
+
+	if install.Target != nil {
+		v.checkInstallTarget(install.Target, "target")
+	}
+
+	if install.MirrorTarget != nil {
+		if install.Target == nil {
+			v.errorf([]any{"mirror_target"}, "mirror_target requires target to also be set")
+		}
+
+		v.checkInstallTarget(install.MirrorTarget, "mirror_target")
+	}
+
+	if install.Layout != nil {
+		v.checkInstallLayout(install.Layout)
+	}
+
+	if install.Report != nil {
+		v.checkInstallReport(install.Report)
+	}
+}
+
+func (v *validator) checkInstallReport(report *apiseed.InstallReport) {
+	u, err := url.Parse(report.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		v.errorf([]any{"report", "url"}, "invalid install report URL %q, must be http or https", report.URL)
+	}
+
+	if report.Certificate != "" || report.Key != "" {
+		_, err := tls.X509KeyPair([]byte(report.Certificate), []byte(report.Key))
+		if err != nil {
+			v.errorf([]any{"report", "certificate"}, "invalid install report client certificate: %s", err.Error())
+		}
+	}
+}
+
+func (v *validator) checkInstallLayout(layout *apiseed.InstallLayout) {
+	for _, entry := range []struct {
+		key     string
+		value   string
+		minimum int64
+	}{
+		{"root_size", layout.RootSize, storage.MinRootPartitionSize},
+		{"swap_size", layout.SwapSize, storage.MinSwapPartitionSize},
+		{"local_pool_size", layout.LocalPoolSize, storage.MinLocalDataPartitionSize},
+		{"reserved_size", layout.ReservedSize, 0},
+	} {
+		if entry.value == "" {
+			continue
+		}
+
+		size, err := units.ParseByteSizeString(entry.value)
+		if err != nil {
+			v.errorf([]any{"layout", entry.key}, "invalid %s %q: %s", entry.key, entry.value, err.Error())
+
+			continue
+		}
+
+		if size < entry.minimum || size < 0 {
+			v.errorf([]any{"layout", entry.key}, "%s %q is too small, must be at least %0.2fGiB", entry.key, entry.value, float64(entry.minimum)/(1024.0*1024.0*1024.0))
+		}
+	}
+}
+
+func (v *validator) checkInstallTarget(target *apiseed.InstallTarget, key string) {
+	minSize := int64(0)
+	maxSize := int64(0)
+
+	if target.MinSize != "" {
+		size, err := units.ParseByteSizeString(target.MinSize)
+		if err != nil {
+			v.errorf([]any{key, "min_size"}, "invalid min_size %q: %s", target.MinSize, err.Error())
+		}
+
+		minSize = size
+	}
+
+	if target.MaxSize != "" {
+		size, err := units.ParseByteSizeString(target.MaxSize)
+		if err != nil {
+			v.errorf([]any{key, "max_size"}, "invalid max_size %q: %s", target.MaxSize, err.Error())
+		}
+
+		maxSize = size
+	}
+
+	if minSize > 0 && maxSize > 0 && minSize > maxSize {
+		v.errorf([]any{key, "min_size"}, "min_size %q is larger than max_size %q, no drive can match", target.MinSize, target.MaxSize)
+	}
+
+	if target.SortOrder != "" && !slices.Contains([]string{"smallest", "largest"}, strings.ToLower(target.SortOrder)) {
+		v.errorf([]any{key, "sort_order"}, "unsupported sort order %q, must be \"smallest\" or \"largest\"", target.SortOrder)
+	}
+
+	if strings.HasPrefix(target.ID, "/") {
+		v.warnf([]any{key, "id"}, "id %q is matched against names in /dev/disk/by-id/, not paths", target.ID)
+	}
+}
+
+func (v *validator) checkNetwork(config api.SystemNetworkConfig, path []any) {
+	err := systemd.ValidateNetworkConfiguration(&config, false)
+	if err != nil {
+		v.errorf(path, "invalid network configuration: %s", err.Error())
+	}
+}
+
+func (v *validator) checkServices(services *apiseed.Services, path []any) {
+	if services.OVN != nil && services.OVN.Enabled && services.OVN.Database == "" {
+		v.errorf(append(slices.Clone(path), "ovn", "database"), "missing OVN database address")
+	}
+}
+
+func (v *validator) checkUpdate(config api.SystemUpdateConfig, path []any) {
+	// A missing check frequency keeps the existing value.
+	if config.CheckFrequency == "" {
+		config.CheckFrequency = "never"
+	}
+
+	err := config.Validate()
+	if err != nil {
+		v.errorf(path, "%s", err.Error())
+	}
+}
+
+func (v *validator) checkHost(host *apiseed.Host) {
+	v.checkApplications(host.Applications)
+
+	if host.Network != nil {
+		v.checkNetwork(*host.Network, []any{"network"})
+	}
+
+	if host.Update != nil {
+		v.checkUpdate(*host.Update, []any{"update"})
+	}
+
+	if host.Services == nil {
+		return
+	}
+
+	// Only the services supported by the services seed are configured at seed time.
+	for _, name := range slices.Sorted(maps.Keys(host.Services)) {
+		content, err := json.Marshal(map[string]any{name: host.Services[name]})
+		if err != nil {
+			v.errorf([]any{"services", name}, "%s", err.Error())
+
+			continue
+		}
+
+		var services apiseed.Services
+
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+
+		err = decoder.Decode(&services)
+		if err != nil {
+			if jsonUnknownField.MatchString(err.Error()) && jsonUnknownField.FindStringSubmatch(err.Error())[1] == name {
+				v.warnf([]any{"services", name}, "service %q isn't configured at seed time, only by \"incus admin os apply\"", name)
+			} else {
+				v.errorf([]any{"services", name}, "invalid %q service configuration: %s", name, strings.TrimPrefix(err.Error(), "json: "))
+			}
+
+			continue
+		}
+
+		v.checkServices(&services, []any{"services"})
+	}
+}
@@ -0,0 +1,139 @@
+package seed
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateFile(t *testing.T) {
+	t.Parallel()
+
+	args := ValidateArgs{Applications: []string{"debug", "incus"}}
+
+	// A valid seed has no issues.
+	issues := ValidateFile("applications.yaml", []byte("version: \"1\"\napplications:\n  - name: incus\n"), args)
+	require.Empty(t, issues)
+
+	// An empty install seed is valid.
+	issues = ValidateFile("install.json", nil, args)
+	require.Empty(t, issues)
+
+	// Unknown fields are reported with their line.
+	issues = ValidateFile("install.yaml", []byte("version: \"1\"\ntarget:\n  bus: nvme\n  bogus: true\n"), args)
+	require.Len(t, issues, 1)
+	require.Equal(t, 4, issues[0].Line)
+	require.False(t, issues[0].Warning)
+
+	issues = ValidateFile("network.json", []byte("{\n  \"version\": \"1\",\n  \"interfaces\": [\n    {\"name\": \"uplink\", \"bogus\": 1}\n  ]\n}\n"), args)
+	require.Len(t, issues, 1)
+	require.Equal(t, "network.json:4: error: unknown field \"bogus\"", issues[0].String())
+
+	// Install target selectors are checked.
+	issues = ValidateFile("install.yaml", []byte("version: \"1\"\ntarget:\n  min_size: 2TiB\n  max_size: 1TiB\n  sort_order: biggest\n"), args)
+	require.Len(t, issues, 2)
+	require.Equal(t, 3, issues[0].Line)
+	require.Equal(t, 5, issues[1].Line)
+
+	// A mirror target requires a main target.
+	issues = ValidateFile("install.yaml", []byte("version: \"1\"\nmirror_target:\n  bus: nvme\n"), args)
+	require.Len(t, issues, 1)
+	require.Equal(t, 2, issues[0].Line)
+
+	// Partition layout sizes are checked against their minimums.
+	issues = ValidateFile("install.yaml", []byte("version: \"1\"\nlayout:\n  root_size: 50GiB\n  swap_size: 512MiB\n  reserved_size: lots\n"), args)
+	require.Len(t, issues, 2)
+	require.Equal(t, 4, issues[0].Line)
+	require.Equal(t, 5, issues[1].Line)
+
+	// The install report URL is checked.
+	issues = ValidateFile("install.yaml", []byte("version: \"1\"\nreport:\n  url: ftp://example.com\n"), args)
+	require.Len(t, issues, 1)
+	require.Equal(t, 3, issues[0].Line)
+
+	// Application names are checked.
+	issues = ValidateFile("applications.yaml", []byte("version: \"1\"\napplications:\n  - name: incus\n  - name: foo\n"), args)
+	require.Len(t, issues, 1)
+	require.Equal(t, 4, issues[0].Line)
+	require.Contains(t, issues[0].Message, "unknown application \"foo\"")
+
+	// Version and unknown seeds only trigger warnings.
+	issues = ValidateFile("kernel.yaml", []byte("version: \"2\"\n"), args)
+	require.Len(t, issues, 1)
+	require.True(t, issues[0].Warning)
+
+	issues = ValidateFile("foo.yaml", []byte("version: \"1\"\n"), args)
+	require.Len(t, issues, 1)
+	require.True(t, issues[0].Warning)
+
+	// Service configurations are checked, including within the host seed.
+	issues = ValidateFile("services.yaml", []byte("version: \"1\"\novn:\n  enabled: true\n"), args)
+	require.Len(t, issues, 1)
+	require.Equal(t, 2, issues[0].Line)
+
+	issues = ValidateFile("host.yaml", []byte("version: \"1\"\nservices:\n  ceph:\n    enabled: true\n  lvm:\n    bogus: true\n"), args)
+	require.Len(t, issues, 2)
+	require.True(t, issues[0].Warning)
+	require.Equal(t, 3, issues[0].Line)
+	require.False(t, issues[1].Warning)
+	require.Equal(t, 5, issues[1].Line)
+
+	// Redacted secrets must be replaced.
+	issues = ValidateFile("services.yaml", []byte("version: \"1\"\ntailscale:\n  auth_key: \"[redacted:0123456789abcdef]\"\n"), args)
+	require.Len(t, issues, 1)
+	require.Equal(t, 3, issues[0].Line)
+	require.False(t, issues[0].Warning)
+}
+
+func TestValidatePath(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(dir, "host.yaml"), []byte("version: \"1\"\nupdate:\n  channel: testing\n"), 0o600)
+	require.NoError(t, err)
+
+	err = os.WriteFile(filepath.Join(dir, "update.yaml"), []byte("version: \"1\"\nchannel: stable\n"), 0o600)
+	require.NoError(t, err)
+
+	err = os.WriteFile(filepath.Join(dir, "update.json"), []byte("{\"version\": \"1\"}"), 0o600)
+	require.NoError(t, err)
+
+	issues, err := ValidatePath(dir, ValidateArgs{})
+	require.NoError(t, err)
+	require.Len(t, issues, 2)
+	require.Contains(t, issues[0].Message, "multiple files provide the \"update\" seed")
+	require.Equal(t, "host.yaml", issues[1].File)
+	require.Equal(t, 2, issues[1].Line)
+
+	// The existing test archive holds invalid seeds.
+	issues, err = ValidatePath("testdata.tar", ValidateArgs{})
+	require.NoError(t, err)
+	require.NotEmpty(t, issues)
+}
+
+func TestSchema(t *testing.T) {
+	t.Parallel()
+
+	schema, err := Schema("install")
+	require.NoError(t, err)
+	require.Equal(t, "object", schema["type"])
+
+	properties, ok := schema["properties"].(map[string]any)
+	require.True(t, ok)
+	require.Contains(t, properties, "target")
+	require.Contains(t, properties, "version")
+
+	// Embedded API structs are flattened.
+	schema, err = Schema("network")
+	require.NoError(t, err)
+
+	properties, ok = schema["properties"].(map[string]any)
+	require.True(t, ok)
+	require.Contains(t, properties, "interfaces")
+
+	_, err = Schema("foo")
+	require.Error(t, err)
+}
@@ -139,13 +139,9 @@ func (n *Multipath) Update(ctx context.Context, req any) error {
 	}
 
 	// Validate the request.
-	if newState.Config.Enabled {
-		for _, wwn := range newState.Config.WWNs {
-			_, err := os.Stat("/dev/disk/by-id/wwn-" + wwn)
-			if err != nil {
-				return fmt.Errorf("failed to locate WWN %q", wwn)
-			}
-		}
+	err := n.Validate(ctx, newState)
+	if err != nil {
+		return err
 	}
 
 	// Save the state on return.
@@ -153,7 +149,7 @@ func (n *Multipath) Update(ctx context.Context, req any) error {
 
 	// Disable the service if requested.
 	if n.state.Services.Multipath.Config.Enabled && !newState.Config.Enabled {
-		err := n.Stop(ctx)
+		err = n.Stop(ctx)
 		if err != nil {
 			return err
 		}
@@ -162,7 +158,7 @@ func (n *Multipath) Update(ctx context.Context, req any) error {
 		n.state.Services.Multipath.Config = newState.Config
 
 		// Enable or reconfigure the service if requested.
-		err := n.Start(ctx)
+		err = n.Start(ctx)
 		if err != nil {
 			return err
 		}
@@ -171,6 +167,25 @@ func (n *Multipath) Update(ctx context.Context, req any) error {
 	return nil
 }
 
+// Validate checks the service configuration without applying it.
+func (*Multipath) Validate(_ context.Context, req any) error {
+	newState, ok := req.(*api.ServiceMultipath)
+	if !ok {
+		return fmt.Errorf("request type \"%T\" isn't expected ServiceMultipath", req)
+	}
+
+	if newState.Config.Enabled {
+		for _, wwn := range newState.Config.WWNs {
+			_, err := os.Stat("/dev/disk/by-id/wwn-" + wwn)
+			if err != nil {
+				return fmt.Errorf("failed to locate WWN %q", wwn)
+			}
+		}
+	}
+
+	return nil
+}
+
 // Stop stops the service.
 func (n *Multipath) Stop(ctx context.Context) error {
 	if !n.state.Services.Multipath.Config.Enabled {
@@ -55,10 +55,9 @@ func (n *OVN) Update(ctx context.Context, req any) error {
 	oldState := n.state.Services.OVN
 
 	// Validate data.
-	if newState.Config.Enabled {
-		if newState.Config.Database == "" {
-			return errors.New("missing OVN database address")
-		}
+	err := n.Validate(ctx, newState)
+	if err != nil {
+		return err
 	}
 
 	// Save the state on return.
@@ -66,7 +65,7 @@ func (n *OVN) Update(ctx context.Context, req any) error {
 
 	// Disable the service if requested.
 	if oldState.Config.Enabled && !newState.Config.Enabled {
-		err := n.Stop(ctx)
+		err = n.Stop(ctx)
 		if err != nil {
 			return err
 		}
@@ -77,7 +76,7 @@ func (n *OVN) Update(ctx context.Context, req any) error {
 
 	// Enable the service if requested.
 	if !oldState.Config.Enabled && newState.Config.Enabled {
-		err := n.Start(ctx)
+		err = n.Start(ctx)
 		if err != nil {
 			return err
 		}
@@ -85,7 +84,7 @@ func (n *OVN) Update(ctx context.Context, req any) error {
 
 	// Configure the service if enabled.
 	if newState.Config.Enabled {
-		err := n.configure(ctx)
+		err = n.configure(ctx)
 		if err != nil {
 			return err
 		}
@@ -94,6 +93,22 @@ func (n *OVN) Update(ctx context.Context, req any) error {
 	return nil
 }
 
+// Validate checks the service configuration without applying it.
+func (*OVN) Validate(_ context.Context, req any) error {
+	newState, ok := req.(*api.ServiceOVN)
+	if !ok {
+		return fmt.Errorf("request type \"%T\" isn't expected ServiceOVN", req)
+	}
+
+	if newState.Config.Enabled {
+		if newState.Config.Database == "" {
+			return errors.New("missing OVN database address")
+		}
+	}
+
+	return nil
+}
+
 // Stop stops the service.
 func (n *OVN) Stop(ctx context.Context) error {
 	if !n.state.Services.OVN.Config.Enabled {
@@ -15,6 +15,7 @@ type Service interface {
 	Struct() any
 	Supported() bool
 	Update(ctx context.Context, req any) error
+	Validate(ctx context.Context, req any) error
 }
 
 type common struct{}
@@ -38,3 +39,7 @@ func (*common) Stop(_ context.Context) error {
 func (*common) Supported() bool {
 	return true
 }
+
+func (*common) Validate(_ context.Context, _ any) error {
+	return nil
+}
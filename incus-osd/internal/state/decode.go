@@ -3,13 +3,20 @@ package state
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
 )
 
-var errUnrecognizedConfigField = errors.New("unrecognized configuration field")
+var (
+	errUnrecognizedConfigField = errors.New("unrecognized configuration field")
+	errUndecryptableSecret     = errors.New("undecryptable secret")
+)
 
 // Decode reconstitutes a given state. Optionally, if provided, a list of upgrade functions will be
 // applied before decoding the state.
@@ -67,8 +74,16 @@ func Decode(b []byte, upgradeFuncs UpgradeFuncs, s *State) error {
 			return fmt.Errorf("malformed line '%s'", line)
 		}
 
-		err := decodeHelper(reflect.ValueOf(s), strings.Split(parts[0], "."), parts[1])
+		err := decodeHelper(reflect.ValueOf(s), parts[0], strings.Split(parts[0], "."), parts[1])
 		if err != nil {
+			// A secret that can't be decrypted, such as after restoring a backup from another
+			// system, is dropped and must be provided again.
+			if errors.Is(err, errUndecryptableSecret) {
+				slog.Warn("Dropping secret which can't be decrypted", "field", parts[0], "err", err)
+
+				continue
+			}
+
 			if !errors.Is(err, errUnrecognizedConfigField) {
 				return err
 			}
@@ -95,7 +110,9 @@ func Decode(b []byte, upgradeFuncs UpgradeFuncs, s *State) error {
 //
 // Because maps are unaddressable, when decoding a map we recursively call ourselves with the new
 // map as the root value, and once it is fully decoded then set the map as the new value and return.
-func decodeHelper(v reflect.Value, keys []string, value string) error {
+//
+// The full name of the key is used to decrypt values of secret fields.
+func decodeHelper(v reflect.Value, name string, keys []string, value string) error {
 	// Walk the state struct to the appropriate location.
 	for keyIndex, key := range keys {
 		if reflect.Indirect(v).Kind() != reflect.Struct {
@@ -115,6 +132,17 @@ func decodeHelper(v reflect.Value, keys []string, value string) error {
 			return errUnrecognizedConfigField
 		}
 
+		// Decrypt the value of secret fields.
+		structField, _ := reflect.Indirect(v).Type().FieldByName(parts[0])
+		if secrets.IsSecret(structField) {
+			decrypted, err := secrets.Decrypt(name, value)
+			if err != nil {
+				return fmt.Errorf("%w: %w", errUndecryptableSecret, err)
+			}
+
+			value = decrypted
+		}
+
 		// Do additional processing, if needed.
 		switch field.Kind() { //nolint:exhaustive
 		case reflect.Map:
@@ -137,7 +165,7 @@ func decodeHelper(v reflect.Value, keys []string, value string) error {
 				mapField = newMapField
 			}
 
-			err := decodeHelper(mapField, keys[keyIndex+1:], value)
+			err := decodeHelper(mapField, name, keys[keyIndex+1:], value)
 			if err != nil {
 				return err
 			}
@@ -182,6 +210,18 @@ func decodeHelper(v reflect.Value, keys []string, value string) error {
 
 // setValue is a helper function to convert and set a string representation of a value.
 func setValue(v reflect.Value, value string) error {
+	// Timestamps are stored in RFC3339 format.
+	if reflect.Indirect(v).Type() == reflect.TypeFor[time.Time]() {
+		tVal, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return err
+		}
+
+		reflect.Indirect(v).Set(reflect.ValueOf(tVal))
+
+		return nil
+	}
+
 	// Set the value.
 	switch v.Kind() { //nolint:exhaustive
 	case reflect.Bool:
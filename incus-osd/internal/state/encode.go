@@ -7,6 +7,9 @@ import (
 	"reflect"
 	"slices"
 	"strings"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
 )
 
 // Encode encodes the state and returns an array of bytes.
@@ -38,6 +41,14 @@ func encodeHelper(b *bytes.Buffer, keyPrefix []string, v reflect.Value) error {
 		return nil
 	}
 
+	// Timestamps only have unexported fields, so serialize them directly.
+	t, ok := v.Interface().(time.Time)
+	if ok {
+		_, err := fmt.Fprintf(b, "%s: %s\n", strings.Join(keyPrefix, "."), t.Format(time.RFC3339Nano))
+
+		return err
+	}
+
 	switch v.Kind() { //nolint:exhaustive
 	case reflect.Bool:
 		_, err := fmt.Fprintf(b, "%s: %v\n", strings.Join(keyPrefix, "."), v.Bool())
@@ -121,6 +132,16 @@ func encodeHelper(b *bytes.Buffer, keyPrefix []string, v reflect.Value) error {
 					continue
 				}
 
+				// Encrypt the value of secret fields.
+				if secrets.IsSecret(field) {
+					err := encodeSecret(b, append(keyPrefix, field.Name), v.FieldByIndex(field.Index))
+					if err != nil {
+						return err
+					}
+
+					continue
+				}
+
 				err := encodeHelper(b, append(keyPrefix, field.Name), v.FieldByIndex(field.Index))
 				if err != nil {
 					return err
@@ -138,3 +159,39 @@ func encodeHelper(b *bytes.Buffer, keyPrefix []string, v reflect.Value) error {
 
 	return nil
 }
+
+// encodeSecret serializes a secret string or string slice, encrypting each value.
+func encodeSecret(b *bytes.Buffer, keyPrefix []string, v reflect.Value) error {
+	if v.IsZero() {
+		return nil
+	}
+
+	switch {
+	case v.Kind() == reflect.String:
+		name := strings.Join(keyPrefix, ".")
+
+		value, err := secrets.Encrypt(name, v.String())
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(b, "%s: %s\n", name, strings.ReplaceAll(value, "\n", "\\n"))
+		if err != nil {
+			return err
+		}
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		keyBase := keyPrefix[len(keyPrefix)-1]
+		for i := range v.Len() {
+			keyPrefix[len(keyPrefix)-1] = fmt.Sprintf("%s[%d]", keyBase, i)
+
+			err := encodeSecret(b, keyPrefix, v.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%s: unsupported kind '%s' for secret field", strings.Join(keyPrefix, "."), v.Kind())
+	}
+
+	return nil
+}
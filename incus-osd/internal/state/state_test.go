@@ -3,6 +3,7 @@ package state_test
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -329,6 +330,33 @@ func TestDottedMapKey(t *testing.T) {
 	require.Equal(t, s.System.Provider.Config.Config["dotted.key"], newS.System.Provider.Config.Config["dotted.key"])
 }
 
+// Test encoding and decoding a state that contains timestamps.
+func TestTimestamps(t *testing.T) {
+	t.Parallel()
+
+	s := state.State{
+		StateVersion: 8,
+	}
+
+	rotated := time.Date(2026, time.March, 14, 15, 9, 26, 535897932, time.FixedZone("EST", -5*60*60))
+
+	s.System.Security.State.EncryptionKeysRotated = map[string]time.Time{
+		"volume/root": rotated,
+	}
+
+	contents, err := state.Encode(&s)
+	require.NoError(t, err)
+
+	require.Contains(t, string(contents), "System.Security.State.EncryptionKeysRotated[volume/root]: 2026-03-14T15:09:26.535897932-05:00\n")
+
+	var newS state.State
+
+	err = state.Decode(contents, nil, &newS)
+	require.NoError(t, err)
+
+	require.True(t, rotated.Equal(newS.System.Security.State.EncryptionKeysRotated["volume/root"]))
+}
+
 // Test basic custom decoding/encoding of state.
 func TestCustomEncoding(t *testing.T) {
 	t.Parallel()
@@ -392,3 +420,19 @@ func TestUpgradeFuncs(t *testing.T) {
 	require.Equal(t, "dhcp4", s.System.Network.Config.Interfaces[0].Addresses[0])
 	require.Equal(t, "dhcp6", s.System.Network.Config.Interfaces[0].Addresses[1])
 }
+
+func TestIsEncryptionRecoveryKey(t *testing.T) {
+	t.Parallel()
+
+	s := &state.State{}
+
+	// No key matches when none are configured.
+	require.False(t, s.IsEncryptionRecoveryKey(""))
+	require.False(t, s.IsEncryptionRecoveryKey("foo"))
+
+	s.System.Security.Config.EncryptionRecoveryKeys = []string{"foo", "bar"}
+
+	require.True(t, s.IsEncryptionRecoveryKey("bar"))
+	require.False(t, s.IsEncryptionRecoveryKey("baz"))
+	require.False(t, s.IsEncryptionRecoveryKey(""))
+}
@@ -1,6 +1,7 @@
 package state
 
 import (
+	"crypto/subtle"
 	"errors"
 	"os"
 	"strings"
@@ -25,6 +26,15 @@ type OS struct {
 	SystemIsReady  bool   `json:"-"`
 }
 
+// HistoryRevision represents a recorded configuration change, along with the configuration it replaced.
+type HistoryRevision struct {
+	api.SystemHistoryRevision
+
+	// The JSON encoded configuration of the section prior to the change. As it may itself hold
+	// secrets, the whole value is handled as a secret.
+	PriorConfig string `incusos:"secret" json:"prior_config"`
+}
+
 // State represents the on-disk persistent state.
 type State struct {
 	path string
@@ -36,23 +46,28 @@ type State struct {
 
 	UpdateMutex sync.Mutex `json:"-"`
 
+	// Protects the fallback listener configuration, which is read by the listener when authenticating clients.
+	FallbackListenerMutex sync.RWMutex `json:"-"`
+
 	JobScheduler scheduling.Scheduler `json:"-"`
 
 	NetworkConfigurationPending bool       `json:"-"`
 	NetworkConfigurationChannel chan error `json:"-"`
 
 	// Triggers for daemon actions.
-	TriggerReboot           chan bool `json:"-"`
-	TriggerShutdown         chan bool `json:"-"`
-	TriggerSuspend          chan bool `json:"-"`
-	TriggerUpdate           chan bool `json:"-"`
-	TriggerFallbackListener chan bool `json:"-"`
+	TriggerReboot            chan bool `json:"-"`
+	TriggerShutdown          chan bool `json:"-"`
+	TriggerSuspend           chan bool `json:"-"`
+	TriggerUpdate            chan bool `json:"-"`
+	TriggerFallbackListener  chan bool `json:"-"`
+	TriggerCertificateUpdate chan bool `json:"-"`
 
 	SecureBoot          SecureBoot `json:"secure_boot"`
 	UsingSWTPM          bool       `json:"using_swtpm"`
 	SecureBootDisabled  bool       `json:"secure_boot_disabled"`
 	FullAgentEnabled    bool       `json:"full_agent_enabled"`
 	ServicesSeedApplied bool       `json:"services_seed_applied"`
+	KernelSeedApplied   bool       `json:"kernel_seed_applied"`
 
 	Applications struct {
 		Debug            api.Application        `json:"debug"`
@@ -81,6 +96,7 @@ type State struct {
 	} `json:"services"`
 
 	System struct {
+		Certificate      api.SystemCertificate      `json:"certificate"`
 		FallbackListener api.SystemFallbackListener `json:"fallback_listener"`
 		Kernel           api.SystemKernel           `json:"kernel"`
 		Logging          api.SystemLogging          `json:"logging"`
@@ -91,6 +107,9 @@ type State struct {
 		Storage          api.SystemStorage          `json:"storage"`
 	} `json:"system"`
 
+	// Recorded configuration changes, oldest first.
+	History []HistoryRevision `json:"history"`
+
 	// Used to handle an edge case of a new network configuration being applied, but
 	// the system is rebooted before the new configuration can be confirmed. This helps
 	// ensure IncusOS will always be able to boot up with a known good configuration.
@@ -155,6 +174,21 @@ func (s *State) Hostname() string {
 	return s.OS.Name
 }
 
+// IsEncryptionRecoveryKey checks whether the provided key is one of the system's encryption recovery keys.
+func (s *State) IsEncryptionRecoveryKey(key string) bool {
+	if key == "" {
+		return false
+	}
+
+	for _, recoveryKey := range s.System.Security.Config.EncryptionRecoveryKeys {
+		if subtle.ConstantTimeCompare([]byte(recoveryKey), []byte(key)) == 1 {
+			return true
+		}
+	}
+
+	return false
+}
+
 // RunningFromBackup returns a boolean to indicate if IncusOS is running from
 // the older (backup) A/B partition.
 func (o *OS) RunningFromBackup() bool {
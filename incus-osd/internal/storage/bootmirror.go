@@ -0,0 +1,268 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/incus/v7/shared/subprocess"
+	"golang.org/x/sys/unix"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// BootMirrorFile is the name of the file on the ESP listing the two drives holding a copy of the boot partitions.
+const BootMirrorFile = "boot-mirror"
+
+var (
+	partitionGUIDRegex = regexp.MustCompile(`Partition unique GUID: (.+)`)
+	partitionNameRegex = regexp.MustCompile(`Partition name: '(.*)'`)
+)
+
+// GetBootMirror returns the IDs of the drives holding a copy of the boot partitions, or nil if the
+// system was installed to a single drive.
+func GetBootMirror() ([]string, error) {
+	content, err := os.ReadFile(filepath.Join("/boot", BootMirrorFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return parseBootMirror(string(content))
+}
+
+// WriteBootMirror records the drives holding a copy of the boot partitions on the ESP mounted at the given path.
+func WriteBootMirror(espPath string, drives []string) error {
+	if len(drives) != 2 {
+		return fmt.Errorf("expected two boot mirror drives, got %d", len(drives))
+	}
+
+	return os.WriteFile(filepath.Join(espPath, BootMirrorFile), []byte(strings.Join(drives, "\n")+"\n"), 0o600)
+}
+
+// GetBootMirrorDrive returns the ID of the mirrored boot drive the system isn't currently running from,
+// or an empty string if the system was installed to a single drive.
+func GetBootMirrorDrive(ctx context.Context) (string, error) {
+	drives, err := GetBootMirror()
+	if err != nil || drives == nil {
+		return "", err
+	}
+
+	bootDevice, err := GetUnderlyingDevice()
+	if err != nil {
+		return "", err
+	}
+
+	bootTarget, err := filepath.EvalSymlinks(bootDevice)
+	if err != nil {
+		return "", err
+	}
+
+	for _, drive := range drives {
+		target, err := filepath.EvalSymlinks(drive)
+		if err != nil || target != bootTarget {
+			return drive, nil
+		}
+	}
+
+	return "", errors.New("unable to determine the boot mirror drive")
+}
+
+// SyncBootMirror copies any /usr partitions that changed since the last sync, typically following an
+// OS update, as well as the contents of the ESP from the drive the system is running from to its mirror.
+func SyncBootMirror(ctx context.Context) error {
+	mirrorDrive, err := GetBootMirrorDrive(ctx)
+	if err != nil || mirrorDrive == "" {
+		return err
+	}
+
+	_, err = os.Stat(mirrorDrive)
+	if err != nil {
+		return fmt.Errorf("boot mirror drive '%s' is missing", mirrorDrive)
+	}
+
+	bootDevice, err := GetUnderlyingDevice()
+	if err != nil {
+		return err
+	}
+
+	bootDrive, err := DeviceToID(ctx, bootDevice, true)
+	if err != nil {
+		return err
+	}
+
+	// Copy the /usr partitions (3-8). Only the partitions written by an update will differ.
+	for idx := 3; idx <= 8; idx++ {
+		bootGUID, bootName, err := getPartitionInfo(ctx, bootDrive, idx)
+		if err != nil {
+			return err
+		}
+
+		mirrorGUID, mirrorName, err := getPartitionInfo(ctx, mirrorDrive, idx)
+		if err != nil {
+			return err
+		}
+
+		if bootGUID == mirrorGUID && bootName == mirrorName {
+			continue
+		}
+
+		slog.InfoContext(ctx, "Syncing boot mirror partition", "drive", mirrorDrive, "partition", idx, "name", bootName)
+
+		err = copyPartition(fmt.Sprintf("%s-part%d", bootDrive, idx), fmt.Sprintf("%s-part%d", mirrorDrive, idx))
+		if err != nil {
+			return err
+		}
+
+		// The partition's unique GUID is used to locate the /usr image at boot, so must match.
+		_, err = subprocess.RunCommandContext(ctx, "sgdisk", "-u", strconv.Itoa(idx)+":"+bootGUID, "-c", strconv.Itoa(idx)+":"+bootName, mirrorDrive)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Replace the contents of the mirror's ESP.
+	mountPath := "/run/incus-os/boot-mirror"
+
+	err = os.MkdirAll(mountPath, 0o700)
+	if err != nil {
+		return err
+	}
+
+	err = unix.Mount(mirrorDrive+"-part1", mountPath, "vfat", 0, "umask=0077")
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = unix.Unmount(mountPath, 0) }()
+
+	_, err = subprocess.RunCommandContext(ctx, "sh", "-c", "rm -rf "+mountPath+"/* && cp -ar /boot/. "+mountPath+"/")
+	if err != nil {
+		return err
+	}
+
+	unix.Sync()
+
+	return nil
+}
+
+// getBootMirrorState returns the state of the mirrored boot drives, or nil if the system was installed to a single drive.
+func getBootMirrorState(ctx context.Context, localPool *api.SystemStoragePool) (*api.SystemStorageBootMirror, error) {
+	drives, err := GetBootMirror()
+	if err != nil || drives == nil {
+		return nil, err
+	}
+
+	ret := &api.SystemStorageBootMirror{
+		Drives: drives,
+		State:  "healthy",
+	}
+
+	for _, drive := range drives {
+		_, err := os.Stat(drive)
+		if err != nil {
+			ret.DrivesMissing = append(ret.DrivesMissing, drive)
+		}
+	}
+
+	// The "local" pool should be mirrored across both drives.
+	if len(ret.DrivesMissing) > 0 || localPool == nil || localPool.State != "ONLINE" || len(localPool.Devices) != 2 {
+		ret.State = "degraded"
+
+		return ret, nil
+	}
+
+	for idx := 3; idx <= 8; idx++ {
+		firstGUID, firstName, err := getPartitionInfo(ctx, drives[0], idx)
+		if err != nil {
+			return nil, err
+		}
+
+		secondGUID, secondName, err := getPartitionInfo(ctx, drives[1], idx)
+		if err != nil {
+			return nil, err
+		}
+
+		if firstGUID != secondGUID || firstName != secondName {
+			ret.State = "out-of-sync"
+
+			break
+		}
+	}
+
+	return ret, nil
+}
+
+// parseBootMirror parses the list of drives recorded on the ESP.
+func parseBootMirror(content string) ([]string, error) {
+	drives := []string{}
+
+	for line := range strings.Lines(content) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		drives = append(drives, line)
+	}
+
+	if len(drives) != 2 {
+		return nil, fmt.Errorf("expected two boot mirror drives, found %d", len(drives))
+	}
+
+	return drives, nil
+}
+
+// getPartitionInfo returns the unique GUID and name of a partition.
+func getPartitionInfo(ctx context.Context, device string, partitionIndex int) (string, string, error) {
+	output, err := subprocess.RunCommandContext(ctx, "sgdisk", "-i", strconv.Itoa(partitionIndex), device)
+	if err != nil {
+		return "", "", err
+	}
+
+	return parsePartitionInfo(output)
+}
+
+// parsePartitionInfo extracts the unique GUID and name of a partition from the output of `sgdisk -i`.
+func parsePartitionInfo(output string) (string, string, error) {
+	guid := partitionGUIDRegex.FindStringSubmatch(output)
+	name := partitionNameRegex.FindStringSubmatch(output)
+
+	if len(guid) != 2 || len(name) != 2 {
+		return "", "", errors.New("unable to parse partition information: " + strings.TrimSpace(output))
+	}
+
+	return guid[1], name[1], nil
+}
+
+// copyPartition copies the contents of one partition to another of the same size.
+func copyPartition(source string, target string) error {
+	sourcePartition, err := os.OpenFile(source, os.O_RDONLY, 0o0600)
+	if err != nil {
+		return err
+	}
+	defer sourcePartition.Close()
+
+	targetPartition, err := os.OpenFile(target, os.O_WRONLY, 0o0600)
+	if err != nil {
+		return err
+	}
+	defer targetPartition.Close()
+
+	_, err = io.CopyBuffer(targetPartition, sourcePartition, make([]byte, 4*1024*1024))
+	if err != nil {
+		return err
+	}
+
+	return targetPartition.Sync()
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/incus/v7/shared/subprocess"
+)
+
+// PartitionLayoutFile is the name of the file on the ESP holding the partition sizes used by systemd-repart on first boot.
+const PartitionLayoutFile = "partition-layout"
+
+// Minimum sizes in bytes of the partitions whose size can be customized at install time.
+const (
+	MinRootPartitionSize      = 25 * 1024 * 1024 * 1024
+	MinSwapPartitionSize      = 1 * 1024 * 1024 * 1024
+	MinLocalDataPartitionSize = 10 * 1024 * 1024 * 1024
+)
+
+var partitionFirstSectorRegex = regexp.MustCompile(`First sector: (\d+)`)
+
+// PartitionLayout holds the sizes in bytes of the partitions created on first boot; a zero size keeps the default.
+type PartitionLayout struct {
+	SwapSize      int64
+	RootSize      int64
+	LocalDataSize int64
+}
+
+// GetPartitionLayout returns the partition sizes chosen at install time, or nil if the default layout is used.
+func GetPartitionLayout() (*PartitionLayout, error) {
+	content, err := os.ReadFile(filepath.Join("/boot", PartitionLayoutFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return parsePartitionLayout(string(content))
+}
+
+// WritePartitionLayout records the partition sizes on the ESP mounted at the given path.
+func WritePartitionLayout(espPath string, layout PartitionLayout) error {
+	content := ""
+
+	for _, entry := range []struct {
+		key  string
+		size int64
+	}{
+		{"swap", layout.SwapSize},
+		{"root", layout.RootSize},
+		{"local-data", layout.LocalDataSize},
+	} {
+		if entry.size > 0 {
+			content += fmt.Sprintf("%s=%d\n", entry.key, entry.size)
+		}
+	}
+
+	return os.WriteFile(filepath.Join(espPath, PartitionLayoutFile), []byte(content), 0o600)
+}
+
+// GetPartitionFirstSector returns the first sector of a partition.
+func GetPartitionFirstSector(ctx context.Context, device string, partitionIndex int) (int, error) {
+	output, err := subprocess.RunCommandContext(ctx, "sgdisk", "-i", strconv.Itoa(partitionIndex), device)
+	if err != nil {
+		return -1, err
+	}
+
+	firstSector := partitionFirstSectorRegex.FindStringSubmatch(output)
+	if len(firstSector) != 2 {
+		return -1, errors.New("unable to parse partition information: " + strings.TrimSpace(output))
+	}
+
+	return strconv.Atoi(firstSector[1])
+}
+
+// parsePartitionLayout parses the partition sizes recorded on the ESP.
+func parsePartitionLayout(content string) (*PartitionLayout, error) {
+	ret := &PartitionLayout{}
+
+	for line := range strings.Lines(content) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid partition layout line %q", line)
+		}
+
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid partition size %q", value)
+		}
+
+		switch key {
+		case "swap":
+			ret.SwapSize = size
+		case "root":
+			ret.RootSize = size
+		case "local-data":
+			ret.LocalDataSize = size
+		default:
+			return nil, fmt.Errorf("unknown partition %q in partition layout", key)
+		}
+	}
+
+	return ret, nil
+}
@@ -157,6 +157,35 @@ func GetDriveKeys() (map[string]string, error) {
 	return keys, nil
 }
 
+// RotateDriveKey replaces the key of an encrypted drive with a newly generated one. Only the
+// keyslot is updated, so the drive's contents don't need to be re-encrypted.
+func RotateDriveKey(ctx context.Context, devID string) error {
+	devPath := "/dev/disk/by-id/" + devID
+	keyfilePath := "/var/lib/incus-os/luks." + devID + ".key"
+	newKeyfilePath := keyfilePath + ".new"
+
+	_, err := os.Stat(devPath)
+	if err != nil {
+		return err
+	}
+
+	// Generate a new random encryption key.
+	err = util.GenerateEncryptionKeyFile(newKeyfilePath)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = os.Remove(newKeyfilePath) }()
+
+	// Replace the existing key.
+	_, err = subprocess.RunCommandContext(ctx, "cryptsetup", "luksChangeKey", "-q", "-d", keyfilePath, devPath, newKeyfilePath)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(newKeyfilePath, keyfilePath)
+}
+
 // LUKSBoundToPCR determines if the given LUKS volume is bound to the specified PCR.
 func LUKSBoundToPCR(ctx context.Context, devPath string, pcrIndex int) (bool, error) {
 	output, err := subprocess.RunCommandContext(ctx, "cryptsetup", "luksDump", "--dump-json-metadata", devPath)
@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NetworkInstallPath is where the initrd stores the install image retrieved over the network.
+const NetworkInstallPath = "/run/incus-os/network-install"
+
+// GetNetworkInstallSource returns the URL of the install image retrieved over the network by the initrd,
+// or an empty string if the system wasn't booted from the network.
+func GetNetworkInstallSource() (string, error) {
+	content, err := os.ReadFile(filepath.Join(NetworkInstallPath, "source"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return strings.TrimSpace(string(content)), nil
+}
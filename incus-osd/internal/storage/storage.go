@@ -719,6 +719,20 @@ func GetStorageInfo(ctx context.Context) (api.SystemStorageState, error) {
 		ret.Pools = append(ret.Pools, poolConfig)
 	}
 
+	// Get the state of the mirrored boot drives, if any.
+	var localPool *api.SystemStoragePool
+
+	for i := range ret.Pools {
+		if ret.Pools[i].Name == "local" {
+			localPool = &ret.Pools[i]
+		}
+	}
+
+	ret.BootMirror, err = getBootMirrorState(ctx, localPool)
+	if err != nil {
+		return ret, err
+	}
+
 	// Get a list of all local drives.
 	// Note that while we can get the VENDOR field from lsblk, it seems to return generic values like "ATA" which isn't useful.
 	// Exclude devices with major numbers 1 (RAM disk), 2 (floppy disks), 7 (loopback), 43 (NBD), 147 (DRBD), 230 (zvols), 251 (Ceph RBD)
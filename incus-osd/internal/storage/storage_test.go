@@ -70,3 +70,56 @@ func TestCalculateScrubProgress(t *testing.T) {
 		})
 	}
 }
+
+func TestParseBootMirror(t *testing.T) {
+	t.Parallel()
+
+	drives, err := parseBootMirror("/dev/disk/by-id/nvme-disk1\n/dev/disk/by-id/nvme-disk2\n")
+	require.NoError(t, err)
+	require.Equal(t, []string{"/dev/disk/by-id/nvme-disk1", "/dev/disk/by-id/nvme-disk2"}, drives)
+
+	_, err = parseBootMirror("/dev/disk/by-id/nvme-disk1\n")
+	require.Error(t, err)
+
+	_, err = parseBootMirror("")
+	require.Error(t, err)
+}
+
+func TestParsePartitionInfo(t *testing.T) {
+	t.Parallel()
+
+	output := `Partition GUID code: 8484680C-9521-48C6-9C11-B0720656F69E (Linux x86-64 /usr)
+Partition unique GUID: 4C1A3C2F-7D6B-4E3A-9B1E-0F2D5C6A7B8C
+First sector: 2408448 (at 1.1 GiB)
+Last sector: 4505599 (at 2.1 GiB)
+Partition size: 2097152 sectors (1024.0 MiB)
+Attribute flags: 0000000000000000
+Partition name: 'IncusOS_202510180000'
+`
+
+	guid, name, err := parsePartitionInfo(output)
+	require.NoError(t, err)
+	require.Equal(t, "4C1A3C2F-7D6B-4E3A-9B1E-0F2D5C6A7B8C", guid)
+	require.Equal(t, "IncusOS_202510180000", name)
+
+	_, _, err = parsePartitionInfo("Partition #9 does not exist.")
+	require.Error(t, err)
+}
+
+func TestParsePartitionLayout(t *testing.T) {
+	t.Parallel()
+
+	layout, err := parsePartitionLayout("root=53687091200\nlocal-data=107374182400\n")
+	require.NoError(t, err)
+	require.Equal(t, &PartitionLayout{RootSize: 53687091200, LocalDataSize: 107374182400}, layout)
+
+	layout, err = parsePartitionLayout("")
+	require.NoError(t, err)
+	require.Equal(t, &PartitionLayout{}, layout)
+
+	_, err = parsePartitionLayout("root=50GiB\n")
+	require.Error(t, err)
+
+	_, err = parsePartitionLayout("usr=1024\n")
+	require.Error(t, err)
+}
@@ -0,0 +1,50 @@
+package systemd
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/lxc/incus/v7/shared/subprocess"
+)
+
+// EncryptCredential seals the provided data to the local TPM using systemd-creds. The credential
+// isn't bound to any PCR, so it remains usable across OS and Secure Boot key updates.
+func EncryptCredential(ctx context.Context, name string, data []byte) ([]byte, error) {
+	var stdout bytes.Buffer
+
+	err := subprocess.RunCommandWithFds(ctx, bytes.NewReader(data), &stdout, "systemd-creds", "encrypt", "--name="+name, "--with-key=host+tpm2", "--tpm2-pcrs=", "-", "-")
+	if err != nil {
+		return nil, err
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// EncryptTPMCredential seals the provided data to the local TPM alone, rather than to the TPM and
+// the host key stored on the root partition, so it can be unsealed from a fresh install on the
+// same system. As the credential isn't protected by the encrypted root partition, it's bound to
+// PCR 7 and to the signed PCR 11 policy of the IncusOS image, so it can only be unsealed by a
+// signed IncusOS image with the same Secure Boot state. It must be sealed again after either
+// the Secure Boot keys or the image signing key change.
+func EncryptTPMCredential(ctx context.Context, name string, data []byte) ([]byte, error) {
+	var stdout bytes.Buffer
+
+	err := subprocess.RunCommandWithFds(ctx, bytes.NewReader(data), &stdout, "systemd-creds", "encrypt", "--name="+name, "--with-key=tpm2", "--tpm2-pcrs=7", "--tpm2-public-key=/run/systemd/tpm2-pcr-public-key.pem", "--tpm2-public-key-pcrs=11", "-", "-")
+	if err != nil {
+		return nil, err
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// DecryptCredential unseals a credential previously returned by EncryptCredential or EncryptTPMCredential.
+func DecryptCredential(ctx context.Context, name string, data []byte) ([]byte, error) {
+	var stdout bytes.Buffer
+
+	err := subprocess.RunCommandWithFds(ctx, bytes.NewReader(data), &stdout, "systemd-creds", "decrypt", "--name="+name, "-", "-")
+	if err != nil {
+		return nil, err
+	}
+
+	return stdout.Bytes(), nil
+}
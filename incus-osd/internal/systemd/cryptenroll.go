@@ -2,20 +2,40 @@ package systemd
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
+	"maps"
 	"os"
 	"slices"
 	"strings"
+	"sync"
 
 	"github.com/lxc/incus/v7/shared/subprocess"
 	"github.com/muesli/crunchy"
 
 	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/scheduling"
 	"github.com/lxc/incus-os/incus-osd/internal/secureboot"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/tang"
 	"github.com/lxc/incus-os/incus-osd/internal/util"
 )
 
+const (
+	// NetworkUnlockCheckJob represents the job checking that each Tang server used for network-bound unlock is reachable.
+	NetworkUnlockCheckJob scheduling.JobName = "network_unlock_check"
+
+	// NetworkUnlockCheckSchedule runs the network unlock check every 15 minutes.
+	NetworkUnlockCheckSchedule = "*/15 * * * *"
+)
+
+var (
+	networkUnlockMu      sync.Mutex
+	networkUnlockServers []api.SystemSecurityNetworkUnlockServer
+	networkUnlockStatus  map[string]string
+)
+
 // GenerateRecoveryKeys utilizes systemd-cryptenroll to generate recovery keys for the
 // root and swap LUKS volumes. The logic depends on an existing tpm2-backed key being
 // enrolled and accessible, which should be the case on first boot.
@@ -146,9 +166,9 @@ func AddEncryptionKey(ctx context.Context, s *state.State, key string) error {
 	return nil
 }
 
-// DeleteEncryptionKey utilizes systemd-cryptenroll to remove a user-specified key from the
-// root and swap LUKS volumes. Due to systemd-cryptenroll only being able to wipe slots by
-// index or type, we must first remove all password slots, then re-add any remaining keys.
+// DeleteEncryptionKey removes a user-specified key from the root and swap LUKS volumes. Only the
+// keyslot unlocked by that key is removed, so the other keys and any network-bound unlock passphrase
+// are left in place.
 func DeleteEncryptionKey(ctx context.Context, s *state.State, key string) error {
 	if !slices.Contains(s.System.Security.Config.EncryptionRecoveryKeys, key) {
 		return errors.New("provided encryption key is not enrolled")
@@ -164,32 +184,294 @@ func DeleteEncryptionKey(ctx context.Context, s *state.State, key string) error
 		return err
 	}
 
-	// First, wipe all recovery and password slots.
-	for name, volume := range luksVolumes {
-		err := WipeAllRecoveryKeys(ctx, name, volume)
+	// Remove the keyslot matching the key, which is provided through stdin.
+	for _, volume := range luksVolumes {
+		err := subprocess.RunCommandWithFds(ctx, strings.NewReader(key), nil, "cryptsetup", "luksRemoveKey", "--key-file=-", volume)
 		if err != nil {
 			return err
 		}
 	}
 
-	existingKeys := s.System.Security.Config.EncryptionRecoveryKeys
-	s.System.Security.Config.EncryptionRecoveryKeys = []string{}
+	s.System.Security.Config.EncryptionRecoveryKeys = slices.DeleteFunc(s.System.Security.Config.EncryptionRecoveryKeys, func(existingKey string) bool {
+		return existingKey == key
+	})
+
+	return nil
+}
 
-	// Re-add remaining keys.
-	for _, existingKey := range existingKeys {
-		if existingKey == key {
+// ConfigureNetworkUnlock binds the root and swap LUKS volumes to the provided Tang servers,
+// replacing any existing binding. A nil configuration removes network-bound unlocking.
+//
+// A random secret is sealed against each Tang server, with the resulting bindings stored in
+// a LUKS2 token on each volume. In "fallback" mode, the secret is enrolled as an additional
+// passphrase which the initrd provides when the TPM can't unlock the volume. In "required"
+// mode, the secret is used as the TPM2 PIN, meaning both the TPM and a reachable Tang server
+// are needed to unlock the volume.
+func ConfigureNetworkUnlock(ctx context.Context, s *state.State, config *api.SystemSecurityNetworkUnlock) error {
+	if config != nil {
+		err := config.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Get the underlying LUKS partitions.
+	luksVolumes, err := util.GetLUKSVolumePartitions(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Remove any existing binding.
+	pinEnrolled := false
+
+	for name, volume := range luksVolumes {
+		token, tokenID, err := util.GetNetworkUnlockToken(ctx, volume)
+		if err != nil {
+			return err
+		}
+
+		if token == nil {
 			continue
 		}
 
-		err := AddEncryptionKey(ctx, s, existingKey)
+		if token.Mode == string(api.NetworkUnlockModeRequired) {
+			pinEnrolled = true
+		}
+
+		for _, keyslot := range token.Keyslots {
+			_, err := subprocess.RunCommandContext(ctx, "systemd-cryptenroll", "--unlock-key-file=/var/lib/incus-os/recovery."+name+".key", "--wipe-slot="+keyslot, volume)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = util.RemoveLUKSToken(ctx, volume, tokenID)
 		if err != nil {
 			return err
 		}
 	}
 
+	s.System.Security.Config.NetworkUnlock = nil
+	setNetworkUnlockServers(nil)
+
+	if config == nil {
+		// Drop the TPM2 PIN now that the token is gone.
+		if pinEnrolled {
+			return secureboot.UpdateTPMBindings(ctx)
+		}
+
+		return nil
+	}
+
+	// Generate a new secret and seal it against each Tang server.
+	rawSecret := make([]byte, 32)
+
+	_, err = rand.Read(rawSecret)
+	if err != nil {
+		return err
+	}
+
+	secret := base64.StdEncoding.EncodeToString(rawSecret)
+
+	bindings := make([]tang.Binding, 0, len(config.Servers))
+
+	for _, server := range config.Servers {
+		binding, err := tang.Seal(ctx, server.URL, server.Thumbprint, []byte(secret))
+		if err != nil {
+			return err
+		}
+
+		bindings = append(bindings, *binding)
+	}
+
+	for name, volume := range luksVolumes {
+		token := &tang.LUKSToken{
+			Keyslots: []string{},
+			Mode:     string(config.Mode),
+			Bindings: bindings,
+		}
+
+		if config.Mode == api.NetworkUnlockModeFallback {
+			// Enroll the secret as an additional passphrase and record which keyslot it was added to.
+			existingKeyslots, err := util.GetLUKSKeyslots(ctx, volume)
+			if err != nil {
+				return err
+			}
+
+			_, _, err = subprocess.RunCommandSplit(ctx, append(os.Environ(), "NEWPASSWORD="+secret), nil, "systemd-cryptenroll", "--unlock-key-file=/var/lib/incus-os/recovery."+name+".key", "--password", volume)
+			if err != nil {
+				return err
+			}
+
+			keyslots, err := util.GetLUKSKeyslots(ctx, volume)
+			if err != nil {
+				return err
+			}
+
+			for _, keyslot := range keyslots {
+				if !slices.Contains(existingKeyslots, keyslot) {
+					token.Keyslots = append(token.Keyslots, keyslot)
+				}
+			}
+		}
+
+		err := util.AddNetworkUnlockToken(ctx, volume, token)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Re-enroll the TPM when its PIN requirement may have changed.
+	if config.Mode == api.NetworkUnlockModeRequired || pinEnrolled {
+		err := secureboot.UpdateTPMBindings(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.System.Security.Config.NetworkUnlock = config
+	setNetworkUnlockServers(config)
+
+	return CheckNetworkUnlock(ctx)
+}
+
+// LoadNetworkUnlock records the Tang servers of the current network-bound unlock configuration, so
+// their reachability can be checked by CheckNetworkUnlock. It must be called once at startup.
+func LoadNetworkUnlock(s *state.State) {
+	setNetworkUnlockServers(s.System.Security.Config.NetworkUnlock)
+}
+
+// setNetworkUnlockServers replaces the Tang servers to be checked, dropping any previous status.
+func setNetworkUnlockServers(config *api.SystemSecurityNetworkUnlock) {
+	networkUnlockMu.Lock()
+	defer networkUnlockMu.Unlock()
+
+	networkUnlockServers = nil
+	networkUnlockStatus = nil
+
+	if config != nil {
+		networkUnlockServers = slices.Clone(config.Servers)
+	}
+}
+
+// CheckNetworkUnlock records whether each Tang server used for network-bound unlock is reachable,
+// to be reported as part of the security state. The state itself isn't modified, so this can be
+// run in the background.
+func CheckNetworkUnlock(ctx context.Context) error {
+	networkUnlockMu.Lock()
+	servers := slices.Clone(networkUnlockServers)
+	networkUnlockMu.Unlock()
+
+	status := make(map[string]string, len(servers))
+
+	for _, server := range servers {
+		_, err := tang.GetAdvertisement(ctx, server.URL, server.Thumbprint)
+		if err != nil {
+			status[server.URL] = err.Error()
+		} else {
+			status[server.URL] = "reachable"
+		}
+	}
+
+	networkUnlockMu.Lock()
+	defer networkUnlockMu.Unlock()
+
+	// Drop the result if the configuration changed while the servers were being checked.
+	if !slices.Equal(servers, networkUnlockServers) {
+		return nil
+	}
+
+	networkUnlockStatus = status
+
 	return nil
 }
 
+// CheckNetworkUnlockJob returns a job function which checks the reachability of the Tang servers.
+func CheckNetworkUnlockJob() scheduling.JobFunc {
+	return CheckNetworkUnlock
+}
+
+// GetNetworkUnlockStatus returns the reachability of each Tang server as of the last check, or nil if
+// network-bound unlock isn't configured or no check completed yet.
+func GetNetworkUnlockStatus() map[string]string {
+	networkUnlockMu.Lock()
+	defer networkUnlockMu.Unlock()
+
+	if networkUnlockStatus == nil {
+		return nil
+	}
+
+	return maps.Clone(networkUnlockStatus)
+}
+
+// RotateRecoveryKeys replaces the IncusOS recovery key of each LUKS volume with a newly generated
+// one and re-seals the TPM keyslots. User-provided encryption recovery keys are left untouched. The
+// names of the rotated volumes are returned.
+//
+// Only the keyslots are replaced, not the volume key itself. A copy of the old LUKS header, or the
+// volume key taken from a running system, still decrypts the volumes.
+//
+// The TPM is bound to the current PCR values, so this must not be run while a pending update or
+// Secure Boot key change expects different values on next boot.
+func RotateRecoveryKeys(ctx context.Context) ([]string, error) {
+	// Get the underlying LUKS partitions.
+	luksVolumes, err := util.GetLUKSVolumePartitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+
+	for name, volume := range luksVolumes {
+		keyfilePath := "/var/lib/incus-os/recovery." + name + ".key"
+
+		// Keep track of the existing recovery keyslots.
+		oldKeyslots, err := util.GetLUKSTokenKeyslots(ctx, volume, "systemd-recovery")
+		if err != nil {
+			return nil, err
+		}
+
+		// Enroll a new recovery password.
+		recoveryPassword, err := subprocess.RunCommandContext(ctx, "systemd-cryptenroll", "--unlock-key-file="+keyfilePath, "--recovery-key", volume)
+		if err != nil {
+			return nil, err
+		}
+
+		recoveryPassword = strings.TrimSuffix(recoveryPassword, "\n")
+
+		// Replace the recovery password on disk.
+		err = os.WriteFile(keyfilePath+".new", []byte(recoveryPassword), 0o400)
+		if err != nil {
+			return nil, err
+		}
+
+		err = os.Rename(keyfilePath+".new", keyfilePath)
+		if err != nil {
+			return nil, err
+		}
+
+		// Only wipe the old recovery keyslots once the new recovery password is safely stored.
+		if len(oldKeyslots) > 0 {
+			_, err = subprocess.RunCommandContext(ctx, "systemd-cryptenroll", "--unlock-key-file="+keyfilePath, "--wipe-slot="+strings.Join(oldKeyslots, ","), volume)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		names = append(names, name)
+	}
+
+	// Re-seal the TPM keyslots.
+	err = secureboot.UpdateTPMBindings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(names)
+
+	return names, nil
+}
+
 // WipeAllRecoveryKeys will wipe all password key slots for the provided volume.
 func WipeAllRecoveryKeys(ctx context.Context, name string, volume string) error {
 	_, err := subprocess.RunCommandContext(ctx, "systemd-cryptenroll", "--unlock-key-file=/var/lib/incus-os/recovery."+name+".key", "--wipe-slot=password", volume)
@@ -0,0 +1,110 @@
+package systemd
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lxc/incus/v7/shared/subprocess"
+)
+
+// StartTemporaryDHCP configures all wired interfaces through DHCP, requesting the provided additional
+// DHCP options, and waits for at least one of them to get an IPv4 address.
+// The configuration is replaced on the next call to ApplyNetworkConfiguration.
+func StartTemporaryDHCP(ctx context.Context, requestOptions []int, timeout time.Duration) error {
+	options := make([]string, 0, len(requestOptions))
+	for _, option := range requestOptions {
+		options = append(options, strconv.Itoa(option))
+	}
+
+	content := `[Match]
+Type=ether
+
+[Network]
+DHCP=ipv4
+LinkLocalAddressing=no
+
+[DHCPv4]
+RequestOptions=` + strings.Join(options, " ") + `
+`
+
+	err := os.MkdirAll(SystemdNetworkConfigPath, 0o755)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(filepath.Join(SystemdNetworkConfigPath, "00-temporary-dhcp.network"), []byte(content), 0o644)
+	if err != nil {
+		return err
+	}
+
+	err = RestartUnit(ctx, "systemd-networkd")
+	if err != nil {
+		return err
+	}
+
+	_, err = subprocess.RunCommandContext(ctx, "/usr/lib/systemd/systemd-networkd-wait-online", "--any", "--ipv4", fmt.Sprintf("--timeout=%d", int(timeout.Seconds())))
+	if err != nil {
+		return errors.New("no interface was configured through DHCP")
+	}
+
+	return nil
+}
+
+// GetDHCPLeaseOption returns the value of a private DHCP option (224 to 254) from the current leases, if any.
+func GetDHCPLeaseOption(option int) ([]byte, error) {
+	entries, err := os.ReadDir(SystemdNetworkLeasesPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		f, err := os.Open(filepath.Join(SystemdNetworkLeasesPath, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := parseLeaseOption(f, option)
+
+		_ = f.Close()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if value != nil {
+			return value, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// parseLeaseOption extracts a private DHCP option from a systemd-networkd lease file.
+func parseLeaseOption(r io.Reader, option int) ([]byte, error) {
+	key := "OPTION_" + strconv.Itoa(option) + "="
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		value, found := strings.CutPrefix(scanner.Text(), key)
+		if !found {
+			continue
+		}
+
+		return hex.DecodeString(value)
+	}
+
+	return nil, scanner.Err()
+}
@@ -0,0 +1,30 @@
+package systemd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLeaseOption(t *testing.T) {
+	t.Parallel()
+
+	lease := `# This is private data. Do not parse.
+ADDRESS=10.0.0.10
+NETMASK=255.255.255.0
+OPTION_224=68747470733a2f2f7365656473
+OPTION_225=00
+`
+
+	value, err := parseLeaseOption(strings.NewReader(lease), 224)
+	require.NoError(t, err)
+	require.Equal(t, "https://seeds", string(value))
+
+	value, err = parseLeaseOption(strings.NewReader(lease), 226)
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	_, err = parseLeaseOption(strings.NewReader("OPTION_224=zz\n"), 224)
+	require.Error(t, err)
+}
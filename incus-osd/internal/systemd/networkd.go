@@ -2238,6 +2238,35 @@ func getMacForInterface(ctx context.Context, iface string) (string, error) {
 	return match[0][1], nil
 }
 
+// GetPredictableInterfaceName returns the predictable name udev gives to the physical interface
+// with the provided MAC address, as it would be named prior to IncusOS renaming it.
+func GetPredictableInterfaceName(ctx context.Context, mac string) (string, error) {
+	iface := "_p" + strings.ToLower(strings.ReplaceAll(mac, ":", ""))
+
+	output, err := subprocess.RunCommandContext(ctx, "udevadm", "info", "--query=property", "/sys/class/net/"+iface)
+	if err != nil {
+		return "", err
+	}
+
+	properties := map[string]string{}
+
+	for line := range strings.SplitSeq(output, "\n") {
+		key, value, found := strings.Cut(line, "=")
+		if found {
+			properties[key] = value
+		}
+	}
+
+	// Follow the default udev naming policy.
+	for _, key := range []string{"ID_NET_NAME_ONBOARD", "ID_NET_NAME_SLOT", "ID_NET_NAME_PATH"} {
+		if properties[key] != "" {
+			return properties[key], nil
+		}
+	}
+
+	return "", errors.New("no predictable name found for " + mac)
+}
+
 // Determine the maximum MTU supported by a given device. Because device names can change,
 // we use the underlying MAC when getting the maximum MTU.
 func getMaxMTUForMAC(ctx context.Context, mac string) (int, error) {
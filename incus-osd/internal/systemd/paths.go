@@ -13,6 +13,9 @@ var (
 	// SystemdNetworkConfigPath is the location for systemd network config files.
 	SystemdNetworkConfigPath = "/run/systemd/network/"
 
+	// SystemdNetworkLeasesPath is the location where systemd-networkd stores DHCP leases.
+	SystemdNetworkLeasesPath = "/run/systemd/netif/leases/"
+
 	// SystemdTimesyncConfigFile is the configuration file for systemd-timesyncd.
 	SystemdTimesyncConfigFile = "/run/systemd/timesyncd.conf"
 )
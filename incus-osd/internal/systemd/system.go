@@ -35,3 +35,13 @@ func SystemSuspend(ctx context.Context) error {
 
 	return nil
 }
+
+// SetOneshotBootEntry selects the boot loader entry to use on the next boot only.
+func SetOneshotBootEntry(ctx context.Context, entry string) error {
+	_, err := subprocess.RunCommandContext(ctx, "bootctl", "set-oneshot", entry)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
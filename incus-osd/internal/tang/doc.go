@@ -0,0 +1,2 @@
+// Package tang implements a minimal client for Tang network-bound encryption servers.
+package tang
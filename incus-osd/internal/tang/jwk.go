@@ -0,0 +1,208 @@
+package tang
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+)
+
+// JWK represents an elliptic curve JSON Web Key, as used by Tang.
+type JWK struct {
+	Kty    string   `json:"kty"`
+	Crv    string   `json:"crv"`
+	X      string   `json:"x"`
+	Y      string   `json:"y"`
+	Alg    string   `json:"alg,omitempty"`
+	KeyOps []string `json:"key_ops,omitempty"` //nolint:tagliatelle
+}
+
+// curves maps the supported JWK curve names to their Go implementation.
+var curves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+// hashes maps the supported JWK curve names to the hash used for ECDSA signatures.
+var hashes = map[string]crypto.Hash{
+	"P-256": crypto.SHA256,
+	"P-384": crypto.SHA384,
+	"P-521": crypto.SHA512,
+}
+
+// newJWK returns a JWK for the provided point on the curve.
+func newJWK(crv string, x *big.Int, y *big.Int) JWK {
+	size := coordinateSize(curves[crv])
+
+	return JWK{
+		Kty: "EC",
+		Crv: crv,
+		X:   base64.RawURLEncoding.EncodeToString(x.FillBytes(make([]byte, size))),
+		Y:   base64.RawURLEncoding.EncodeToString(y.FillBytes(make([]byte, size))),
+	}
+}
+
+// Point returns the curve and coordinates of the key, verifying that the point is on the curve.
+func (k *JWK) Point() (elliptic.Curve, *big.Int, *big.Int, error) {
+	if k.Kty != "EC" {
+		return nil, nil, nil, fmt.Errorf("unsupported key type '%s'", k.Kty)
+	}
+
+	curve, ok := curves[k.Crv]
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("unsupported curve '%s'", k.Crv)
+	}
+
+	rawX, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	rawY, err := base64.RawURLEncoding.DecodeString(k.Y)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	x := new(big.Int).SetBytes(rawX)
+	y := new(big.Int).SetBytes(rawY)
+
+	if !curve.IsOnCurve(x, y) { //nolint:staticcheck
+		return nil, nil, nil, errors.New("key isn't a valid point on the curve")
+	}
+
+	return curve, x, y, nil
+}
+
+// Thumbprints returns the base64url-encoded RFC 7638 SHA-256 and SHA-1 thumbprints of the key.
+func (k *JWK) Thumbprints() []string {
+	// Members must be in lexicographic order with no whitespace.
+	canonical := fmt.Sprintf(`{"crv":"%s","kty":"%s","x":"%s","y":"%s"}`, k.Crv, k.Kty, k.X, k.Y)
+
+	sum256 := sha256.Sum256([]byte(canonical))
+	sum1 := sha1.Sum([]byte(canonical)) //nolint:gosec
+
+	return []string{
+		base64.RawURLEncoding.EncodeToString(sum256[:]),
+		base64.RawURLEncoding.EncodeToString(sum1[:]),
+	}
+}
+
+// HasOp returns true if the key advertises the given operation.
+func (k *JWK) HasOp(op string) bool {
+	return slices.Contains(k.KeyOps, op)
+}
+
+// verify checks an ECDSA JWS signature made by this key over the signing input.
+func (k *JWK) verify(signingInput string, signature []byte) error {
+	curve, x, y, err := k.Point()
+	if err != nil {
+		return err
+	}
+
+	size := coordinateSize(curve)
+	if len(signature) != 2*size {
+		return errors.New("invalid signature length")
+	}
+
+	h := hashes[k.Crv].New()
+	_, _ = h.Write([]byte(signingInput))
+
+	pub := &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+	r := new(big.Int).SetBytes(signature[:size])
+	s := new(big.Int).SetBytes(signature[size:])
+
+	if !ecdsa.Verify(pub, h.Sum(nil), r, s) {
+		return errors.New("invalid signature")
+	}
+
+	return nil
+}
+
+// jws represents a JSON Web Signature, in either the general or flattened JSON serialization.
+type jws struct {
+	Payload    string         `json:"payload"`
+	Protected  string         `json:"protected,omitempty"`
+	Signature  string         `json:"signature,omitempty"`
+	Signatures []jwsSignature `json:"signatures,omitempty"`
+}
+
+type jwsSignature struct {
+	Protected string `json:"protected"`
+	Signature string `json:"signature"`
+}
+
+// jwkSet represents a JSON Web Key Set.
+type jwkSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// parseAdvertisement validates a Tang advertisement against the trusted signing key thumbprint
+// and returns the advertised keys.
+func parseAdvertisement(body []byte, thumbprint string) ([]JWK, error) {
+	adv := jws{}
+
+	err := json.Unmarshal(body, &adv)
+	if err != nil {
+		return nil, err
+	}
+
+	// Normalize the flattened serialization.
+	if adv.Signature != "" {
+		adv.Signatures = append(adv.Signatures, jwsSignature{Protected: adv.Protected, Signature: adv.Signature})
+	}
+
+	rawPayload, err := base64.RawURLEncoding.DecodeString(adv.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := jwkSet{}
+
+	err = json.Unmarshal(rawPayload, &keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the trusted signing key.
+	var signingKey *JWK
+
+	for i, key := range keys.Keys {
+		if key.HasOp("verify") && slices.Contains(key.Thumbprints(), thumbprint) {
+			signingKey = &keys.Keys[i]
+
+			break
+		}
+	}
+
+	if signingKey == nil {
+		return nil, errors.New("advertisement doesn't contain a signing key matching thumbprint '" + thumbprint + "'")
+	}
+
+	// Make sure the trusted key signed the advertisement.
+	for _, sig := range adv.Signatures {
+		rawSig, err := base64.RawURLEncoding.DecodeString(sig.Signature)
+		if err != nil {
+			continue
+		}
+
+		err = signingKey.verify(sig.Protected+"."+adv.Payload, rawSig)
+		if err == nil {
+			return keys.Keys, nil
+		}
+	}
+
+	return nil, errors.New("advertisement isn't signed by the trusted signing key")
+}
+
+// coordinateSize returns the number of bytes needed to encode a coordinate on the curve.
+func coordinateSize(curve elliptic.Curve) int {
+	return (curve.Params().BitSize + 7) / 8
+}
@@ -0,0 +1,16 @@
+package tang
+
+// LUKSTokenType is the LUKS2 token type used to store Tang bindings in a LUKS header.
+const LUKSTokenType = "incusos-tang"
+
+// LUKSToken is the LUKS2 token holding the Tang bindings for a LUKS volume.
+//
+// The mode is either "fallback", in which case the keyslots list the passphrase slot
+// holding the sealed secret, or "required", in which case the secret is used as the PIN
+// of the TPM2 keyslot.
+type LUKSToken struct {
+	Type     string    `json:"type"`
+	Keyslots []string  `json:"keyslots"`
+	Mode     string    `json:"incusos-mode"`     //nolint:tagliatelle
+	Bindings []Binding `json:"incusos-bindings"` //nolint:tagliatelle
+}
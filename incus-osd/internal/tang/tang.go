@@ -0,0 +1,282 @@
+package tang
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Binding holds the information needed to recover a sealed secret from a Tang server.
+//
+// The binding follows the McCallum-Relyea exchange used by Clevis: the client public key
+// is stored alongside the ciphertext, while the matching private key is discarded. The
+// encryption key can then only be recomputed with the help of the Tang server, without
+// the server ever learning the key itself.
+type Binding struct {
+	URL        string `json:"url"`
+	Thumbprint string `json:"thumbprint"`
+	KeyID      string `json:"kid"`
+	ServerKey  JWK    `json:"server_key"`
+	ClientKey  JWK    `json:"client_key"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// ecdhCurves maps the supported JWK curve names to their crypto/ecdh implementation.
+var ecdhCurves = map[string]ecdh.Curve{
+	"P-256": ecdh.P256(),
+	"P-384": ecdh.P384(),
+	"P-521": ecdh.P521(),
+}
+
+// httpClient is used for all requests to Tang servers.
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// GetAdvertisement fetches the advertisement from the Tang server and returns the advertised
+// keys once verified against the trusted signing key thumbprint.
+func GetAdvertisement(ctx context.Context, serverURL string, thumbprint string) ([]JWK, error) {
+	if thumbprint == "" {
+		return nil, errors.New("a trusted signing key thumbprint is required")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(serverURL, "/")+"/adv", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch Tang advertisement from '%s': %s", serverURL, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseAdvertisement(body, thumbprint)
+}
+
+// Seal encrypts the secret so it can only be decrypted while the Tang server is reachable.
+func Seal(ctx context.Context, serverURL string, thumbprint string, secret []byte) (*Binding, error) {
+	keys, err := GetAdvertisement(ctx, serverURL, thumbprint)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find an exchange key.
+	var serverKey *JWK
+
+	for i, key := range keys {
+		if key.Alg == "ECMR" && key.HasOp("deriveKey") {
+			serverKey = &keys[i]
+
+			break
+		}
+	}
+
+	if serverKey == nil {
+		return nil, errors.New("advertisement doesn't contain an exchange key")
+	}
+
+	curve, sx, sy, err := serverKey.Point()
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate the client key and compute the shared point.
+	c, cx, cy, err := generateKey(serverKey.Crv)
+	if err != nil {
+		return nil, err
+	}
+
+	kx, _ := curve.ScalarMult(sx, sy, c) //nolint:staticcheck
+
+	// Encrypt the secret.
+	aead, err := newAEAD(curve, kx)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Binding{
+		URL:        serverURL,
+		Thumbprint: thumbprint,
+		KeyID:      serverKey.Thumbprints()[0],
+		ServerKey:  *serverKey,
+		ClientKey:  newJWK(serverKey.Crv, cx, cy),
+		Nonce:      base64.RawURLEncoding.EncodeToString(nonce),
+		Ciphertext: base64.RawURLEncoding.EncodeToString(aead.Seal(nil, nonce, secret, []byte(serverURL))),
+	}, nil
+}
+
+// Recover decrypts the secret of the provided binding with the help of its Tang server.
+func Recover(ctx context.Context, b *Binding) ([]byte, error) {
+	curve, sx, sy, err := b.ServerKey.Point()
+	if err != nil {
+		return nil, err
+	}
+
+	_, cx, cy, err := b.ClientKey.Point()
+	if err != nil {
+		return nil, err
+	}
+
+	if b.ClientKey.Crv != b.ServerKey.Crv {
+		return nil, errors.New("mismatched client and server key curves")
+	}
+
+	// Blind the client key with an ephemeral key before sending it to the server.
+	e, ex, ey, err := generateKey(b.ServerKey.Crv)
+	if err != nil {
+		return nil, err
+	}
+
+	xx, xy := curve.Add(cx, cy, ex, ey) //nolint:staticcheck
+
+	request := newJWK(b.ServerKey.Crv, xx, xy)
+	request.Alg = "ECMR"
+	request.KeyOps = []string{"deriveKey"}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(b.URL, "/")+"/rec/"+b.KeyID, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/jwk+json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to perform Tang recovery with '%s': %s", b.URL, resp.Status)
+	}
+
+	response := JWK{}
+
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		return nil, err
+	}
+
+	yCurve, yx, yy, err := response.Point()
+	if err != nil {
+		return nil, err
+	}
+
+	if yCurve != curve {
+		return nil, errors.New("server responded with a key on the wrong curve")
+	}
+
+	// Remove the blinding factor: K = Y - e*S.
+	zx, zy := curve.ScalarMult(sx, sy, e) //nolint:staticcheck
+	zy = new(big.Int).Sub(curve.Params().P, zy)
+
+	kx, _ := curve.Add(yx, yy, zx, zy) //nolint:staticcheck
+
+	// Decrypt the secret.
+	aead, err := newAEAD(curve, kx)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := base64.RawURLEncoding.DecodeString(b.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := base64.RawURLEncoding.DecodeString(b.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Open(nil, nonce, ciphertext, []byte(b.URL))
+}
+
+// RecoverAny attempts recovery using each binding in turn, returning the first secret recovered.
+func RecoverAny(ctx context.Context, bindings []Binding) ([]byte, error) {
+	errs := []error{}
+
+	for i := range bindings {
+		secret, err := Recover(ctx, &bindings[i])
+		if err == nil {
+			return secret, nil
+		}
+
+		errs = append(errs, err)
+	}
+
+	if len(errs) == 0 {
+		return nil, errors.New("no Tang bindings available")
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// generateKey returns a new random scalar and the corresponding public point.
+func generateKey(crv string) ([]byte, *big.Int, *big.Int, error) {
+	curve, ok := ecdhCurves[crv]
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("unsupported curve '%s'", crv)
+	}
+
+	key, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// The public key is encoded as an uncompressed point (0x04 || X || Y).
+	pub := key.PublicKey().Bytes()
+	size := (len(pub) - 1) / 2
+
+	return key.Bytes(), new(big.Int).SetBytes(pub[1 : 1+size]), new(big.Int).SetBytes(pub[1+size:]), nil
+}
+
+// newAEAD derives an AES-256-GCM cipher from the x coordinate of the shared point.
+func newAEAD(curve elliptic.Curve, x *big.Int) (cipher.AEAD, error) {
+	key, err := hkdf.Key(sha256.New, x.FillBytes(make([]byte, coordinateSize(curve))), nil, "incus-os tang", 32)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,227 @@
+package tang_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/internal/tang"
+)
+
+// tangServer is a minimal stand-in for a Tang server.
+type tangServer struct {
+	signingKey  *ecdsa.PrivateKey
+	exchangeKey *ecdsa.PrivateKey
+	available   atomic.Bool
+}
+
+func newTangServer(t *testing.T) (*tangServer, *httptest.Server) {
+	t.Helper()
+
+	signingKey, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	require.NoError(t, err)
+
+	exchangeKey, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	require.NoError(t, err)
+
+	ts := &tangServer{signingKey: signingKey, exchangeKey: exchangeKey}
+	ts.available.Store(true)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/adv", ts.advertise)
+	mux.HandleFunc("/rec/{kid}", ts.recover)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return ts, server
+}
+
+func toJWK(key *ecdsa.PublicKey, alg string, ops ...string) tang.JWK {
+	return tang.JWK{
+		Kty:    "EC",
+		Crv:    "P-521",
+		X:      base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 66))), //nolint:staticcheck
+		Y:      base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 66))), //nolint:staticcheck
+		Alg:    alg,
+		KeyOps: ops,
+	}
+}
+
+func (ts *tangServer) thumbprint() string {
+	jwk := toJWK(&ts.signingKey.PublicKey, "ES512", "sign", "verify")
+
+	return jwk.Thumbprints()[0]
+}
+
+func (ts *tangServer) advertise(w http.ResponseWriter, _ *http.Request) {
+	keys := map[string][]tang.JWK{
+		"keys": {
+			toJWK(&ts.signingKey.PublicKey, "ES512", "sign", "verify"),
+			toJWK(&ts.exchangeKey.PublicKey, "ECMR", "deriveKey"),
+		},
+	}
+
+	rawKeys, _ := json.Marshal(keys)
+	payload := base64.RawURLEncoding.EncodeToString(rawKeys)
+	protected := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES512","cty":"jwk-set+json"}`))
+
+	h := sha512.Sum512([]byte(protected + "." + payload))
+
+	r, s, err := ecdsa.Sign(rand.Reader, ts.signingKey, h[:])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	signature := append(r.FillBytes(make([]byte, 66)), s.FillBytes(make([]byte, 66))...)
+
+	w.Header().Set("Content-Type", "application/jose+json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"payload": payload,
+		"signatures": []map[string]string{
+			{
+				"protected": protected,
+				"signature": base64.RawURLEncoding.EncodeToString(signature),
+			},
+		},
+	})
+}
+
+func (ts *tangServer) recover(w http.ResponseWriter, r *http.Request) {
+	if !ts.available.Load() {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+
+		return
+	}
+
+	exchange := toJWK(&ts.exchangeKey.PublicKey, "ECMR", "deriveKey")
+	if r.PathValue("kid") != exchange.Thumbprints()[0] {
+		http.Error(w, "unknown key", http.StatusNotFound)
+
+		return
+	}
+
+	request := tang.JWK{}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	curve, x, y, err := request.Point()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	rx, ry := curve.ScalarMult(x, y, ts.exchangeKey.D.Bytes()) //nolint:staticcheck
+
+	response := toJWK(&ecdsa.PublicKey{Curve: curve, X: rx, Y: ry}, "ECMR", "deriveKey")
+
+	w.Header().Set("Content-Type", "application/jwk+json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func TestSealRecover(t *testing.T) {
+	t.Parallel()
+
+	ts, server := newTangServer(t)
+
+	binding, err := tang.Seal(t.Context(), server.URL, ts.thumbprint(), []byte("super secret"))
+	require.NoError(t, err)
+	require.NotContains(t, binding.Ciphertext, "super secret")
+
+	// Round-trip the binding through JSON, as it would be when stored in a LUKS token.
+	rawBinding, err := json.Marshal(binding)
+	require.NoError(t, err)
+
+	storedBinding := tang.Binding{}
+
+	err = json.Unmarshal(rawBinding, &storedBinding)
+	require.NoError(t, err)
+
+	secret, err := tang.Recover(t.Context(), &storedBinding)
+	require.NoError(t, err)
+	require.Equal(t, []byte("super secret"), secret)
+
+	// Recovery must fail while the server is unavailable.
+	ts.available.Store(false)
+
+	_, err = tang.Recover(t.Context(), &storedBinding)
+	require.Error(t, err)
+}
+
+func TestSealUntrustedThumbprint(t *testing.T) {
+	t.Parallel()
+
+	_, server := newTangServer(t)
+
+	_, err := tang.Seal(t.Context(), server.URL, "", []byte("secret"))
+	require.Error(t, err)
+
+	_, err = tang.Seal(t.Context(), server.URL, "bm90LWEtdmFsaWQtdGh1bWJwcmludA", []byte("secret"))
+	require.ErrorContains(t, err, "doesn't contain a signing key matching thumbprint")
+}
+
+func TestRecoverAny(t *testing.T) {
+	t.Parallel()
+
+	ts1, server1 := newTangServer(t)
+	ts2, server2 := newTangServer(t)
+
+	binding1, err := tang.Seal(t.Context(), server1.URL, ts1.thumbprint(), []byte("secret"))
+	require.NoError(t, err)
+
+	binding2, err := tang.Seal(t.Context(), server2.URL, ts2.thumbprint(), []byte("secret"))
+	require.NoError(t, err)
+
+	// Any single reachable server is enough to recover the secret.
+	ts1.available.Store(false)
+
+	secret, err := tang.RecoverAny(t.Context(), []tang.Binding{*binding1, *binding2})
+	require.NoError(t, err)
+	require.Equal(t, []byte("secret"), secret)
+
+	ts2.available.Store(false)
+
+	_, err = tang.RecoverAny(t.Context(), []tang.Binding{*binding1, *binding2})
+	require.Error(t, err)
+
+	_, err = tang.RecoverAny(t.Context(), nil)
+	require.Error(t, err)
+}
+
+func TestThumbprints(t *testing.T) {
+	t.Parallel()
+
+	key := tang.JWK{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(big.NewInt(1).FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(big.NewInt(2).FillBytes(make([]byte, 32))),
+		Alg: "ES256",
+	}
+
+	thumbprints := key.Thumbprints()
+	require.Len(t, thumbprints, 2)
+
+	// Optional members don't change the thumbprint.
+	key.Alg = ""
+	key.KeyOps = []string{"verify"}
+	require.Equal(t, thumbprints, key.Thumbprints())
+}
@@ -0,0 +1,564 @@
+package tui
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"golang.org/x/sys/unix"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/history"
+	"github.com/lxc/incus-os/incus-osd/internal/nftables"
+	"github.com/lxc/incus-os/incus-osd/internal/providers"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+	"github.com/lxc/incus-os/incus-osd/internal/util"
+)
+
+// recoveryConsoleKey is the key to press on the console to open the recovery console.
+const recoveryConsoleKey = tcell.KeyF12
+
+// recoveryConsoleActor identifies changes made from the recovery console in the configuration history.
+const recoveryConsoleActor = "recovery console"
+
+// recoveryPort holds a physical network port which can be configured from the recovery console.
+type recoveryPort struct {
+	name   string
+	hwaddr string
+	link   bool
+}
+
+// recoveryCertificate holds a client certificate found on removable media.
+type recoveryCertificate struct {
+	source      string
+	subject     string
+	fingerprint string
+	pem         string
+}
+
+// handleInput opens the recovery console when the recovery console key is pressed.
+func (t *TUI) handleInput(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() != recoveryConsoleKey || t.state.ShouldPerformInstall || t.recoveryActive.Load() {
+		return event
+	}
+
+	t.recoveryActive.Store(true)
+	t.showRecoveryUnlock("")
+
+	return nil
+}
+
+// showRecoveryPage displays the given primitive as the current recovery console page.
+func (t *TUI) showRecoveryPage(p tview.Primitive, width int, height int) {
+	t.pages.AddPage("recovery", centered(p, width, height), true, true)
+	t.app.SetFocus(p)
+}
+
+// closeRecoveryConsole closes the recovery console, requiring the recovery key to open it again.
+func (t *TUI) closeRecoveryConsole() {
+	t.pages.RemovePage("recovery")
+	t.app.SetFocus(t.pages)
+	t.recoveryActive.Store(false)
+
+	// Show any modal hidden by the recovery console.
+	go t.quickDraw()
+}
+
+// showRecoveryMessage displays a message, then returns to the recovery menu.
+func (t *TUI) showRecoveryMessage(msg string) {
+	modal := tview.NewModal().
+		SetText(msg).
+		AddButtons([]string{"OK"}).
+		SetDoneFunc(func(_ int, _ string) {
+			t.showRecoveryMenu()
+		})
+
+	t.pages.AddPage("recovery", modal, true, true)
+	t.app.SetFocus(modal)
+}
+
+// runRecoveryAction runs a potentially slow recovery action in the background, then displays its result.
+func (t *TUI) runRecoveryAction(title string, action func(ctx context.Context) (string, error)) {
+	t.pages.AddPage("recovery", tview.NewModal().SetText(title+"..."), true, true)
+
+	go func() {
+		msg, err := action(context.Background())
+		if err != nil {
+			slog.Error("Recovery console action failed", "action", title, "err", err)
+
+			msg = "Error: " + err.Error()
+		}
+
+		t.app.QueueUpdateDraw(func() {
+			t.showRecoveryMessage(msg)
+		})
+	}()
+}
+
+// showRecoveryUnlock prompts for one of the system's encryption recovery keys.
+func (t *TUI) showRecoveryUnlock(errMsg string) {
+	form := tview.NewForm()
+	form.AddPasswordField("Recovery key", "", 0, '*', nil)
+	form.AddButton("Unlock", func() {
+		key, _ := form.GetFormItemByLabel("Recovery key").(*tview.InputField)
+
+		if !t.state.IsEncryptionRecoveryKey(strings.TrimSpace(key.GetText())) {
+			slog.Warn("Invalid recovery key entered on the recovery console")
+
+			// Slow down any attempt at guessing the recovery key.
+			t.pages.AddPage("recovery", tview.NewModal().SetText("Verifying recovery key..."), true, true)
+
+			go func() {
+				time.Sleep(3 * time.Second)
+
+				t.app.QueueUpdateDraw(func() {
+					t.showRecoveryUnlock("Invalid recovery key")
+				})
+			}()
+
+			return
+		}
+
+		slog.Info("Recovery console unlocked")
+
+		t.showRecoveryMenu()
+	})
+	form.AddButton("Cancel", t.closeRecoveryConsole)
+	form.SetCancelFunc(t.closeRecoveryConsole)
+
+	title := " Recovery console "
+	if errMsg != "" {
+		title = " Recovery console: " + errMsg + " "
+	}
+
+	form.SetBorder(true).SetTitle(title)
+
+	t.showRecoveryPage(form, 100, 7)
+}
+
+// showRecoveryMenu displays the list of recovery actions.
+func (t *TUI) showRecoveryMenu() {
+	list := tview.NewList().
+		ShowSecondaryText(false).
+		AddItem("Reset network to DHCP on a port", "", '1', t.showRecoveryNetwork).
+		AddItem("Add trusted client certificate from USB", "", '2', t.showRecoveryCertificates).
+		AddItem("Enable fallback listener", "", '3', func() {
+			t.state.FallbackListenerMutex.RLock()
+			active := t.state.System.FallbackListener.State.Active
+			t.state.FallbackListenerMutex.RUnlock()
+
+			if active {
+				t.showRecoveryMessage("The fallback HTTPS listener is already running.")
+
+				return
+			}
+
+			select {
+			case t.state.TriggerFallbackListener <- true:
+				t.showRecoveryMessage("The fallback HTTPS listener is being started.")
+			default:
+				t.showRecoveryMessage("The fallback HTTPS listener can't be started yet, try again later.")
+			}
+		}).
+		AddItem("Reboot into previous release", "", '4', t.showRecoveryReboot).
+		AddItem("Show recovery key status", "", '5', func() {
+			t.showRecoveryMessage(t.getRecoveryKeyStatus())
+		}).
+		AddItem("Exit", "", 'q', t.closeRecoveryConsole)
+
+	list.SetDoneFunc(t.closeRecoveryConsole)
+	list.SetBorder(true).SetTitle(" Recovery console ")
+
+	t.showRecoveryPage(list, 60, 8)
+}
+
+// showRecoveryNetwork lists the physical network ports, replacing the network configuration with DHCP on the
+// selected one.
+func (t *TUI) showRecoveryNetwork() {
+	ports, err := getRecoveryPorts(context.Background())
+	if err != nil {
+		t.showRecoveryMessage("Error: " + err.Error())
+
+		return
+	}
+
+	if len(ports) == 0 {
+		t.showRecoveryMessage("No physical network port found.")
+
+		return
+	}
+
+	list := tview.NewList().ShowSecondaryText(false)
+
+	for _, port := range ports {
+		link := "no link"
+		if port.link {
+			link = "link up"
+		}
+
+		list.AddItem(fmt.Sprintf("%s (%s, %s)", port.name, port.hwaddr, link), "", 0, func() {
+			t.runRecoveryAction("Configuring DHCP on "+port.name, func(ctx context.Context) (string, error) {
+				return t.resetNetwork(ctx, port)
+			})
+		})
+	}
+
+	list.SetDoneFunc(t.showRecoveryMenu)
+	list.SetBorder(true).SetTitle(" Select the port to configure with DHCP ")
+
+	t.showRecoveryPage(list, 80, len(ports)+2)
+}
+
+// resetNetwork replaces the network configuration with DHCP and SLAAC on a single port.
+func (t *TUI) resetNetwork(ctx context.Context, port recoveryPort) (string, error) {
+	priorConfig := t.state.System.Network.Config
+
+	networkCfg := &api.SystemNetworkConfig{
+		Interfaces: []api.SystemNetworkInterface{{
+			Name:              port.name,
+			Hwaddr:            port.hwaddr,
+			Addresses:         []string{"dhcp4", "slaac"},
+			RequiredForOnline: "no",
+		}},
+	}
+
+	// Keep the configured time zone.
+	if priorConfig != nil {
+		networkCfg.Time = priorConfig.Time
+	}
+
+	err := nftables.ApplyHwaddrFilters(ctx, networkCfg)
+	if err != nil {
+		return "", err
+	}
+
+	err = systemd.ApplyNetworkConfiguration(ctx, t.state, networkCfg, 30*time.Second, true, providers.Notify, false)
+	if err != nil {
+		return "", err
+	}
+
+	err = history.Record(t.state, recoveryConsoleActor, history.SectionNetwork, priorConfig, t.state.System.Network.Config)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to record configuration history", "section", history.SectionNetwork, "err", err.Error())
+	}
+
+	err = t.state.Save()
+	if err != nil {
+		return "", err
+	}
+
+	addrs, _ := systemd.GetIPAddresses(ctx, port.name)
+
+	return "Network reset to DHCP on " + port.name + ".\n\nAddresses: " + strings.Join(addrs, ", "), nil
+}
+
+// showRecoveryCertificates lists the client certificates found on removable media, adding the selected one
+// to the fallback listener's trusted client certificates.
+func (t *TUI) showRecoveryCertificates() {
+	certs, err := getRecoveryCertificates(context.Background())
+	if err != nil {
+		t.showRecoveryMessage("Error: " + err.Error())
+
+		return
+	}
+
+	if len(certs) == 0 {
+		t.showRecoveryMessage("No certificate (.crt or .pem file) found on removable media.")
+
+		return
+	}
+
+	list := tview.NewList()
+
+	for _, cert := range certs {
+		list.AddItem(cert.source, cert.subject+" ("+cert.fingerprint[:12]+")", 0, func() {
+			t.showRecoveryMessage(t.addTrustedCertificate(cert))
+		})
+	}
+
+	list.SetDoneFunc(t.showRecoveryMenu)
+	list.SetBorder(true).SetTitle(" Select the certificate to trust ")
+
+	t.showRecoveryPage(list, 80, 2*len(certs)+2)
+}
+
+// addTrustedCertificate adds a client certificate to the fallback listener's trusted client certificates.
+func (t *TUI) addTrustedCertificate(cert recoveryCertificate) string {
+	t.state.FallbackListenerMutex.Lock()
+	defer t.state.FallbackListenerMutex.Unlock()
+
+	if slices.Contains(t.state.System.FallbackListener.Config.TrustedClientCertificates, cert.pem) {
+		return "The certificate is already trusted."
+	}
+
+	t.state.System.FallbackListener.Config.TrustedClientCertificates = append(t.state.System.FallbackListener.Config.TrustedClientCertificates, cert.pem)
+
+	err := t.state.Save()
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+
+	slog.Info("Trusted client certificate added from the recovery console", "fingerprint", cert.fingerprint)
+
+	return "The certificate is now trusted by the fallback listener."
+}
+
+// showRecoveryReboot asks for confirmation, then reboots the system into the previous release.
+func (t *TUI) showRecoveryReboot() {
+	ukis, err := util.GetUKIVersions()
+	if err != nil {
+		t.showRecoveryMessage("Error: " + err.Error())
+
+		return
+	}
+
+	// The other image is either a previous release or a pending update.
+	if ukis.OtherVersion == "" || ukis.OtherVersion > ukis.CurrentVersion {
+		t.showRecoveryMessage("No previous release is available.")
+
+		return
+	}
+
+	modal := tview.NewModal().
+		SetText("Reboot into release " + ukis.OtherVersion + "?\n\nThe current release " + ukis.CurrentVersion + " will be used again on the following boot.").
+		AddButtons([]string{"Reboot", "Cancel"}).
+		SetDoneFunc(func(buttonIndex int, _ string) {
+			if buttonIndex != 0 {
+				t.showRecoveryMenu()
+
+				return
+			}
+
+			t.runRecoveryAction("Rebooting into release "+ukis.OtherVersion, func(ctx context.Context) (string, error) {
+				err := systemd.SetOneshotBootEntry(ctx, filepath.Base(ukis.OtherFilepath))
+				if err != nil {
+					return "", err
+				}
+
+				slog.InfoContext(ctx, "Rebooting into the previous release from the recovery console", "version", ukis.OtherVersion)
+
+				// Prefer a clean shutdown once the daemon has fully started.
+				if t.state.TriggerReboot == nil {
+					return "Rebooting...", systemd.SystemReboot(ctx)
+				}
+
+				t.state.TriggerReboot <- true
+
+				return "Rebooting...", nil
+			})
+		})
+
+	t.pages.AddPage("recovery", modal, true, true)
+	t.app.SetFocus(modal)
+}
+
+// getRecoveryKeyStatus returns a description of the encryption recovery keys and encrypted volumes.
+func (t *TUI) getRecoveryKeyStatus() string {
+	security := t.state.System.Security
+
+	retrieved := "no"
+	if security.State.EncryptionRecoveryKeysRetrieved {
+		retrieved = "yes"
+	}
+
+	lines := []string{
+		fmt.Sprintf("Encryption recovery keys: %d", len(security.Config.EncryptionRecoveryKeys)),
+		"Retrieved since last change: " + retrieved,
+	}
+
+	for _, volume := range security.State.EncryptedVolumes {
+		lines = append(lines, "Volume "+volume.Volume+": "+volume.State)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// getRecoveryPorts returns the physical network ports.
+func getRecoveryPorts(ctx context.Context) ([]recoveryPort, error) {
+	entries, err := os.ReadDir("/sys/class/net/")
+	if err != nil {
+		return nil, err
+	}
+
+	ports := []recoveryPort{}
+
+	for _, entry := range entries {
+		// Only physical devices have an underlying device.
+		_, err := os.Stat(filepath.Join("/sys/class/net/", entry.Name(), "device"))
+		if err != nil {
+			continue
+		}
+
+		hwaddr, err := os.ReadFile(filepath.Join("/sys/class/net/", entry.Name(), "address"))
+		if err != nil {
+			continue
+		}
+
+		port := recoveryPort{
+			name:   entry.Name(),
+			hwaddr: strings.TrimSpace(string(hwaddr)),
+		}
+
+		// Physical devices configured by IncusOS are renamed after their MAC address.
+		if strings.HasPrefix(port.name, "_p") {
+			name, err := systemd.GetPredictableInterfaceName(ctx, port.hwaddr)
+			if err != nil {
+				return nil, err
+			}
+
+			port.name = name
+		}
+
+		carrier, err := os.ReadFile(filepath.Join("/sys/class/net/", entry.Name(), "carrier"))
+		if err == nil && strings.TrimSpace(string(carrier)) == "1" {
+			port.link = true
+		}
+
+		ports = append(ports, port)
+	}
+
+	return ports, nil
+}
+
+// getRecoveryCertificates returns the certificates found at the root of any removable media.
+func getRecoveryCertificates(ctx context.Context) ([]recoveryCertificate, error) {
+	disks, err := os.ReadDir("/sys/block/")
+	if err != nil {
+		return nil, err
+	}
+
+	certs := []recoveryCertificate{}
+
+	for _, disk := range disks {
+		removable, err := os.ReadFile(filepath.Join("/sys/block/", disk.Name(), "removable"))
+		if err != nil {
+			continue
+		}
+
+		devicePath, _ := filepath.EvalSymlinks(filepath.Join("/sys/block/", disk.Name()))
+		if strings.TrimSpace(string(removable)) != "1" && !strings.Contains(devicePath, "/usb") {
+			continue
+		}
+
+		// Look at each partition, or the whole disk if not partitioned.
+		devices := []string{}
+
+		entries, err := os.ReadDir(filepath.Join("/sys/block/", disk.Name()))
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name(), disk.Name()) {
+				devices = append(devices, entry.Name())
+			}
+		}
+
+		if len(devices) == 0 {
+			devices = append(devices, disk.Name())
+		}
+
+		for _, device := range devices {
+			deviceCerts, err := getDeviceCertificates(filepath.Join("/dev/", device))
+			if err != nil {
+				slog.DebugContext(ctx, "Unable to read certificates from removable media", "device", device, "err", err)
+
+				continue
+			}
+
+			certs = append(certs, deviceCerts...)
+		}
+	}
+
+	return certs, nil
+}
+
+// getDeviceCertificates mounts the device read-only and returns the certificates found at its root.
+func getDeviceCertificates(device string) ([]recoveryCertificate, error) {
+	mountDir, err := os.MkdirTemp("", "incus-os-recovery-console")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(mountDir)
+
+	mounted := false
+
+	for _, fsType := range []string{"vfat", "iso9660", "ext4"} {
+		err = unix.Mount(device, mountDir, fsType, unix.MS_RDONLY, "")
+		if err == nil {
+			mounted = true
+
+			break
+		}
+	}
+
+	if !mounted {
+		return nil, errors.New("unable to mount " + device)
+	}
+	defer unix.Unmount(mountDir, 0)
+
+	entries, err := os.ReadDir(mountDir)
+	if err != nil {
+		return nil, err
+	}
+
+	certs := []recoveryCertificate{}
+
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if !entry.Type().IsRegular() || (ext != ".crt" && ext != ".pem") {
+			continue
+		}
+
+		// #nosec G304
+		content, err := os.ReadFile(filepath.Join(mountDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+
+		block, _ := pem.Decode(content)
+		if block == nil || block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+
+		fingerprint := sha256.Sum256(cert.Raw)
+
+		certs = append(certs, recoveryCertificate{
+			source:      filepath.Base(device) + ": " + entry.Name(),
+			subject:     cert.Subject.String(),
+			fingerprint: hex.EncodeToString(fingerprint[:]),
+			pem:         string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+		})
+	}
+
+	return certs, nil
+}
+
+// centered returns a new primitive which puts the provided primitive in the center and
+// sets its size to the given width and height.
+func centered(p tview.Primitive, width int, height int) tview.Primitive {
+	return tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(p, height, 1, true).
+			AddItem(nil, 0, 1, false), width, 1, true).
+		AddItem(nil, 0, 1, false)
+}
@@ -9,6 +9,7 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
@@ -18,6 +19,7 @@ import (
 	"github.com/rivo/tview"
 
 	"github.com/lxc/incus-os/incus-osd/internal/applications"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
 	"github.com/lxc/incus-os/incus-osd/internal/systemd"
 )
@@ -41,6 +43,8 @@ type TUI struct {
 
 	state           *state.State
 	systemResources *api.Resources
+
+	recoveryActive atomic.Bool
 }
 
 // GetTUI returns a singleton TUI application that will show basic information and recent
@@ -115,6 +119,9 @@ func GetTUI(s *state.State) (*TUI, error) {
 	// Define the TUI application.
 	singletonTUI.app = tview.NewApplication().SetScreen(singletonTUI.screen).SetRoot(singletonTUI.pages, true)
 
+	// Allow opening the recovery console from the system's console.
+	singletonTUI.app.SetInputCapture(singletonTUI.handleInput)
+
 	return singletonTUI, nil
 }
 
@@ -139,6 +146,18 @@ func (t *TUI) Write(p []byte) (int, error) {
 	return fmt.Fprint(os.Stdout, s)
 }
 
+// Print displays the given text as-is, without interpreting any coloring tags, and writes it to stdout for the journal.
+func (t *TUI) Print(s string) error {
+	_, err := fmt.Fprint(t.textView, tview.Escape(s))
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprint(os.Stdout, s)
+
+	return err
+}
+
 // Run is a wrapper to start the underlying TUI application.
 func (t *TUI) Run() error {
 	// Setup a gofunc to cycle through modal dialogs, one per second.
@@ -244,16 +263,9 @@ func (t *TUI) quickDraw() {
 // renderModal displays a centered popup dialog. Optionally, if progress is greater than zero,
 // renders a progress bar at the bottom.
 func (t *TUI) renderModal(title string, msg string, progress float64) {
-	// Returns a new primitive which puts the provided primitive in the center and
-	// sets its size to the given width and height.
-	modal := func(p tview.Primitive, width, height int) tview.Primitive {
-		return tview.NewFlex().
-			AddItem(nil, 0, 1, false).
-			AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
-				AddItem(nil, 0, 1, false).
-				AddItem(p, height, 1, true).
-				AddItem(nil, 0, 1, false), width, 1, true).
-			AddItem(nil, 0, 1, false)
+	// Don't cover the recovery console, which would also take its focus.
+	if t.recoveryActive.Load() {
+		return
 	}
 
 	// Calculate width and height for modal dialog.
@@ -285,7 +297,7 @@ func (t *TUI) renderModal(title string, msg string, progress float64) {
 
 	grid.SetTitle(" " + title + " ").SetBorder(true)
 
-	t.pages.AddPage("modal", modal(grid, modalWidth, modalHeight), true, true)
+	t.pages.AddPage("modal", centered(grid, modalWidth, modalHeight), true, true)
 	t.app.Draw()
 }
 
@@ -321,6 +333,10 @@ func (t *TUI) redrawScreen() {
 			t.frame.AddText("WARNING: Degraded security state: incus-agent has been fully enabled", true, tview.AlignCenter, tcell.ColorRed)
 		}
 
+		if secrets.Unavailable() != nil {
+			t.frame.AddText("WARNING: Stored secrets can't be decrypted and are read-only", true, tview.AlignCenter, tcell.ColorRed)
+		}
+
 		// Get list of applications from state.
 		apps, err := applications.GetInstalled(context.Background(), t.state)
 		if err != nil {
@@ -347,6 +363,8 @@ func (t *TUI) redrawScreen() {
 			t.frame.AddText(line, false, tview.AlignLeft, tcell.ColorWhite)
 		}
 
+		t.frame.AddText("[green]Recovery console:[white] press F12", false, tview.AlignRight, tcell.ColorWhite)
+
 		if !t.state.System.Security.State.EncryptionRecoveryKeysRetrieved {
 			t.frame.AddText("WARNING: Some encryption recovery keys have not been retrieved yet!", false, tview.AlignLeft, tcell.ColorRed)
 		}
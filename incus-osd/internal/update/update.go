@@ -543,6 +543,12 @@ func applyUpdate(ctx context.Context, s *state.State, t *tui.TUI, update provide
 			return "", err
 		}
 
+		// Copy the update to the boot mirror drive, if any. A failure only leaves the mirror out of sync until the next boot.
+		err = storage.SyncBootMirror(ctx)
+		if err != nil {
+			slog.WarnContext(ctx, "Unable to sync boot mirror drive: "+err.Error())
+		}
+
 		// Record the new release.
 		if !s.System.Update.Config.AutoReboot && !isStartupCheck {
 			// Mark the system as needing a reboot down the line.
@@ -1,13 +1,21 @@
 package util
 
 import (
+	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
+	"regexp"
+
+	"github.com/lxc/incus/v7/shared/subprocess"
 )
 
+// bootOptionRegex matches the name of a firmware boot option variable.
+var bootOptionRegex = regexp.MustCompile(`^Boot[0-9A-F]{4}$`)
+
 // ReadEFIVariable returns the current value, if any, of the specified EFI variable.
 func ReadEFIVariable(variableName string) ([]byte, error) {
 	// Determine which file to open.
@@ -48,8 +56,36 @@ func ReadEFIVariable(variableName string) ([]byte, error) {
 	return buf[4:], nil
 }
 
+// WriteEFIVariable sets the value of the specified EFI variable, as a non-volatile variable
+// accessible at runtime.
+func WriteEFIVariable(ctx context.Context, variableName string, value []byte) error {
+	filename, err := EfiVariableToFilename(variableName)
+	if err != nil {
+		return err
+	}
+
+	// Existing variables are marked as immutable by the kernel.
+	_, err = os.Stat(filename)
+	if err == nil {
+		_, err := subprocess.RunCommandContext(ctx, "chattr", "-i", filename)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The attributes and value must be written at once; https://docs.kernel.org/filesystems/efivarfs.html
+	buf := binary.LittleEndian.AppendUint32(nil, 0x7) // Non-volatile, boot service and runtime access.
+	buf = append(buf, value...)
+
+	return os.WriteFile(filename, buf, 0o600)
+}
+
 // EfiVariableToFilename maps an EFI variable name to its file under /sys/.
 func EfiVariableToFilename(variableName string) (string, error) {
+	if bootOptionRegex.MatchString(variableName) {
+		return "/sys/firmware/efi/efivars/" + variableName + "-8be4df61-93ca-11d2-aa0d-00e098032b8c", nil
+	}
+
 	switch variableName {
 	case "SecureBoot":
 		return "/sys/firmware/efi/efivars/SecureBoot-8be4df61-93ca-11d2-aa0d-00e098032b8c", nil
@@ -59,6 +95,10 @@ func EfiVariableToFilename(variableName string) (string, error) {
 		return "/sys/firmware/efi/efivars/DeployedMode-8be4df61-93ca-11d2-aa0d-00e098032b8c", nil
 	case "AuditMode":
 		return "/sys/firmware/efi/efivars/AuditMode-8be4df61-93ca-11d2-aa0d-00e098032b8c", nil
+	case "BootNext":
+		return "/sys/firmware/efi/efivars/BootNext-8be4df61-93ca-11d2-aa0d-00e098032b8c", nil
+	case "BootOrder":
+		return "/sys/firmware/efi/efivars/BootOrder-8be4df61-93ca-11d2-aa0d-00e098032b8c", nil
 	case "PK":
 		return "/sys/firmware/efi/efivars/PK-8be4df61-93ca-11d2-aa0d-00e098032b8c", nil
 	case "KEK":
@@ -67,6 +107,8 @@ func EfiVariableToFilename(variableName string) (string, error) {
 		return "/sys/firmware/efi/efivars/db-d719b2cb-3d3a-4596-a3bc-dad00e67656f", nil
 	case "dbx":
 		return "/sys/firmware/efi/efivars/dbx-d719b2cb-3d3a-4596-a3bc-dad00e67656f", nil
+	case "LoaderDevicePartUUID":
+		return "/sys/firmware/efi/efivars/LoaderDevicePartUUID-4a67b082-0a4c-41cf-b6c7-440b29bb8c4f", nil
 	case "LoaderEntrySelected":
 		return "/sys/firmware/efi/efivars/LoaderEntrySelected-4a67b082-0a4c-41cf-b6c7-440b29bb8c4f", nil
 	case "IncusOSInstallComplete":
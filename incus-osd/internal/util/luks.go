@@ -1,16 +1,27 @@
 package util
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/lxc/incus/v7/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/internal/tang"
 )
 
+type luksDumpTokensAndKeyslots struct {
+	Keyslots map[string]json.RawMessage `json:"keyslots"`
+	Tokens   map[string]json.RawMessage `json:"tokens"`
+}
+
 // GetLUKSVolumePartitions returns the underlying partitions that hold the root and swap LUKS volumes.
 // We can't just rely on /dev/disk/by-partlabel/root-ARCH, because as soon as an overlay is applied
 // that symlink is repointed to the newly mapped loop device.
@@ -58,3 +69,102 @@ func ResolveMapperSymlink(ctx context.Context, mappedDev string) (string, error)
 
 	return "/dev/mapper/" + nameRegex.FindStringSubmatch(output)[1], nil
 }
+
+// GetLUKSKeyslots returns the IDs of all keyslots currently in use on the LUKS volume.
+func GetLUKSKeyslots(ctx context.Context, volume string) ([]string, error) {
+	metadata, err := getLUKSMetadata(ctx, volume)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.Sorted(maps.Keys(metadata.Keyslots)), nil
+}
+
+// GetLUKSTokenKeyslots returns the IDs of the keyslots referenced by tokens of the given type.
+func GetLUKSTokenKeyslots(ctx context.Context, volume string, tokenType string) ([]string, error) {
+	metadata, err := getLUKSMetadata(ctx, volume)
+	if err != nil {
+		return nil, err
+	}
+
+	keyslots := []string{}
+
+	for _, rawToken := range metadata.Tokens {
+		token := struct {
+			Type     string   `json:"type"`
+			Keyslots []string `json:"keyslots"`
+		}{}
+
+		err := json.Unmarshal(rawToken, &token)
+		if err != nil {
+			return nil, err
+		}
+
+		if token.Type == tokenType {
+			keyslots = append(keyslots, token.Keyslots...)
+		}
+	}
+
+	slices.Sort(keyslots)
+
+	return keyslots, nil
+}
+
+// GetNetworkUnlockToken returns the Tang binding token of the LUKS volume along with its token ID.
+// If the volume isn't bound to any Tang server, a nil token is returned.
+func GetNetworkUnlockToken(ctx context.Context, volume string) (*tang.LUKSToken, string, error) {
+	metadata, err := getLUKSMetadata(ctx, volume)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for id, rawToken := range metadata.Tokens {
+		token := &tang.LUKSToken{}
+
+		err := json.Unmarshal(rawToken, token)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if token.Type == tang.LUKSTokenType {
+			return token, id, nil
+		}
+	}
+
+	return nil, "", nil
+}
+
+// AddNetworkUnlockToken imports the Tang binding token into the LUKS volume's header.
+func AddNetworkUnlockToken(ctx context.Context, volume string, token *tang.LUKSToken) error {
+	token.Type = tang.LUKSTokenType
+
+	rawToken, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	return subprocess.RunCommandWithFds(ctx, bytes.NewReader(rawToken), nil, "cryptsetup", "token", "import", volume)
+}
+
+// RemoveLUKSToken removes the token with the given ID from the LUKS volume's header.
+func RemoveLUKSToken(ctx context.Context, volume string, tokenID string) error {
+	_, err := subprocess.RunCommandContext(ctx, "cryptsetup", "token", "remove", "--token-id", tokenID, volume)
+
+	return err
+}
+
+func getLUKSMetadata(ctx context.Context, volume string) (*luksDumpTokensAndKeyslots, error) {
+	output, err := subprocess.RunCommandContext(ctx, "cryptsetup", "luksDump", "--dump-json-metadata", volume)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &luksDumpTokensAndKeyslots{}
+
+	err = json.Unmarshal([]byte(output), metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
@@ -1,6 +1,7 @@
 package zfs
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"errors"
@@ -20,9 +21,17 @@ import (
 	"github.com/lxc/incus-os/incus-osd/internal/scheduling"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
 	"github.com/lxc/incus-os/incus-osd/internal/storage"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
 	"github.com/lxc/incus-os/incus-osd/internal/util"
 )
 
+// localPoolEscrowPath is where the TPM-sealed key of a mirrored "local" pool is kept on the ESP, so it's
+// copied to the boot mirror drive and remains available if the drive holding the root partition fails.
+const localPoolEscrowPath = "/boot/local-pool.key.cred"
+
+// localPoolEscrowName is the name the escrowed "local" pool key is sealed under.
+const localPoolEscrowName = "incus-os-local-pool"
+
 const (
 	// PoolScrubJob represents the job to scrub all storage pools.
 	PoolScrubJob scheduling.JobName = "pool_scrub"
@@ -82,18 +91,99 @@ func LoadPools(ctx context.Context, s *state.State) error {
 			if err != nil {
 				return err
 			}
+
+			// Any key escrowed for a previous "local" pool no longer applies.
+			err = os.Remove(localPoolEscrowPath)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+
+			// If installed to mirrored boot drives, extend the new pool onto the second drive.
+			err = mirrorLocalPool(ctx)
+			if err != nil {
+				slog.WarnContext(ctx, "Unable to mirror storage pool 'local' onto the boot mirror drive: "+err.Error())
+			}
 		} else {
 			// We were able to import the existing "local" pool.
 			err := recoverLocalPool(ctx)
 			if err != nil {
 				return err
 			}
+
+			// Use the escrowed key, if any, rather than requiring the pool to be imported by hand.
+			err = restoreLocalPoolKey(ctx)
+			if err != nil {
+				slog.WarnContext(ctx, "Unable to import storage pool 'local' using its escrowed key: "+err.Error())
+			}
+		}
+	}
+
+	// If the "local" pool is mirrored onto the boot mirror drive, keep its key on the ESP.
+	if storage.PoolExists(ctx, "local") {
+		err := escrowLocalPoolKey(ctx)
+		if err != nil {
+			slog.WarnContext(ctx, "Unable to escrow the key of storage pool 'local': "+err.Error())
 		}
 	}
 
 	return nil
 }
 
+// escrowLocalPoolKey seals the key of the "local" pool to the TPM and stores it on the ESP, if the system
+// was installed to mirrored boot drives. The ESP is then copied to the boot mirror drive.
+func escrowLocalPoolKey(ctx context.Context) error {
+	mirrorDrive, err := storage.GetBootMirrorDrive(ctx)
+	if err != nil || mirrorDrive == "" {
+		return err
+	}
+
+	key, err := os.ReadFile("/var/lib/incus-os/zpool.local.key")
+	if err != nil {
+		return err
+	}
+
+	// Keep the escrowed key as long as it can be unsealed. It otherwise needs to be sealed again,
+	// such as after an update of the Secure Boot keys.
+	sealed, err := os.ReadFile(localPoolEscrowPath)
+	if err == nil {
+		escrowed, err := systemd.DecryptCredential(ctx, localPoolEscrowName, sealed)
+		if err == nil && bytes.Equal(escrowed, key) {
+			return nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	sealed, err = systemd.EncryptTPMCredential(ctx, localPoolEscrowName, key)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(localPoolEscrowPath, sealed, 0o600)
+}
+
+// restoreLocalPoolKey imports the "local" pool using the key escrowed on the ESP, if any. This happens
+// when booting from the boot mirror drive after the drive holding the root partition failed.
+func restoreLocalPoolKey(ctx context.Context) error {
+	sealed, err := os.ReadFile(localPoolEscrowPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	key, err := systemd.DecryptCredential(ctx, localPoolEscrowName, sealed)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Importing storage pool 'local' using its escrowed key")
+
+	return ImportExistingPool(ctx, "local", base64.StdEncoding.EncodeToString(key))
+}
+
 func recoverLocalPool(ctx context.Context) error {
 	poolConfig, err := storage.GetZpoolMembers(ctx, "local")
 	if err != nil {
@@ -145,6 +235,40 @@ func recoverLocalPool(ctx context.Context) error {
 	return err
 }
 
+// mirrorLocalPool converts a freshly created "local" pool into a mirror across the main system drive
+// and the boot mirror drive, if the system was installed to mirrored boot drives.
+func mirrorLocalPool(ctx context.Context) error {
+	mirrorDrive, err := storage.GetBootMirrorDrive(ctx)
+	if err != nil || mirrorDrive == "" {
+		return err
+	}
+
+	rootDev, err := storage.GetUnderlyingDevice()
+	if err != nil {
+		return err
+	}
+
+	actualrootDev, err := storage.DeviceToID(ctx, rootDev, false)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Mirroring storage pool 'local' onto boot mirror drive", "drive", mirrorDrive)
+
+	return UpdateZpool(ctx, api.SystemStoragePool{
+		Name:    "local",
+		Type:    "zfs-raid1",
+		Devices: []string{mirrorDrive, actualrootDev + "-part11"},
+	})
+}
+
+// HasKey returns whether the encryption key of the pool is saved locally, as done once the pool is imported.
+func HasKey(pool string) bool {
+	_, err := os.Stat("/var/lib/incus-os/zpool." + pool + ".key")
+
+	return err == nil
+}
+
 // Helper function to return a list of ZFS pools that have a corresponding known encryption key saved locally.
 func getPoolsWithKnownKeys() ([]string, error) {
 	files, err := os.ReadDir("/var/lib/incus-os/")
@@ -655,8 +779,30 @@ func convertPoolToMirror(ctx context.Context, currentConfig api.SystemStoragePoo
 // attempt to partition the second device in a similar fashion so the two underlying
 // devices are the same size.
 func partitionLocalPoolDevice(ctx context.Context, device string) (string, error) {
+	// Get the offset of the partition on the main system drive, which depends on the partition layout.
+	rootDev, err := storage.GetUnderlyingDevice()
+	if err != nil {
+		return "", err
+	}
+
+	firstSector, err := storage.GetPartitionFirstSector(ctx, rootDev, 11)
+	if err != nil {
+		return "", err
+	}
+
+	// If the partition layout was customized at install time, also match the partition's size.
+	layout, err := storage.GetPartitionLayout()
+	if err != nil {
+		return "", err
+	}
+
+	lastSector := ""
+	if layout != nil && layout.LocalDataSize > 0 {
+		lastSector = fmt.Sprintf("+%dK", layout.LocalDataSize/1024)
+	}
+
 	// Create the partition at the correct offset
-	_, err := subprocess.RunCommandContext(ctx, "sgdisk", "-n", "11:69826560:", device)
+	_, err = subprocess.RunCommandContext(ctx, "sgdisk", "-n", fmt.Sprintf("11:%d:%s", firstSector, lastSector), device)
 	if err != nil {
 		return "", err
 	}
@@ -891,6 +1037,46 @@ func GetZpoolEncryptionKeys() (map[string]string, error) {
 	return ret, nil
 }
 
+// RotatePoolKey replaces the wrapping key of an encrypted pool with a newly generated one.
+// The pool's data encryption keys are unchanged, so no data needs to be re-encrypted.
+func RotatePoolKey(ctx context.Context, pool string) error {
+	keyfilePath := "/var/lib/incus-os/zpool." + pool + ".key"
+	newKeyfilePath := keyfilePath + ".new"
+
+	// The current key must be loaded to change it.
+	keyStatus, err := subprocess.RunCommandContext(ctx, "zfs", "get", "keystatus", "-H", "-o", "value", pool)
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(keyStatus) != "available" {
+		return errors.New("encryption key for pool '" + pool + "' isn't loaded")
+	}
+
+	// Generate a new random encryption key.
+	err = util.GenerateEncryptionKeyFile(newKeyfilePath)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = os.Remove(newKeyfilePath) }()
+
+	// Replace the wrapping key, then move the new key into place.
+	_, err = subprocess.RunCommandContext(ctx, "zfs", "change-key", "-o", "keyformat=raw", "-o", "keylocation=file://"+newKeyfilePath, pool)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(newKeyfilePath, keyfilePath)
+	if err != nil {
+		return err
+	}
+
+	_, err = subprocess.RunCommandContext(ctx, "zfs", "set", "keylocation=file://"+keyfilePath, pool)
+
+	return err
+}
+
 // ImportExistingPool will import an existing but currently unmanaged ZFS pool.
 // After importing an encrypted pool, it will write the key to disk and then use it
 // to unlock the pool. Unencrypted pools are imported, but are not managed by IncusOS
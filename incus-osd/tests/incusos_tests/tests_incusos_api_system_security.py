@@ -1,4 +1,7 @@
+import base64
 import json
+import os
+import time
 
 from .incus_test_vm import IncusTestVM, IncusOSException, util
 
@@ -59,6 +62,114 @@ def TestIncusOSAPISystemSecurity(install_image):
         if not result["metadata"]["state"]["encryption_recovery_keys_retrieved"]:
             raise IncusOSException("invalid encryption_recovery_keys_retrieved state")
 
+def TestIncusOSAPISystemSecurityRotateKeys(install_image):
+    test_name = "incusos-api-system-security-rotate-keys"
+    test_seed = {
+        "install.json": "{}",
+    }
+
+    test_image, os_name, os_version, client_cert_name = util._prepare_test_image(install_image, test_seed)
+
+    with IncusTestVM(os_name, test_name, test_image, client_cert_name) as vm:
+        vm.WaitSystemReady(os_version)
+
+        # Get the current keys and mark them as retrieved.
+        result = vm.APIRequest("/1.0/system/security")
+        if result["status_code"] != 200:
+            raise IncusOSException("unexpected status code %d: %s" % (result["error_code"], result["error"]))
+
+        old_pool_keys = result["metadata"]["state"]["pool_recovery_keys"]
+        if "local" not in old_pool_keys:
+            raise IncusOSException("missing encryption key for the local pool")
+
+        result = vm.APIRequest("/1.0/system/security/:retrieved", method="POST")
+        if result["status_code"] != 200:
+            raise IncusOSException("unexpected status code %d: %s" % (result["error_code"], result["error"]))
+
+        # Rotate the keys.
+        result = vm.APIRequest("/1.0/system/security/:rotate-keys", method="POST")
+        if result["status_code"] != 200:
+            raise IncusOSException("unexpected status code %d: %s" % (result["error_code"], result["error"]))
+
+        # Verify the keys were replaced and need to be retrieved again.
+        result = vm.APIRequest("/1.0/system/security")
+        if result["status_code"] != 200:
+            raise IncusOSException("unexpected status code %d: %s" % (result["error_code"], result["error"]))
+
+        if result["metadata"]["state"]["pool_recovery_keys"]["local"] == old_pool_keys["local"]:
+            raise IncusOSException("encryption key for the local pool wasn't rotated")
+
+        if result["metadata"]["state"]["encryption_recovery_keys_retrieved"]:
+            raise IncusOSException("invalid encryption_recovery_keys_retrieved state")
+
+        for key in ["volume/root", "volume/swap", "pool/local"]:
+            if key not in result["metadata"]["state"]["encryption_keys_rotated"]:
+                raise IncusOSException("missing rotation timestamp for " + key)
+
+        # The system should still boot unattended using the re-sealed TPM bindings.
+        result = vm.APIRequest("/1.0/system/:reboot", method="POST")
+        if result["status_code"] != 200:
+            raise IncusOSException("unexpected status code %d: %s" % (result["error_code"], result["error"]))
+
+        time.sleep(5)
+
+        vm.WaitAgentRunning()
+        vm.WaitExpectedLog("incus-osd", "System is ready version="+os_version)
+
+        result = vm.APIRequest("/1.0/system/security")
+        if result["status_code"] != 200:
+            raise IncusOSException("unexpected status code %d: %s" % (result["error_code"], result["error"]))
+
+        if result["metadata"]["state"]["tpm_status"] != "ok":
+            raise IncusOSException("tpm_status != ok, got " + result["metadata"]["state"]["tpm_status"])
+
+def TestIncusOSAPISystemSecurityAttest(install_image):
+    test_name = "incusos-api-system-security-attest"
+    test_seed = {
+        "install.json": "{}",
+    }
+
+    test_image, os_name, os_version, client_cert_name = util._prepare_test_image(install_image, test_seed)
+
+    with IncusTestVM(os_name, test_name, test_image, client_cert_name) as vm:
+        vm.WaitSystemReady(os_version)
+
+        # A nonce is required.
+        result = vm.APIRequest("/1.0/system/security/:attest", method="POST", body=json.dumps({}))
+        if result["status_code"] != 0 or result["error"] != "the nonce must be between 1 and 32 bytes long":
+            raise IncusOSException("unexpected status code %d: %s" % (result["error_code"], result["error"]))
+
+        # Request a quote.
+        nonce = os.urandom(16)
+
+        result = vm.APIRequest("/1.0/system/security/:attest", method="POST", body=json.dumps({"nonce": base64.b64encode(nonce).decode()}))
+        if result["status_code"] != 200:
+            raise IncusOSException("unexpected status code %d: %s" % (result["error_code"], result["error"]))
+
+        attestation = result["metadata"]
+
+        # The quote must embed the nonce and cover PCRs 0-15.
+        if nonce not in base64.b64decode(attestation["quote"]):
+            raise IncusOSException("quote doesn't contain the provided nonce")
+
+        if len(attestation["pcrs"]) != 16:
+            raise IncusOSException("expected 16 PCR values, got %d" % len(attestation["pcrs"]))
+
+        if len(base64.b64decode(attestation["event_log"])) == 0:
+            raise IncusOSException("missing TPM event log")
+
+        # The attestation key certificate is issued by the TPM authentication key.
+        if len(attestation["certificates"]) != 2:
+            raise IncusOSException("expected a certificate chain of length two")
+
+        # A second quote reuses the same attestation key.
+        result = vm.APIRequest("/1.0/system/security/:attest", method="POST", body=json.dumps({"nonce": base64.b64encode(os.urandom(16)).decode()}))
+        if result["status_code"] != 200:
+            raise IncusOSException("unexpected status code %d: %s" % (result["error_code"], result["error"]))
+
+        if result["metadata"]["certificates"] != attestation["certificates"]:
+            raise IncusOSException("attestation key changed between quotes")
+
 def TestIncusOSAPISystemSecurityTPMRebind(install_image):
     test_name = "incusos-api-system-security-tpm-rebind"
     test_seed = {
@@ -20,7 +20,7 @@ for MP in $(dmsetup ls --target multipath | cut -f1); do
     # support disks with multiple backing devices (like mulitpath), so our normal reliance
     # on the systemd-repart service won't work.
     if [ ! -e "/dev/mapper/${MP}-part9" ] && [ -e "/dev/mapper/${MP}-part8" ]; then
-        systemd-repart --dry-run=no --definitions=/usr/lib/incus-os/repart.d/ "/dev/mapper/${MP}"
+        systemd-repart --dry-run=no --definitions=/run/incus-os/repart.d/ "/dev/mapper/${MP}"
 
         kpartx -p "-part" -a "/dev/mapper/${MP}"
     fi
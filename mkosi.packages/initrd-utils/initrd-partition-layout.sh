@@ -0,0 +1,37 @@
+#!/bin/sh
+
+# Generate the systemd-repart definitions, applying any partition sizes recorded on the ESP at install time.
+rm -rf /run/incus-os/repart.d/
+mkdir -p /run/incus-os/repart.d/
+cp /usr/lib/incus-os/repart.d/*.conf /run/incus-os/repart.d/
+
+if [ ! -e /boot/partition-layout ]; then
+    exit 0
+fi
+
+while IFS="=" read -r KEY VALUE; do
+    # Only accept plain sizes in bytes.
+    case "$VALUE" in
+        ""|*[!0-9]*)
+            continue
+            ;;
+    esac
+
+    case "$KEY" in
+        swap)
+            CONF="30-swap.conf"
+            ;;
+        root)
+            CONF="40-root.conf"
+            ;;
+        local-data)
+            CONF="50-local-data.conf"
+            ;;
+        *)
+            continue
+            ;;
+    esac
+
+    sed -i -e "/^SizeMinBytes=/d" -e "/^SizeMaxBytes=/d" "/run/incus-os/repart.d/${CONF}"
+    printf "SizeMinBytes=%s\nSizeMaxBytes=%s\n" "$VALUE" "$VALUE" >> "/run/incus-os/repart.d/${CONF}"
+done < /boot/partition-layout
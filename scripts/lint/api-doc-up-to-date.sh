@@ -6,11 +6,11 @@ cd "$(git rev-parse --show-toplevel)"
 make update-api
 
 # Check that the committed API spec is up to date.
-if [ -n "$(git status --porcelain -- doc/rest-api.yaml)" ]; then
-  git status -- doc/rest-api.yaml
-  echo "==> Please run 'make update-api' and commit the changes to doc/rest-api.yaml"
+if [ -n "$(git status --porcelain -- incus-osd/internal/openapi/rest-api.yaml)" ]; then
+  git status -- incus-osd/internal/openapi/rest-api.yaml
+  echo "==> Please run 'make update-api' and commit the changes to incus-osd/internal/openapi/rest-api.yaml"
   exit 1
 fi
 
 # Validate the API spec.
-swagger validate doc/rest-api.yaml
+swagger validate incus-osd/internal/openapi/rest-api.yaml